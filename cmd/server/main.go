@@ -2,10 +2,19 @@ package main // 声明当前文件属于 main 包，是程序的入口点
 
 // 导入所需的包
 import (
+	"campus-logistics/internal/fieldcrypt"
 	"campus-logistics/internal/handler" // 项目内部的处理函数包，包含业务逻辑处理器
 	"campus-logistics/internal/middleware"
 	"campus-logistics/internal/repository" // 项目内部的数据访问层包，负责数据库操作
-	"log"                                  // Go标准日志库，用于记录程序运行状态
+	"campus-logistics/internal/service"
+	"campus-logistics/internal/storage"
+	"campus-logistics/internal/validation"
+	"context"
+	"errors"
+	"log" // Go标准日志库，用于记录程序运行状态
+	"net/http"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/gin-gonic/gin" // Gin Web框架，用于构建HTTP API服务器
@@ -41,10 +50,45 @@ func main() {
 		log.Fatalf("Database initialization failed: %s", err)
 	}
 
+	// 手机号字段加密密钥环；启动时先加密旧版明文手机号（按手机号查找只走盲索引）
+	if err := fieldcrypt.Init(); err != nil {
+		log.Fatalf("Field encryption initialization failed: %s", err)
+	}
+	if err := service.MigrateUserPhones(); err != nil {
+		log.Fatalf("Phone encryption migration failed: %s", err)
+	}
+
+	// 附件存储（本地目录或 S3 兼容存储）
+	if err := storage.Init(); err != nil {
+		log.Fatalf("Attachment storage initialization failed: %s", err)
+	}
+
+	// 注册请求校验规则（手机号、运单号、货架编号、区域白名单等）
+	if err := validation.Register(); err != nil {
+		log.Fatalf("Register validators failed: %s", err)
+	}
+
+	// ==================== 限流初始化部分 ====================
+	// 多副本部署时使用 postgres 共享令牌桶，否则使用进程内存
+	var rateStore middleware.RateLimitStore = middleware.NewMemoryRateLimitStore()
+	if viper.GetString("rate_limit.backend") == "postgres" {
+		rateStore = repository.PostgresRateLimitStore{}
+	}
+
+	// 幂等键存储：入库、取件、状态变更可携带 Idempotency-Key 安全重试
+	idemStore := repository.PostgresIdempotencyStore{}
+
 	// ==================== 路由初始化部分 ====================
 	// 创建一个默认的Gin引擎实例
 	// Default()函数会附加Logger和Recovery两个中间件，用于日志记录和错误恢复
 	r := gin.Default()
+	// 只信任 server.trusted_proxies 中的反向代理转发的客户端 IP；未配置时 ClientIP 使用连接的对端地址，
+	// 避免客户端伪造 X-Forwarded-For 绕过按 IP 限流
+	if err := r.SetTrustedProxies(viper.GetStringSlice("server.trusted_proxies")); err != nil {
+		log.Fatalf("Invalid server.trusted_proxies: %s", err)
+	}
+	// 统一错误响应：handler 通过 c.Error 上报的错误在这里转换为 {"error", "code"}
+	r.Use(middleware.ErrorHandler())
 
 	// 创建API版本分组v1，所有以/api/v1开头的请求都会进入该分组
 	v1 := r.Group("/api/v1")
@@ -52,53 +96,194 @@ func main() {
 		// 认证接口
 		auth := v1.Group("/auth")
 		{
-			auth.POST("/admin/login", handler.AdminLoginHandler)
-			auth.POST("/student/login", handler.StudentLoginHandler)
-			auth.POST("/courier/login", handler.CourierLoginHandler)
+			auth.POST("/admin/login", middleware.RateLimit(rateStore, "admin_login"), handler.AdminLoginHandler)
+			auth.POST("/admin/mfa", middleware.RateLimit(rateStore, "admin_login"), handler.AdminMFAHandler)
+			auth.POST("/student/login", middleware.RateLimit(rateStore, "student_login"), handler.StudentLoginHandler)
+			auth.POST("/courier/login", middleware.RateLimit(rateStore, "courier_login"), handler.CourierLoginHandler)
 		}
 
 		// 学生接口（需要 JWT + student 角色）
 		student := v1.Group("", middleware.AuthRequired(), middleware.RequireRole(middleware.RoleStudent))
 		{
 			student.GET("/parcels", handler.GetMyParcelHandler)
-			student.POST("/pickup", handler.PickupHandler)
+			student.GET("/parcels/:tracking_number/qrcode", handler.PickupQRCodeHandler)
+			student.GET("/parcels/:tracking_number/attachments", handler.ListMyParcelAttachmentsHandler)
+			student.POST("/pickup", middleware.RateLimit(rateStore, "pickup"), middleware.Idempotency(idemStore), handler.PickupHandler)
+			student.GET("/pickup/token", handler.CounterPickupTokenHandler)
+			student.GET("/pickup/qrcode", handler.CounterPickupQRCodeHandler)
+			// 代取授权
+			student.POST("/delegations", handler.CreateDelegationHandler)
+			student.GET("/delegations", handler.ListDelegationsHandler)
+			student.DELETE("/delegations/:id", handler.RevokeDelegationHandler)
+			// 寄件：运费试算、下单、查询与取消
+			student.POST("/shipping/quote", handler.ShippingQuoteHandler)
+			student.POST("/shipments", middleware.Idempotency(idemStore), handler.CreateShipmentHandler)
+			student.GET("/shipments", handler.ListMyShipmentsHandler)
+			student.GET("/shipments/:order_no", handler.GetMyShipmentHandler)
+			student.POST("/shipments/:order_no/cancel", handler.CancelShipmentHandler)
+			// 本人资料与手机号变更
+			student.GET("/me", handler.GetMeHandler)
+			student.PATCH("/me", handler.UpdateMeHandler)
+			student.GET("/me/export", handler.ExportMeHandler)
+			student.POST("/me/phone", middleware.RateLimit(rateStore, "phone_change"), handler.RequestPhoneChangeHandler)
+			student.POST("/me/phone/verify", middleware.RateLimit(rateStore, "phone_change"), handler.ConfirmPhoneChangeHandler)
 		}
 
 		// 快递员接口（需要 JWT + courier 角色）
 		courier := v1.Group("", middleware.AuthRequired(), middleware.RequireRole(middleware.RoleCourier))
 		{
-			courier.POST("/inbound", handler.InboundHandler)
+			courier.POST("/inbound", middleware.Idempotency(idemStore), handler.InboundHandler)
+			courier.POST("/inbound/batch", middleware.Idempotency(idemStore), handler.BatchInboundHandler)
+			courier.POST("/inbound/:tracking_number/photos", handler.UploadInboundPhotoHandler)
 		}
 
 		courierAPI := v1.Group("/courier", middleware.AuthRequired(), middleware.RequireRole(middleware.RoleCourier))
 		{
 			courierAPI.GET("/tasks", handler.GetCourierTasksHandler)
+			courierAPI.GET("/tasks/summary", handler.GetCourierTaskSummaryHandler)
+			courierAPI.GET("/tasks/today", handler.GetCourierTodayTasksHandler)
+			courierAPI.GET("/tasks/:tracking_number", handler.GetCourierTaskHandler)
+			courierAPI.POST("/tasks/:tracking_number/phone", handler.RevealCourierTaskPhoneHandler)
+			// 退件：查看需取回的批次，扫码交接
+			courierAPI.GET("/returns", handler.CourierReturnsHandler)
+			courierAPI.POST("/returns/:id/handover", middleware.Idempotency(idemStore), handler.HandoverReturnsHandler)
+			// 寄件揽收
+			courierAPI.GET("/shipments", handler.CourierShipmentsHandler)
+			courierAPI.POST("/shipments/:order_no/collect", middleware.Idempotency(idemStore), handler.CollectShipmentHandler)
 		}
 	}
 
-	// 管理员相关接口分组 /api/v1/admin（需要 JWT + admin 角色）
-	admin := r.Group("/api/v1/admin", middleware.AuthRequired(), middleware.RequireRole(middleware.RoleAdmin))
+	// 管理员账号自助接口：修改密码、绑定 MFA（密码过期或尚未绑定 MFA 时也允许访问）
+	adminAccount := r.Group("/api/v1/admin/account", middleware.AuthRequired(), middleware.RequireRole(middleware.RoleAdmin))
+	{
+		adminAccount.POST("/password", handler.ChangeAdminPasswordHandler)
+		adminAccount.GET("/mfa", handler.GetMFAStatusHandler)
+		adminAccount.POST("/mfa/enroll", handler.BeginMFAEnrollmentHandler)
+		adminAccount.POST("/mfa/activate", handler.ActivateMFAHandler)
+		adminAccount.DELETE("/mfa", handler.DisableMFAHandler)
+		adminAccount.POST("/mfa/recovery-codes", handler.RegenerateRecoveryCodesHandler)
+	}
+
+	// 管理员相关接口分组 /api/v1/admin（需要 JWT + admin 角色，密码未过期且满足 MFA 要求）
+	admin := r.Group("/api/v1/admin",
+		middleware.AuthRequired(),
+		middleware.RequireRole(middleware.RoleAdmin),
+		middleware.RequirePasswordFresh(),
+		middleware.RequireMFASatisfied(),
+	)
 	{
 		// 仪表盘统计数据
 		admin.GET("/dashboard", handler.AdminDashboardHandler)
+		admin.GET("/analytics", handler.AdminAnalyticsHandler)
+		admin.GET("/stats/daily", handler.AdminDailyStatsHandler)
+		// 报表导出与定时报表
+		admin.GET("/reports/files", handler.ListReportFilesHandler)
+		admin.GET("/reports/files/:name", handler.DownloadReportFileHandler)
+		admin.GET("/reports/:kind", handler.ExportReportHandler)
 		// 滞留包裹查询
 		admin.GET("/parcels/retention", handler.GetRetentionParcelsHandler)
 		// 包裹状态更新（待取、异常、退回等）
-		admin.POST("/parcels/:tracking_number/status", handler.UpdateParcelStatusHandler)
+		admin.POST("/parcels/:tracking_number/status", middleware.Idempotency(idemStore), handler.UpdateParcelStatusHandler)
+		// 包裹附件（入库照片、取件照片、签名）
+		admin.GET("/parcels/:tracking_number/attachments", handler.ListParcelAttachmentsHandler)
+		// 查看收件人完整手机号（列表中默认脱敏，查看记录写入 phone_access_logs）
+		admin.POST("/parcels/:tracking_number/phone", handler.RevealParcelPhoneHandler)
+
+		// 退件批次（按快递公司取回滞留或异常包裹）
+		admin.GET("/returns", handler.ListReturnBatchesHandler)
+		admin.POST("/returns", middleware.Idempotency(idemStore), handler.CreateReturnBatchHandler)
+		admin.POST("/returns/retention", handler.CreateRetentionReturnsHandler)
+		admin.GET("/returns/:id", handler.GetReturnBatchHandler)
+		admin.DELETE("/returns/:id", handler.CancelReturnBatchHandler)
+
+		// 寄件订单与运费表
+		admin.GET("/shipments", handler.AdminShipmentsHandler)
+		admin.GET("/shipments/:order_no", handler.AdminShipmentHandler)
+		admin.GET("/shipping/rates", handler.ListShippingRatesHandler)
+		admin.PUT("/shipping/rates/:courier/:zone", handler.SetShippingRateHandler)
+		admin.DELETE("/shipping/rates/:courier/:zone", handler.DeleteShippingRateHandler)
 
 		// 快递公司管理
 		admin.GET("/couriers", handler.ListCouriersHandler)
 		admin.POST("/couriers", handler.CreateCourierHandler)
+		admin.GET("/couriers/detect", handler.DetectCourierHandler)
+		admin.PATCH("/couriers/:code", handler.UpdateCourierHandler)
 		admin.DELETE("/couriers/:code", handler.DeleteCourierHandler)
 
 		// 货架管理
 		admin.GET("/shelves", handler.ListShelvesHandler)
+		admin.GET("/shelves/labels", handler.ShelfLabelSheetHandler)
+		admin.GET("/shelves/:code/label", handler.ShelfLabelHandler)
 		admin.POST("/shelves", handler.CreateShelfHandler)
 		admin.DELETE("/shelves/:code", handler.DeleteShelfHandler)
+
+		// 管理员账号管理（仅超级管理员）
+		superAdmin := admin.Group("/admins", middleware.RequireAdminRole(middleware.AdminRoleSuper))
+		{
+			superAdmin.GET("", handler.ListAdminsHandler)
+			superAdmin.POST("", handler.CreateAdminHandler)
+			superAdmin.PATCH("/:username", handler.UpdateAdminHandler)
+			superAdmin.DELETE("/:username", handler.DeleteAdminHandler)
+		}
+
+		// 站点管理（查询对所有管理员开放，增删仅超级管理员）
+		admin.GET("/stations", handler.ListStationsHandler)
+		stations := admin.Group("/stations", middleware.RequireAdminRole(middleware.AdminRoleSuper))
+		{
+			stations.POST("", handler.CreateStationHandler)
+			stations.DELETE("/:code", handler.DeleteStationHandler)
+		}
+
+		// 每日统计汇总回填（仅超级管理员）
+		admin.POST("/stats/rollup", middleware.RequireAdminRole(middleware.AdminRoleSuper), handler.RollupDailyStatsHandler)
+
+		// 用户查询、重复账号合并与个人信息删除（仅超级管理员）
+		users := admin.Group("/users", middleware.RequireAdminRole(middleware.AdminRoleSuper))
+		{
+			users.GET("", handler.SearchUsersHandler)
+			users.POST("/merge", handler.MergeUsersHandler)
+			users.POST("/erase", handler.EraseUserHandler)
+		}
+
+		// 学生、快递公司批量导入（仅超级管理员）
+		imports := admin.Group("/imports", middleware.RequireAdminRole(middleware.AdminRoleSuper))
+		{
+			imports.GET("", handler.ListImportJobsHandler)
+			imports.POST("/:kind", handler.StartImportHandler)
+			imports.GET("/:id", handler.GetImportJobHandler)
+		}
+
+		// 安全设置（仅超级管理员）
+		settings := admin.Group("/settings", middleware.RequireAdminRole(middleware.AdminRoleSuper))
+		{
+			settings.GET("/security", handler.GetSecuritySettingsHandler)
+			settings.PUT("/security", handler.UpdateSecuritySettingsHandler)
+		}
 	}
 
+	// 驿站柜台接口（管理员扫码核验取件，要求与后台接口相同）
+	station := r.Group("/api/v1/station",
+		middleware.AuthRequired(),
+		middleware.RequireRole(middleware.RoleAdmin),
+		middleware.RequirePasswordFresh(),
+		middleware.RequireMFASatisfied(),
+	)
+	{
+		station.POST("/pickup/verify", middleware.Idempotency(idemStore), handler.VerifyCounterPickupHandler)
+		// 驿站代收入库（快递公司可由运单号识别）
+		station.POST("/inbound", middleware.Idempotency(idemStore), handler.StationInboundHandler)
+		station.POST("/inbound/batch", middleware.Idempotency(idemStore), handler.BatchStationInboundHandler)
+		station.POST("/parcels/:tracking_number/attachments", handler.UploadStationAttachmentHandler)
+		// 寄件收件（称重、确认运费）
+		station.GET("/shipments", handler.StationShipmentsHandler)
+		station.POST("/shipments/:order_no/dropoff", middleware.Idempotency(idemStore), handler.DropOffShipmentHandler)
+	}
+
+	// 附件下载：凭签名地址访问（地址由附件列表接口按权限签发，短期有效）
+	r.GET("/api/v1/attachments/:id", handler.DownloadAttachmentHandler)
+
 	// 定义健康检查端点：GET /ping
-	// 用于检查服务器和数据库的健康状态
+	// 用于快速判断服务可达（兼容旧的探活配置；存活 / 就绪检查请使用 /healthz 与 /readyz）
 	r.GET("/ping", func(c *gin.Context) {
 		// 返回JSON响应，包含服务器状态和数据库连接状态
 		c.JSON(200, gin.H{
@@ -106,35 +291,172 @@ func main() {
 			"db_status": "connected", // 表示数据库连接正常
 		})
 	})
+	// 存活检查：进程存活即 200；就绪检查：停机排空中或数据库不可用时 503
+	r.GET("/healthz", handler.LivenessHandler)
+	r.GET("/readyz", handler.ReadinessHandler)
 
 	// ==================== 服务器启动部分 ====================
 	// 从Viper配置中获取服务器端口号
 	// 配置项"server.port"需要在配置文件中定义
 	port := viper.GetString("server.port")
 
+	// 使用 http.Server 而不是 r.Run，以便设置超时并在停机时排空请求
+	srv := &http.Server{
+		Addr:              ":" + port,
+		Handler:           r,
+		ReadTimeout:       configSeconds("server.read_timeout_seconds", 30),
+		ReadHeaderTimeout: configSeconds("server.read_header_timeout_seconds", 10),
+		WriteTimeout:      configSeconds("server.write_timeout_seconds", 120),
+		IdleTimeout:       configSeconds("server.idle_timeout_seconds", 120),
+	}
+
+	// SIGINT（Ctrl+C）与 SIGTERM（docker stop）触发优雅停机
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	// 后台任务使用独立的 context：收到停机信号立即取消，当前任务执行完后退出
+	// 管理员发起的名单导入同样使用该 context，停机时在当前行处理完后中止
+	jobsCtx, cancelJobs := context.WithCancel(context.Background())
+	service.SetImportContext(jobsCtx)
+	jobsDone := make(chan struct{})
+	go func() {
+		defer close(jobsDone)
+		runBackgroundJobs(jobsCtx)
+	}()
+
 	// 在控制台输出服务器启动信息
 	log.Printf("Server starting on port %s... ", port)
 
-	// Background expiry marker: write EXPIRED audit logs for parcels older than 3 days.
-	// This does not change database schema or parcel status; it records an immutable event.
+	// 启动HTTP服务器，监听指定端口
+	// ListenAndServe 会阻塞，因此放在单独的 goroutine 中；Shutdown 后返回 http.ErrServerClosed
 	go func() {
-		// run once on boot
-		if n, err := repository.InsertExpiredAuditLogs(3); err != nil {
-			log.Printf("expiry job failed: %v", err)
-		} else if n > 0 {
-			log.Printf("expiry job inserted %d audit logs", n)
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("Server failed: %s", err)
 		}
+	}()
+
+	// ==================== 优雅停机部分 ====================
+	<-ctx.Done()
+	// 恢复默认信号处理：再次按 Ctrl+C 立即退出
+	stop()
+	log.Println("Shutdown signal received, draining...")
+
+	// 1) 就绪检查改为 503 并停止后台任务；等待 drain_delay 让负载均衡摘除本实例
+	handler.SetDraining()
+	cancelJobs()
+	time.Sleep(configSeconds("server.drain_delay_seconds", 0))
+
+	// 2) 停止接受新连接，等待进行中的请求完成（最长 shutdown_timeout）
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), configSeconds("server.shutdown_timeout_seconds", 25))
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Printf("HTTP server shutdown incomplete: %v", err)
+	}
+
+	// 3) 等待后台任务与导入任务退出；HTTP 服务已停止，不会再创建新的导入任务。超时则不再等待（中断的事务由数据库回滚）
+	allJobsDone := make(chan struct{})
+	go func() {
+		<-jobsDone
+		service.WaitImports()
+		close(allJobsDone)
+	}()
+	select {
+	case <-allJobsDone:
+	case <-shutdownCtx.Done():
+		log.Println("Background jobs did not stop before shutdown timeout")
+	}
+
+	// 4) 最后关闭数据库连接池
+	if err := repository.CloseDB(); err != nil {
+		log.Printf("Close database failed: %v", err)
+	}
+	log.Println("Server stopped")
+}
+
+// configSeconds 读取以秒为单位的配置项；未配置时使用默认值
+func configSeconds(key string, def int) time.Duration {
+	if viper.IsSet(key) {
+		return time.Duration(viper.GetInt(key)) * time.Second
+	}
+	return time.Duration(def) * time.Second
+}
+
+// runBackgroundJobs 后台定时任务：启动时执行一次，之后每 10 分钟执行一次，直到 ctx 取消。
+// 停机时不会中断正在执行的任务，而是在任务之间检查 ctx 并退出
+func runBackgroundJobs(ctx context.Context) {
+	perishableHours := viper.GetInt("retention.perishable_hours")
 
-		ticker := time.NewTicker(10 * time.Minute)
-		defer ticker.Stop()
-		for range ticker.C {
-			if _, err := repository.InsertExpiredAuditLogs(3); err != nil {
+	// Background expiry marker: write EXPIRED audit logs for parcels older than 3 days
+	// (perishable parcels: retention.perishable_hours).
+	// This does not change database schema or parcel status; it records an immutable event.
+	// run once on boot
+	boot := []func(){
+		func() {
+			if n, err := repository.InsertExpiredAuditLogs(3, perishableHours); err != nil {
+				log.Printf("expiry job failed: %v", err)
+			} else if n > 0 {
+				log.Printf("expiry job inserted %d audit logs", n)
+			}
+		},
+		service.RunDailyRollups,
+		func() { service.RunPhoneEncryption(ctx) },
+	}
+	periodic := []func(){
+		func() {
+			if _, err := repository.InsertExpiredAuditLogs(3, perishableHours); err != nil {
 				log.Printf("expiry job failed: %v", err)
 			}
+		},
+		func() {
+			if _, err := repository.PurgeExpiredIdempotencyKeys(middleware.IdempotencyTTL()); err != nil {
+				log.Printf("idempotency purge failed: %v", err)
+			}
+		},
+		func() {
+			if _, err := repository.PurgeExpiredPickupTokenUses(); err != nil {
+				log.Printf("pickup token purge failed: %v", err)
+			}
+		},
+		service.RunRetentionReturns,
+		service.RunDailyRollups,
+		service.RunScheduledReports,
+		func() { service.RunPhoneEncryption(ctx) },
+		service.RunPhoneRedaction,
+		service.RunUserRetention,
+		// 进程重启会中断正在运行的导入任务
+		func() {
+			if _, err := repository.FailStaleImportJobs(10 * time.Minute); err != nil {
+				log.Printf("import job sweep failed: %v", err)
+			}
+		},
+		func() {
+			if viper.GetString("rate_limit.backend") == "postgres" {
+				if _, err := repository.PurgeStaleRateLimitBuckets(time.Hour); err != nil {
+					log.Printf("rate limit purge failed: %v", err)
+				}
+			}
+		},
+	}
+
+	runJobs(ctx, boot)
+	ticker := time.NewTicker(10 * time.Minute)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			runJobs(ctx, periodic)
 		}
-	}()
+	}
+}
 
-	// 启动HTTP服务器，监听指定端口
-	// Run()函数会阻塞当前goroutine，直到服务器关闭
-	r.Run(":" + port)
+// runJobs 依次执行任务，ctx 取消后不再开始新的任务
+func runJobs(ctx context.Context, jobs []func()) {
+	for _, job := range jobs {
+		if ctx.Err() != nil {
+			return
+		}
+		job()
+	}
 }
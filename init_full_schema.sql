@@ -23,36 +23,134 @@ CREATE TYPE parcel_status AS ENUM (
     'exception'  -- 异常
 );
 
+-- 包裹尺寸等级 (枚举按声明顺序比较大小，用于货架分配)
+CREATE TYPE parcel_size AS ENUM (
+    'document',  -- 文件、信件
+    'small',     -- 小件 (默认)
+    'medium',    -- 中件
+    'large',     -- 大件
+    'bulky'      -- 超大件
+);
+
+-- 寄件订单状态：学生下单 -> 驿站收件 -> 快递员揽收；下单后、收件前可取消
+CREATE TYPE shipment_status AS ENUM (
+    'created',     -- 已下单
+    'dropped_off', -- 驿站已收件 (称重、确认运费)
+    'collected',   -- 快递员已揽收 (登记运单号)
+    'cancelled'    -- 已取消
+);
+
 -- ============================================================
 -- 2. 实体层 (Tables) - 3NF 设计
 -- ============================================================
 
+-- [2.0] 驿站/取件点 (多站点: 北门、宿舍区、图书馆...)
+CREATE TABLE stations (
+    id SERIAL PRIMARY KEY,
+    code VARCHAR(20) NOT NULL UNIQUE,  -- 站点编号，如 NORTH / DORM / LIB
+    name VARCHAR(50) NOT NULL UNIQUE,
+    address VARCHAR(200),
+    created_at TIMESTAMPTZ DEFAULT NOW()
+);
+
 -- [2.1] 系统管理员表 (新增: 用于后台登录)
 CREATE TABLE admins (
     id SERIAL PRIMARY KEY,
     username VARCHAR(50) NOT NULL UNIQUE,
     password_hash VARCHAR(100) NOT NULL, -- 生产环境请存储 Bcrypt 哈希
-    role VARCHAR(20) DEFAULT 'super_admin',
+    role VARCHAR(20) DEFAULT 'super_admin', -- super_admin / operator
+    station_id INT REFERENCES stations(id) ON DELETE RESTRICT, -- NULL 表示可管理全部站点
     last_login_at TIMESTAMPTZ,
+    failed_attempts INT NOT NULL DEFAULT 0,  -- 连续登录失败次数
+    locked_until TIMESTAMPTZ,                -- 锁定截止时间 (NULL 表示未锁定)
+    password_changed_at TIMESTAMPTZ DEFAULT NOW(),
+    totp_secret VARCHAR(64),                 -- TOTP 密钥 (Base32)，启用前为待激活状态
+    mfa_enabled BOOLEAN NOT NULL DEFAULT FALSE,
+    mfa_enrolled_at TIMESTAMPTZ,
+    totp_last_step BIGINT NOT NULL DEFAULT 0, -- 最近一次使用的时间步，防止验证码重放
+    created_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+-- [2.1.1] 管理员历史密码 (用于密码策略: 禁止重复使用最近 N 个密码)
+CREATE TABLE admin_password_history (
+    id BIGSERIAL PRIMARY KEY,
+    admin_id INT NOT NULL REFERENCES admins(id) ON DELETE CASCADE,
+    password_hash VARCHAR(100) NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+-- [2.1.2] 管理员 MFA 恢复码 (仅存 SHA-256 哈希，一次性使用)
+CREATE TABLE admin_recovery_codes (
+    id BIGSERIAL PRIMARY KEY,
+    admin_id INT NOT NULL REFERENCES admins(id) ON DELETE CASCADE,
+    code_hash CHAR(64) NOT NULL,
+    used_at TIMESTAMPTZ,
     created_at TIMESTAMPTZ DEFAULT NOW()
 );
 
+-- [2.1.3] 系统设置 (键值对，如 admin_mfa_required)
+CREATE TABLE system_settings (
+    key VARCHAR(64) PRIMARY KEY,
+    value TEXT NOT NULL,
+    updated_by VARCHAR(50),
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+-- [2.1.4] 限流令牌桶 (rate_limit.backend = postgres 时使用，多副本共享)
+CREATE TABLE rate_limit_buckets (
+    key CHAR(64) PRIMARY KEY,         -- SHA-256(rl:<group>:<维度>:<值>) 十六进制
+    tokens DOUBLE PRECISION NOT NULL,
+    allowed BOOLEAN NOT NULL DEFAULT TRUE, -- 最近一次请求是否放行
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- [2.1.5] 幂等键 (Idempotency-Key 请求头，用于入库/取件/状态变更的安全重试)
+CREATE TABLE idempotency_keys (
+    key VARCHAR(300) PRIMARY KEY,       -- <角色>:<身份>:<方法> <路由>:<Idempotency-Key>
+    request_hash CHAR(64) NOT NULL,     -- 请求方法 + 路径 + 请求体的 SHA-256
+    completed BOOLEAN NOT NULL DEFAULT FALSE,
+    status_code INT NOT NULL DEFAULT 0,
+    content_type VARCHAR(100) NOT NULL DEFAULT '',
+    response_body BYTEA,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    locked_at TIMESTAMPTZ NOT NULL DEFAULT NOW(), -- 占用时间：未完成且超过租约 (idempotency.lease_seconds) 的占用可被重试请求接管
+    completed_at TIMESTAMPTZ
+);
+
+-- [2.1.6] 柜台取件凭证使用记录 (凭证一次性使用，防止重放)
+CREATE TABLE pickup_token_uses (
+    nonce VARCHAR(32) PRIMARY KEY,      -- 凭证随机数
+    user_id BIGINT NOT NULL,
+    operator VARCHAR(50) NOT NULL,      -- 扫码核验的管理员
+    expires_at TIMESTAMPTZ NOT NULL,    -- 凭证过期时间，过期后记录可清理
+    used_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
 -- [2.2] 快递公司字典
 CREATE TABLE couriers (
     id SERIAL PRIMARY KEY,
     name VARCHAR(50) NOT NULL UNIQUE, 
     code VARCHAR(20) NOT NULL UNIQUE, 
     contact_phone VARCHAR(20),
+    -- 运单号规则：入库校验与按运单号识别快递公司
+    tracking_pattern VARCHAR(200),    -- 运单号格式（正则），为空不限制
+    tracking_min_length INT NOT NULL DEFAULT 0 CHECK (tracking_min_length >= 0), -- 0 表示不限制
+    tracking_max_length INT NOT NULL DEFAULT 0 CHECK (tracking_max_length >= 0),
+    tracking_checksum VARCHAR(20),    -- 校验位算法，如 s10（万国邮联 S10），为空不校验
     created_at TIMESTAMPTZ DEFAULT NOW()
 );
 
 -- [2.3] 货架资源管理
 CREATE TABLE shelves (
     id SERIAL PRIMARY KEY,
+    station_id INT NOT NULL REFERENCES stations(id) ON DELETE RESTRICT,
     zone VARCHAR(10) NOT NULL,        -- 区域
     code VARCHAR(20) NOT NULL UNIQUE, -- 物理编号
     capacity INT DEFAULT 50,
     current_load INT DEFAULT 0,
+    -- 货架能力：可存放的最大尺寸与温控类型 (冷链包裹只能放冷藏货架)
+    max_size parcel_size NOT NULL DEFAULT 'large',
+    temperature VARCHAR(10) NOT NULL DEFAULT 'ambient' CHECK (temperature IN ('ambient', 'chilled')),
     updated_at TIMESTAMPTZ DEFAULT NOW(),
     CONSTRAINT check_capacity CHECK (current_load <= capacity)
 );
@@ -61,8 +159,53 @@ CREATE TABLE shelves (
 CREATE TABLE users (
     id BIGSERIAL PRIMARY KEY,
     student_id VARCHAR(20),           -- 学号
-    phone VARCHAR(20) NOT NULL UNIQUE,-- 核心身份标识
+    -- 手机号 (核心身份标识) 由应用层加密保存，见 internal/fieldcrypt
+    phone_enc TEXT,                   -- 密文 <密钥 id>:<base64>
+    phone_hash CHAR(64) UNIQUE,       -- 盲索引 HMAC-SHA256，用于按手机号查找与唯一约束
+    phone VARCHAR(20) UNIQUE,         -- 旧版明文，仅加密迁移前的数据；迁移后为 NULL
     name VARCHAR(50) DEFAULT '同学',
+    dorm VARCHAR(100),                -- 宿舍
+    -- 通知偏好
+    notify_inbound BOOLEAN NOT NULL DEFAULT TRUE,    -- 包裹入库提醒
+    notify_retention BOOLEAN NOT NULL DEFAULT TRUE,  -- 滞留提醒
+    erased_at TIMESTAMPTZ,            -- 个人信息已删除 (匿名化) 的时间，见 func_erase_user
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+-- [2.4.1] 手机号变更验证码 (发送到新手机号，仅存 SHA-256 哈希；同一用户只保留最新一条)
+CREATE TABLE phone_change_requests (
+    user_id BIGINT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    new_phone TEXT NOT NULL,              -- 新手机号密文 (应用层加密)
+    code_hash VARCHAR(64) NOT NULL,
+    attempts INT NOT NULL DEFAULT 0,      -- 验证失败次数，达到上限后需重新获取
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+-- [2.4.2] 用户变更记录 (手机号变更与重复账号合并，不可变)
+CREATE TABLE user_change_logs (
+    id BIGSERIAL PRIMARY KEY,
+    action VARCHAR(20) NOT NULL CHECK (action IN ('PHONE_CHANGE', 'MERGE')),
+    user_id BIGINT NOT NULL,              -- 保留的用户
+    source_user_id BIGINT,                -- 合并时被删除的用户
+    old_phone TEXT,                       -- 手机号密文 (应用层加密)
+    new_phone TEXT,
+    parcels_moved INT NOT NULL DEFAULT 0,
+    reason VARCHAR(200),
+    operator VARCHAR(50) NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+-- [2.4.3] 个人信息删除记录 (管理员按学生申请删除，或超过保留期限后自动删除；不记录被删除的内容，不可变)
+CREATE TABLE user_erasure_logs (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL,
+    source VARCHAR(20) NOT NULL CHECK (source IN ('request', 'retention')), -- 学生申请 / 保留期限到期
+    parcels_anonymized INT NOT NULL DEFAULT 0,
+    shipments_anonymized INT NOT NULL DEFAULT 0,
+    reason VARCHAR(200),
+    operator VARCHAR(50) NOT NULL,        -- 管理员用户名 / SYSTEM
     created_at TIMESTAMPTZ DEFAULT NOW()
 );
 
@@ -75,14 +218,25 @@ CREATE TABLE parcels (
     user_id BIGINT NOT NULL REFERENCES users(id),
     courier_id INT NOT NULL REFERENCES couriers(id),
     shelf_id INT REFERENCES shelves(id) ON DELETE SET NULL,
+    station_id INT NOT NULL REFERENCES stations(id),
     
     -- 核心业务字段
     pickup_code VARCHAR(20),              -- 取件码
     status parcel_status NOT NULL DEFAULT 'inbound',
+
+    -- 物理属性 (入库时登记，用于货架分配与滞留提醒)
+    size_class parcel_size NOT NULL DEFAULT 'small',
+    weight_grams INT CHECK (weight_grams > 0),
+    fragile BOOLEAN NOT NULL DEFAULT FALSE,
+    perishable BOOLEAN NOT NULL DEFAULT FALSE,          -- 生鲜/易腐，滞留提醒提前
+    cold_chain BOOLEAN NOT NULL DEFAULT FALSE,          -- 需冷藏
+    declared_value_cents BIGINT CHECK (declared_value_cents >= 0), -- 声明价值 (分)
     
     -- 冗余快照 (用于历史追溯)
     recipient_name_snapshot VARCHAR(64),
-    recipient_phone_snapshot VARCHAR(20),
+    recipient_phone_enc TEXT,             -- 收件人手机号密文 (应用层加密)
+    recipient_phone_masked VARCHAR(20),   -- 脱敏形式 138****1234，用于列表展示与尾号搜索
+    recipient_phone_snapshot VARCHAR(20), -- 旧版明文，仅加密迁移前的数据；迁移后为 NULL
     
     -- 时间戳
     created_at TIMESTAMPTZ DEFAULT NOW(),
@@ -90,17 +244,175 @@ CREATE TABLE parcels (
     picked_up_at TIMESTAMPTZ
 );
 
+-- [2.5.1] 代取授权 (包裹所有人授权他人在时间窗口内代取单个或全部包裹)
+CREATE TABLE pickup_delegations (
+    id BIGSERIAL PRIMARY KEY,
+    owner_id BIGINT NOT NULL REFERENCES users(id),
+    delegate_phone TEXT NOT NULL,         -- 被授权人手机号密文 (应用层加密，可尚未注册)
+    delegate_phone_hash CHAR(64),         -- 被授权人手机号盲索引，与 users.phone_hash 匹配
+    parcel_id BIGINT REFERENCES parcels(id) ON DELETE CASCADE, -- NULL 表示全部包裹
+    starts_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMPTZ NOT NULL,
+    revoked_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    CONSTRAINT check_delegation_window CHECK (expires_at > starts_at)
+);
+
+-- [2.5.2] 包裹附件 (入库照片、取件照片、签名；文件保存在对象存储中，此处只存元数据)
+CREATE TABLE parcel_attachments (
+    id BIGSERIAL PRIMARY KEY,
+    parcel_id BIGINT NOT NULL REFERENCES parcels(id) ON DELETE CASCADE,
+    kind VARCHAR(20) NOT NULL CHECK (kind IN ('inbound_photo', 'pickup_photo', 'signature')),
+    storage_key VARCHAR(200) NOT NULL UNIQUE,
+    thumb_key VARCHAR(200),               -- 缩略图
+    content_type VARCHAR(50) NOT NULL,
+    size_bytes BIGINT NOT NULL,
+    width INT NOT NULL,
+    height INT NOT NULL,
+    sha256 CHAR(64) NOT NULL,
+    uploaded_by VARCHAR(50) NOT NULL,     -- courier:<快递公司代码> / admin:<用户名>
+    created_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+-- [2.5.3] 退件批次 (按快递公司 + 站点汇总需退回的包裹，快递员扫码交接)
+CREATE TABLE return_batches (
+    id BIGSERIAL PRIMARY KEY,
+    courier_id INT NOT NULL REFERENCES couriers(id),
+    station_id INT NOT NULL REFERENCES stations(id),
+    status VARCHAR(20) NOT NULL DEFAULT 'open' CHECK (status IN ('open', 'completed', 'cancelled')),
+    source VARCHAR(20) NOT NULL CHECK (source IN ('manual', 'retention')), -- 管理员创建 / 滞留策略生成
+    created_by VARCHAR(50) NOT NULL,      -- admin:<用户名> / SYSTEM
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    closed_at TIMESTAMPTZ                 -- 全部交接或取消的时间
+);
+
+CREATE TABLE return_batch_items (
+    batch_id BIGINT NOT NULL REFERENCES return_batches(id) ON DELETE CASCADE,
+    parcel_id BIGINT NOT NULL REFERENCES parcels(id),
+    handed_over_at TIMESTAMPTZ,
+    handed_over_by VARCHAR(64),           -- 交接的快递员工作人员
+    PRIMARY KEY (batch_id, parcel_id)
+);
+
+-- [2.5.4] 寄件运费表 (每个快递公司按区域配置首重/续重价格；区域由收件省份按配置 shipping.zones 划分)
+CREATE TABLE shipping_rates (
+    id SERIAL PRIMARY KEY,
+    courier_id INT NOT NULL REFERENCES couriers(id) ON DELETE CASCADE,
+    zone VARCHAR(20) NOT NULL,
+    first_weight_grams INT NOT NULL CHECK (first_weight_grams > 0),       -- 首重
+    first_price_cents INT NOT NULL CHECK (first_price_cents >= 0),
+    additional_weight_grams INT NOT NULL CHECK (additional_weight_grams > 0), -- 续重计费单位
+    additional_price_cents INT NOT NULL CHECK (additional_price_cents >= 0),
+    updated_at TIMESTAMPTZ DEFAULT NOW(),
+    UNIQUE (courier_id, zone)
+);
+
+-- [2.5.5] 寄件订单
+CREATE SEQUENCE shipment_order_seq;
+CREATE TABLE shipments (
+    id BIGSERIAL PRIMARY KEY,
+    order_no VARCHAR(20) NOT NULL UNIQUE
+        DEFAULT 'SH' || to_char(NOW(), 'YYMMDD') || LPAD(nextval('shipment_order_seq')::TEXT, 6, '0'),
+    user_id BIGINT NOT NULL REFERENCES users(id),
+    courier_id INT NOT NULL REFERENCES couriers(id),
+    station_id INT NOT NULL REFERENCES stations(id),  -- 交寄的驿站
+    status shipment_status NOT NULL DEFAULT 'created',
+
+    sender_name VARCHAR(50) NOT NULL,
+    sender_phone TEXT NOT NULL,           -- 手机号密文 (应用层加密)；删除个人信息后为 '-'
+    recipient_name VARCHAR(50) NOT NULL,
+    recipient_phone TEXT NOT NULL,        -- 手机号密文 (应用层加密)；删除个人信息后为 '-'
+    recipient_province VARCHAR(20) NOT NULL,
+    recipient_address VARCHAR(200) NOT NULL,
+
+    -- 运费：下单时按申报重量报价，驿站称重后按实际重量重新计算
+    zone VARCHAR(20) NOT NULL,
+    weight_grams INT NOT NULL CHECK (weight_grams > 0),
+    price_cents INT NOT NULL CHECK (price_cents >= 0),
+    tracking_number VARCHAR(64),          -- 揽收时由快递员登记
+
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW(),
+    dropped_off_at TIMESTAMPTZ,
+    collected_at TIMESTAMPTZ
+);
+
+CREATE TABLE shipment_audit_logs (
+    id BIGSERIAL PRIMARY KEY,
+    shipment_id BIGINT NOT NULL REFERENCES shipments(id),
+    action VARCHAR(50) NOT NULL,          -- CREATE, DROP_OFF, COLLECT, CANCEL
+    old_status shipment_status,
+    new_status shipment_status,
+    operator VARCHAR(50) DEFAULT 'SYSTEM',
+    created_at TIMESTAMPTZ DEFAULT NOW()
+);
+
 -- [2.6] 审计日志表 (不可变)
 CREATE TABLE parcel_audit_logs (
     id BIGSERIAL PRIMARY KEY,
     parcel_id BIGINT NOT NULL REFERENCES parcels(id),
-    action VARCHAR(50) NOT NULL,          -- CREATE, PICKUP, RETURN
+    action VARCHAR(50) NOT NULL,          -- CREATE, PICKUP, RETURN_HANDOVER
     old_status parcel_status,
     new_status parcel_status,
     operator VARCHAR(50) DEFAULT 'SYSTEM',
+    owner_user_id BIGINT,                 -- 包裹所有人
+    delegate_user_id BIGINT,              -- 代取人 (仅代取时记录)
+    created_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+-- [2.6.1] 手机号查看记录 (快递员 / 管理员查看完整收件人手机号时写入，不可变)
+CREATE TABLE phone_access_logs (
+    id BIGSERIAL PRIMARY KEY,
+    parcel_id BIGINT NOT NULL REFERENCES parcels(id),
+    operator VARCHAR(50) NOT NULL,        -- courier:<快递公司编码> / 管理员用户名
+    role VARCHAR(20) NOT NULL CHECK (role IN ('courier', 'admin')),
     created_at TIMESTAMPTZ DEFAULT NOW()
 );
 
+-- [2.6.2] 批量导入任务 (学生 / 快递公司表格导入，后台逐行处理，可轮询进度)
+CREATE TABLE import_jobs (
+    id BIGSERIAL PRIMARY KEY,
+    kind VARCHAR(20) NOT NULL CHECK (kind IN ('students', 'couriers')),
+    file_name VARCHAR(200) NOT NULL DEFAULT '',
+    dry_run BOOLEAN NOT NULL DEFAULT FALSE,  -- 仅校验，不写入
+    status VARCHAR(20) NOT NULL DEFAULT 'running' CHECK (status IN ('running', 'completed', 'failed')),
+    total INT NOT NULL DEFAULT 0,            -- 数据行数 (不含表头与空行)
+    processed INT NOT NULL DEFAULT 0,
+    created INT NOT NULL DEFAULT 0,
+    updated INT NOT NULL DEFAULT 0,
+    unchanged INT NOT NULL DEFAULT 0,
+    failed INT NOT NULL DEFAULT 0,
+    errors JSONB NOT NULL DEFAULT '[]',      -- 逐行错误 [{row, field, rule, param}]，最多保留前 1000 条
+    message TEXT,                            -- 任务整体失败的原因
+    created_by VARCHAR(50) NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW(),    -- 处理过程中定期刷新，长时间未刷新的任务视为中断
+    finished_at TIMESTAMPTZ
+);
+
+-- [2.7] 每日统计汇总 (按天、站点、快递公司、货架区域聚合，由汇总任务 func_rollup_daily_stats 重算)
+-- 仪表盘与统计接口读取汇总表，避免扫描 parcels / parcel_audit_logs
+CREATE TABLE daily_parcel_stats (
+    stat_date DATE NOT NULL,
+    station_id INT NOT NULL REFERENCES stations(id),
+    courier_id INT NOT NULL REFERENCES couriers(id),
+    zone VARCHAR(10) NOT NULL DEFAULT '', -- 货架区域，未上架为空
+    inbound INT NOT NULL DEFAULT 0,       -- 当天入库
+    picked_up INT NOT NULL DEFAULT 0,     -- 当天取件
+    returned INT NOT NULL DEFAULT 0,      -- 当天变为 returned
+    exceptions INT NOT NULL DEFAULT 0,    -- 当天变为 exception
+    -- 当天取件包裹的滞留时长 (入库到取件)：总秒数与分布 [<1h, 1-6h, 6-24h, 1-3d, 3-7d, >=7d]
+    dwell_seconds_total BIGINT NOT NULL DEFAULT 0,
+    dwell_histogram INT[] NOT NULL DEFAULT '{0,0,0,0,0,0}',
+    PRIMARY KEY (stat_date, station_id, courier_id, zone)
+);
+
+-- 汇总运行记录：rolled_up_at 晚于当天结束即为最终结果，否则后台任务会重新汇总
+CREATE TABLE daily_stats_runs (
+    stat_date DATE PRIMARY KEY,
+    rolled_up_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
 -- ============================================================
 -- 3. 索引优化层 (Indexes)
 -- ============================================================
@@ -109,14 +421,44 @@ CREATE UNIQUE INDEX idx_tracking_number ON parcels(tracking_number);
 CREATE INDEX idx_active_pickup_code ON parcels(pickup_code) WHERE status IN ('stored', 'pending');
 -- 仅索引活跃的用户包裹
 CREATE INDEX idx_user_active_parcels ON parcels(user_id) WHERE status IN ('stored', 'pending');
+CREATE INDEX idx_parcels_user ON parcels(user_id, created_at DESC); -- 个人数据导出、删除与保留期限检查
+-- 按站点查询活跃包裹 (仪表盘、滞留件)
+CREATE INDEX idx_station_active_parcels ON parcels(station_id, created_at) WHERE status IN ('stored', 'pending');
+-- 运营分析按时间范围统计入库量 / 取件量
+CREATE INDEX idx_parcels_created_at ON parcels(created_at);
+-- 快递员任务列表按快递公司 + 入库时间查询
+CREATE INDEX idx_parcels_courier_created ON parcels(courier_id, created_at DESC);
+CREATE INDEX idx_parcels_picked_up_at ON parcels(picked_up_at) WHERE picked_up_at IS NOT NULL;
+CREATE INDEX idx_phone_access_parcel ON phone_access_logs(parcel_id);
+CREATE INDEX idx_parcel_audit_created_at ON parcel_audit_logs(created_at);
+-- 学号唯一 (未登记的为 NULL)
+CREATE UNIQUE INDEX idx_users_student_id ON users(student_id) WHERE student_id IS NOT NULL;
+CREATE INDEX idx_shelves_station ON shelves(station_id);
+CREATE INDEX idx_admin_password_history ON admin_password_history(admin_id, created_at DESC);
+CREATE INDEX idx_attachments_parcel ON parcel_attachments(parcel_id);
+CREATE INDEX idx_delegations_owner ON pickup_delegations(owner_id) WHERE revoked_at IS NULL;
+CREATE INDEX idx_delegations_delegate ON pickup_delegations(delegate_phone_hash) WHERE revoked_at IS NULL;
+CREATE INDEX idx_admin_recovery_codes ON admin_recovery_codes(admin_id) WHERE used_at IS NULL;
+-- 每个包裹同时只能在一个未交接的退件批次中
+CREATE UNIQUE INDEX idx_return_items_pending ON return_batch_items(parcel_id) WHERE handed_over_at IS NULL;
+CREATE INDEX idx_return_batches_courier ON return_batches(courier_id, status);
+CREATE INDEX idx_shipments_user ON shipments(user_id, created_at DESC);
+CREATE INDEX idx_shipments_station ON shipments(station_id, status);
+CREATE INDEX idx_shipments_courier ON shipments(courier_id, status);
+CREATE UNIQUE INDEX idx_shipments_tracking ON shipments(tracking_number) WHERE tracking_number IS NOT NULL;
+CREATE INDEX idx_shipment_audit ON shipment_audit_logs(shipment_id, created_at);
 
 -- ============================================================
 -- 4. 逻辑层 (Functions & Triggers)
 -- ============================================================
 
 -- [4.1] 自动更新 updated_at
+-- 会话 / 事务设置 app.keep_updated_at = on 时保持不变 (密文重写、手机号脱敏等不属于业务修改的批量任务)
 CREATE OR REPLACE FUNCTION func_update_timestamp() RETURNS TRIGGER AS $$
 BEGIN
+    IF current_setting('app.keep_updated_at', true) = 'on' THEN
+        RETURN NEW;
+    END IF;
     NEW.updated_at = NOW();
     RETURN NEW;
 END;
@@ -125,16 +467,25 @@ $$ LANGUAGE plpgsql;
 CREATE TRIGGER trg_parcels_updated_at BEFORE UPDATE ON parcels
 FOR EACH ROW EXECUTE FUNCTION func_update_timestamp();
 
+CREATE TRIGGER trg_users_updated_at BEFORE UPDATE ON users
+FOR EACH ROW EXECUTE FUNCTION func_update_timestamp();
+
 -- [4.2] 自动审计日志 (核心安全功能)
+-- 操作人、动作与代取人可由事务内的 app.audit_operator / app.audit_action / app.audit_delegate_user_id 设置
+-- (set_config(..., true))，未设置时为 SYSTEM / 默认动作 / NULL
 CREATE OR REPLACE FUNCTION func_audit_parcel_change() RETURNS TRIGGER AS $$
 BEGIN
     IF (TG_OP = 'INSERT') OR (OLD.status IS DISTINCT FROM NEW.status) THEN
-        INSERT INTO parcel_audit_logs (parcel_id, action, old_status, new_status)
+        INSERT INTO parcel_audit_logs (parcel_id, action, old_status, new_status, operator, owner_user_id, delegate_user_id)
         VALUES (
             NEW.id, 
-            CASE WHEN TG_OP = 'INSERT' THEN 'CREATE' ELSE 'STATUS_CHANGE' END,
+            COALESCE(NULLIF(current_setting('app.audit_action', true), ''),
+                     CASE WHEN TG_OP = 'INSERT' THEN 'CREATE' ELSE 'STATUS_CHANGE' END),
             CASE WHEN TG_OP = 'INSERT' THEN NULL ELSE OLD.status END,
-            NEW.status
+            NEW.status,
+            COALESCE(NULLIF(current_setting('app.audit_operator', true), ''), 'SYSTEM'),
+            NEW.user_id,
+            NULLIF(current_setting('app.audit_delegate_user_id', true), '')::BIGINT
         );
     END IF;
     RETURN NEW;
@@ -144,52 +495,268 @@ $$ LANGUAGE plpgsql;
 CREATE TRIGGER trg_parcel_audit AFTER INSERT OR UPDATE ON parcels
 FOR EACH ROW EXECUTE FUNCTION func_audit_parcel_change();
 
+-- [4.2.2] 寄件订单审计：与包裹审计相同，操作人与动作来自 app.audit_operator / app.audit_action
+CREATE OR REPLACE FUNCTION func_audit_shipment_change() RETURNS TRIGGER AS $$
+BEGIN
+    IF (TG_OP = 'INSERT') OR (OLD.status IS DISTINCT FROM NEW.status) THEN
+        INSERT INTO shipment_audit_logs (shipment_id, action, old_status, new_status, operator)
+        VALUES (
+            NEW.id,
+            COALESCE(NULLIF(current_setting('app.audit_action', true), ''),
+                     CASE WHEN TG_OP = 'INSERT' THEN 'CREATE' ELSE 'STATUS_CHANGE' END),
+            CASE WHEN TG_OP = 'INSERT' THEN NULL ELSE OLD.status END,
+            NEW.status,
+            COALESCE(NULLIF(current_setting('app.audit_operator', true), ''), 'SYSTEM')
+        );
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER trg_shipments_updated_at BEFORE UPDATE ON shipments
+FOR EACH ROW EXECUTE FUNCTION func_update_timestamp();
+
+CREATE TRIGGER trg_shipment_audit AFTER INSERT OR UPDATE ON shipments
+FOR EACH ROW EXECUTE FUNCTION func_audit_shipment_change();
+
+-- [4.2.1] 生效中的代取授权：返回被授权人 p_delegate_user_id 可代取包裹 p_parcel_id 的授权 ID，没有时返回 NULL
+-- 按被授权人当前手机号匹配，未撤销且处于时间窗口内
+CREATE OR REPLACE FUNCTION func_active_delegation(p_parcel_id BIGINT, p_delegate_user_id BIGINT) RETURNS BIGINT AS $$
+    SELECT d.id
+    FROM pickup_delegations d
+    JOIN parcels p ON p.id = p_parcel_id AND p.user_id = d.owner_id
+    JOIN users u ON u.id = p_delegate_user_id AND u.phone_hash = d.delegate_phone_hash
+    WHERE (d.parcel_id IS NULL OR d.parcel_id = p.id)
+      AND d.revoked_at IS NULL
+      AND NOW() >= d.starts_at AND NOW() < d.expires_at
+    ORDER BY d.id
+    LIMIT 1
+$$ LANGUAGE sql STABLE;
+
+-- [4.2.3] 每日统计汇总：删除并重算 p_date 当天 (数据库时区) 的汇总行，可重复执行；返回写入的行数
+-- 入库按 created_at、取件按 picked_up_at、退回/异常按审计日志中的状态变更时间归入当天
+CREATE OR REPLACE FUNCTION func_rollup_daily_stats(p_date DATE) RETURNS INT AS $$
+DECLARE
+    v_rows INT;
+BEGIN
+    -- 同一天的汇总串行执行，避免并发重算时主键冲突
+    PERFORM pg_advisory_xact_lock(hashtext('daily_parcel_stats'), p_date - DATE '2000-01-01');
+
+    DELETE FROM daily_parcel_stats WHERE stat_date = p_date;
+
+    INSERT INTO daily_parcel_stats (stat_date, station_id, courier_id, zone, inbound, picked_up, returned, exceptions,
+                                    dwell_seconds_total, dwell_histogram)
+    SELECT p_date, e.station_id, e.courier_id, e.zone,
+           COUNT(*) FILTER (WHERE e.kind = 'inbound'),
+           COUNT(*) FILTER (WHERE e.kind = 'pickup'),
+           COUNT(*) FILTER (WHERE e.kind = 'returned'),
+           COUNT(*) FILTER (WHERE e.kind = 'exception'),
+           COALESCE(SUM(e.dwell) FILTER (WHERE e.kind = 'pickup'), 0)::BIGINT,
+           ARRAY[
+               COUNT(*) FILTER (WHERE e.kind = 'pickup' AND e.dwell < 3600),
+               COUNT(*) FILTER (WHERE e.kind = 'pickup' AND e.dwell >= 3600 AND e.dwell < 21600),
+               COUNT(*) FILTER (WHERE e.kind = 'pickup' AND e.dwell >= 21600 AND e.dwell < 86400),
+               COUNT(*) FILTER (WHERE e.kind = 'pickup' AND e.dwell >= 86400 AND e.dwell < 259200),
+               COUNT(*) FILTER (WHERE e.kind = 'pickup' AND e.dwell >= 259200 AND e.dwell < 604800),
+               COUNT(*) FILTER (WHERE e.kind = 'pickup' AND e.dwell >= 604800)
+           ]::INT[]
+    FROM (
+        SELECT 'inbound' AS kind, p.station_id, p.courier_id, COALESCE(s.zone, '') AS zone, NULL::NUMERIC AS dwell
+        FROM parcels p
+        LEFT JOIN shelves s ON s.id = p.shelf_id
+        WHERE p.created_at >= p_date AND p.created_at < p_date + 1
+        UNION ALL
+        SELECT 'pickup', p.station_id, p.courier_id, COALESCE(s.zone, ''), EXTRACT(EPOCH FROM p.picked_up_at - p.created_at)
+        FROM parcels p
+        LEFT JOIN shelves s ON s.id = p.shelf_id
+        WHERE p.picked_up_at >= p_date AND p.picked_up_at < p_date + 1
+        UNION ALL
+        SELECT l.new_status::TEXT, p.station_id, p.courier_id, COALESCE(s.zone, ''), NULL
+        FROM parcel_audit_logs l
+        JOIN parcels p ON p.id = l.parcel_id
+        LEFT JOIN shelves s ON s.id = p.shelf_id
+        WHERE l.new_status IN ('returned', 'exception') AND l.old_status IS DISTINCT FROM l.new_status
+          AND l.created_at >= p_date AND l.created_at < p_date + 1
+    ) e
+    GROUP BY e.station_id, e.courier_id, e.zone;
+    GET DIAGNOSTICS v_rows = ROW_COUNT;
+
+    INSERT INTO daily_stats_runs (stat_date) VALUES (p_date)
+    ON CONFLICT (stat_date) DO UPDATE SET rolled_up_at = NOW();
+    RETURN v_rows;
+END;
+$$ LANGUAGE plpgsql;
+
+-- [4.2.4] 合并重复用户：把 p_source 的包裹、代取授权、寄件订单转移给 p_target 并删除 p_source；
+-- 每个被转移的包裹写入一条 REASSIGN 审计日志，合并本身记入 user_change_logs (手机号密文由调用方传入 p_source_phone，未传入时使用被合并用户的密文)。
+-- 返回转移的包裹数
+CREATE OR REPLACE FUNCTION func_merge_users(p_source BIGINT, p_target BIGINT, p_operator VARCHAR, p_reason VARCHAR DEFAULT NULL,
+                                            p_source_phone VARCHAR DEFAULT NULL)
+RETURNS INT AS $$
+DECLARE
+    v_source users%ROWTYPE;
+    v_moved INT;
+BEGIN
+    IF p_source = p_target THEN
+        RAISE EXCEPTION 'cannot merge a user into itself' USING HINT = 'INVALID_REQUEST';
+    END IF;
+    -- 按 id 顺序加锁，避免并发合并死锁
+    PERFORM 1 FROM users WHERE id IN (p_source, p_target) ORDER BY id FOR UPDATE;
+    SELECT * INTO v_source FROM users WHERE id = p_source;
+    IF NOT FOUND OR NOT EXISTS (SELECT 1 FROM users WHERE id = p_target) THEN
+        RAISE EXCEPTION 'user not found' USING HINT = 'USER_NOT_FOUND';
+    END IF;
+
+    INSERT INTO parcel_audit_logs (parcel_id, action, old_status, new_status, operator, owner_user_id)
+    SELECT id, 'REASSIGN', status, status, p_operator, p_target FROM parcels WHERE user_id = p_source;
+    UPDATE parcels SET user_id = p_target WHERE user_id = p_source;
+    GET DIAGNOSTICS v_moved = ROW_COUNT;
+
+    UPDATE pickup_delegations SET owner_id = p_target WHERE owner_id = p_source;
+    UPDATE shipments SET user_id = p_target WHERE user_id = p_source;
+    DELETE FROM users WHERE id = p_source;
+
+    -- 保留用户缺少的资料用被合并用户的补齐 (学号唯一，需先删除被合并用户)
+    UPDATE users
+    SET student_id = COALESCE(student_id, v_source.student_id),
+        name = CASE WHEN name IS NULL OR name = '同学' THEN COALESCE(v_source.name, name) ELSE name END,
+        dorm = COALESCE(dorm, v_source.dorm)
+    WHERE id = p_target;
+
+    INSERT INTO user_change_logs (action, user_id, source_user_id, old_phone, parcels_moved, reason, operator)
+    VALUES ('MERGE', p_target, p_source, COALESCE(p_source_phone, v_source.phone_enc), v_moved, p_reason, p_operator);
+    RETURN v_moved;
+END;
+$$ LANGUAGE plpgsql;
+
+-- [4.2.5] 删除学生个人信息：users 行保留 (包裹、寄件订单的外键与统计口径不变)，
+-- 手机号 (密文、盲索引) 与学号、姓名、宿舍清空；包裹与寄件订单上的姓名 / 手机号快照清空，
+-- 代取授权、待验证的手机号变更删除，变更记录中的手机号清空。仍有未取件包裹或未完成的寄件订单时拒绝。
+-- 删除本身记入 user_erasure_logs，返回匿名化的包裹数
+CREATE OR REPLACE FUNCTION func_erase_user(p_user BIGINT, p_operator VARCHAR, p_source VARCHAR, p_reason VARCHAR DEFAULT NULL)
+RETURNS INT AS $$
+DECLARE
+    v_user users%ROWTYPE;
+    v_parcels INT;
+    v_shipments INT;
+BEGIN
+    SELECT * INTO v_user FROM users WHERE id = p_user FOR UPDATE;
+    IF NOT FOUND OR v_user.erased_at IS NOT NULL THEN
+        RAISE EXCEPTION 'user not found' USING HINT = 'USER_NOT_FOUND';
+    END IF;
+    IF EXISTS (SELECT 1 FROM parcels WHERE user_id = p_user AND status IN ('inbound', 'stored', 'pending'))
+       OR EXISTS (SELECT 1 FROM shipments WHERE user_id = p_user AND status IN ('created', 'dropped_off')) THEN
+        RAISE EXCEPTION 'user has parcels awaiting pickup or open shipments' USING HINT = 'USER_HAS_ACTIVE_ITEMS';
+    END IF;
+
+    -- 只修改快照列，状态不变，不会触发包裹审计
+    UPDATE parcels
+    SET recipient_name_snapshot = NULL, recipient_phone_snapshot = NULL, recipient_phone_enc = NULL, recipient_phone_masked = NULL
+    WHERE user_id = p_user;
+    GET DIAGNOSTICS v_parcels = ROW_COUNT;
+    UPDATE shipments
+    SET sender_name = '已删除', sender_phone = '-', recipient_name = '已删除', recipient_phone = '-', recipient_address = '已删除'
+    WHERE user_id = p_user;
+    GET DIAGNOSTICS v_shipments = ROW_COUNT;
+
+    DELETE FROM pickup_delegations WHERE owner_id = p_user OR delegate_phone_hash = v_user.phone_hash;
+    DELETE FROM phone_change_requests WHERE user_id = p_user;
+    UPDATE user_change_logs SET old_phone = NULL, new_phone = NULL WHERE user_id = p_user OR source_user_id = p_user;
+    UPDATE users
+    SET phone = NULL, phone_enc = NULL, phone_hash = NULL, student_id = NULL, name = NULL, dorm = NULL,
+        notify_inbound = FALSE, notify_retention = FALSE, erased_at = NOW()
+    WHERE id = p_user;
+
+    INSERT INTO user_erasure_logs (user_id, source, parcels_anonymized, shipments_anonymized, reason, operator)
+    VALUES (p_user, p_source, v_parcels, v_shipments, p_reason, p_operator);
+    RETURN v_parcels;
+END;
+$$ LANGUAGE plpgsql;
+
 -- [4.3] 智能入库存储过程 (事务原子性)
 CREATE OR REPLACE PROCEDURE sp_parcel_inbound(
     p_tracking_no VARCHAR,
-    p_phone VARCHAR,
+    p_phone_hash VARCHAR,     -- 收件人手机号盲索引
+    p_phone_enc TEXT,         -- 收件人手机号密文
+    p_phone_masked VARCHAR,   -- 收件人手机号脱敏形式
     p_courier_code VARCHAR,
-    p_user_name VARCHAR DEFAULT '同学'
+    p_user_name VARCHAR DEFAULT '同学',
+    p_station_code VARCHAR DEFAULT NULL,  -- 为空时仅在只有一个站点时使用该站点
+    p_size_class parcel_size DEFAULT 'small',
+    p_weight_grams INT DEFAULT NULL,
+    p_fragile BOOLEAN DEFAULT FALSE,
+    p_perishable BOOLEAN DEFAULT FALSE,
+    p_cold_chain BOOLEAN DEFAULT FALSE,
+    p_declared_value_cents BIGINT DEFAULT NULL
 )
 LANGUAGE plpgsql
 AS $$
 DECLARE
     v_user_id BIGINT;
     v_courier_id INT;
+    v_station_id INT;
     v_shelf_id INT;
     v_shelf_code VARCHAR;
     v_pickup_code VARCHAR;
 BEGIN
     -- A. 用户处理
-    SELECT id INTO v_user_id FROM users WHERE phone = p_phone;
+    SELECT id INTO v_user_id FROM users WHERE phone_hash = p_phone_hash;
     IF v_user_id IS NULL THEN
-        INSERT INTO users (phone, name) VALUES (p_phone, p_user_name) RETURNING id INTO v_user_id;
+        INSERT INTO users (phone_hash, phone_enc, name) VALUES (p_phone_hash, p_phone_enc, p_user_name) RETURNING id INTO v_user_id;
     END IF;
 
     -- B. 快递商验证
     SELECT id INTO v_courier_id FROM couriers WHERE code = p_courier_code;
     IF v_courier_id IS NULL THEN
-        RAISE EXCEPTION '无效快递商: %', p_courier_code;
+        RAISE EXCEPTION '无效快递商: %', p_courier_code USING HINT = 'INVALID_COURIER';
     END IF;
 
-    -- C. 货架分配 (行锁)
+    -- C. 站点解析
+    IF p_station_code IS NULL OR p_station_code = '' THEN
+        -- 多站点时不能替调用方猜测站点，必须显式指定
+        IF (SELECT COUNT(*) FROM stations) > 1 THEN
+            RAISE EXCEPTION '无效站点: 存在多个站点时必须指定站点编号' USING HINT = 'INVALID_STATION';
+        END IF;
+        SELECT id INTO v_station_id FROM stations;
+    ELSE
+        SELECT id INTO v_station_id FROM stations WHERE code = p_station_code;
+    END IF;
+    IF v_station_id IS NULL THEN
+        RAISE EXCEPTION '无效站点: %', p_station_code USING HINT = 'INVALID_STATION';
+    END IF;
+
+    -- D. 货架分配 (仅限本站点，行锁)
+    -- 尺寸不超过货架上限；冷链只放冷藏货架，其余优先常温货架；
+    -- 优先选择尺寸上限最小的货架，把大货架留给大件
     SELECT id, code INTO v_shelf_id, v_shelf_code 
     FROM shelves 
-    WHERE current_load < capacity 
-    ORDER BY id ASC LIMIT 1 FOR UPDATE;
+    WHERE station_id = v_station_id AND current_load < capacity
+      AND max_size >= COALESCE(p_size_class, 'small')
+      AND (NOT COALESCE(p_cold_chain, FALSE) OR temperature = 'chilled')
+    ORDER BY (temperature = 'chilled') <> COALESCE(p_cold_chain, FALSE), max_size ASC, id ASC
+    LIMIT 1 FOR UPDATE;
 
     IF v_shelf_id IS NULL THEN
-        RAISE EXCEPTION '仓库爆满，请扩容';
+        -- 有空位但尺寸或温控不符时给出单独的错误码
+        IF EXISTS (SELECT 1 FROM shelves WHERE station_id = v_station_id AND current_load < capacity) THEN
+            RAISE EXCEPTION '没有符合尺寸或温控要求的货架' USING HINT = 'NO_SUITABLE_SHELF';
+        END IF;
+        RAISE EXCEPTION '仓库爆满，请扩容' USING HINT = 'WAREHOUSE_FULL';
     END IF;
 
-    -- D. 生成取件码
+    -- E. 生成取件码
     v_pickup_code := v_shelf_code || '-' || LPAD(FLOOR(RANDOM() * 1000)::TEXT, 3, '0');
 
-    -- E. 落库
-    INSERT INTO parcels (tracking_number, user_id, courier_id, shelf_id, pickup_code, status, recipient_phone_snapshot)
-    VALUES (p_tracking_no, v_user_id, v_courier_id, v_shelf_id, v_pickup_code, 'stored', p_phone);
+    -- F. 落库
+    INSERT INTO parcels (tracking_number, user_id, courier_id, shelf_id, station_id, pickup_code, status,
+                         recipient_phone_enc, recipient_phone_masked,
+                         size_class, weight_grams, fragile, perishable, cold_chain, declared_value_cents)
+    VALUES (p_tracking_no, v_user_id, v_courier_id, v_shelf_id, v_station_id, v_pickup_code, 'stored',
+            p_phone_enc, p_phone_masked,
+            COALESCE(p_size_class, 'small'), p_weight_grams, COALESCE(p_fragile, FALSE),
+            COALESCE(p_perishable, FALSE) OR COALESCE(p_cold_chain, FALSE), COALESCE(p_cold_chain, FALSE), p_declared_value_cents);
 
-    -- F. 更新库存
+    -- G. 更新库存
     UPDATE shelves SET current_load = current_load + 1 WHERE id = v_shelf_id;
 END;
 $$;
@@ -210,10 +777,13 @@ SELECT
         ELSE '待上架' 
     END AS pickup_code,
     s.zone AS shelf_zone, -- 只显示区域，不显示具体内部ID
+    st.name AS station_name,
     p.status,
+    p.size_class, p.fragile, p.perishable, p.cold_chain,
     p.updated_at
 FROM parcels p
 JOIN couriers c ON p.courier_id = c.id
+JOIN stations st ON p.station_id = st.id
 LEFT JOIN shelves s ON p.shelf_id = s.id;
 
 -- [5.2] 快递员视图：只能看状态，不可看取件码
@@ -221,24 +791,74 @@ CREATE OR REPLACE VIEW v_courier_tasks AS
 SELECT 
     p.courier_id,
     p.tracking_number,
-    p.recipient_phone_snapshot AS phone, -- 需要联系客户
+    -- 需要联系客户：密文 (加密迁移前为明文，已清理的为脱敏形式)，由应用层解密
+    COALESCE(p.recipient_phone_enc, p.recipient_phone_snapshot, p.recipient_phone_masked) AS phone,
+    p.recipient_phone_masked AS phone_masked,
     p.status,
-    p.created_at
-FROM parcels p;
+    p.size_class, p.weight_grams, p.fragile, p.perishable, p.cold_chain,
+    st.code AS station_code, st.name AS station_name,
+    s.code AS shelf_code, s.zone AS shelf_zone, -- 货架位置，便于回答收件人询问
+    p.created_at,
+    p.picked_up_at,
+    -- 滞留时长：未取件的计到当前，已取件/退回/异常的计到取件或最后更新时间
+    EXTRACT(EPOCH FROM CASE WHEN p.status IN ('inbound', 'stored', 'pending') THEN NOW()
+                            ELSE COALESCE(p.picked_up_at, p.updated_at) END - p.created_at) AS dwell_seconds
+FROM parcels p
+JOIN stations st ON st.id = p.station_id
+LEFT JOIN shelves s ON s.id = p.shelf_id;
 
 -- [5.3] 管理员视图：全知全能
+-- 待取件数走活跃包裹部分索引；当天作业量读取每日汇总表 (随后台汇总任务更新)
 CREATE OR REPLACE VIEW v_admin_dashboard AS
 SELECT 
-    COUNT(*) FILTER (WHERE status = 'stored') as waiting_pickup,
+    (SELECT COUNT(*) FROM parcels WHERE status = 'stored') as waiting_pickup,
+    (SELECT COUNT(*) FROM parcels WHERE status = 'stored' AND perishable) as waiting_perishable,
     (SELECT COUNT(*) FROM shelves WHERE current_load >= capacity) as full_shelves,
-    (SELECT COUNT(*) FROM parcel_audit_logs WHERE created_at > NOW() - INTERVAL '24 hours') as today_ops
-FROM parcels;
+    COALESCE(SUM(d.inbound + d.picked_up + d.returned + d.exceptions), 0) as today_ops,
+    COALESCE(SUM(d.inbound), 0) as today_inbound,
+    COALESCE(SUM(d.picked_up), 0) as today_picked_up,
+    COALESCE(SUM(d.returned), 0) as today_returned,
+    COALESCE(SUM(d.exceptions), 0) as today_exceptions
+FROM daily_parcel_stats d
+WHERE d.stat_date = CURRENT_DATE;
+
+-- [5.4] 站点仪表盘：按站点拆分的同口径统计
+CREATE OR REPLACE VIEW v_station_dashboard AS
+SELECT 
+    st.id AS station_id,
+    st.code AS station_code,
+    (SELECT COUNT(*) FROM parcels p WHERE p.station_id = st.id AND p.status = 'stored') as waiting_pickup,
+    (SELECT COUNT(*) FROM parcels p WHERE p.station_id = st.id AND p.status = 'stored' AND p.perishable) as waiting_perishable,
+    (SELECT COUNT(*) FROM shelves s WHERE s.station_id = st.id AND s.current_load >= s.capacity) as full_shelves,
+    COALESCE(SUM(d.inbound + d.picked_up + d.returned + d.exceptions), 0) as today_ops,
+    COALESCE(SUM(d.inbound), 0) as today_inbound,
+    COALESCE(SUM(d.picked_up), 0) as today_picked_up,
+    COALESCE(SUM(d.returned), 0) as today_returned,
+    COALESCE(SUM(d.exceptions), 0) as today_exceptions
+FROM stations st
+LEFT JOIN daily_parcel_stats d ON d.station_id = st.id AND d.stat_date = CURRENT_DATE
+GROUP BY st.id, st.code;
 
 -- ============================================================
 -- 6. 数据预热 (Seeds)
 -- ============================================================
-INSERT INTO admins (username, password_hash) VALUES ('admin', 'secret');
-INSERT INTO couriers (name, code) VALUES ('顺丰', 'SF'), ('京东', 'JD'), ('邮政', 'EMS');
+-- 初始管理员密码为 secret (bcrypt)；password_changed_at 置空，首次登录后必须修改密码才能使用其他接口
+INSERT INTO admins (username, password_hash, password_changed_at) VALUES ('admin', '$2a$10$QV7DGPcBWVVkafPDtrlOpOktDQpRscFAWszINfgtPkCZFJyix73qO', NULL);
+INSERT INTO system_settings (key, value) VALUES ('admin_mfa_required', 'false');
+INSERT INTO stations (code, name) VALUES ('MAIN', '主站');
+INSERT INTO couriers (name, code, tracking_pattern, tracking_min_length, tracking_max_length, tracking_checksum) VALUES
+    ('顺丰', 'SF', 'SF\d{12,13}', 14, 15, NULL),
+    ('京东', 'JD', 'JD[A-Z0-9]{13,16}', 15, 18, NULL),
+    ('邮政', 'EMS', '[A-Z]{2}\d{9}[A-Z]{2}|\d{13}', 13, 13, 'ems');
+-- 寄件运费：同城 / 邻近 / 较远 / 偏远 (单位：克、分)
+INSERT INTO shipping_rates (courier_id, zone, first_weight_grams, first_price_cents, additional_weight_grams, additional_price_cents)
+SELECT c.id, r.zone, 1000, r.first_price, 1000, r.additional_price
+FROM couriers c
+JOIN (VALUES
+    ('SF', 'local', 1200, 200), ('SF', 'near', 1300, 400), ('SF', 'far', 2300, 1300), ('SF', 'remote', 2800, 1800),
+    ('JD', 'local', 1000, 200), ('JD', 'near', 1200, 300), ('JD', 'far', 1800, 1000), ('JD', 'remote', 2400, 1500),
+    ('EMS', 'local', 800, 200), ('EMS', 'near', 1000, 300), ('EMS', 'far', 1500, 800), ('EMS', 'remote', 2000, 1200)
+) AS r(code, zone, first_price, additional_price) ON r.code = c.code;
 
 -- ============================================================
 -- 7. 权限配置 (确保应用用户有完整权限)
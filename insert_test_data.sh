@@ -44,36 +44,36 @@ TOKEN_SF=$(login_courier "SF") || exit 1
 TOKEN_JD=$(login_courier "JD") || exit 1
 TOKEN_EMS=$(login_courier "EMS") || exit 1
 
-echo "  -> 包裹1: SF10001"
+echo "  -> 包裹1: SF100000010001"
 curl -s -X POST "$BASE_URL/api/v1/inbound" \
   -H "Content-Type: application/json" \
   -H "Authorization: Bearer $TOKEN_SF" \
   -d "{
-    \"tracking_number\": \"SF10001\",
+    \"tracking_number\": \"SF100000010001\",
     \"phone\": \"$PHONE1\",
     \"user_name\": \"张三\"
   }" | grep -o "success\\|error" || echo " error"
 
 sleep 0.5
 
-echo "  -> 包裹2: JD20001"
+echo "  -> 包裹2: JD0000000020001"
 curl -s -X POST "$BASE_URL/api/v1/inbound" \
   -H "Content-Type: application/json" \
   -H "Authorization: Bearer $TOKEN_JD" \
   -d "{
-    \"tracking_number\": \"JD20001\",
+    \"tracking_number\": \"JD0000000020001\",
     \"phone\": \"$PHONE1\",
     \"user_name\": \"张三\"
   }" | grep -o "success\\|error" || echo " error"
 
 sleep 0.5
 
-echo "  -> 包裹3: EMS30001"
+echo "  -> 包裹3: 1000000030001"
 curl -s -X POST "$BASE_URL/api/v1/inbound" \
   -H "Content-Type: application/json" \
   -H "Authorization: Bearer $TOKEN_EMS" \
   -d "{
-    \"tracking_number\": \"EMS30001\",
+    \"tracking_number\": \"1000000030001\",
     \"phone\": \"$PHONE1\",
     \"user_name\": \"张三\"
   }" | grep -o "success\\|error" || echo " error"
@@ -81,24 +81,24 @@ curl -s -X POST "$BASE_URL/api/v1/inbound" \
 echo ""
 echo "2. 为手机号 $PHONE2 插入2个包裹..."
 
-echo "  -> 包裹1: SF10002"
+echo "  -> 包裹1: SF100000010002"
 curl -s -X POST "$BASE_URL/api/v1/inbound" \
   -H "Content-Type: application/json" \
   -H "Authorization: Bearer $TOKEN_SF" \
   -d "{
-    \"tracking_number\": \"SF10002\",
+    \"tracking_number\": \"SF100000010002\",
     \"phone\": \"$PHONE2\",
     \"user_name\": \"李四\"
   }" | grep -o "success\\|error" || echo " error"
 
 sleep 0.5
 
-echo "  -> 包裹2: JD20002"
+echo "  -> 包裹2: JD0000000020002"
 curl -s -X POST "$BASE_URL/api/v1/inbound" \
   -H "Content-Type: application/json" \
   -H "Authorization: Bearer $TOKEN_JD" \
   -d "{
-    \"tracking_number\": \"JD20002\",
+    \"tracking_number\": \"JD0000000020002\",
     \"phone\": \"$PHONE2\",
     \"user_name\": \"李四\"
   }" | grep -o "success\\|error" || echo " error"
@@ -106,12 +106,12 @@ curl -s -X POST "$BASE_URL/api/v1/inbound" \
 echo ""
 echo "3. 为手机号 $PHONE3 插入1个包裹..."
 
-echo "  -> 包裹: EMS30002"
+echo "  -> 包裹: RA123456785CN"
 curl -s -X POST "$BASE_URL/api/v1/inbound" \
   -H "Content-Type: application/json" \
   -H "Authorization: Bearer $TOKEN_EMS" \
   -d "{
-    \"tracking_number\": \"EMS30002\",
+    \"tracking_number\": \"RA123456785CN\",
     \"phone\": \"$PHONE3\",
     \"user_name\": \"王五\"
   }" | grep -o "success\\|error" || echo " error"
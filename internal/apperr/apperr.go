@@ -0,0 +1,242 @@
+// Package apperr 定义对外稳定的业务错误码。
+// handler 通过 c.Error 上报错误，由 middleware.ErrorHandler 统一转换为
+// {"error": "<本地化提示>", "code": "<错误码>"} 响应，原始数据库错误只写日志、不返回给客户端。
+package apperr
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Code 对外稳定的错误码，客户端应依据 code 而非 error 文案做判断
+type Code string
+
+const (
+	CodeInvalidRequest   Code = "INVALID_REQUEST"
+	CodeValidationFailed Code = "VALIDATION_FAILED"
+	CodeUnauthorized     Code = "UNAUTHORIZED"
+	CodeForbidden        Code = "FORBIDDEN"
+	CodeNotFound         Code = "NOT_FOUND"
+	CodeConflict         Code = "CONFLICT"
+	CodeRateLimited      Code = "RATE_LIMITED"
+	CodeServiceBusy      Code = "SERVICE_BUSY"
+	CodeInternal         Code = "INTERNAL_ERROR"
+	CodeAlreadyExists    Code = "ALREADY_EXISTS"
+	CodeResourceInUse    Code = "RESOURCE_IN_USE"
+
+	// 登录与管理员账号
+	CodeInvalidCredentials Code = "INVALID_CREDENTIALS"
+	CodeAccountLocked      Code = "ACCOUNT_LOCKED"
+	CodeInvalidMFAToken    Code = "INVALID_MFA_TOKEN"
+	CodeInvalidMFACode     Code = "INVALID_MFA_CODE"
+	CodePasswordExpired    Code = "PASSWORD_EXPIRED"
+	CodeMFASetupRequired   Code = "MFA_SETUP_REQUIRED"
+	CodeWrongPassword      Code = "WRONG_PASSWORD"
+	CodeWeakPassword       Code = "WEAK_PASSWORD"
+	CodePasswordReused     Code = "PASSWORD_REUSED"
+	CodeAdminNotFound      Code = "ADMIN_NOT_FOUND"
+	CodeLastSuperAdmin     Code = "LAST_SUPER_ADMIN"
+	CodeCannotModifySelf   Code = "CANNOT_MODIFY_SELF"
+	CodeEnvManagedAccount  Code = "ENV_MANAGED_ACCOUNT"
+	CodeMFAAlreadyEnabled  Code = "MFA_ALREADY_ENABLED"
+	CodeMFANotEnabled      Code = "MFA_NOT_ENABLED"
+	CodeMFAEnforced        Code = "MFA_ENFORCED"
+
+	// 快递公司、站点与货架
+	CodeCourierNotFound Code = "COURIER_NOT_FOUND"
+	CodeStationNotFound Code = "STATION_NOT_FOUND"
+	CodeShelfNotFound   Code = "SHELF_NOT_FOUND"
+	CodeShelfNotEmpty   Code = "SHELF_NOT_EMPTY"
+
+	// 包裹相关
+	CodeParcelNotFound       Code = "PARCEL_NOT_FOUND"
+	CodeDuplicateTracking    Code = "DUPLICATE_TRACKING"
+	CodeWarehouseFull        Code = "WAREHOUSE_FULL"
+	CodeNoSuitableShelf      Code = "NO_SUITABLE_SHELF"
+	CodeInvalidPickupCode    Code = "INVALID_PICKUP_CODE"
+	CodeParcelNotPickable    Code = "PARCEL_NOT_PICKABLE"
+	CodeInvalidStatus        Code = "INVALID_STATUS"
+	CodeIllegalTransition    Code = "ILLEGAL_TRANSITION"
+	CodeInvalidCourier       Code = "INVALID_COURIER"
+	CodeCourierUndetermined  Code = "COURIER_UNDETERMINED"
+	CodeInvalidStation       Code = "INVALID_STATION"
+	CodeBatchTooLarge        Code = "BATCH_TOO_LARGE"
+	CodeNoParcelsReady       Code = "NO_PARCELS_READY"
+	CodeInvalidPickupToken   Code = "INVALID_PICKUP_TOKEN"
+	CodePickupTokenExpired   Code = "PICKUP_TOKEN_EXPIRED"
+	CodePickupTokenUsed      Code = "PICKUP_TOKEN_USED"
+	CodeAttachmentTooLarge   Code = "ATTACHMENT_TOO_LARGE"
+	CodeUnsupportedMedia     Code = "UNSUPPORTED_MEDIA_TYPE"
+	CodeInvalidDownloadLink  Code = "INVALID_DOWNLOAD_LINK"
+	CodeReturnBatchNotFound  Code = "RETURN_BATCH_NOT_FOUND"
+	CodeReturnBatchClosed    Code = "RETURN_BATCH_CLOSED"
+	CodeParcelNotReturnable  Code = "PARCEL_NOT_RETURNABLE"
+	CodeShipmentNotFound     Code = "SHIPMENT_NOT_FOUND"
+	CodeShippingUnavailable  Code = "SHIPPING_UNAVAILABLE"
+	CodeImportJobNotFound    Code = "IMPORT_JOB_NOT_FOUND"
+	CodeUserNotFound         Code = "USER_NOT_FOUND"
+	CodePhoneInUse           Code = "PHONE_IN_USE"
+	CodeInvalidVerification  Code = "INVALID_VERIFICATION_CODE"
+	CodeVerificationExpired  Code = "VERIFICATION_CODE_EXPIRED"
+	CodeSMSNotConfigured     Code = "SMS_NOT_CONFIGURED"
+	CodePhoneNotAvailable    Code = "PHONE_NOT_AVAILABLE"
+	CodeUserHasActiveItems   Code = "USER_HAS_ACTIVE_ITEMS"
+	CodeIdempotencyMismatch  Code = "IDEMPOTENCY_KEY_REUSED"
+	CodeIdempotencyInProcess Code = "IDEMPOTENCY_IN_PROGRESS"
+	CodeRequestTooLarge      Code = "REQUEST_TOO_LARGE"
+)
+
+var httpStatus = map[Code]int{
+	CodeInvalidRequest:       http.StatusBadRequest,
+	CodeValidationFailed:     http.StatusBadRequest,
+	CodeUnauthorized:         http.StatusUnauthorized,
+	CodeForbidden:            http.StatusForbidden,
+	CodeNotFound:             http.StatusNotFound,
+	CodeConflict:             http.StatusConflict,
+	CodeRateLimited:          http.StatusTooManyRequests,
+	CodeServiceBusy:          http.StatusServiceUnavailable,
+	CodeInternal:             http.StatusInternalServerError,
+	CodeAlreadyExists:        http.StatusConflict,
+	CodeResourceInUse:        http.StatusConflict,
+	CodeInvalidCredentials:   http.StatusUnauthorized,
+	CodeAccountLocked:        http.StatusLocked,
+	CodeInvalidMFAToken:      http.StatusUnauthorized,
+	CodeInvalidMFACode:       http.StatusUnauthorized,
+	CodePasswordExpired:      http.StatusForbidden,
+	CodeMFASetupRequired:     http.StatusForbidden,
+	CodeWrongPassword:        http.StatusUnauthorized,
+	CodeWeakPassword:         http.StatusBadRequest,
+	CodePasswordReused:       http.StatusBadRequest,
+	CodeAdminNotFound:        http.StatusNotFound,
+	CodeLastSuperAdmin:       http.StatusConflict,
+	CodeCannotModifySelf:     http.StatusConflict,
+	CodeEnvManagedAccount:    http.StatusConflict,
+	CodeMFAAlreadyEnabled:    http.StatusConflict,
+	CodeMFANotEnabled:        http.StatusConflict,
+	CodeMFAEnforced:          http.StatusConflict,
+	CodeCourierNotFound:      http.StatusNotFound,
+	CodeStationNotFound:      http.StatusNotFound,
+	CodeShelfNotFound:        http.StatusNotFound,
+	CodeShelfNotEmpty:        http.StatusConflict,
+	CodeParcelNotFound:       http.StatusNotFound,
+	CodeDuplicateTracking:    http.StatusConflict,
+	CodeWarehouseFull:        http.StatusConflict,
+	CodeNoSuitableShelf:      http.StatusConflict,
+	CodeInvalidPickupCode:    http.StatusBadRequest,
+	CodeParcelNotPickable:    http.StatusConflict,
+	CodeInvalidStatus:        http.StatusBadRequest,
+	CodeIllegalTransition:    http.StatusConflict,
+	CodeInvalidCourier:       http.StatusBadRequest,
+	CodeCourierUndetermined:  http.StatusBadRequest,
+	CodeInvalidStation:       http.StatusBadRequest,
+	CodeBatchTooLarge:        http.StatusBadRequest,
+	CodeNoParcelsReady:       http.StatusNotFound,
+	CodeInvalidPickupToken:   http.StatusBadRequest,
+	CodePickupTokenExpired:   http.StatusBadRequest,
+	CodePickupTokenUsed:      http.StatusConflict,
+	CodeAttachmentTooLarge:   http.StatusRequestEntityTooLarge,
+	CodeUnsupportedMedia:     http.StatusUnsupportedMediaType,
+	CodeInvalidDownloadLink:  http.StatusForbidden,
+	CodeReturnBatchNotFound:  http.StatusNotFound,
+	CodeReturnBatchClosed:    http.StatusConflict,
+	CodeParcelNotReturnable:  http.StatusConflict,
+	CodeShipmentNotFound:     http.StatusNotFound,
+	CodeShippingUnavailable:  http.StatusBadRequest,
+	CodeImportJobNotFound:    http.StatusNotFound,
+	CodeUserNotFound:         http.StatusNotFound,
+	CodePhoneInUse:           http.StatusConflict,
+	CodeInvalidVerification:  http.StatusBadRequest,
+	CodeVerificationExpired:  http.StatusBadRequest,
+	CodeSMSNotConfigured:     http.StatusServiceUnavailable,
+	CodePhoneNotAvailable:    http.StatusConflict,
+	CodeUserHasActiveItems:   http.StatusConflict,
+	CodeIdempotencyMismatch:  http.StatusUnprocessableEntity,
+	CodeIdempotencyInProcess: http.StatusConflict,
+	CodeRequestTooLarge:      http.StatusRequestEntityTooLarge,
+}
+
+// HTTPStatus 错误码对应的 HTTP 状态码，未登记的错误码按 500 处理
+func (c Code) HTTPStatus() int {
+	if s, ok := httpStatus[c]; ok {
+		return s
+	}
+	return http.StatusInternalServerError
+}
+
+// Error 带错误码的业务错误
+type Error struct {
+	Code Code
+	// Detail 可返回给客户端的补充说明（如非法的状态值），不得包含内部错误
+	Detail string
+	// Fields 逐字段的校验失败信息，仅 VALIDATION_FAILED 使用
+	Fields []FieldError
+	// Err 原始错误，只用于日志与 errors.Is / errors.As
+	Err error
+}
+
+func (e *Error) Error() string {
+	msg := string(e.Code)
+	if e.Detail != "" {
+		msg += ": " + e.Detail
+	}
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+func (e *Error) Unwrap() error { return e.Err }
+
+// Is 让 errors.Is(err, apperr.New(code)) 按错误码匹配
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+// Message 按语言返回本地化提示，有 Detail 时附在提示之后
+func (e *Error) Message(lang string) string {
+	msg := Message(e.Code, lang)
+	if e.Detail != "" {
+		msg += ": " + e.Detail
+	}
+	return msg
+}
+
+func New(code Code) *Error {
+	return &Error{Code: code}
+}
+
+// Newf 带补充说明的业务错误，说明会返回给客户端
+func Newf(code Code, format string, args ...any) *Error {
+	return &Error{Code: code, Detail: fmt.Sprintf(format, args...)}
+}
+
+// Wrap 为底层错误附加错误码，底层错误不会返回给客户端
+func Wrap(code Code, err error) *Error {
+	return &Error{Code: code, Err: err}
+}
+
+// CodeOf 返回错误对应的错误码，非业务错误视为 INTERNAL_ERROR
+func CodeOf(err error) Code {
+	return Translate(err).Code
+}
+
+// Translate 把任意错误转换为业务错误：
+// 已是 *Error 的原样返回，Postgres 错误按 SQLSTATE / RAISE 信息转换，其余视为内部错误
+func Translate(err error) *Error {
+	if err == nil {
+		return nil
+	}
+	var e *Error
+	if errors.As(err, &e) {
+		return e
+	}
+	if e := fromBinding(err); e != nil {
+		return e
+	}
+	if e := fromPostgres(err); e != nil {
+		return e
+	}
+	return Wrap(CodeInternal, err)
+}
@@ -0,0 +1,149 @@
+package apperr
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// FieldError 单个字段的校验失败信息
+type FieldError struct {
+	// Field 字段路径，使用 json 字段名，如 phone、items[0].tracking_number
+	Field string `json:"field"`
+	// Rule 未通过的规则，如 required、cnmobile、tracking_pattern
+	Rule  string `json:"rule"`
+	Param string `json:"param,omitempty"`
+	// Message 本地化提示，由 Localize 按请求语言填写
+	Message string `json:"message"`
+}
+
+// Invalid 单个字段校验失败，用于 service 层的业务规则校验（如快递公司运单号格式）
+func Invalid(field, rule string) *Error {
+	return &Error{Code: CodeValidationFailed, Fields: []FieldError{{Field: field, Rule: rule}}}
+}
+
+// WithParam 为唯一的字段错误补充规则参数（如 max 的上限）
+func (e *Error) WithParam(param string) *Error {
+	if len(e.Fields) == 1 {
+		e.Fields[0].Param = param
+	}
+	return e
+}
+
+// Localize 按语言填写各字段的提示
+func (e *Error) Localize(lang string) []FieldError {
+	out := make([]FieldError, len(e.Fields))
+	for i, f := range e.Fields {
+		f.Message = fieldMessage(f.Rule, f.Param, lang)
+		out[i] = f
+	}
+	return out
+}
+
+// fromBinding 转换 Gin 绑定阶段的错误：校验失败列出所有字段，JSON 解析失败指出出错字段
+func fromBinding(err error) *Error {
+	var verrs validator.ValidationErrors
+	if errors.As(err, &verrs) {
+		e := &Error{Code: CodeValidationFailed, Err: err}
+		for _, fe := range verrs {
+			e.Fields = append(e.Fields, FieldError{
+				Field: fieldPath(fe.Namespace()),
+				Rule:  fe.Tag(),
+				Param: fe.Param(),
+			})
+		}
+		return e
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		return &Error{Code: CodeValidationFailed, Err: err, Fields: []FieldError{{Field: typeErr.Field, Rule: "type", Param: jsonKind(typeErr.Type)}}}
+	}
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return Newf(CodeInvalidRequest, "malformed JSON body")
+	}
+	return nil
+}
+
+// jsonKind 期望的 JSON 类型名，避免把 Go 类型名暴露给客户端
+func jsonKind(t reflect.Type) string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	case reflect.Map, reflect.Struct:
+		return "object"
+	default:
+		return "number"
+	}
+}
+
+// fieldPath 去掉校验器命名空间中的结构体名前缀：BatchInboundRequest.items[0].phone -> items[0].phone
+func fieldPath(namespace string) string {
+	if i := strings.Index(namespace, "."); i >= 0 {
+		return namespace[i+1:]
+	}
+	return namespace
+}
+
+var fieldMessages = map[string]map[string]string{
+	"required":              {LangZH: "必填", LangEN: "is required"},
+	"min":                   {LangZH: "不能少于 %s", LangEN: "must be at least %s"},
+	"max":                   {LangZH: "不能超过 %s", LangEN: "must be at most %s"},
+	"oneof":                 {LangZH: "取值只能是 %s", LangEN: "must be one of %s"},
+	"type":                  {LangZH: "类型错误，应为 %s", LangEN: "must be of type %s"},
+	"cnmobile":              {LangZH: "不是有效的大陆手机号", LangEN: "must be a valid mainland China mobile number"},
+	"tracking_number":       {LangZH: "运单号只能包含 6~32 位字母和数字", LangEN: "must be 6-32 letters or digits"},
+	"tracking_pattern":      {LangZH: "运单号不符合该快递公司的格式", LangEN: "does not match this courier's tracking number format"},
+	"tracking_length":       {LangZH: "运单号长度不符合该快递公司的规则", LangEN: "has an invalid length for this courier"},
+	"tracking_checksum":     {LangZH: "运单号校验位错误", LangEN: "has an invalid check digit"},
+	"tracking_length_range": {LangZH: "最大长度不能小于最小长度", LangEN: "must not be less than tracking_min_length"},
+	"shelf_code":            {LangZH: "货架编号格式应为 A01 或 A-01", LangEN: "must look like A01 or A-01"},
+	"shelf_zone":            {LangZH: "不在允许的区域列表中", LangEN: "is not an allowed zone"},
+	"regexp":                {LangZH: "不是合法的正则表达式", LangEN: "must be a valid regular expression"},
+	"delegate_self":         {LangZH: "不能授权给本人", LangEN: "must not be your own phone number"},
+	"future":                {LangZH: "必须晚于当前时间", LangEN: "must be in the future"},
+	"after_starts_at":       {LangZH: "必须晚于生效时间", LangEN: "must be after starts_at"},
+	"delegation_window":     {LangZH: "授权时长不能超过 %s 天", LangEN: "delegation window must not exceed %s days"},
+	"date":                  {LangZH: "日期格式应为 YYYY-MM-DD", LangEN: "must be a date in YYYY-MM-DD format"},
+	"after_from":            {LangZH: "不能早于开始日期", LangEN: "must not be before from"},
+	"analytics_range":       {LangZH: "统计范围不能超过 %s 天", LangEN: "range must not exceed %s days"},
+	"import_file":           {LangZH: "无法解析文件，请上传 CSV 或 XLSX", LangEN: "could not be parsed as CSV or XLSX"},
+	"import_columns":        {LangZH: "缺少必需的列: %s", LangEN: "is missing required columns: %s"},
+	"duplicate_in_file":     {LangZH: "与第 %s 行重复", LangEN: "duplicates row %s"},
+	"taken":                 {LangZH: "已被其他记录使用", LangEN: "is already used by another record"},
+	"len":                   {LangZH: "长度必须为 %s", LangEN: "must be exactly %s characters"},
+	"numeric":               {LangZH: "只能包含数字", LangEN: "must contain only digits"},
+	"same_phone":            {LangZH: "与当前手机号相同", LangEN: "is the same as the current phone number"},
+	"task_query":            {LangZH: "请输入至少 4 位运单号或手机号尾号", LangEN: "must be at least 4 letters or digits of a tracking number or phone suffix"},
+	"merge_self":            {LangZH: "不能与保留的用户相同", LangEN: "must differ from target_phone"},
+}
+
+func fieldMessage(rule, param, lang string) string {
+	m, ok := fieldMessages[rule]
+	if !ok {
+		if lang == LangEN {
+			return "failed on rule " + rule
+		}
+		return "未通过校验: " + rule
+	}
+	msg, ok := m[lang]
+	if !ok {
+		msg = m[LangZH]
+	}
+	if strings.Contains(msg, "%s") {
+		msg = strings.Replace(msg, "%s", param, 1)
+	}
+	return msg
+}
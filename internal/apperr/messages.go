@@ -0,0 +1,104 @@
+package apperr
+
+import "strings"
+
+// 支持的语言，默认中文
+const (
+	LangZH = "zh"
+	LangEN = "en"
+)
+
+var messages = map[Code]map[string]string{
+	CodeInvalidRequest:       {LangZH: "请求参数错误", LangEN: "invalid request"},
+	CodeValidationFailed:     {LangZH: "请求参数校验失败", LangEN: "validation failed"},
+	CodeUnauthorized:         {LangZH: "未登录或登录已失效", LangEN: "unauthorized"},
+	CodeForbidden:            {LangZH: "无权执行该操作", LangEN: "forbidden"},
+	CodeNotFound:             {LangZH: "资源不存在", LangEN: "resource not found"},
+	CodeConflict:             {LangZH: "数据冲突，请刷新后重试", LangEN: "conflict with current state"},
+	CodeRateLimited:          {LangZH: "请求过于频繁，请稍后再试", LangEN: "too many requests"},
+	CodeServiceBusy:          {LangZH: "系统繁忙，请稍后重试", LangEN: "service busy, please retry"},
+	CodeInternal:             {LangZH: "服务器内部错误", LangEN: "internal server error"},
+	CodeAlreadyExists:        {LangZH: "名称或编号已存在", LangEN: "name or code already exists"},
+	CodeResourceInUse:        {LangZH: "仍被其他数据引用，无法删除", LangEN: "still referenced by other records; cannot delete"},
+	CodeInvalidCredentials:   {LangZH: "账号或密码错误", LangEN: "invalid credentials"},
+	CodeAccountLocked:        {LangZH: "账号已锁定，请稍后再试", LangEN: "account locked; try again later"},
+	CodeInvalidMFAToken:      {LangZH: "登录已过期，请重新输入密码", LangEN: "invalid or expired mfa token; log in again"},
+	CodeInvalidMFACode:       {LangZH: "动态验证码或恢复码错误", LangEN: "invalid mfa code"},
+	CodePasswordExpired:      {LangZH: "密码已过期，请先修改密码", LangEN: "password expired; change password first"},
+	CodeMFASetupRequired:     {LangZH: "请先绑定二次验证", LangEN: "mfa enrollment required"},
+	CodeWrongPassword:        {LangZH: "当前密码错误", LangEN: "current password is incorrect"},
+	CodeWeakPassword:         {LangZH: "密码不符合安全策略", LangEN: "password does not meet policy"},
+	CodePasswordReused:       {LangZH: "不能使用最近用过的密码", LangEN: "password was used recently"},
+	CodeAdminNotFound:        {LangZH: "管理员不存在", LangEN: "admin not found"},
+	CodeLastSuperAdmin:       {LangZH: "不能删除或降级最后一个超级管理员", LangEN: "cannot remove the last super admin"},
+	CodeCannotModifySelf:     {LangZH: "不能删除或降级自己的账号", LangEN: "cannot delete or demote yourself"},
+	CodeEnvManagedAccount:    {LangZH: "该账号由环境变量管理，不能在此修改", LangEN: "account is managed via environment variables"},
+	CodeMFAAlreadyEnabled:    {LangZH: "已启用二次验证", LangEN: "mfa already enabled"},
+	CodeMFANotEnabled:        {LangZH: "尚未启用二次验证", LangEN: "mfa not enabled"},
+	CodeMFAEnforced:          {LangZH: "系统要求所有管理员启用二次验证，不能关闭", LangEN: "mfa is required for all admins"},
+	CodeCourierNotFound:      {LangZH: "快递公司不存在", LangEN: "courier not found"},
+	CodeStationNotFound:      {LangZH: "站点不存在", LangEN: "station not found"},
+	CodeShelfNotFound:        {LangZH: "货架不存在", LangEN: "shelf not found"},
+	CodeShelfNotEmpty:        {LangZH: "货架上仍有包裹，无法删除", LangEN: "shelf is not empty; cannot delete"},
+	CodeParcelNotFound:       {LangZH: "包裹不存在", LangEN: "parcel not found"},
+	CodeDuplicateTracking:    {LangZH: "运单号已入库", LangEN: "tracking number already exists"},
+	CodeWarehouseFull:        {LangZH: "仓库爆满，请扩容", LangEN: "no shelf capacity left at this station"},
+	CodeNoSuitableShelf:      {LangZH: "没有符合尺寸或温控要求的空闲货架", LangEN: "no free shelf matches the parcel's size or temperature requirements"},
+	CodeInvalidPickupCode:    {LangZH: "取件码错误", LangEN: "invalid pickup code"},
+	CodeParcelNotPickable:    {LangZH: "包裹当前状态不可取件", LangEN: "parcel is not awaiting pickup"},
+	CodeInvalidStatus:        {LangZH: "无效的包裹状态", LangEN: "invalid parcel status"},
+	CodeIllegalTransition:    {LangZH: "不允许的状态变更", LangEN: "illegal status transition"},
+	CodeInvalidCourier:       {LangZH: "无效快递商", LangEN: "invalid courier"},
+	CodeCourierUndetermined:  {LangZH: "无法根据运单号识别快递公司", LangEN: "cannot determine courier from tracking number"},
+	CodeInvalidStation:       {LangZH: "无效站点", LangEN: "invalid station"},
+	CodeBatchTooLarge:        {LangZH: "批量条数超出上限", LangEN: "batch too large"},
+	CodeNoParcelsReady:       {LangZH: "没有待取件的包裹", LangEN: "no parcels awaiting pickup"},
+	CodeInvalidPickupToken:   {LangZH: "取件二维码无效", LangEN: "invalid pickup QR token"},
+	CodePickupTokenExpired:   {LangZH: "取件二维码已过期，请刷新后重试", LangEN: "pickup QR token expired; refresh and retry"},
+	CodePickupTokenUsed:      {LangZH: "取件二维码已使用", LangEN: "pickup QR token already used"},
+	CodeAttachmentTooLarge:   {LangZH: "附件过大", LangEN: "attachment too large"},
+	CodeUnsupportedMedia:     {LangZH: "不支持的文件类型", LangEN: "unsupported file type"},
+	CodeInvalidDownloadLink:  {LangZH: "下载链接无效或已过期", LangEN: "download link is invalid or expired"},
+	CodeReturnBatchNotFound:  {LangZH: "退件批次不存在", LangEN: "return batch not found"},
+	CodeReturnBatchClosed:    {LangZH: "退件批次已完成或已取消", LangEN: "return batch is already closed"},
+	CodeParcelNotReturnable:  {LangZH: "包裹已交接或不在架上，无法退回", LangEN: "parcel already handed over or no longer on the shelf"},
+	CodeShipmentNotFound:     {LangZH: "寄件订单不存在", LangEN: "shipment not found"},
+	CodeShippingUnavailable:  {LangZH: "该快递公司暂不支持寄往该地区", LangEN: "courier does not ship to this destination"},
+	CodeImportJobNotFound:    {LangZH: "导入任务不存在", LangEN: "import job not found"},
+	CodeUserNotFound:         {LangZH: "用户不存在", LangEN: "user not found"},
+	CodePhoneInUse:           {LangZH: "该手机号已被其他登记了学号的用户使用，请联系管理员合并账号", LangEN: "phone number belongs to another registered student; ask an administrator to merge the accounts"},
+	CodeInvalidVerification:  {LangZH: "验证码错误", LangEN: "invalid verification code"},
+	CodeVerificationExpired:  {LangZH: "验证码已失效，请重新获取", LangEN: "verification code expired; request a new one"},
+	CodeSMSNotConfigured:     {LangZH: "短信服务未配置，暂不能更换手机号", LangEN: "SMS is not configured; phone change is unavailable"},
+	CodePhoneNotAvailable:    {LangZH: "包裹已不在库，无法查看完整手机号", LangEN: "full phone number is only available while the parcel is awaiting pickup"},
+	CodeUserHasActiveItems:   {LangZH: "该用户仍有未取件的包裹或未完成的寄件订单，暂不能删除个人信息", LangEN: "user still has parcels awaiting pickup or open shipments"},
+	CodeIdempotencyMismatch:  {LangZH: "Idempotency-Key 已用于不同的请求内容", LangEN: "Idempotency-Key reused with a different request payload"},
+	CodeIdempotencyInProcess: {LangZH: "相同 Idempotency-Key 的请求正在处理中，请稍后重试", LangEN: "request with this Idempotency-Key is in progress; retry later"},
+	CodeRequestTooLarge:      {LangZH: "请求体过大", LangEN: "request body too large"},
+}
+
+// Message 错误码的本地化提示；缺少对应语言时回退到中文
+func Message(code Code, lang string) string {
+	m, ok := messages[code]
+	if !ok {
+		m = messages[CodeInternal]
+	}
+	if s, ok := m[lang]; ok {
+		return s
+	}
+	return m[LangZH]
+}
+
+// ParseLang 从 Accept-Language 请求头选出支持的语言，按出现顺序取第一个匹配项
+func ParseLang(acceptLanguage string) string {
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag := strings.ToLower(strings.TrimSpace(strings.SplitN(part, ";", 2)[0]))
+		switch {
+		case strings.HasPrefix(tag, LangZH):
+			return LangZH
+		case strings.HasPrefix(tag, LangEN):
+			return LangEN
+		}
+	}
+	return LangZH
+}
@@ -0,0 +1,69 @@
+package apperr
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// Postgres SQLSTATE
+const (
+	pqUniqueViolation      = "23505"
+	pqForeignKeyViolation  = "23503"
+	pqCheckViolation       = "23514"
+	pqInvalidTextRepr      = "22P02"
+	pqRaiseException       = "P0001"
+	pqSerializationFailure = "40001"
+	pqDeadlockDetected     = "40P01"
+	pqLockNotAvailable     = "55P03"
+)
+
+// uniqueConstraintCodes 唯一约束/唯一索引名到错误码
+var uniqueConstraintCodes = map[string]Code{
+	"idx_tracking_number": CodeDuplicateTracking,
+}
+
+// raisePrefixes sp_parcel_inbound 等存储过程 RAISE EXCEPTION 的信息前缀。
+// 新版存储过程通过 HINT 直接给出错误码，前缀匹配用于兼容尚未升级的数据库。
+var raisePrefixes = []struct {
+	prefix string
+	code   Code
+}{
+	{"无效快递商", CodeInvalidCourier},
+	{"无效站点", CodeInvalidStation},
+	{"仓库爆满", CodeWarehouseFull},
+	{"没有符合尺寸或温控要求的货架", CodeNoSuitableShelf},
+}
+
+// fromPostgres 按 SQLSTATE 与 RAISE 信息转换 Postgres 错误；非 Postgres 错误返回 nil
+func fromPostgres(err error) *Error {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return nil
+	}
+
+	switch string(pqErr.Code) {
+	case pqUniqueViolation:
+		if code, ok := uniqueConstraintCodes[pqErr.Constraint]; ok {
+			return Wrap(code, err)
+		}
+		return Wrap(CodeConflict, err)
+	case pqForeignKeyViolation:
+		return Wrap(CodeConflict, err)
+	case pqCheckViolation, pqInvalidTextRepr:
+		return Wrap(CodeInvalidRequest, err)
+	case pqSerializationFailure, pqDeadlockDetected, pqLockNotAvailable:
+		return Wrap(CodeServiceBusy, err)
+	case pqRaiseException:
+		if _, ok := httpStatus[Code(pqErr.Hint)]; ok {
+			return Wrap(Code(pqErr.Hint), err)
+		}
+		for _, p := range raisePrefixes {
+			if strings.HasPrefix(pqErr.Message, p.prefix) {
+				return Wrap(p.code, err)
+			}
+		}
+	}
+	return nil
+}
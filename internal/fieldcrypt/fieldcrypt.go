@@ -0,0 +1,160 @@
+// Package fieldcrypt 敏感字段（手机号）的应用层加密。
+// 密文使用 AES-256-GCM，格式为 "<密钥 id>:<base64(nonce || 密文)>"，密钥 id 用于轮换后解密旧数据；
+// 按值查找使用 HMAC-SHA256 盲索引（十六进制，64 位），与加密密钥分开配置。
+package fieldcrypt
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// ErrUnknownKey 密文使用的密钥 id 不在密钥环中
+var ErrUnknownKey = errors.New("unknown encryption key id")
+
+// KeyRing 加密密钥环：新数据使用 active 密钥加密，其余密钥只用于解密
+type KeyRing struct {
+	active   string
+	aeads    map[string]cipher.AEAD
+	indexKey []byte
+}
+
+// New 创建密钥环；keys 为密钥 id -> 32 字节 AES-256 密钥，active 必须在其中，indexKey 为盲索引密钥（至少 32 字节）
+func New(active string, keys map[string][]byte, indexKey []byte) (*KeyRing, error) {
+	if _, ok := keys[active]; !ok {
+		return nil, fmt.Errorf("active encryption key %q not configured", active)
+	}
+	if len(indexKey) < 32 {
+		return nil, errors.New("blind index key must be at least 32 bytes")
+	}
+	k := &KeyRing{active: active, aeads: make(map[string]cipher.AEAD, len(keys)), indexKey: indexKey}
+	for id, key := range keys {
+		if id == "" || strings.Contains(id, ":") {
+			return nil, fmt.Errorf("invalid encryption key id %q", id)
+		}
+		if len(key) != 32 {
+			return nil, fmt.Errorf("encryption key %q must be 32 bytes", id)
+		}
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, err
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+		k.aeads[id] = aead
+	}
+	return k, nil
+}
+
+// ActiveKeyID 当前用于加密的密钥 id
+func (k *KeyRing) ActiveKeyID() string {
+	return k.active
+}
+
+// Encrypt 使用当前密钥加密
+func (k *KeyRing) Encrypt(plain string) (string, error) {
+	aead := k.aeads[k.active]
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(plain), nil)
+	return k.active + ":" + base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt 按密文中的密钥 id 解密
+func (k *KeyRing) Decrypt(s string) (string, error) {
+	id, payload, ok := strings.Cut(s, ":")
+	if !ok {
+		return "", errors.New("malformed ciphertext")
+	}
+	aead, ok := k.aeads[id]
+	if !ok {
+		return "", fmt.Errorf("%w %q", ErrUnknownKey, id)
+	}
+	raw, err := base64.RawStdEncoding.DecodeString(payload)
+	if err != nil || len(raw) < aead.NonceSize() {
+		return "", errors.New("malformed ciphertext")
+	}
+	plain, err := aead.Open(nil, raw[:aead.NonceSize()], raw[aead.NonceSize():], nil)
+	if err != nil {
+		return "", fmt.Errorf("decrypt failed: %w", err)
+	}
+	return string(plain), nil
+}
+
+// BlindIndex 盲索引：相同的值得到相同的结果，可用于等值查找与唯一约束，但不能还原原值
+func (k *KeyRing) BlindIndex(v string) string {
+	mac := hmac.New(sha256.New, k.indexKey)
+	mac.Write([]byte(v))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// IsCiphertext 判断列值是否为密文；明文手机号（含脱敏形式）不包含 ":"
+func IsCiphertext(s string) bool {
+	return strings.Contains(s, ":")
+}
+
+// Default 全局密钥环，由 Init 按配置创建
+var Default *KeyRing
+
+// Init 按配置创建全局密钥环；启动时调用一次。
+// encryption.keys 为密钥 id -> base64 编码的 32 字节密钥，encryption.active_key 为当前密钥 id，
+// encryption.blind_index_key 为 base64 编码的盲索引密钥。
+// 生产环境优先读取环境变量 ENCRYPTION_KEYS（"id:base64,id:base64"）、ENCRYPTION_ACTIVE_KEY 与 ENCRYPTION_BLIND_INDEX_KEY；
+// server.mode 为 release 时未配置密钥直接报错，避免使用提交在仓库中的开发密钥或空密钥启动
+func Init() error {
+	encoded := viper.GetStringMapString("encryption.keys")
+	if s := strings.TrimSpace(os.Getenv("ENCRYPTION_KEYS")); s != "" {
+		encoded = map[string]string{}
+		for _, pair := range strings.Split(s, ",") {
+			id, key, ok := strings.Cut(strings.TrimSpace(pair), ":")
+			if !ok {
+				return errors.New("ENCRYPTION_KEYS must be comma separated id:base64 pairs")
+			}
+			encoded[id] = key
+		}
+	}
+	indexEncoded := envOr("ENCRYPTION_BLIND_INDEX_KEY", viper.GetString("encryption.blind_index_key"))
+	if viper.GetString("server.mode") == "release" && (len(encoded) == 0 || indexEncoded == "") {
+		return errors.New("encryption keys are required in release mode; set ENCRYPTION_KEYS, ENCRYPTION_ACTIVE_KEY and ENCRYPTION_BLIND_INDEX_KEY")
+	}
+	keys := make(map[string][]byte, len(encoded))
+	for id, s := range encoded {
+		key, err := base64.StdEncoding.DecodeString(s)
+		if err != nil {
+			return fmt.Errorf("encryption key %q is not valid base64: %w", id, err)
+		}
+		keys[id] = key
+	}
+	indexKey, err := base64.StdEncoding.DecodeString(indexEncoded)
+	if err != nil {
+		return fmt.Errorf("blind index key is not valid base64: %w", err)
+	}
+
+	k, err := New(envOr("ENCRYPTION_ACTIVE_KEY", viper.GetString("encryption.active_key")), keys, indexKey)
+	if err != nil {
+		return err
+	}
+	Default = k
+	return nil
+}
+
+func envOr(key, fallback string) string {
+	if v := strings.TrimSpace(os.Getenv(key)); v != "" {
+		return v
+	}
+	return fallback
+}
@@ -0,0 +1,128 @@
+package fieldcrypt
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func testKey(b byte) []byte {
+	return bytes.Repeat([]byte{b}, 32)
+}
+
+func TestEncryptDecryptRoundTrip(t *testing.T) {
+	k, err := New("k1", map[string][]byte{"k1": testKey(1)}, testKey(9))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, plain := range []string{"13800138000", "", "电话 010-12345678"} {
+		enc, err := k.Encrypt(plain)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(enc, "k1:") {
+			t.Errorf("Encrypt(%q) = %q, want k1 ciphertext", plain, enc)
+		}
+		got, err := k.Decrypt(enc)
+		if err != nil || got != plain {
+			t.Errorf("Decrypt(Encrypt(%q)) = %q, %v", plain, got, err)
+		}
+	}
+
+	a, _ := k.Encrypt("13800138000")
+	b, _ := k.Encrypt("13800138000")
+	if a == b {
+		t.Error("Encrypt should use a random nonce")
+	}
+	tampered := []byte(a)
+	if i := len(tampered) / 2; tampered[i] == 'A' {
+		tampered[i] = 'B'
+	} else {
+		tampered[i] = 'A'
+	}
+	if _, err := k.Decrypt(string(tampered)); err == nil {
+		t.Error("Decrypt should reject tampered ciphertext")
+	}
+}
+
+func TestRotation(t *testing.T) {
+	old, err := New("k1", map[string][]byte{"k1": testKey(1)}, testKey(9))
+	if err != nil {
+		t.Fatal(err)
+	}
+	enc, _ := old.Encrypt("13800138000")
+
+	rotated, err := New("k2", map[string][]byte{"k1": testKey(1), "k2": testKey(2)}, testKey(9))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := rotated.Decrypt(enc); err != nil || got != "13800138000" {
+		t.Errorf("rotated ring Decrypt(old) = %q, %v", got, err)
+	}
+	reenc, _ := rotated.Encrypt("13800138000")
+	if !strings.HasPrefix(reenc, "k2:") {
+		t.Errorf("rotated ring should encrypt with k2, got %q", reenc)
+	}
+	if _, err := old.Decrypt(reenc); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("old ring Decrypt(k2 ciphertext) error = %v, want ErrUnknownKey", err)
+	}
+}
+
+func TestBlindIndexDeterministic(t *testing.T) {
+	k1, _ := New("k1", map[string][]byte{"k1": testKey(1)}, testKey(9))
+	k2, _ := New("k2", map[string][]byte{"k2": testKey(2)}, testKey(9))
+	other, _ := New("k1", map[string][]byte{"k1": testKey(1)}, testKey(8))
+
+	h := k1.BlindIndex("13800138000")
+	if len(h) != 64 {
+		t.Errorf("BlindIndex length = %d, want 64", len(h))
+	}
+	if h != k1.BlindIndex("13800138000") || h != k2.BlindIndex("13800138000") {
+		t.Error("BlindIndex should depend only on the value and the index key")
+	}
+	if h == k1.BlindIndex("13800138001") {
+		t.Error("different values should have different blind indexes")
+	}
+	if h == other.BlindIndex("13800138000") {
+		t.Error("different index keys should have different blind indexes")
+	}
+}
+
+func TestIsCiphertext(t *testing.T) {
+	k, _ := New("k1", map[string][]byte{"k1": testKey(1)}, testKey(9))
+	enc, _ := k.Encrypt("13800138000")
+	tests := []struct {
+		value string
+		want  bool
+	}{
+		{enc, true},
+		{"13800138000", false},
+		{"138****8000", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := IsCiphertext(tt.value); got != tt.want {
+			t.Errorf("IsCiphertext(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestNewRejectsInvalidKeys(t *testing.T) {
+	tests := []struct {
+		name     string
+		active   string
+		keys     map[string][]byte
+		indexKey []byte
+	}{
+		{"missing active key", "k2", map[string][]byte{"k1": testKey(1)}, testKey(9)},
+		{"short key", "k1", map[string][]byte{"k1": testKey(1)[:16]}, testKey(9)},
+		{"key id with colon", "k:1", map[string][]byte{"k:1": testKey(1)}, testKey(9)},
+		{"short index key", "k1", map[string][]byte{"k1": testKey(1)}, testKey(9)[:16]},
+	}
+	for _, tt := range tests {
+		if _, err := New(tt.active, tt.keys, tt.indexKey); err == nil {
+			t.Errorf("%s: New should fail", tt.name)
+		}
+	}
+}
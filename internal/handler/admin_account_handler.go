@@ -0,0 +1,175 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"campus-logistics/internal/apperr"
+	"campus-logistics/internal/middleware"
+	"campus-logistics/internal/repository"
+	"campus-logistics/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type createAdminRequest struct {
+	Username string `json:"username" binding:"required,max=50"`
+	Password string `json:"password" binding:"required"`
+	Role     string `json:"role"`
+	// StationCode 为空表示可管理全部站点
+	StationCode string `json:"station_code"`
+}
+
+type changePasswordRequest struct {
+	OldPassword string `json:"old_password" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required"`
+}
+
+// ListAdminsHandler 超级管理员查看管理员账号（含 last_login_at / failed_attempts / locked_until）
+// GET /api/v1/admin/admins?page=1&page_size=20
+func ListAdminsHandler(c *gin.Context) {
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil {
+		page = 1
+	}
+	pageSize, err := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if err != nil {
+		pageSize = 20
+	}
+
+	admins, err := service.ListAdminAccounts(page, pageSize)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":   "success",
+		"data":      admins,
+		"count":     len(admins),
+		"page":      page,
+		"page_size": pageSize,
+	})
+}
+
+// CreateAdminHandler 创建管理员账号
+// POST /api/v1/admin/admins
+func CreateAdminHandler(c *gin.Context) {
+	var req createAdminRequest
+	if err := bindJSON(c, &req); err != nil {
+		c.Error(err)
+		return
+	}
+
+	// 用户名已存在 409 ALREADY_EXISTS
+	created, err := service.CreateAdminAccount(req.Username, req.Password, req.Role, req.StationCode)
+	if err != nil {
+		if isUniqueViolation(err) {
+			c.Error(apperr.Wrap(apperr.CodeAlreadyExists, err))
+			return
+		}
+		c.Error(adminAccountError(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "success", "data": created})
+}
+
+// UpdateAdminHandler 修改角色、解锁或重置密码
+// PATCH /api/v1/admin/admins/:username
+func UpdateAdminHandler(c *gin.Context) {
+	claims, ok := middleware.GetClaims(c)
+	if !ok {
+		c.Error(apperr.Newf(apperr.CodeUnauthorized, "missing admin claims"))
+		return
+	}
+
+	var req service.UpdateAdminAccountRequest
+	if err := bindJSON(c, &req); err != nil {
+		c.Error(err)
+		return
+	}
+
+	updated, err := service.UpdateAdminAccount(claims.AdminID, c.Param("username"), req)
+	if err != nil {
+		c.Error(adminAccountError(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "success", "data": updated})
+}
+
+// DeleteAdminHandler 删除管理员账号（不能删除自己或最后一个超级管理员）
+// DELETE /api/v1/admin/admins/:username
+func DeleteAdminHandler(c *gin.Context) {
+	claims, ok := middleware.GetClaims(c)
+	if !ok {
+		c.Error(apperr.Newf(apperr.CodeUnauthorized, "missing admin claims"))
+		return
+	}
+
+	if err := service.DeleteAdminAccount(claims.AdminID, c.Param("username")); err != nil {
+		c.Error(adminAccountError(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "success"})
+}
+
+// ChangeAdminPasswordHandler 管理员修改自己的密码（密码过期时也可调用）
+// POST /api/v1/admin/account/password
+func ChangeAdminPasswordHandler(c *gin.Context) {
+	claims, ok := middleware.GetClaims(c)
+	if !ok {
+		c.Error(apperr.Newf(apperr.CodeUnauthorized, "missing admin claims"))
+		return
+	}
+
+	var req changePasswordRequest
+	if err := bindJSON(c, &req); err != nil {
+		c.Error(err)
+		return
+	}
+
+	if err := service.ChangeAdminPassword(claims.AdminID, req.OldPassword, req.NewPassword); err != nil {
+		c.Error(adminAccountError(err))
+		return
+	}
+
+	// 旧 token 中仍带有 pwd_expired 标记，客户端需重新登录获取新 token
+	c.JSON(http.StatusOK, gin.H{"message": "success", "relogin_required": true})
+}
+
+// adminAccountError 把管理员账号与 MFA 相关的业务错误转换为错误码；其余错误原样返回
+func adminAccountError(err error) error {
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		return apperr.Wrap(apperr.CodeAdminNotFound, err)
+	case errors.Is(err, service.ErrPasswordReused):
+		return apperr.Wrap(apperr.CodePasswordReused, err)
+	case errors.Is(err, service.ErrInvalidAdminRole):
+		return apperr.Invalid("role", "oneof").WithParam(middleware.AdminRoleSuper + " " + middleware.AdminRoleOperator)
+	case errors.Is(err, service.ErrInvalidAdminParams):
+		return apperr.Invalid("username", "required")
+	case errors.Is(err, service.ErrInvalidStation):
+		return apperr.Wrap(apperr.CodeInvalidStation, err)
+	case errors.Is(err, service.ErrWrongPassword):
+		return apperr.Wrap(apperr.CodeWrongPassword, err)
+	case errors.Is(err, service.ErrInvalidMFACode):
+		return apperr.Wrap(apperr.CodeInvalidMFACode, err)
+	case errors.Is(err, service.ErrLastSuperAdmin):
+		return apperr.Wrap(apperr.CodeLastSuperAdmin, err)
+	case errors.Is(err, service.ErrCannotModifySelf):
+		return apperr.Wrap(apperr.CodeCannotModifySelf, err)
+	case errors.Is(err, service.ErrEnvManagedAccount):
+		return apperr.Wrap(apperr.CodeEnvManagedAccount, err)
+	case errors.Is(err, service.ErrMFAAlreadyEnabled):
+		return apperr.Wrap(apperr.CodeMFAAlreadyEnabled, err)
+	case errors.Is(err, service.ErrMFANotEnabled):
+		return apperr.Wrap(apperr.CodeMFANotEnabled, err)
+	case errors.Is(err, service.ErrMFAEnforced):
+		return apperr.Wrap(apperr.CodeMFAEnforced, err)
+	}
+	return err
+}
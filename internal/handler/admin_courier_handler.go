@@ -1,18 +1,30 @@
 package handler
 
 import (
+	"campus-logistics/internal/apperr"
 	"campus-logistics/internal/repository"
+	"campus-logistics/internal/service"
+	"campus-logistics/internal/validation"
+	"errors"
 	"net/http"
 	"strings"
 
 	"github.com/gin-gonic/gin"
-	"github.com/lib/pq"
 )
 
 type createCourierRequest struct {
-	Name         string `json:"name" binding:"required"`
-	Code         string `json:"code" binding:"required"`
-	ContactPhone string `json:"contact_phone"`
+	Name         string `json:"name" binding:"required,max=50"`
+	Code         string `json:"code" binding:"required,max=20"`
+	ContactPhone string `json:"contact_phone" binding:"max=20"`
+	// TrackingPattern 运单号格式（正则，整串匹配），为空不限制
+	TrackingPattern string `json:"tracking_pattern" binding:"omitempty,max=200,regexp"`
+}
+
+func (r *createCourierRequest) Normalize() {
+	r.Name = validation.Text(r.Name)
+	r.Code = validation.Code(r.Code)
+	r.ContactPhone = validation.Text(r.ContactPhone)
+	r.TrackingPattern = strings.TrimSpace(r.TrackingPattern)
 }
 
 func ListCouriersHandler(c *gin.Context) {
@@ -22,7 +34,7 @@ func ListCouriersHandler(c *gin.Context) {
 
 	couriers, err := repository.ListCouriers(limit, offset)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "list couriers failed"})
+		c.Error(err)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"message": "success", "data": couriers})
@@ -30,56 +42,103 @@ func ListCouriersHandler(c *gin.Context) {
 
 func CreateCourierHandler(c *gin.Context) {
 	var req createCourierRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request: " + err.Error()})
+	if err := bindJSON(c, &req); err != nil {
+		c.Error(err)
 		return
 	}
 
-	name := strings.TrimSpace(req.Name)
-	code := strings.ToUpper(strings.TrimSpace(req.Code))
-	contactPhone := strings.TrimSpace(req.ContactPhone)
+	// 名称或代码已存在 409 ALREADY_EXISTS
+	created, err := repository.CreateCourier(req.Name, req.Code, req.ContactPhone, req.TrackingPattern)
+	if err != nil {
+		if isUniqueViolation(err) {
+			c.Error(apperr.Wrap(apperr.CodeAlreadyExists, err))
+			return
+		}
+		c.Error(err)
+		return
+	}
 
-	if name == "" || code == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "name and code are required"})
+	c.JSON(http.StatusOK, gin.H{"message": "success", "data": created})
+}
+
+// DetectCourierHandler 根据扫描到的运单号识别快递公司
+// GET /api/v1/admin/couriers/detect?tracking_number=SF1234567890123
+func DetectCourierHandler(c *gin.Context) {
+	trackingNumber := validation.Code(c.Query("tracking_number"))
+	if trackingNumber == "" {
+		c.Error(apperr.Invalid("tracking_number", "required"))
 		return
 	}
 
-	created, err := repository.CreateCourier(name, code, contactPhone)
+	codes, err := service.DetectCouriers(trackingNumber)
 	if err != nil {
-		if pqErr, ok := err.(*pq.Error); ok {
-			switch string(pqErr.Code) {
-			case "23505":
-				c.JSON(http.StatusConflict, gin.H{"error": "courier name/code already exists"})
-				return
-			}
+		c.Error(err)
+		return
+	}
+	if codes == nil {
+		codes = []string{}
+	}
+
+	// 只有唯一匹配时才给出 courier_code，多家匹配时由调用方从 candidates 中选择
+	courierCode := ""
+	if len(codes) == 1 {
+		courierCode = codes[0]
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message": "success",
+		"data": gin.H{
+			"tracking_number": trackingNumber,
+			"courier_code":    courierCode,
+			"candidates":      codes,
+		},
+	})
+}
+
+// UpdateCourierHandler 修改快递公司的运单号规则（格式、长度、校验位），未传的字段保持不变
+// PATCH /api/v1/admin/couriers/:code
+func UpdateCourierHandler(c *gin.Context) {
+	code := validation.Code(c.Param("code"))
+	if code == "" {
+		c.Error(apperr.Invalid("code", "required"))
+		return
+	}
+
+	var req service.UpdateTrackingRuleRequest
+	if err := bindJSON(c, &req); err != nil {
+		c.Error(err)
+		return
+	}
+
+	updated, err := service.UpdateCourierTrackingRule(code, req)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			c.Error(apperr.New(apperr.CodeCourierNotFound))
+			return
 		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "create courier failed"})
+		c.Error(err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "success", "data": created})
+	c.JSON(http.StatusOK, gin.H{"message": "success", "data": updated})
 }
 
 func DeleteCourierHandler(c *gin.Context) {
 	code := strings.ToUpper(strings.TrimSpace(c.Param("code")))
 	if code == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "code is required"})
+		c.Error(apperr.Invalid("code", "required"))
 		return
 	}
 
+	// 已有包裹引用时 409 RESOURCE_IN_USE
 	if err := repository.DeleteCourierByCode(code); err != nil {
-		if err == repository.ErrNotFound {
-			c.JSON(http.StatusNotFound, gin.H{"error": "courier not found"})
-			return
-		}
-		if pqErr, ok := err.(*pq.Error); ok {
-			switch string(pqErr.Code) {
-			case "23503":
-				c.JSON(http.StatusConflict, gin.H{"error": "courier is referenced by parcels; cannot delete"})
-				return
-			}
+		switch {
+		case errors.Is(err, repository.ErrNotFound):
+			c.Error(apperr.New(apperr.CodeCourierNotFound))
+		case isForeignKeyViolation(err):
+			c.Error(apperr.Wrap(apperr.CodeResourceInUse, err))
+		default:
+			c.Error(err)
 		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "delete courier failed"})
 		return
 	}
 
@@ -4,20 +4,25 @@ import (
 	"net/http"
 	"strconv"
 
-	"campus-logistics/internal/model"
+	"campus-logistics/internal/apperr"
+	"campus-logistics/internal/middleware"
 	"campus-logistics/internal/service"
+	"campus-logistics/internal/validation"
 
 	"github.com/gin-gonic/gin"
 )
 
 // AdminDashboardHandler 管理员仪表盘接口
-// GET /api/v1/admin/dashboard
+// GET /api/v1/admin/dashboard?station=NORTH
 func AdminDashboardHandler(c *gin.Context) {
-	dashboard, err := service.GetAdminDashboardService()
+	stationID, ok := stationScope(c)
+	if !ok {
+		return
+	}
+
+	dashboard, err := service.GetAdminDashboardService(stationID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "failed to load dashboard",
-		})
+		c.Error(err)
 		return
 	}
 
@@ -27,9 +32,65 @@ func AdminDashboardHandler(c *gin.Context) {
 	})
 }
 
+// AdminAnalyticsHandler 管理员运营分析接口：时间序列、滞留时长、快递公司与货架区域统计
+// GET /api/v1/admin/analytics?from=2026-10-01&to=2026-10-07&granularity=day&station=NORTH
+func AdminAnalyticsHandler(c *gin.Context) {
+	stationID, ok := stationScope(c)
+	if !ok {
+		return
+	}
+
+	// 日期格式错误或范围过大 400 VALIDATION_FAILED
+	analytics, err := service.GetAnalytics(c.Query("from"), c.Query("to"), c.Query("granularity"), stationID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "success", "data": analytics})
+}
+
+// AdminDailyStatsHandler 查询每日统计汇总（入库、取件、退回、异常及滞留时长分布）
+// GET /api/v1/admin/stats/daily?from=2026-10-01&to=2026-10-07&group_by=courier&station=NORTH
+func AdminDailyStatsHandler(c *gin.Context) {
+	stationID, ok := stationScope(c)
+	if !ok {
+		return
+	}
+
+	result, err := service.GetDailyStats(c.Query("from"), c.Query("to"), c.Query("group_by"), stationID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "success", "data": result})
+}
+
+// RollupDailyStatsHandler 按需回填（重算）每日统计汇总，可重复执行
+// POST /api/v1/admin/stats/rollup
+// 请求体：{"from": "2026-09-01", "to": "2026-09-30"}
+func RollupDailyStatsHandler(c *gin.Context) {
+	var req service.StatsRollupRequest
+	if err := bindJSON(c, &req); err != nil {
+		c.Error(err)
+		return
+	}
+
+	result, err := service.RollupDailyStats(req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "success", "data": result})
+}
+
 // GetRetentionParcelsHandler 查询滞留包裹列表
-// GET /api/v1/admin/parcels/retention?days=7&page=1&page_size=20
+// GET /api/v1/admin/parcels/retention?days=7&page=1&page_size=20&station=NORTH
 func GetRetentionParcelsHandler(c *gin.Context) {
+	stationID, ok := stationScope(c)
+	if !ok {
+		return
+	}
+
 	daysStr := c.DefaultQuery("days", "7")
 	pageStr := c.DefaultQuery("page", "1")
 	pageSizeStr := c.DefaultQuery("page_size", "20")
@@ -47,18 +108,12 @@ func GetRetentionParcelsHandler(c *gin.Context) {
 		pageSize = 20
 	}
 
-	parcels, err := service.GetRetentionParcelsService(days, page, pageSize)
+	parcels, err := service.GetRetentionParcelsService(days, stationID, page, pageSize)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "failed to query retention parcels",
-		})
+		c.Error(err)
 		return
 	}
 
-	if parcels == nil {
-		parcels = []model.ParcelViewStudent{}
-	}
-
 	c.JSON(http.StatusOK, gin.H{
 		"message":   "success",
 		"data":      parcels,
@@ -69,15 +124,35 @@ func GetRetentionParcelsHandler(c *gin.Context) {
 	})
 }
 
+// RevealParcelPhoneHandler 管理员查看收件人完整手机号（仅限未取件的包裹，每次查看都会记录）
+// POST /api/v1/admin/parcels/:tracking_number/phone
+func RevealParcelPhoneHandler(c *gin.Context) {
+	claims, ok := middleware.GetClaims(c)
+	if !ok || claims.Username == "" {
+		c.Error(apperr.Newf(apperr.CodeUnauthorized, "missing admin claims"))
+		return
+	}
+	stationID, ok := stationScope(c)
+	if !ok {
+		return
+	}
+
+	result, err := service.RevealParcelPhone(validation.Code(c.Param("tracking_number")),
+		0, stationID, claims.Username, "admin")
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "success", "data": result})
+}
+
 // UpdateParcelStatusHandler 管理员更新包裹状态接口
 // POST /api/v1/admin/parcels/:tracking_number/status
 // body: {"status": "pending" | "returned" | "exception" | "stored"}
 func UpdateParcelStatusHandler(c *gin.Context) {
 	trackingNum := c.Param("tracking_number")
 	if trackingNum == "" {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "tracking_number is required",
-		})
+		c.Error(apperr.Invalid("tracking_number", "required"))
 		return
 	}
 
@@ -85,17 +160,19 @@ func UpdateParcelStatusHandler(c *gin.Context) {
 		Status string `json:"status" binding:"required"`
 	}
 
-	if err := c.ShouldBindJSON(&req); err != nil || req.Status == "" {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "invalid status payload",
-		})
+	if err := bindJSON(c, &req); err != nil {
+		c.Error(err)
+		return
+	}
+
+	stationID, ok := stationScope(c)
+	if !ok {
 		return
 	}
 
-	if err := service.UpdateParcelStatusService(trackingNum, req.Status); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": err.Error(),
-		})
+	// 包裹不存在 404 PARCEL_NOT_FOUND，非法状态 400 INVALID_STATUS，当前状态不允许变更 409 ILLEGAL_TRANSITION
+	if err := service.UpdateParcelStatusService(trackingNum, req.Status, stationID); err != nil {
+		c.Error(err)
 		return
 	}
 
@@ -0,0 +1,193 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"campus-logistics/internal/apperr"
+	"campus-logistics/internal/middleware"
+	"campus-logistics/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type adminMFARequest struct {
+	MFAToken     string `json:"mfa_token" binding:"required"`
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+type mfaCodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+type disableMFARequest struct {
+	Password     string `json:"password" binding:"required"`
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+// POST /api/v1/auth/admin/mfa
+// 登录第二步：提交 mfa_token 与 6 位验证码（或一次性恢复码）
+func AdminMFAHandler(c *gin.Context) {
+	var req adminMFARequest
+	if err := bindJSON(c, &req); err != nil {
+		c.Error(err)
+		return
+	}
+
+	// 账号锁定 423 ACCOUNT_LOCKED，mfa_token 无效或过期 401 INVALID_MFA_TOKEN，验证码错误 401 INVALID_MFA_CODE
+	resp, err := service.CompleteAdminMFA(req.MFAToken, req.Code, req.RecoveryCode)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrAccountLocked):
+			c.Error(apperr.Wrap(apperr.CodeAccountLocked, err))
+		case errors.Is(err, service.ErrInvalidMFAToken):
+			c.Error(apperr.Wrap(apperr.CodeInvalidMFAToken, err))
+		default:
+			c.Error(adminAccountError(err))
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "success", "data": resp})
+}
+
+// GetMFAStatusHandler 查看本人 MFA 状态
+// GET /api/v1/admin/account/mfa
+func GetMFAStatusHandler(c *gin.Context) {
+	claims, ok := middleware.GetClaims(c)
+	if !ok {
+		c.Error(apperr.Newf(apperr.CodeUnauthorized, "missing admin claims"))
+		return
+	}
+
+	status, err := service.GetMFAStatus(claims.AdminID)
+	if err != nil {
+		c.Error(adminAccountError(err))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "success", "data": status})
+}
+
+// BeginMFAEnrollmentHandler 生成 TOTP 密钥与 otpauth:// URI
+// POST /api/v1/admin/account/mfa/enroll
+func BeginMFAEnrollmentHandler(c *gin.Context) {
+	claims, ok := middleware.GetClaims(c)
+	if !ok {
+		c.Error(apperr.Newf(apperr.CodeUnauthorized, "missing admin claims"))
+		return
+	}
+
+	enrollment, err := service.BeginMFAEnrollment(claims.AdminID)
+	if err != nil {
+		c.Error(adminAccountError(err))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "success", "data": enrollment})
+}
+
+// ActivateMFAHandler 提交验证器上的验证码完成绑定，返回恢复码（仅此一次）
+// POST /api/v1/admin/account/mfa/activate
+func ActivateMFAHandler(c *gin.Context) {
+	claims, ok := middleware.GetClaims(c)
+	if !ok {
+		c.Error(apperr.Newf(apperr.CodeUnauthorized, "missing admin claims"))
+		return
+	}
+
+	var req mfaCodeRequest
+	if err := bindJSON(c, &req); err != nil {
+		c.Error(err)
+		return
+	}
+
+	codes, err := service.ActivateMFA(claims.AdminID, req.Code)
+	if err != nil {
+		c.Error(adminAccountError(err))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message":          "success",
+		"data":             gin.H{"recovery_codes": codes},
+		"relogin_required": claims.MFASetupRequired,
+	})
+}
+
+// DisableMFAHandler 关闭 MFA（系统强制时不允许），需要密码以及验证码或恢复码
+// DELETE /api/v1/admin/account/mfa
+func DisableMFAHandler(c *gin.Context) {
+	claims, ok := middleware.GetClaims(c)
+	if !ok {
+		c.Error(apperr.Newf(apperr.CodeUnauthorized, "missing admin claims"))
+		return
+	}
+
+	var req disableMFARequest
+	if err := bindJSON(c, &req); err != nil {
+		c.Error(err)
+		return
+	}
+
+	if err := service.DisableMFA(claims.AdminID, req.Password, req.Code, req.RecoveryCode); err != nil {
+		c.Error(adminAccountError(err))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "success"})
+}
+
+// RegenerateRecoveryCodesHandler 重新生成恢复码，旧恢复码全部作废
+// POST /api/v1/admin/account/mfa/recovery-codes
+func RegenerateRecoveryCodesHandler(c *gin.Context) {
+	claims, ok := middleware.GetClaims(c)
+	if !ok {
+		c.Error(apperr.Newf(apperr.CodeUnauthorized, "missing admin claims"))
+		return
+	}
+
+	var req mfaCodeRequest
+	if err := bindJSON(c, &req); err != nil {
+		c.Error(err)
+		return
+	}
+
+	codes, err := service.RegenerateRecoveryCodes(claims.AdminID, req.Code)
+	if err != nil {
+		c.Error(adminAccountError(err))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "success", "data": gin.H{"recovery_codes": codes}})
+}
+
+// GetSecuritySettingsHandler 查看安全设置
+// GET /api/v1/admin/settings/security
+func GetSecuritySettingsHandler(c *gin.Context) {
+	settings, err := service.GetSecuritySettings()
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "success", "data": settings})
+}
+
+// UpdateSecuritySettingsHandler 修改安全设置（如强制所有管理员启用 MFA）
+// PUT /api/v1/admin/settings/security
+func UpdateSecuritySettingsHandler(c *gin.Context) {
+	claims, ok := middleware.GetClaims(c)
+	if !ok {
+		c.Error(apperr.Newf(apperr.CodeUnauthorized, "missing admin claims"))
+		return
+	}
+
+	var req service.SecuritySettings
+	if err := bindJSON(c, &req); err != nil {
+		c.Error(err)
+		return
+	}
+
+	if err := service.UpdateSecuritySettings(req, claims.Username); err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "success", "data": req})
+}
@@ -1,27 +1,57 @@
 package handler
 
 import (
+	"campus-logistics/internal/apperr"
+	"campus-logistics/internal/middleware"
 	"campus-logistics/internal/repository"
+	"campus-logistics/internal/service"
+	"campus-logistics/internal/validation"
+	"errors"
 	"net/http"
 	"strings"
 
 	"github.com/gin-gonic/gin"
-	"github.com/lib/pq"
 )
 
 type createShelfRequest struct {
-	Zone     string `json:"zone" binding:"required"`
-	Code     string `json:"code" binding:"required"`
-	Capacity int    `json:"capacity" binding:"required"`
+	// StationCode 货架所属站点；站点管理员可省略（使用本站点）
+	StationCode string `json:"station_code"`
+	// Zone 受 validation.shelf_zones 白名单限制
+	Zone     string `json:"zone" binding:"required,shelf_zone"`
+	Code     string `json:"code" binding:"required,shelf_code"`
+	Capacity int    `json:"capacity" binding:"required,min=1,max=10000"`
+	// MaxSize 可存放的最大尺寸等级，默认 large
+	MaxSize string `json:"max_size" binding:"omitempty,oneof=document small medium large bulky"`
+	// Temperature 温控类型：ambient（常温，默认）/ chilled（冷藏）
+	Temperature string `json:"temperature" binding:"omitempty,oneof=ambient chilled"`
+}
+
+func (r *createShelfRequest) Normalize() {
+	r.StationCode = validation.Code(r.StationCode)
+	r.Zone = validation.Code(r.Zone)
+	r.Code = validation.Code(r.Code)
+	r.MaxSize = strings.ToLower(strings.TrimSpace(r.MaxSize))
+	r.Temperature = strings.ToLower(strings.TrimSpace(r.Temperature))
+	if r.MaxSize == "" {
+		r.MaxSize = "large"
+	}
+	if r.Temperature == "" {
+		r.Temperature = "ambient"
+	}
 }
 
 func ListShelvesHandler(c *gin.Context) {
+	stationID, ok := stationScope(c)
+	if !ok {
+		return
+	}
+
 	limit := 200
 	offset := 0
 
-	shelves, err := repository.ListShelves(limit, offset)
+	shelves, err := repository.ListShelves(stationID, limit, offset)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "list shelves failed"})
+		c.Error(err)
 		return
 	}
 
@@ -30,34 +60,34 @@ func ListShelvesHandler(c *gin.Context) {
 
 func CreateShelfHandler(c *gin.Context) {
 	var req createShelfRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request: " + err.Error()})
+	if err := bindJSON(c, &req); err != nil {
+		c.Error(err)
 		return
 	}
 
-	zone := strings.TrimSpace(req.Zone)
-	code := strings.ToUpper(strings.TrimSpace(req.Code))
-	capacity := req.Capacity
-
-	if zone == "" || code == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "zone and code are required"})
+	claims, ok := middleware.GetClaims(c)
+	if !ok {
+		c.Error(apperr.Newf(apperr.CodeUnauthorized, "missing admin claims"))
+		return
+	}
+	stationID, err := service.ResolveStationScope(claims.StationID, req.StationCode)
+	if err != nil {
+		c.Error(stationScopeError(err))
 		return
 	}
-	if capacity <= 0 || capacity > 10000 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "capacity must be between 1 and 10000"})
+	if stationID == 0 {
+		c.Error(apperr.Invalid("station_code", "required"))
 		return
 	}
 
-	created, err := repository.CreateShelf(zone, code, capacity)
+	// 货架编号已存在 409 ALREADY_EXISTS
+	created, err := repository.CreateShelf(stationID, req.Zone, req.Code, req.Capacity, req.MaxSize, req.Temperature)
 	if err != nil {
-		if pqErr, ok := err.(*pq.Error); ok {
-			switch string(pqErr.Code) {
-			case "23505":
-				c.JSON(http.StatusConflict, gin.H{"error": "shelf code already exists"})
-				return
-			}
+		if isUniqueViolation(err) {
+			c.Error(apperr.Wrap(apperr.CodeAlreadyExists, err))
+			return
 		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "create shelf failed"})
+		c.Error(err)
 		return
 	}
 
@@ -67,20 +97,25 @@ func CreateShelfHandler(c *gin.Context) {
 func DeleteShelfHandler(c *gin.Context) {
 	code := strings.ToUpper(strings.TrimSpace(c.Param("code")))
 	if code == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "code is required"})
+		c.Error(apperr.Invalid("code", "required"))
 		return
 	}
 
-	if err := repository.DeleteEmptyShelfByCode(code); err != nil {
-		if err == repository.ErrNotFound {
-			c.JSON(http.StatusNotFound, gin.H{"error": "shelf not found"})
-			return
-		}
-		if err == repository.ErrConflict {
-			c.JSON(http.StatusConflict, gin.H{"error": "shelf is not empty; cannot delete"})
-			return
+	stationID, ok := stationScope(c)
+	if !ok {
+		return
+	}
+
+	// 货架上仍有包裹时 409 SHELF_NOT_EMPTY
+	if err := repository.DeleteEmptyShelfByCode(code, stationID); err != nil {
+		switch {
+		case errors.Is(err, repository.ErrNotFound):
+			c.Error(apperr.New(apperr.CodeShelfNotFound))
+		case errors.Is(err, repository.ErrConflict):
+			c.Error(apperr.New(apperr.CodeShelfNotEmpty))
+		default:
+			c.Error(err)
 		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "delete shelf failed"})
 		return
 	}
 
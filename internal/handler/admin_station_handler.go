@@ -0,0 +1,96 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"campus-logistics/internal/apperr"
+	"campus-logistics/internal/middleware"
+	"campus-logistics/internal/repository"
+	"campus-logistics/internal/service"
+	"campus-logistics/internal/validation"
+
+	"github.com/gin-gonic/gin"
+)
+
+type createStationRequest struct {
+	Code    string `json:"code" binding:"required,max=20"`
+	Name    string `json:"name" binding:"required,max=50"`
+	Address string `json:"address" binding:"max=200"`
+}
+
+func (r *createStationRequest) Normalize() {
+	r.Code = validation.Code(r.Code)
+	r.Name = validation.Text(r.Name)
+	r.Address = validation.Text(r.Address)
+}
+
+// stationScope 解析当前管理员可访问的站点（0 表示全部），失败时已通过 c.Error 上报
+// 未绑定站点的管理员可用 ?station=CODE 过滤；站点管理员始终限定在本站点
+func stationScope(c *gin.Context) (int64, bool) {
+	claims, ok := middleware.GetClaims(c)
+	if !ok {
+		c.Error(apperr.Newf(apperr.CodeUnauthorized, "missing admin claims"))
+		return 0, false
+	}
+
+	stationID, err := service.ResolveStationScope(claims.StationID, c.Query("station"))
+	if err != nil {
+		c.Error(stationScopeError(err))
+		return 0, false
+	}
+	return stationID, true
+}
+
+// GET /api/v1/admin/stations
+func ListStationsHandler(c *gin.Context) {
+	stations, err := service.ListStationsService()
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "success", "data": stations})
+}
+
+// POST /api/v1/admin/stations
+func CreateStationHandler(c *gin.Context) {
+	var req createStationRequest
+	if err := bindJSON(c, &req); err != nil {
+		c.Error(err)
+		return
+	}
+
+	// 编号或名称已存在 409 ALREADY_EXISTS
+	created, err := service.CreateStationService(req.Code, req.Name, req.Address)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidStation):
+			c.Error(apperr.Invalid("code", "required"))
+		case isUniqueViolation(err):
+			c.Error(apperr.Wrap(apperr.CodeAlreadyExists, err))
+		default:
+			c.Error(err)
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "success", "data": created})
+}
+
+// DELETE /api/v1/admin/stations/:code
+func DeleteStationHandler(c *gin.Context) {
+	// 仍有货架、包裹或管理员引用时 409 RESOURCE_IN_USE
+	if err := service.DeleteStationService(c.Param("code")); err != nil {
+		switch {
+		case errors.Is(err, repository.ErrNotFound):
+			c.Error(apperr.New(apperr.CodeStationNotFound))
+		case isForeignKeyViolation(err):
+			c.Error(apperr.Wrap(apperr.CodeResourceInUse, err))
+		default:
+			c.Error(err)
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "success"})
+}
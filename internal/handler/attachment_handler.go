@@ -0,0 +1,166 @@
+package handler
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"campus-logistics/internal/apperr"
+	"campus-logistics/internal/middleware"
+	"campus-logistics/internal/service"
+	"campus-logistics/internal/validation"
+
+	"github.com/gin-gonic/gin"
+)
+
+// multipartOverhead multipart 表单中除文件外的开销上限（分隔符、表单头、其他字段）
+const multipartOverhead = 64 << 10
+
+// UploadInboundPhotoHandler 快递员上传入库照片
+// POST /api/v1/inbound/:tracking_number/photos
+// multipart/form-data，文件字段 file（JPEG / PNG）
+func UploadInboundPhotoHandler(c *gin.Context) {
+	claims, ok := middleware.GetClaims(c)
+	if !ok || claims.CourierID == 0 {
+		c.Error(apperr.Newf(apperr.CodeUnauthorized, "missing courier claims"))
+		return
+	}
+	data, ok := readUpload(c)
+	if !ok {
+		return
+	}
+
+	a, err := service.UploadCourierAttachment(claims.CourierID, claims.CourierCode, validation.Code(c.Param("tracking_number")), data)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "success", "data": a})
+}
+
+// UploadStationAttachmentHandler 驿站管理员上传取件照片或签名
+// POST /api/v1/station/parcels/:tracking_number/attachments
+// multipart/form-data，字段 kind（pickup_photo / signature）与 file（JPEG / PNG）
+func UploadStationAttachmentHandler(c *gin.Context) {
+	claims, ok := middleware.GetClaims(c)
+	if !ok || claims.Username == "" {
+		c.Error(apperr.Newf(apperr.CodeUnauthorized, "missing admin claims"))
+		return
+	}
+	stationID, ok := stationScope(c)
+	if !ok {
+		return
+	}
+	data, ok := readUpload(c)
+	if !ok {
+		return
+	}
+
+	a, err := service.UploadStationAttachment(stationID, claims.Username,
+		validation.Code(c.Param("tracking_number")), c.PostForm("kind"), data)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "success", "data": a})
+}
+
+// ListMyParcelAttachmentsHandler 学生查看本人包裹的附件（含短期有效的下载地址）
+// GET /api/v1/parcels/:tracking_number/attachments
+func ListMyParcelAttachmentsHandler(c *gin.Context) {
+	claims, ok := middleware.GetClaims(c)
+	if !ok || claims.UserID == 0 {
+		c.Error(apperr.Newf(apperr.CodeUnauthorized, "missing student claims"))
+		return
+	}
+
+	attachments, err := service.ListStudentAttachments(claims.UserID, validation.Code(c.Param("tracking_number")))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "success", "data": attachments, "count": len(attachments)})
+}
+
+// ListParcelAttachmentsHandler 管理员查看包裹附件
+// GET /api/v1/admin/parcels/:tracking_number/attachments
+func ListParcelAttachmentsHandler(c *gin.Context) {
+	stationID, ok := stationScope(c)
+	if !ok {
+		return
+	}
+
+	attachments, err := service.ListStationAttachments(stationID, validation.Code(c.Param("tracking_number")))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "success", "data": attachments, "count": len(attachments)})
+}
+
+// DownloadAttachmentHandler 下载附件，凭列表接口返回的签名地址访问，无需登录
+// GET /api/v1/attachments/:id?variant=original|thumb&expires=...&signature=...
+func DownloadAttachmentHandler(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || id <= 0 {
+		c.Error(apperr.New(apperr.CodeNotFound))
+		return
+	}
+
+	contentType, rc, err := service.OpenAttachment(c.Request.Context(), id,
+		c.DefaultQuery("variant", service.VariantOriginal), c.Query("expires"), c.Query("signature"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	defer rc.Close()
+
+	c.Header("Cache-Control", "private, max-age=300")
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Header("Content-Disposition", "inline")
+	c.DataFromReader(http.StatusOK, -1, contentType, rc, nil)
+}
+
+// readUpload 读取 multipart 中的 file 字段，超过 attachments.max_size_mb 时返回 413；失败时已写入错误
+func readUpload(c *gin.Context) ([]byte, bool) {
+	data, _, ok := readUploadFile(c, service.MaxAttachmentBytes())
+	return data, ok
+}
+
+// readUploadFile 读取 multipart 中的 file 字段及其文件名，超过 limit 字节时返回 413；失败时已写入错误
+func readUploadFile(c *gin.Context, limit int64) ([]byte, string, bool) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, limit+multipartOverhead)
+
+	fh, err := c.FormFile("file")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.Error(apperr.Newf(apperr.CodeAttachmentTooLarge, "max %d MB", limit>>20))
+		} else {
+			c.Error(apperr.Invalid("file", "required"))
+		}
+		return nil, "", false
+	}
+	if fh.Size == 0 {
+		c.Error(apperr.Invalid("file", "required"))
+		return nil, "", false
+	}
+	if fh.Size > limit {
+		c.Error(apperr.Newf(apperr.CodeAttachmentTooLarge, "max %d MB", limit>>20))
+		return nil, "", false
+	}
+
+	f, err := fh.Open()
+	if err != nil {
+		c.Error(err)
+		return nil, "", false
+	}
+	defer f.Close()
+	data, err := io.ReadAll(io.LimitReader(f, limit+1))
+	if err != nil {
+		c.Error(err)
+		return nil, "", false
+	}
+	return data, fh.Filename, true
+}
@@ -1,9 +1,12 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
 
+	"campus-logistics/internal/apperr"
 	"campus-logistics/internal/service"
+	"campus-logistics/internal/validation"
 
 	"github.com/gin-gonic/gin"
 )
@@ -14,25 +17,36 @@ type adminLoginRequest struct {
 }
 
 type studentLoginRequest struct {
-	Phone string `json:"phone" binding:"required"`
-	Name  string `json:"name"`
+	Phone string `json:"phone" binding:"required,cnmobile"`
+	Name  string `json:"name" binding:"max=64"`
+}
+
+// Normalize 与入库时的手机号规范化一致，保证同一学生对应同一账号
+func (r *studentLoginRequest) Normalize() {
+	r.Phone = validation.Phone(r.Phone)
+	r.Name = validation.Text(r.Name)
 }
 
 type courierLoginRequest struct {
 	CourierCode string `json:"courier_code" binding:"required"`
 }
 
+func (r *courierLoginRequest) Normalize() {
+	r.CourierCode = validation.Code(r.CourierCode)
+}
+
 // POST /api/v1/auth/admin/login
 func AdminLoginHandler(c *gin.Context) {
 	var req adminLoginRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+	if err := bindJSON(c, &req); err != nil {
+		c.Error(err)
 		return
 	}
 
+	// 账号或密码错误 401 INVALID_CREDENTIALS，连续失败被锁定 423 ACCOUNT_LOCKED
 	resp, err := service.AdminLogin(req.Username, req.Password)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid credentials"})
+		c.Error(loginError(err))
 		return
 	}
 
@@ -42,14 +56,14 @@ func AdminLoginHandler(c *gin.Context) {
 // POST /api/v1/auth/student/login
 func StudentLoginHandler(c *gin.Context) {
 	var req studentLoginRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+	if err := bindJSON(c, &req); err != nil {
+		c.Error(err)
 		return
 	}
 
 	resp, err := service.StudentLogin(req.Phone, req.Name)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid credentials"})
+		c.Error(loginError(err))
 		return
 	}
 
@@ -59,16 +73,27 @@ func StudentLoginHandler(c *gin.Context) {
 // POST /api/v1/auth/courier/login
 func CourierLoginHandler(c *gin.Context) {
 	var req courierLoginRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+	if err := bindJSON(c, &req); err != nil {
+		c.Error(err)
 		return
 	}
 
 	resp, err := service.CourierLogin(req.CourierCode)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid credentials"})
+		c.Error(loginError(err))
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "success", "data": resp})
 }
+
+// loginError 登录失败的错误码；数据库等内部错误按 500 处理，不再伪装成凭证错误
+func loginError(err error) error {
+	switch {
+	case errors.Is(err, service.ErrAccountLocked):
+		return apperr.Wrap(apperr.CodeAccountLocked, err)
+	case errors.Is(err, service.ErrInvalidCredentials):
+		return apperr.Wrap(apperr.CodeInvalidCredentials, err)
+	}
+	return err
+}
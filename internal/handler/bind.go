@@ -0,0 +1,25 @@
+package handler
+
+import (
+	"encoding/json"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+// normalizer 请求体在校验前的规范化（去空白、全角转半角、转大写等）
+type normalizer interface {
+	Normalize()
+}
+
+// bindJSON 解析 JSON 请求体，先规范化再按 binding 标签校验。
+// 返回的错误交给 c.Error，由 ErrorHandler 转换为逐字段的 VALIDATION_FAILED 响应。
+func bindJSON(c *gin.Context, obj any) error {
+	if err := json.NewDecoder(c.Request.Body).Decode(obj); err != nil {
+		return err
+	}
+	if n, ok := obj.(normalizer); ok {
+		n.Normalize()
+	}
+	return binding.Validator.ValidateStruct(obj)
+}
@@ -0,0 +1,104 @@
+package handler
+
+import (
+	"bytes"
+	"net/http"
+	"time"
+
+	"campus-logistics/internal/apperr"
+	"campus-logistics/internal/label"
+	"campus-logistics/internal/middleware"
+	"campus-logistics/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// verifyCounterPickupRequest 柜台扫码核验请求，token 为二维码内容
+type verifyCounterPickupRequest struct {
+	Token string `json:"token" binding:"required,max=200"`
+}
+
+// CounterPickupTokenHandler 学生获取柜台取件凭证（JSON，由 App 自行生成二维码）
+// GET /api/v1/pickup/token
+func CounterPickupTokenHandler(c *gin.Context) {
+	claims, ok := middleware.GetClaims(c)
+	if !ok || claims.UserID == 0 {
+		c.Error(apperr.Newf(apperr.CodeUnauthorized, "missing student claims"))
+		return
+	}
+
+	token, err := service.IssueCounterPickupToken(claims.UserID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, gin.H{"message": "success", "data": token})
+}
+
+// CounterPickupQRCodeHandler 学生柜台取件二维码图片
+// GET /api/v1/pickup/qrcode?format=png|svg&size=256
+func CounterPickupQRCodeHandler(c *gin.Context) {
+	claims, ok := middleware.GetClaims(c)
+	if !ok || claims.UserID == 0 {
+		c.Error(apperr.Newf(apperr.CodeUnauthorized, "missing student claims"))
+		return
+	}
+	format, ok := imageFormat(c)
+	if !ok {
+		return
+	}
+	size := clampQuery(c, "size", 256, 64, 1024)
+
+	token, err := service.IssueCounterPickupToken(claims.UserID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	var buf bytes.Buffer
+	if format == "svg" {
+		err = label.QRSVG(&buf, token.Token, size)
+	} else {
+		err = label.QRPNG(&buf, token.Token, size)
+	}
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.Header("X-Credential-Expires-At", token.ExpiresAt.Format(time.RFC3339))
+	c.Data(http.StatusOK, imageContentType(format), buf.Bytes())
+}
+
+// VerifyCounterPickupHandler 驿站管理员扫描学生的取件二维码，核验后一次取走该学生在本站点的全部待取包裹
+// POST /api/v1/station/pickup/verify
+// 请求体：{"token": "PT1...."}，也可以是单个包裹的取件凭证 "PC1...."（只取走该包裹）；
+// 全局管理员可用 ?station=CODE 指定站点
+func VerifyCounterPickupHandler(c *gin.Context) {
+	var req verifyCounterPickupRequest
+	if err := bindJSON(c, &req); err != nil {
+		c.Error(err)
+		return
+	}
+
+	claims, ok := middleware.GetClaims(c)
+	if !ok || claims.Username == "" {
+		c.Error(apperr.Newf(apperr.CodeUnauthorized, "missing admin claims"))
+		return
+	}
+	stationID, ok := stationScope(c)
+	if !ok {
+		return
+	}
+
+	// 签名错误 400 INVALID_PICKUP_TOKEN，过期 400 PICKUP_TOKEN_EXPIRED，
+	// 已使用 409 PICKUP_TOKEN_USED，没有待取包裹 404 NO_PARCELS_READY
+	result, err := service.VerifyCounterPickup(req.Token, claims.Username, stationID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "success", "data": result})
+}
@@ -3,50 +3,127 @@ package handler
 import (
 	"net/http"
 	"strconv"
+	"time"
 
+	"campus-logistics/internal/apperr"
 	"campus-logistics/internal/middleware"
-	"campus-logistics/internal/model"
 	"campus-logistics/internal/service"
+	"campus-logistics/internal/validation"
 
 	"github.com/gin-gonic/gin"
 )
 
 // GetCourierTasksHandler 快递员查看自己的任务列表
-// GET /api/v1/courier/tasks?page=1&page_size=20
+// GET /api/v1/courier/tasks?status=stored,pending&from=2026-10-01&to=2026-10-07&q=5678&page=1&page_size=20
+// q: 运单号片段，4 位数字时同时匹配收件人手机号尾号
 func GetCourierTasksHandler(c *gin.Context) {
 	claims, ok := middleware.GetClaims(c)
 	if !ok || claims.CourierID == 0 {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "missing courier claims"})
+		c.Error(apperr.Newf(apperr.CodeUnauthorized, "missing courier claims"))
 		return
 	}
 
-	pageStr := c.DefaultQuery("page", "1")
-	pageSizeStr := c.DefaultQuery("page_size", "20")
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	result, err := service.ListCourierTasks(claims.CourierID, service.CourierTaskQuery{
+		Status:   c.Query("status"),
+		From:     c.Query("from"),
+		To:       c.Query("to"),
+		Q:        c.Query("q"),
+		Page:     page,
+		PageSize: pageSize,
+	})
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	courierTasksJSON(c, result)
+}
 
-	page, err := strconv.Atoi(pageStr)
+// GetCourierTodayTasksHandler 快递员今天入库的包裹
+// GET /api/v1/courier/tasks/today?page=1&page_size=20
+func GetCourierTodayTasksHandler(c *gin.Context) {
+	claims, ok := middleware.GetClaims(c)
+	if !ok || claims.CourierID == 0 {
+		c.Error(apperr.Newf(apperr.CodeUnauthorized, "missing courier claims"))
+		return
+	}
+
+	today := time.Now().Format("2006-01-02")
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	result, err := service.ListCourierTasks(claims.CourierID, service.CourierTaskQuery{
+		From:     today,
+		To:       today,
+		Page:     page,
+		PageSize: pageSize,
+	})
 	if err != nil {
-		page = 1
+		c.Error(err)
+		return
 	}
-	pageSize, err := strconv.Atoi(pageSizeStr)
+	courierTasksJSON(c, result)
+}
+
+func courierTasksJSON(c *gin.Context, result *service.CourierTaskPage) {
+	c.JSON(http.StatusOK, gin.H{
+		"message":   "success",
+		"data":      result.Tasks,
+		"count":     len(result.Tasks),
+		"total":     result.Total,
+		"page":      result.Page,
+		"page_size": result.PageSize,
+	})
+}
+
+// GetCourierTaskSummaryHandler 快递员名下包裹按状态的数量
+// GET /api/v1/courier/tasks/summary?from=2026-10-01&to=2026-10-07
+func GetCourierTaskSummaryHandler(c *gin.Context) {
+	claims, ok := middleware.GetClaims(c)
+	if !ok || claims.CourierID == 0 {
+		c.Error(apperr.Newf(apperr.CodeUnauthorized, "missing courier claims"))
+		return
+	}
+
+	summary, err := service.GetCourierTaskSummary(claims.CourierID, c.Query("from"), c.Query("to"))
 	if err != nil {
-		pageSize = 20
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "success", "data": summary})
+}
+
+// GetCourierTaskHandler 快递员查看单个包裹：货架位置、滞留时长与状态记录
+// GET /api/v1/courier/tasks/:tracking_number
+func GetCourierTaskHandler(c *gin.Context) {
+	claims, ok := middleware.GetClaims(c)
+	if !ok || claims.CourierID == 0 {
+		c.Error(apperr.Newf(apperr.CodeUnauthorized, "missing courier claims"))
+		return
 	}
 
-	tasks, err := service.GetCourierTasksForCourier(claims.CourierID, page, pageSize)
+	task, err := service.GetCourierTask(claims.CourierID, validation.Code(c.Param("tracking_number")))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to query tasks"})
+		c.Error(err)
 		return
 	}
+	c.JSON(http.StatusOK, gin.H{"message": "success", "data": task})
+}
 
-	if tasks == nil {
-		tasks = []model.CourierTask{}
+// RevealCourierTaskPhoneHandler 快递员查看收件人完整手机号（仅限未取件的包裹，每次查看都会记录）
+// POST /api/v1/courier/tasks/:tracking_number/phone
+func RevealCourierTaskPhoneHandler(c *gin.Context) {
+	claims, ok := middleware.GetClaims(c)
+	if !ok || claims.CourierID == 0 {
+		c.Error(apperr.Newf(apperr.CodeUnauthorized, "missing courier claims"))
+		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"message":   "success",
-		"data":      tasks,
-		"count":     len(tasks),
-		"page":      page,
-		"page_size": pageSize,
-	})
+	result, err := service.RevealParcelPhone(validation.Code(c.Param("tracking_number")),
+		claims.CourierID, 0, "courier:"+claims.CourierCode, "courier")
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "success", "data": result})
 }
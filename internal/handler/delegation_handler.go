@@ -0,0 +1,74 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"campus-logistics/internal/apperr"
+	"campus-logistics/internal/middleware"
+	"campus-logistics/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CreateDelegationHandler 授权他人代取包裹
+// POST /api/v1/delegations
+// 请求体：{"delegate_phone": "...", "tracking_number": "...", "starts_at": "...", "expires_at": "..."}
+func CreateDelegationHandler(c *gin.Context) {
+	var req service.CreateDelegationRequest
+	if err := bindJSON(c, &req); err != nil {
+		c.Error(err)
+		return
+	}
+
+	claims, ok := middleware.GetClaims(c)
+	if !ok || claims.UserID == 0 {
+		c.Error(apperr.Newf(apperr.CodeUnauthorized, "missing student claims"))
+		return
+	}
+
+	d, err := service.CreateDelegation(claims.UserID, claims.Phone, req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "success", "data": d})
+}
+
+// ListDelegationsHandler 本人发出的代取授权；received=true 时为他人授权给本人的
+// GET /api/v1/delegations?received=true
+func ListDelegationsHandler(c *gin.Context) {
+	claims, ok := middleware.GetClaims(c)
+	if !ok || claims.UserID == 0 {
+		c.Error(apperr.Newf(apperr.CodeUnauthorized, "missing student claims"))
+		return
+	}
+
+	delegations, err := service.ListDelegations(claims.UserID, c.Query("received") == "true")
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "success", "data": delegations, "count": len(delegations)})
+}
+
+// RevokeDelegationHandler 撤销本人发出的代取授权
+// DELETE /api/v1/delegations/:id
+func RevokeDelegationHandler(c *gin.Context) {
+	claims, ok := middleware.GetClaims(c)
+	if !ok || claims.UserID == 0 {
+		c.Error(apperr.Newf(apperr.CodeUnauthorized, "missing student claims"))
+		return
+	}
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || id <= 0 {
+		c.Error(apperr.New(apperr.CodeNotFound))
+		return
+	}
+
+	if err := service.RevokeDelegation(id, claims.UserID); err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "success"})
+}
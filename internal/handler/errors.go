@@ -0,0 +1,34 @@
+package handler
+
+import (
+	"errors"
+
+	"campus-logistics/internal/apperr"
+	"campus-logistics/internal/repository"
+	"campus-logistics/internal/service"
+
+	"github.com/lib/pq"
+)
+
+// isUniqueViolation 是否为唯一约束冲突（编号、名称、用户名已存在）
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
+
+// isForeignKeyViolation 是否为外键冲突（删除仍被引用的记录）
+func isForeignKeyViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23503"
+}
+
+// stationScopeError 站点范围解析失败：越权访问其他站点 403 FORBIDDEN，站点编号不存在 400 INVALID_STATION
+func stationScopeError(err error) error {
+	switch {
+	case errors.Is(err, service.ErrStationForbidden):
+		return apperr.Newf(apperr.CodeForbidden, "station out of scope")
+	case errors.Is(err, repository.ErrNotFound):
+		return apperr.New(apperr.CodeInvalidStation)
+	}
+	return err
+}
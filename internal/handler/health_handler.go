@@ -0,0 +1,40 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"campus-logistics/internal/repository"
+)
+
+// draining 收到停机信号后置为 true：就绪检查返回 503，负载均衡不再转发新请求
+var draining atomic.Bool
+
+// SetDraining 进入停机排空状态
+func SetDraining() {
+	draining.Store(true)
+}
+
+// LivenessHandler 存活检查：进程能处理请求即返回 200，不检查数据库（数据库故障时重启进程无济于事）
+func LivenessHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// ReadinessHandler 就绪检查：停机排空中或数据库不可用时返回 503，否则返回 200
+func ReadinessHandler(c *gin.Context) {
+	if draining.Load() {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"status": "draining"})
+		return
+	}
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 2*time.Second)
+	defer cancel()
+	if err := repository.PingDB(ctx); err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"status": "unavailable", "db_status": "disconnected"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "ready", "db_status": "connected"})
+}
@@ -0,0 +1,79 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"campus-logistics/internal/apperr"
+	"campus-logistics/internal/middleware"
+	"campus-logistics/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// StartImportHandler 上传学生或快递公司名单，创建后台导入任务；返回任务 id，通过查询接口轮询进度
+// POST /api/v1/admin/imports/:kind?dry_run=true
+// kind: students（学号、手机号、姓名、宿舍）/ couriers（编码、名称、联系电话、运单号格式）
+// multipart/form-data，文件字段 file（.csv / .xlsx，第一行为表头）
+func StartImportHandler(c *gin.Context) {
+	claims, ok := middleware.GetClaims(c)
+	if !ok || claims.Username == "" {
+		c.Error(apperr.Newf(apperr.CodeUnauthorized, "missing admin claims"))
+		return
+	}
+	dryRun := false
+	if s := c.Query("dry_run"); s != "" {
+		v, err := strconv.ParseBool(s)
+		if err != nil {
+			c.Error(apperr.Invalid("dry_run", "type").WithParam("boolean"))
+			return
+		}
+		dryRun = v
+	}
+	data, fileName, ok := readUploadFile(c, service.MaxImportBytes())
+	if !ok {
+		return
+	}
+
+	job, err := service.StartImport(c.Param("kind"), fileName, data, dryRun, claims.Username)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{"message": "success", "data": job})
+}
+
+// GetImportJobHandler 查询导入任务的进度、计数与逐行错误
+// GET /api/v1/admin/imports/:id
+func GetImportJobHandler(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || id <= 0 {
+		c.Error(apperr.New(apperr.CodeImportJobNotFound))
+		return
+	}
+
+	job, err := service.GetImportJob(id)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	lang := apperr.ParseLang(c.GetHeader("Accept-Language"))
+	for i, e := range job.Errors {
+		job.Errors[i].Message = apperr.Invalid(e.Field, e.Rule).WithParam(e.Param).Localize(lang)[0].Message
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "success", "data": job})
+}
+
+// ListImportJobsHandler 最近的导入任务
+// GET /api/v1/admin/imports?page=1&page_size=20
+func ListImportJobsHandler(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+
+	jobs, err := service.ListImportJobs(page, pageSize)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "success", "data": jobs, "count": len(jobs)})
+}
@@ -0,0 +1,189 @@
+package handler
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"campus-logistics/internal/apperr"
+	"campus-logistics/internal/label"
+	"campus-logistics/internal/middleware"
+	"campus-logistics/internal/repository"
+	"campus-logistics/internal/service"
+	"campus-logistics/internal/validation"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxSheetLabels 单个 PDF 标签页文件的标签上限，避免按货格展开时生成过大的文件
+const maxSheetLabels = 2000
+
+const (
+	contentTypeSVG = "image/svg+xml"
+	contentTypePNG = "image/png"
+	contentTypePDF = "application/pdf"
+)
+
+// PickupQRCodeHandler 学生取件二维码（签名的取件凭证，短期有效）
+// GET /api/v1/parcels/:tracking_number/qrcode?format=png|svg&size=256
+func PickupQRCodeHandler(c *gin.Context) {
+	claims, ok := middleware.GetClaims(c)
+	if !ok || claims.UserID == 0 {
+		c.Error(apperr.Newf(apperr.CodeUnauthorized, "missing student claims"))
+		return
+	}
+	format, ok := imageFormat(c)
+	if !ok {
+		return
+	}
+	size := clampQuery(c, "size", 256, 64, 1024)
+
+	cred, err := service.IssuePickupCredential(claims.UserID, validation.Code(c.Param("tracking_number")))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	var buf bytes.Buffer
+	if format == "svg" {
+		err = label.QRSVG(&buf, cred.Payload, size)
+	} else {
+		err = label.QRPNG(&buf, cred.Payload, size)
+	}
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	// 凭证短期有效，禁止缓存
+	c.Header("Cache-Control", "no-store")
+	c.Header("X-Credential-Expires-At", cred.ExpiresAt.Format(time.RFC3339))
+	c.Data(http.StatusOK, imageContentType(format), buf.Bytes())
+}
+
+// ShelfLabelHandler 货架（或货格）Code128 标签
+// GET /api/v1/admin/shelves/:code/label?format=png|svg&slot=3&module=2&height=80
+// slot 为货格序号（1~capacity），标签内容为 <货架编号>-<三位序号>
+func ShelfLabelHandler(c *gin.Context) {
+	stationID, ok := stationScope(c)
+	if !ok {
+		return
+	}
+	format, ok := imageFormat(c)
+	if !ok {
+		return
+	}
+
+	shelf, err := repository.GetShelfByCode(validation.Code(c.Param("code")), stationID)
+	if err != nil {
+		if err == repository.ErrNotFound {
+			err = apperr.New(apperr.CodeNotFound)
+		}
+		c.Error(err)
+		return
+	}
+
+	content := shelf.Code
+	if s := c.Query("slot"); s != "" {
+		slot, err := strconv.Atoi(s)
+		if err != nil || slot < 1 || slot > shelf.Capacity {
+			c.Error(apperr.Invalid("slot", "max").WithParam(strconv.Itoa(shelf.Capacity)))
+			return
+		}
+		content = slotCode(shelf.Code, slot)
+	}
+
+	module := clampQuery(c, "module", 2, 1, 6)
+	height := clampQuery(c, "height", 80, 20, 300)
+
+	var buf bytes.Buffer
+	if format == "svg" {
+		err = label.Code128SVG(&buf, content, module, height)
+	} else {
+		err = label.Code128PNG(&buf, content, module, height)
+	}
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.Data(http.StatusOK, imageContentType(format), buf.Bytes())
+}
+
+// ShelfLabelSheetHandler 某个区域全部货架的 A4 标签页 PDF
+// GET /api/v1/admin/shelves/labels?zone=A&slots=false&station=NORTH
+// slots=true 时按容量展开为每个货格一个标签
+func ShelfLabelSheetHandler(c *gin.Context) {
+	stationID, ok := stationScope(c)
+	if !ok {
+		return
+	}
+	zone := validation.Code(c.Query("zone"))
+	if zone == "" {
+		c.Error(apperr.Invalid("zone", "required"))
+		return
+	}
+	withSlots := c.Query("slots") == "true"
+
+	shelves, err := repository.ListShelvesByZone(stationID, zone)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	labels := make([]label.Label, 0, len(shelves))
+	for _, s := range shelves {
+		caption := fmt.Sprintf("ZONE %s  CAP %d", s.Zone, s.Capacity)
+		if !withSlots {
+			labels = append(labels, label.Label{Code: s.Code, Caption: caption})
+			continue
+		}
+		for slot := 1; slot <= s.Capacity; slot++ {
+			labels = append(labels, label.Label{Code: slotCode(s.Code, slot), Caption: caption})
+		}
+	}
+	if len(labels) > maxSheetLabels {
+		c.Error(apperr.Newf(apperr.CodeBatchTooLarge, "max %d labels", maxSheetLabels))
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := label.SheetPDF(&buf, "Shelf labels - zone "+zone, labels); err != nil {
+		c.Error(err)
+		return
+	}
+	c.Header("Content-Disposition", fmt.Sprintf(`inline; filename="shelf-labels-%s.pdf"`, zone))
+	c.Data(http.StatusOK, contentTypePDF, buf.Bytes())
+}
+
+func slotCode(shelfCode string, slot int) string {
+	return fmt.Sprintf("%s-%03d", shelfCode, slot)
+}
+
+// imageFormat 解析 format 参数（png / svg，默认 png），非法时写出错误并返回 false
+func imageFormat(c *gin.Context) (string, bool) {
+	switch f := c.DefaultQuery("format", "png"); f {
+	case "png", "svg":
+		return f, true
+	default:
+		c.Error(apperr.Invalid("format", "oneof").WithParam("png svg"))
+		return "", false
+	}
+}
+
+func imageContentType(format string) string {
+	if format == "svg" {
+		return contentTypeSVG
+	}
+	return contentTypePNG
+}
+
+// clampQuery 读取整数查询参数，缺省或非法时取默认值，并限制在 [lo, hi] 内
+func clampQuery(c *gin.Context, key string, def, lo, hi int) int {
+	v, err := strconv.Atoi(c.Query(key))
+	if err != nil {
+		return def
+	}
+	return min(max(v, lo), hi)
+}
@@ -3,6 +3,7 @@ package handler
 
 // 导入所需的包
 import (
+	"campus-logistics/internal/apperr"
 	"campus-logistics/internal/middleware"
 	"campus-logistics/internal/model"   // 项目内部数据模型定义
 	"campus-logistics/internal/service" // 项目内部业务逻辑服务层
@@ -24,27 +25,24 @@ func InboundHandler(c *gin.Context) {
 	// 将请求体中的JSON数据绑定到req变量
 	// ShouldBindJSON会自动根据结构体字段标签解析JSON
 	// 如果绑定失败（如JSON格式错误、字段类型不匹配等），返回400错误
-	if err := c.ShouldBindJSON(&req); err != nil {
-		// 返回HTTP 400状态码，并包含错误信息
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "入库失败 " + err.Error(), // 将错误信息附加到响应中
-		})
+	// 先规范化（去空白、全角转半角、转大写）再校验，校验失败时逐字段返回原因
+	if err := bindJSON(c, &req); err != nil {
+		// 返回HTTP 400状态码，并列出所有未通过校验的字段
+		c.Error(err)
 		return // 终止函数执行，不继续后续处理
 	}
 
 	// courier_code 由鉴权信息决定，避免客户端伪造
 	claims, ok := middleware.GetClaims(c)
 	if !ok || claims.CourierCode == "" {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "missing courier claims"})
+		c.Error(apperr.Newf(apperr.CodeUnauthorized, "missing courier claims"))
 		return
 	}
 
 	// 调用service层的InboundByCourier函数执行入库业务逻辑
+	// 重复运单号、仓库爆满等由 ErrorHandler 转换为对应错误码，数据库原始错误不返回给客户端
 	if err := service.InboundByCourier(req, claims.CourierCode); err != nil {
-		// 返回HTTP 500状态码，表示服务器内部错误
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "入库失败: " + err.Error(),
-		})
+		c.Error(err)
 		return
 	}
 
@@ -58,6 +56,120 @@ func InboundHandler(c *gin.Context) {
 	})
 }
 
+// BatchInboundHandler 批量入库
+// 请求方法：POST
+// 请求路径：/api/v1/inbound/batch
+// 请求体：{"items": [InboundRequest, ...]}，最多 service.MaxBatchInboundSize 条
+// 每条独立入库，部分失败不影响其他条目；响应中逐条返回结果
+func BatchInboundHandler(c *gin.Context) {
+	var req service.BatchInboundRequest
+	if err := bindJSON(c, &req); err != nil {
+		c.Error(err)
+		return
+	}
+	if len(req.Items) > service.MaxBatchInboundSize {
+		c.Error(apperr.Newf(apperr.CodeBatchTooLarge, "max %d", service.MaxBatchInboundSize))
+		return
+	}
+
+	claims, ok := middleware.GetClaims(c)
+	if !ok || claims.CourierCode == "" {
+		c.Error(apperr.Newf(apperr.CodeUnauthorized, "missing courier claims"))
+		return
+	}
+
+	results, stored := service.BatchInboundByCourier(req.Items, claims.CourierCode)
+	batchInboundJSON(c, results, stored)
+}
+
+// batchInboundJSON 写出批量入库结果，失败条目按请求语言填写提示
+func batchInboundJSON(c *gin.Context, results []service.BatchInboundResult, stored int) {
+	lang := apperr.ParseLang(c.GetHeader("Accept-Language"))
+	for i := range results {
+		if results[i].Code != "" {
+			results[i].Error = apperr.Message(results[i].Code, lang)
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message": "success",
+		"data":    results,
+		"total":   len(results),
+		"stored":  stored,
+		"failed":  len(results) - stored,
+	})
+}
+
+// StationInboundHandler 驿站代收入库：由驿站工作人员扫码入库，courier_code 可选，未提供时根据运单号识别快递公司
+// 请求方法：POST
+// 请求路径：/api/v1/station/inbound
+// 绑定站点的管理员只能入库到本站点（忽略请求体中的 station_code）；未填写 user_name 时记为 admin:<用户名>
+func StationInboundHandler(c *gin.Context) {
+	var req service.InboundRequest
+	if err := bindJSON(c, &req); err != nil {
+		c.Error(err)
+		return
+	}
+	claims, ok := middleware.GetClaims(c)
+	if !ok || claims.Username == "" {
+		c.Error(apperr.Newf(apperr.CodeUnauthorized, "missing admin claims"))
+		return
+	}
+	stationID, ok := stationScope(c)
+	if !ok {
+		return
+	}
+	if req.UserName == "" {
+		req.UserName = "admin:" + claims.Username
+	}
+
+	// 无法识别或匹配多家快递公司时返回 400 COURIER_UNDETERMINED，需在请求中指定 courier_code
+	courierCode, err := service.StationInbound(req, stationID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message": "success",
+		"data": gin.H{
+			"tracking_number": req.TrackingNumber,
+			"courier_code":    courierCode,
+			"status":          "stored",
+		},
+	})
+}
+
+// BatchStationInboundHandler 驿站代收批量入库，每条分别识别快递公司
+// 请求方法：POST
+// 请求路径：/api/v1/station/inbound/batch
+func BatchStationInboundHandler(c *gin.Context) {
+	var req service.BatchInboundRequest
+	if err := bindJSON(c, &req); err != nil {
+		c.Error(err)
+		return
+	}
+	if len(req.Items) > service.MaxBatchInboundSize {
+		c.Error(apperr.Newf(apperr.CodeBatchTooLarge, "max %d", service.MaxBatchInboundSize))
+		return
+	}
+	claims, ok := middleware.GetClaims(c)
+	if !ok || claims.Username == "" {
+		c.Error(apperr.Newf(apperr.CodeUnauthorized, "missing admin claims"))
+		return
+	}
+	stationID, ok := stationScope(c)
+	if !ok {
+		return
+	}
+	for i := range req.Items {
+		if req.Items[i].UserName == "" {
+			req.Items[i].UserName = "admin:" + claims.Username
+		}
+	}
+
+	results, stored := service.BatchStationInbound(req.Items, stationID)
+	batchInboundJSON(c, results, stored)
+}
+
 // PickupHandler 处理包裹取件请求
 // 功能：接收取件请求，验证取件码和运单号，执行取件操作
 // 请求方法：POST
@@ -68,26 +180,23 @@ func PickupHandler(c *gin.Context) {
 	var req service.PickupRequest
 
 	// 绑定JSON请求数据
-	if err := c.ShouldBindJSON(&req); err != nil {
+	if err := bindJSON(c, &req); err != nil {
 		// 返回HTTP 400状态码，表示客户端请求参数有误
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "参数错误：" + err.Error(),
-		})
+		c.Error(err)
 		return
 	}
 
 	claims, ok := middleware.GetClaims(c)
 	if !ok || claims.UserID == 0 {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "missing student claims"})
+		c.Error(apperr.Newf(apperr.CodeUnauthorized, "missing student claims"))
 		return
 	}
 
 	// 调用service层的Pickup函数执行取件业务逻辑（绑定到当前 student）
+	// 包裹不存在 404 PARCEL_NOT_FOUND，取件码错误 400 INVALID_PICKUP_CODE，
+	// 已取出或不在架上 409 PARCEL_NOT_PICKABLE
 	if err := service.Pickup(req, claims.UserID); err != nil {
-		// 返回HTTP 409状态码，表示请求与服务器当前状态冲突
-		c.JSON(http.StatusConflict, gin.H{
-			"error": "取件失败，请检查取件码或包裹是否已取出",
-		})
+		c.Error(err)
 		return
 	}
 
@@ -111,7 +220,7 @@ func GetMyParcelHandler(c *gin.Context) {
 	// phone 不再从 query 获取，改为从 JWT claims 获取，避免越权
 	claims, ok := middleware.GetClaims(c)
 	if !ok || claims.UserID == 0 {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "missing student claims"})
+		c.Error(apperr.Newf(apperr.CodeUnauthorized, "missing student claims"))
 		return
 	}
 
@@ -133,10 +242,8 @@ func GetMyParcelHandler(c *gin.Context) {
 	// 返回值：包裹列表和可能的错误
 	parcels, err := service.GetMyParcels(claims.UserID, page, pageSize)
 	if err != nil {
-		// 查询过程中发生错误，返回500错误
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "查询失败", // 不暴露具体错误细节给客户端
-		})
+		// 查询过程中发生错误，由 ErrorHandler 返回 500，不暴露具体错误细节给客户端
+		c.Error(err)
 		return
 	}
 
@@ -0,0 +1,107 @@
+package handler
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"campus-logistics/internal/apperr"
+	"campus-logistics/internal/report"
+	"campus-logistics/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ExportReportHandler 导出报表（CSV / XLSX），数据从数据库逐行读取后直接写入响应
+// GET /api/v1/admin/reports/:kind?period=weekly&date=2026-10-12&format=xlsx&station=NORTH
+// kind: summary（每日入库/取件汇总）/ couriers（快递公司统计）/ retention（滞留包裹）/ exceptions（异常包裹）
+func ExportReportHandler(c *gin.Context) {
+	stationID, ok := stationScope(c)
+	if !ok {
+		return
+	}
+	r, err := service.NewReport(c.Param("kind"), c.Query("format"), c.Query("period"), c.Query("date"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	if s := c.Query("days"); s != "" {
+		days, err := strconv.Atoi(s)
+		if err != nil || days < 1 {
+			c.Error(apperr.Invalid("days", "min").WithParam("1"))
+			return
+		}
+		r.RetentionDays = days
+	}
+	stationCode, err := service.ReportStationCode(stationID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Header("Content-Type", report.ContentType(r.Format))
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, r.FileName(stationCode)))
+	if err := r.Write(c.Writer, stationID); err != nil {
+		if !c.Writer.Written() {
+			// 尚未输出任何内容时仍可返回 JSON 错误
+			c.Writer.Header().Del("Content-Type")
+			c.Writer.Header().Del("Content-Disposition")
+			c.Error(err)
+			return
+		}
+		log.Printf("report export %s failed: %v", r.FileName(stationCode), err)
+	}
+}
+
+// ListReportFilesHandler 列出已保存的定时报表；站点管理员只能看到本站点的报表
+// GET /api/v1/admin/reports/files
+func ListReportFilesHandler(c *gin.Context) {
+	stationID, ok := stationScope(c)
+	if !ok {
+		return
+	}
+	stationCode, err := service.ReportStationCode(stationID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	files, err := service.ListReportFiles(stationCode)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "success", "data": files, "count": len(files)})
+}
+
+// DownloadReportFileHandler 下载已保存的定时报表
+// GET /api/v1/admin/reports/files/:name
+func DownloadReportFileHandler(c *gin.Context) {
+	stationID, ok := stationScope(c)
+	if !ok {
+		return
+	}
+	stationCode, err := service.ReportStationCode(stationID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	name := c.Param("name")
+	f, err := service.OpenReportFile(name, stationCode)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	format := strings.TrimPrefix(filepath.Ext(name), ".")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, name))
+	c.DataFromReader(http.StatusOK, info.Size(), report.ContentType(format), f, nil)
+}
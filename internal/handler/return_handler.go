@@ -0,0 +1,231 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"campus-logistics/internal/apperr"
+	"campus-logistics/internal/middleware"
+	"campus-logistics/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// returnBatchStatus 校验 ?status= 参数，为空表示全部状态
+func returnBatchStatus(c *gin.Context, def string) (string, bool) {
+	status := c.DefaultQuery("status", def)
+	switch status {
+	case "", "open", "completed", "cancelled":
+		return status, true
+	}
+	c.Error(apperr.Invalid("status", "oneof").WithParam("open completed cancelled"))
+	return "", false
+}
+
+// returnBatchID 解析路径中的批次 ID
+func returnBatchID(c *gin.Context) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || id <= 0 {
+		c.Error(apperr.New(apperr.CodeReturnBatchNotFound))
+		return 0, false
+	}
+	return id, true
+}
+
+// CreateReturnBatchHandler 管理员为快递公司创建退件批次
+// POST /api/v1/admin/returns
+// 请求体：{"courier_code": "SF", "station_code": "MAIN", "tracking_numbers": [...]}
+func CreateReturnBatchHandler(c *gin.Context) {
+	var req service.CreateReturnBatchRequest
+	if err := bindJSON(c, &req); err != nil {
+		c.Error(err)
+		return
+	}
+
+	claims, ok := middleware.GetClaims(c)
+	if !ok || claims.Username == "" {
+		c.Error(apperr.Newf(apperr.CodeUnauthorized, "missing admin claims"))
+		return
+	}
+	stationID, err := service.ResolveStationScope(claims.StationID, req.StationCode)
+	if err != nil {
+		c.Error(stationScopeError(err))
+		return
+	}
+	if stationID == 0 {
+		c.Error(apperr.Invalid("station_code", "required"))
+		return
+	}
+
+	// 快递公司无效 400 INVALID_COURIER，有不可退回的运单号 409 PARCEL_NOT_RETURNABLE
+	batch, err := service.CreateReturnBatch(req, stationID, claims.Username)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "success", "data": batch})
+}
+
+// CreateRetentionReturnsHandler 管理员按滞留策略生成退件批次（每个快递公司、站点一个批次）
+// POST /api/v1/admin/returns/retention?station=NORTH
+// 请求体：{"days": 14}，可省略
+func CreateRetentionReturnsHandler(c *gin.Context) {
+	var req service.RetentionReturnRequest
+	if c.Request.ContentLength != 0 {
+		if err := bindJSON(c, &req); err != nil {
+			c.Error(err)
+			return
+		}
+	}
+
+	claims, ok := middleware.GetClaims(c)
+	if !ok || claims.Username == "" {
+		c.Error(apperr.Newf(apperr.CodeUnauthorized, "missing admin claims"))
+		return
+	}
+	stationID, ok := stationScope(c)
+	if !ok {
+		return
+	}
+
+	result, err := service.CreateRetentionReturnBatches(req.Days, stationID, "admin:"+claims.Username)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "success", "data": result})
+}
+
+// ListReturnBatchesHandler 管理员查询退件批次
+// GET /api/v1/admin/returns?status=open&courier=SF&station=NORTH&page=1&page_size=20
+func ListReturnBatchesHandler(c *gin.Context) {
+	stationID, ok := stationScope(c)
+	if !ok {
+		return
+	}
+	status, ok := returnBatchStatus(c, "")
+	if !ok {
+		return
+	}
+	var courierID int64
+	if code := c.Query("courier"); code != "" {
+		id, err := service.CourierIDByCode(code)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+		courierID = id
+	}
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+
+	batches, err := service.ListReturnBatches(courierID, stationID, status, page, pageSize, false)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "success", "data": batches, "count": len(batches)})
+}
+
+// GetReturnBatchHandler 管理员查看退件批次及包裹明细
+// GET /api/v1/admin/returns/:id
+func GetReturnBatchHandler(c *gin.Context) {
+	stationID, ok := stationScope(c)
+	if !ok {
+		return
+	}
+	id, ok := returnBatchID(c)
+	if !ok {
+		return
+	}
+
+	batch, err := service.GetReturnBatch(id, 0, stationID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "success", "data": batch})
+}
+
+// CancelReturnBatchHandler 管理员取消未完成的退件批次，未交接的包裹留在原货架
+// DELETE /api/v1/admin/returns/:id
+func CancelReturnBatchHandler(c *gin.Context) {
+	stationID, ok := stationScope(c)
+	if !ok {
+		return
+	}
+	id, ok := returnBatchID(c)
+	if !ok {
+		return
+	}
+
+	if err := service.CancelReturnBatch(id, stationID); err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "success"})
+}
+
+// CourierReturnsHandler 快递员查看本公司需取回的退件批次及包裹明细（默认只看待交接的批次）
+// GET /api/v1/courier/returns?status=open&page=1&page_size=20
+func CourierReturnsHandler(c *gin.Context) {
+	claims, ok := middleware.GetClaims(c)
+	if !ok || claims.CourierID == 0 {
+		c.Error(apperr.Newf(apperr.CodeUnauthorized, "missing courier claims"))
+		return
+	}
+	status, ok := returnBatchStatus(c, "open")
+	if !ok {
+		return
+	}
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+
+	batches, err := service.ListReturnBatches(claims.CourierID, 0, status, page, pageSize, true)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "success", "data": batches, "count": len(batches)})
+}
+
+// HandoverReturnsHandler 快递员在驿站逐个扫码确认取回退件
+// POST /api/v1/courier/returns/:id/handover
+// 请求体：{"tracking_numbers": [...], "staff_name": "张三", "staff_id": "SF0012"}
+// 每个包裹独立处理，响应中逐条返回结果；包裹变为 returned 并释放货架
+func HandoverReturnsHandler(c *gin.Context) {
+	var req service.HandoverRequest
+	if err := bindJSON(c, &req); err != nil {
+		c.Error(err)
+		return
+	}
+	claims, ok := middleware.GetClaims(c)
+	if !ok || claims.CourierID == 0 {
+		c.Error(apperr.Newf(apperr.CodeUnauthorized, "missing courier claims"))
+		return
+	}
+	id, ok := returnBatchID(c)
+	if !ok {
+		return
+	}
+
+	// 批次不存在 404 RETURN_BATCH_NOT_FOUND，已结束 409 RETURN_BATCH_CLOSED
+	results, returned, err := service.HandoverReturns(id, claims.CourierID, claims.CourierCode, req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	lang := apperr.ParseLang(c.GetHeader("Accept-Language"))
+	for i := range results {
+		if results[i].Code != "" {
+			results[i].Error = apperr.Message(results[i].Code, lang)
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "success",
+		"data":     results,
+		"total":    len(results),
+		"returned": returned,
+		"failed":   len(results) - returned,
+	})
+}
@@ -0,0 +1,260 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"campus-logistics/internal/apperr"
+	"campus-logistics/internal/middleware"
+	"campus-logistics/internal/repository"
+	"campus-logistics/internal/service"
+	"campus-logistics/internal/validation"
+
+	"github.com/gin-gonic/gin"
+)
+
+// shipmentStatus 校验 ?status= 参数，为空表示全部状态
+func shipmentStatus(c *gin.Context, def string) (string, bool) {
+	status := c.DefaultQuery("status", def)
+	switch status {
+	case "", "created", "dropped_off", "collected", "cancelled":
+		return status, true
+	}
+	c.Error(apperr.Invalid("status", "oneof").WithParam("created dropped_off collected cancelled"))
+	return "", false
+}
+
+// listShipments 按访问范围分页查询寄件订单
+func listShipments(c *gin.Context, scope repository.ShipmentScope, defStatus string) {
+	status, ok := shipmentStatus(c, defStatus)
+	if !ok {
+		return
+	}
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+
+	shipments, err := service.ListShipments(scope, status, page, pageSize)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "success", "data": shipments, "count": len(shipments)})
+}
+
+// ShippingQuoteHandler 运费试算
+// POST /api/v1/shipping/quote
+// 请求体：{"courier_code": "SF", "province": "浙江", "weight_grams": 1500}，courier_code 可省略
+func ShippingQuoteHandler(c *gin.Context) {
+	var req service.QuoteRequest
+	if err := bindJSON(c, &req); err != nil {
+		c.Error(err)
+		return
+	}
+	quotes, err := service.QuoteShipping(req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "success", "data": quotes})
+}
+
+// CreateShipmentHandler 学生寄件下单
+// POST /api/v1/shipments
+func CreateShipmentHandler(c *gin.Context) {
+	var req service.CreateShipmentRequest
+	if err := bindJSON(c, &req); err != nil {
+		c.Error(err)
+		return
+	}
+	claims, ok := middleware.GetClaims(c)
+	if !ok || claims.UserID == 0 {
+		c.Error(apperr.Newf(apperr.CodeUnauthorized, "missing student claims"))
+		return
+	}
+
+	// 快递公司无效 400 INVALID_COURIER，不支持寄往该地区 400 SHIPPING_UNAVAILABLE
+	shipment, err := service.CreateShipment(claims.UserID, req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "success", "data": shipment})
+}
+
+// ListMyShipmentsHandler 学生查看本人的寄件订单
+// GET /api/v1/shipments?status=created&page=1&page_size=20
+func ListMyShipmentsHandler(c *gin.Context) {
+	claims, ok := middleware.GetClaims(c)
+	if !ok || claims.UserID == 0 {
+		c.Error(apperr.Newf(apperr.CodeUnauthorized, "missing student claims"))
+		return
+	}
+	listShipments(c, repository.ShipmentScope{UserID: claims.UserID}, "")
+}
+
+// GetMyShipmentHandler 学生查看寄件订单详情及状态记录
+// GET /api/v1/shipments/:order_no
+func GetMyShipmentHandler(c *gin.Context) {
+	claims, ok := middleware.GetClaims(c)
+	if !ok || claims.UserID == 0 {
+		c.Error(apperr.Newf(apperr.CodeUnauthorized, "missing student claims"))
+		return
+	}
+	shipment, err := service.GetShipment(validation.Code(c.Param("order_no")), repository.ShipmentScope{UserID: claims.UserID}, false)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "success", "data": shipment})
+}
+
+// CancelShipmentHandler 学生取消尚未交寄的订单
+// POST /api/v1/shipments/:order_no/cancel
+func CancelShipmentHandler(c *gin.Context) {
+	claims, ok := middleware.GetClaims(c)
+	if !ok || claims.UserID == 0 {
+		c.Error(apperr.Newf(apperr.CodeUnauthorized, "missing student claims"))
+		return
+	}
+	// 已交寄 409 ILLEGAL_TRANSITION
+	if err := service.CancelShipment(validation.Code(c.Param("order_no")), claims.UserID); err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "success"})
+}
+
+// StationShipmentsHandler 驿站查看交寄到本站点的寄件订单（默认只看待收件的）
+// GET /api/v1/station/shipments?status=created&page=1&page_size=20
+func StationShipmentsHandler(c *gin.Context) {
+	stationID, ok := stationScope(c)
+	if !ok {
+		return
+	}
+	listShipments(c, repository.ShipmentScope{StationID: stationID}, "created")
+}
+
+// DropOffShipmentHandler 驿站收件：称重并确认运费
+// POST /api/v1/station/shipments/:order_no/dropoff
+// 请求体：{"weight_grams": 1800}，可省略（沿用申报重量）
+func DropOffShipmentHandler(c *gin.Context) {
+	var req service.DropOffRequest
+	if c.Request.ContentLength != 0 {
+		if err := bindJSON(c, &req); err != nil {
+			c.Error(err)
+			return
+		}
+	}
+	claims, ok := middleware.GetClaims(c)
+	if !ok || claims.Username == "" {
+		c.Error(apperr.Newf(apperr.CodeUnauthorized, "missing admin claims"))
+		return
+	}
+	stationID, ok := stationScope(c)
+	if !ok {
+		return
+	}
+
+	shipment, err := service.DropOffShipment(validation.Code(c.Param("order_no")), stationID, req, claims.Username)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "success", "data": shipment})
+}
+
+// CourierShipmentsHandler 快递员查看本公司待揽收的寄件订单
+// GET /api/v1/courier/shipments?status=dropped_off&page=1&page_size=20
+func CourierShipmentsHandler(c *gin.Context) {
+	claims, ok := middleware.GetClaims(c)
+	if !ok || claims.CourierID == 0 {
+		c.Error(apperr.Newf(apperr.CodeUnauthorized, "missing courier claims"))
+		return
+	}
+	listShipments(c, repository.ShipmentScope{CourierID: claims.CourierID}, "dropped_off")
+}
+
+// CollectShipmentHandler 快递员揽收并登记运单号
+// POST /api/v1/courier/shipments/:order_no/collect
+// 请求体：{"tracking_number": "SF1234567890123"}
+func CollectShipmentHandler(c *gin.Context) {
+	var req service.CollectRequest
+	if err := bindJSON(c, &req); err != nil {
+		c.Error(err)
+		return
+	}
+	claims, ok := middleware.GetClaims(c)
+	if !ok || claims.CourierID == 0 {
+		c.Error(apperr.Newf(apperr.CodeUnauthorized, "missing courier claims"))
+		return
+	}
+
+	// 运单号已使用 409 DUPLICATE_TRACKING，驿站尚未收件 409 ILLEGAL_TRANSITION
+	shipment, err := service.CollectShipment(validation.Code(c.Param("order_no")), claims.CourierID, claims.CourierCode, req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "success", "data": shipment})
+}
+
+// AdminShipmentsHandler 管理员查询寄件订单
+// GET /api/v1/admin/shipments?status=&station=NORTH&page=1&page_size=20
+func AdminShipmentsHandler(c *gin.Context) {
+	stationID, ok := stationScope(c)
+	if !ok {
+		return
+	}
+	listShipments(c, repository.ShipmentScope{StationID: stationID}, "")
+}
+
+// AdminShipmentHandler 管理员查看寄件订单详情及审计记录（含操作人）
+// GET /api/v1/admin/shipments/:order_no
+func AdminShipmentHandler(c *gin.Context) {
+	stationID, ok := stationScope(c)
+	if !ok {
+		return
+	}
+	shipment, err := service.GetShipment(validation.Code(c.Param("order_no")), repository.ShipmentScope{StationID: stationID}, true)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "success", "data": shipment})
+}
+
+// ListShippingRatesHandler 查询寄件运费表
+// GET /api/v1/admin/shipping/rates?courier=SF
+func ListShippingRatesHandler(c *gin.Context) {
+	rates, err := service.ListShippingRates(c.Query("courier"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "success", "data": rates})
+}
+
+// SetShippingRateHandler 新增或修改快递公司在某个区域的运费
+// PUT /api/v1/admin/shipping/rates/:courier/:zone
+func SetShippingRateHandler(c *gin.Context) {
+	var req service.ShippingRateRequest
+	if err := bindJSON(c, &req); err != nil {
+		c.Error(err)
+		return
+	}
+	if err := service.SetShippingRate(c.Param("courier"), c.Param("zone"), req); err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "success"})
+}
+
+// DeleteShippingRateHandler 删除运费，该快递公司不再接受寄往该区域的订单
+// DELETE /api/v1/admin/shipping/rates/:courier/:zone
+func DeleteShippingRateHandler(c *gin.Context) {
+	if err := service.DeleteShippingRate(c.Param("courier"), c.Param("zone")); err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "success"})
+}
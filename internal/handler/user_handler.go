@@ -0,0 +1,172 @@
+package handler
+
+import (
+	"net/http"
+
+	"campus-logistics/internal/apperr"
+	"campus-logistics/internal/middleware"
+	"campus-logistics/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetMeHandler 查看本人资料
+// GET /api/v1/me
+func GetMeHandler(c *gin.Context) {
+	claims, ok := middleware.GetClaims(c)
+	if !ok || claims.UserID == 0 {
+		c.Error(apperr.Newf(apperr.CodeUnauthorized, "missing student claims"))
+		return
+	}
+
+	u, err := service.GetProfile(claims.UserID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "success", "data": u})
+}
+
+// UpdateMeHandler 修改本人资料（姓名、学号、宿舍、通知偏好）
+// PATCH /api/v1/me
+// 请求体：{"name": "...", "student_id": "...", "dorm": "...", "notify_inbound": true, "notify_retention": false}
+func UpdateMeHandler(c *gin.Context) {
+	var req service.UpdateProfileRequest
+	if err := bindJSON(c, &req); err != nil {
+		c.Error(err)
+		return
+	}
+	claims, ok := middleware.GetClaims(c)
+	if !ok || claims.UserID == 0 {
+		c.Error(apperr.Newf(apperr.CodeUnauthorized, "missing student claims"))
+		return
+	}
+
+	u, err := service.UpdateProfile(claims.UserID, req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "success", "data": u})
+}
+
+// RequestPhoneChangeHandler 申请更换手机号，验证码发送到新号码
+// POST /api/v1/me/phone
+// 请求体：{"phone": "13800138001"}
+func RequestPhoneChangeHandler(c *gin.Context) {
+	var req service.PhoneChangeRequest
+	if err := bindJSON(c, &req); err != nil {
+		c.Error(err)
+		return
+	}
+	claims, ok := middleware.GetClaims(c)
+	if !ok || claims.UserID == 0 {
+		c.Error(apperr.Newf(apperr.CodeUnauthorized, "missing student claims"))
+		return
+	}
+
+	pc, err := service.RequestPhoneChange(claims.UserID, req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "success", "data": pc})
+}
+
+// ConfirmPhoneChangeHandler 提交验证码完成手机号变更，返回新的 token（旧 token 中的手机号已失效）
+// POST /api/v1/me/phone/verify
+// 请求体：{"code": "123456"}
+func ConfirmPhoneChangeHandler(c *gin.Context) {
+	var req service.ConfirmPhoneChangeRequest
+	if err := bindJSON(c, &req); err != nil {
+		c.Error(err)
+		return
+	}
+	claims, ok := middleware.GetClaims(c)
+	if !ok || claims.UserID == 0 {
+		c.Error(apperr.Newf(apperr.CodeUnauthorized, "missing student claims"))
+		return
+	}
+
+	result, err := service.ConfirmPhoneChange(claims.UserID, req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "success", "data": result})
+}
+
+// ExportMeHandler 导出本人的全部记录（资料、包裹、代取授权、寄件订单、变更记录），JSON 附件
+// GET /api/v1/me/export
+func ExportMeHandler(c *gin.Context) {
+	claims, ok := middleware.GetClaims(c)
+	if !ok || claims.UserID == 0 {
+		c.Error(apperr.Newf(apperr.CodeUnauthorized, "missing student claims"))
+		return
+	}
+
+	data, err := service.ExportMyData(claims.UserID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.Header("Content-Disposition", `attachment; filename="my-data.json"`)
+	c.JSON(http.StatusOK, gin.H{"message": "success", "data": data})
+}
+
+// SearchUsersHandler 按手机号、学号或姓名查找用户
+// GET /api/v1/admin/users?q=13800138000
+func SearchUsersHandler(c *gin.Context) {
+	users, err := service.SearchUsers(c.Query("q"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "success", "data": users, "count": len(users)})
+}
+
+// MergeUsersHandler 合并重复用户：source 的包裹、代取授权、寄件订单转移给 target，随后删除 source
+// POST /api/v1/admin/users/merge
+// 请求体：{"source_phone": "...", "target_phone": "...", "reason": "..."}
+func MergeUsersHandler(c *gin.Context) {
+	var req service.MergeUsersRequest
+	if err := bindJSON(c, &req); err != nil {
+		c.Error(err)
+		return
+	}
+	claims, ok := middleware.GetClaims(c)
+	if !ok || claims.Username == "" {
+		c.Error(apperr.Newf(apperr.CodeUnauthorized, "missing admin claims"))
+		return
+	}
+
+	result, err := service.MergeUsers(req, claims.Username)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "success", "data": result})
+}
+
+// EraseUserHandler 按学生申请删除其个人信息（资料与包裹、寄件订单上的快照匿名化，统计数据保留）
+// POST /api/v1/admin/users/erase
+// 请求体：{"phone": "...", "reason": "..."}
+func EraseUserHandler(c *gin.Context) {
+	var req service.EraseUserRequest
+	if err := bindJSON(c, &req); err != nil {
+		c.Error(err)
+		return
+	}
+	claims, ok := middleware.GetClaims(c)
+	if !ok || claims.Username == "" {
+		c.Error(apperr.Newf(apperr.CodeUnauthorized, "missing admin claims"))
+		return
+	}
+
+	result, err := service.EraseUser(req, claims.Username)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "success", "data": result})
+}
@@ -0,0 +1,149 @@
+// Package label 渲染取件二维码、货架 Code128 条码以及可打印的 PDF 标签页。
+// 全部为纯 Go 实现，驿站电脑离线也可使用。
+package label
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"strings"
+
+	"github.com/boombuler/barcode"
+	"github.com/boombuler/barcode/code128"
+	"github.com/boombuler/barcode/qr"
+)
+
+// 静区（条码四周留白）模块数：QR 规范要求 4，Code128 要求 10
+const (
+	qrQuietZone      = 4
+	code128QuietZone = 10
+)
+
+// EncodeQR 生成 QR 码（纠错等级 M），未缩放：每个像素对应一个模块
+func EncodeQR(content string) (barcode.Barcode, error) {
+	return qr.Encode(content, qr.M, qr.Auto)
+}
+
+// EncodeCode128 生成 Code128 条码，未缩放：宽度为模块数，高度为 1
+func EncodeCode128(content string) (barcode.Barcode, error) {
+	return code128.Encode(content)
+}
+
+// QRPNG 输出边长约为 size 像素的 QR 码 PNG（按整数倍放大，保证模块清晰）
+func QRPNG(w io.Writer, content string, size int) error {
+	bc, err := EncodeQR(content)
+	if err != nil {
+		return err
+	}
+	total := bc.Bounds().Dx() + 2*qrQuietZone
+	scale := max(1, size/total)
+	return png.Encode(w, rasterize(bc, scale, scale, qrQuietZone))
+}
+
+// QRSVG 输出 QR 码 SVG，size 为显示尺寸（像素）；viewBox 以模块为单位，任意缩放都不失真
+func QRSVG(w io.Writer, content string, size int) error {
+	bc, err := EncodeQR(content)
+	if err != nil {
+		return err
+	}
+	n := bc.Bounds().Dx() + 2*qrQuietZone
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`, size, size, n, n)
+	fmt.Fprintf(&sb, `<rect width="%d" height="%d" fill="#fff"/><path fill="#000" d="`, n, n)
+	for y := 0; y < bc.Bounds().Dy(); y++ {
+		eachRun(bc, y, func(start, length int) {
+			fmt.Fprintf(&sb, "M%d %dh%dv1h-%dz", start+qrQuietZone, y+qrQuietZone, length, length)
+		})
+	}
+	sb.WriteString(`"/></svg>`)
+	_, err = io.WriteString(w, sb.String())
+	return err
+}
+
+// Code128PNG 输出 Code128 条码 PNG：moduleWidth 为单个模块的像素宽度，height 为条高
+func Code128PNG(w io.Writer, content string, moduleWidth, height int) error {
+	bc, err := EncodeCode128(content)
+	if err != nil {
+		return err
+	}
+	return png.Encode(w, rasterize(bc, max(1, moduleWidth), max(1, height), code128QuietZone))
+}
+
+// Code128SVG 输出 Code128 条码 SVG，条码下方附可读文本
+func Code128SVG(w io.Writer, content string, moduleWidth, height int) error {
+	bc, err := EncodeCode128(content)
+	if err != nil {
+		return err
+	}
+	moduleWidth, height = max(1, moduleWidth), max(1, height)
+	width := (bc.Bounds().Dx() + 2*code128QuietZone) * moduleWidth
+	const textHeight = 18
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`,
+		width, height+textHeight, width, height+textHeight)
+	fmt.Fprintf(&sb, `<rect width="%d" height="%d" fill="#fff"/><path fill="#000" d="`, width, height+textHeight)
+	eachRun(bc, 0, func(start, length int) {
+		fmt.Fprintf(&sb, "M%d 0h%dv%dh-%dz", (start+code128QuietZone)*moduleWidth, length*moduleWidth, height, length*moduleWidth)
+	})
+	fmt.Fprintf(&sb, `"/><text x="%d" y="%d" font-family="monospace" font-size="14" text-anchor="middle">%s</text></svg>`,
+		width/2, height+textHeight-3, escapeXML(content))
+	_, err = io.WriteString(w, sb.String())
+	return err
+}
+
+// rasterize 把未缩放的条码按模块放大为灰度图，并加上静区；
+// 一维码只有一行，纵向放大 scaleY 即为条高
+func rasterize(bc barcode.Barcode, scaleX, scaleY, quiet int) *image.Gray {
+	b := bc.Bounds()
+	quietY := quiet
+	if b.Dy() == 1 {
+		quietY = 0
+	}
+	img := image.NewGray(image.Rect(0, 0, (b.Dx()+2*quiet)*scaleX, (b.Dy()+2*quietY)*scaleY))
+	for i := range img.Pix {
+		img.Pix[i] = 0xff
+	}
+	for y := 0; y < b.Dy(); y++ {
+		for x := 0; x < b.Dx(); x++ {
+			if !isDark(bc.At(b.Min.X+x, b.Min.Y+y)) {
+				continue
+			}
+			for dy := 0; dy < scaleY; dy++ {
+				row := ((y+quietY)*scaleY + dy) * img.Stride
+				for dx := 0; dx < scaleX; dx++ {
+					img.Pix[row+(x+quiet)*scaleX+dx] = 0
+				}
+			}
+		}
+	}
+	return img
+}
+
+// eachRun 遍历第 y 行中连续的深色模块，按 (起点, 长度) 回调，用于合并 SVG 矩形
+func eachRun(bc barcode.Barcode, y int, emit func(start, length int)) {
+	b := bc.Bounds()
+	for x := 0; x < b.Dx(); {
+		if !isDark(bc.At(b.Min.X+x, b.Min.Y+y)) {
+			x++
+			continue
+		}
+		start := x
+		for x < b.Dx() && isDark(bc.At(b.Min.X+x, b.Min.Y+y)) {
+			x++
+		}
+		emit(start, x-start)
+	}
+}
+
+func isDark(c color.Color) bool {
+	r, g, b, _ := c.RGBA()
+	return r+g+b < 3*0x8000
+}
+
+func escapeXML(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&quot;").Replace(s)
+}
@@ -0,0 +1,77 @@
+package label
+
+import (
+	"bytes"
+	"fmt"
+	"image/png"
+	"io"
+
+	"github.com/go-pdf/fpdf"
+)
+
+// Label 标签页中的一个标签：Code 编码为条码，Caption 显示在条码下方
+type Label struct {
+	Code    string
+	Caption string
+}
+
+// A4 标签页布局（单位 mm）：3 列 × 8 行，常见 A4 不干胶标签纸尺寸
+const (
+	sheetColumns   = 3
+	sheetRows      = 8
+	sheetMarginX   = 7.0
+	sheetMarginTop = 12.0
+	cellWidth      = 65.0
+	cellHeight     = 34.0
+	barHeight      = 15.0
+)
+
+// SheetPDF 输出 A4 标签页 PDF，每页 24 个标签，超出自动分页；title 打印在每页页眉
+func SheetPDF(w io.Writer, title string, labels []Label) error {
+	pdf := fpdf.New("P", "mm", "A4", "")
+	pdf.SetTitle(title, false)
+	pdf.SetAutoPageBreak(false, 0)
+	pdf.SetFont("Courier", "", 10)
+
+	perPage := sheetColumns * sheetRows
+	for i, l := range labels {
+		if i%perPage == 0 {
+			pdf.AddPage()
+			pdf.SetFont("Helvetica", "", 8)
+			pdf.Text(sheetMarginX, 7, fmt.Sprintf("%s  (%d/%d)", title, i/perPage+1, (len(labels)+perPage-1)/perPage))
+		}
+
+		slot := i % perPage
+		x := sheetMarginX + float64(slot%sheetColumns)*cellWidth
+		y := sheetMarginTop + float64(slot/sheetColumns)*cellHeight
+
+		bc, err := EncodeCode128(l.Code)
+		if err != nil {
+			return fmt.Errorf("encode %q: %w", l.Code, err)
+		}
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, rasterize(bc, 2, 1, code128QuietZone)); err != nil {
+			return err
+		}
+		name := fmt.Sprintf("bc-%d", i)
+		opts := fpdf.ImageOptions{ImageType: "PNG"}
+		pdf.RegisterImageOptionsReader(name, opts, &buf)
+		pdf.ImageOptions(name, x+4, y+3, cellWidth-8, barHeight, false, opts, 0, "")
+
+		pdf.SetFont("Courier", "B", 14)
+		pdf.SetXY(x, y+barHeight+4)
+		pdf.CellFormat(cellWidth, 6, l.Code, "", 0, "C", false, 0, "")
+		if l.Caption != "" {
+			pdf.SetFont("Helvetica", "", 8)
+			pdf.SetXY(x, y+barHeight+10)
+			pdf.CellFormat(cellWidth, 4, l.Caption, "", 0, "C", false, 0, "")
+		}
+	}
+	if len(labels) == 0 {
+		pdf.AddPage()
+	}
+	if err := pdf.Error(); err != nil {
+		return err
+	}
+	return pdf.Output(w)
+}
@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"log"
+
+	"campus-logistics/internal/apperr"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ErrorHandler 统一的错误响应：handler 通过 c.Error(err) 上报错误并直接返回，
+// 这里按错误码写出 {"error": "<本地化提示>", "code": "<错误码>"}，校验失败时附带 fields 逐字段说明。
+// 语言由 Accept-Language 决定（zh / en，默认 zh）；内部错误只写日志，不向客户端暴露细节。
+// 需在 gin 引擎上全局注册。
+func ErrorHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+		renderError(c)
+	}
+}
+
+// AbortWithError 中止后续 handler，并交由 ErrorHandler 写出错误响应
+func AbortWithError(c *gin.Context, err error) {
+	_ = c.Error(err)
+	c.Abort()
+	renderError(c)
+}
+
+// renderError 写出 c.Errors 中最后一个错误；响应已写出时不做处理
+func renderError(c *gin.Context) {
+	if len(c.Errors) == 0 || c.Writer.Written() {
+		return
+	}
+
+	e := apperr.Translate(c.Errors.Last().Err)
+	status := e.Code.HTTPStatus()
+	if status >= 500 {
+		log.Printf("%s %s: %v", c.Request.Method, c.Request.URL.Path, e)
+	}
+
+	lang := apperr.ParseLang(c.GetHeader("Accept-Language"))
+	body := gin.H{
+		"error": e.Message(lang),
+		"code":  e.Code,
+	}
+	if len(e.Fields) > 0 {
+		body["fields"] = e.Localize(lang)
+	}
+	c.JSON(status, body)
+}
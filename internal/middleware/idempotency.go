@@ -0,0 +1,180 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"campus-logistics/internal/apperr"
+	"campus-logistics/internal/model"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+)
+
+const (
+	IdempotencyKeyHeader      = "Idempotency-Key"
+	idempotentReplayedHeader  = "Idempotent-Replayed"
+	maxIdempotencyKeyLength   = 128
+	maxIdempotencyRequestBody = 1 << 20
+)
+
+// IdempotencyStore 保存幂等键与首个请求的响应
+type IdempotencyStore interface {
+	// Reserve 占用幂等键；键已存在时返回已有记录且 reserved 为 false
+	// 未完成且占用超过 lease 的键视为首个请求已中断，可被重新占用
+	Reserve(key, requestHash string, ttl, lease time.Duration) (rec *model.IdempotencyRecord, reserved bool, err error)
+	Complete(key string, statusCode int, contentType string, body []byte) error
+	Release(key string) error
+}
+
+// IdempotencyTTL 幂等键保留时长，来自 idempotency.ttl_hours，默认 24 小时
+func IdempotencyTTL() time.Duration {
+	if h := viper.GetInt("idempotency.ttl_hours"); h > 0 {
+		return time.Duration(h) * time.Hour
+	}
+	return 24 * time.Hour
+}
+
+// IdempotencyLease 首个请求占用幂等键的租约，来自 idempotency.lease_seconds，默认 60 秒；
+// 超时未完成（进程崩溃、停机中断）的占用可被重试请求接管，应大于最慢的幂等接口耗时
+func IdempotencyLease() time.Duration {
+	if s := viper.GetInt("idempotency.lease_seconds"); s > 0 {
+		return time.Duration(s) * time.Second
+	}
+	return 60 * time.Second
+}
+
+// Idempotency 支持 Idempotency-Key 请求头的安全重试：
+//   - 首个请求正常执行，响应（非 5xx）与请求哈希一起保存
+//   - 相同键 + 相同请求体：直接返回保存的响应，并带 Idempotent-Replayed: true
+//   - 相同键 + 不同请求体：422
+//   - 首个请求仍在处理中：409；占用超过租约仍未完成时视为中断，由重试请求接管
+//
+// 键按调用者身份和路由隔离，需放在 AuthRequired 之后。未携带请求头时不做任何处理。
+func Idempotency(store IdempotencyStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		idemKey := strings.TrimSpace(c.GetHeader(IdempotencyKeyHeader))
+		if idemKey == "" || store == nil {
+			c.Next()
+			return
+		}
+		if len(idemKey) > maxIdempotencyKeyLength {
+			AbortWithError(c, apperr.Newf(apperr.CodeInvalidRequest, "Idempotency-Key too long"))
+			return
+		}
+
+		// 多读 1 字节判断是否超限：截断的请求体会让处理函数拿到不完整的 JSON，且哈希无法区分不同请求
+		raw, err := io.ReadAll(io.LimitReader(c.Request.Body, maxIdempotencyRequestBody+1))
+		if err != nil {
+			AbortWithError(c, apperr.Newf(apperr.CodeInvalidRequest, "read request body failed"))
+			return
+		}
+		if len(raw) > maxIdempotencyRequestBody {
+			AbortWithError(c, apperr.New(apperr.CodeRequestTooLarge))
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(raw))
+
+		key := idempotencyScope(c) + ":" + idemKey
+		hash := requestHash(c, raw)
+
+		rec, reserved, err := store.Reserve(key, hash, IdempotencyTTL(), IdempotencyLease())
+		if err != nil {
+			// 存储不可用不代表重复请求，返回 503 让客户端稍后用同一个键重试
+			log.Printf("idempotency reserve failed: %v", err)
+			AbortWithError(c, apperr.Wrap(apperr.CodeServiceBusy, err))
+			return
+		}
+
+		if !reserved {
+			switch {
+			case rec.RequestHash != hash:
+				AbortWithError(c, apperr.New(apperr.CodeIdempotencyMismatch))
+			case !rec.Completed:
+				AbortWithError(c, apperr.New(apperr.CodeIdempotencyInProcess))
+			default:
+				c.Header(idempotentReplayedHeader, "true")
+				c.Data(rec.StatusCode, rec.ContentType, rec.Body)
+				c.Abort()
+			}
+			return
+		}
+
+		// handler panic 时（Recovery 在本中间件外层）释放占用，客户端可用同一个键重试
+		finished := false
+		defer func() {
+			if !finished {
+				if err := store.Release(key); err != nil {
+					log.Printf("idempotency release failed: %v", err)
+				}
+			}
+		}()
+
+		w := &capturingWriter{ResponseWriter: c.Writer}
+		c.Writer = w
+		c.Next()
+		finished = true
+		// handler 通过 c.Error 上报的错误需在此写出，才能被记录下来用于重放
+		renderError(c)
+
+		status := w.Status()
+		if status >= http.StatusInternalServerError {
+			if err := store.Release(key); err != nil {
+				log.Printf("idempotency release failed: %v", err)
+			}
+			return
+		}
+		if err := store.Complete(key, status, w.Header().Get("Content-Type"), w.body.Bytes()); err != nil {
+			log.Printf("idempotency complete failed: %v", err)
+		}
+	}
+}
+
+// idempotencyScope 由调用者身份与路由组成，不同用户、不同接口的相同键互不影响
+func idempotencyScope(c *gin.Context) string {
+	principal := "anonymous"
+	if claims, ok := GetClaims(c); ok {
+		switch claims.Role {
+		case RoleStudent:
+			principal = "student:" + strconv.FormatInt(claims.UserID, 10)
+		case RoleCourier:
+			principal = "courier:" + strconv.FormatInt(claims.CourierID, 10)
+		case RoleAdmin:
+			principal = "admin:" + strconv.FormatInt(claims.AdminID, 10) + ":" + claims.Username
+		}
+	}
+	return principal + ":" + c.Request.Method + " " + c.FullPath()
+}
+
+func requestHash(c *gin.Context, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(c.Request.Method))
+	h.Write([]byte{0})
+	h.Write([]byte(c.Request.URL.Path))
+	h.Write([]byte{0})
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// capturingWriter 在写出响应的同时保留一份副本用于幂等重放
+type capturingWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *capturingWriter) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *capturingWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
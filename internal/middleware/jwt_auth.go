@@ -2,11 +2,12 @@ package middleware
 
 import (
 	"errors"
-	"net/http"
 	"os"
 	"strings"
 	"time"
 
+	"campus-logistics/internal/apperr"
+
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/spf13/viper"
@@ -20,6 +21,16 @@ const (
 	RoleStudent Role = "student"
 	RoleCourier Role = "courier"
 	RoleAdmin   Role = "admin"
+
+	// RoleAdminMFAPending 密码校验通过、尚未完成二次验证的中间态 token，
+	// 不在任何 RequireRole 白名单中，只能用于 POST /api/v1/auth/admin/mfa
+	RoleAdminMFAPending Role = "admin_mfa_pending"
+)
+
+// 管理员内部角色（admins.role）
+const (
+	AdminRoleSuper    = "super_admin"
+	AdminRoleOperator = "operator"
 )
 
 type Claims struct {
@@ -30,6 +41,13 @@ type Claims struct {
 	CourierCode string `json:"courier_code,omitempty"`
 	AdminID     int64  `json:"admin_id,omitempty"`
 	Username    string `json:"username,omitempty"`
+	AdminRole   string `json:"admin_role,omitempty"`
+	// StationID 站点管理员所属站点；0 表示可访问全部站点
+	StationID int64 `json:"station_id,omitempty"`
+	// PasswordExpired 为 true 时，管理员只能访问修改密码接口
+	PasswordExpired bool `json:"pwd_expired,omitempty"`
+	// MFASetupRequired 为 true 时，系统要求 MFA 但该管理员尚未绑定，只能访问绑定接口
+	MFASetupRequired bool `json:"mfa_setup,omitempty"`
 
 	jwt.RegisteredClaims
 }
@@ -60,45 +78,52 @@ func IssueToken(claims Claims, ttl time.Duration) (string, error) {
 	return token.SignedString([]byte(secret))
 }
 
+// ParseToken 校验签名与有效期并返回 claims
+func ParseToken(raw string) (*Claims, error) {
+	secret, err := JwtSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	parsed, err := jwt.ParseWithClaims(raw, &Claims{}, func(token *jwt.Token) (any, error) {
+		if token.Method != jwt.SigningMethodHS256 {
+			return nil, errors.New("unexpected signing method")
+		}
+		return []byte(secret), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Name}))
+	if err != nil {
+		return nil, err
+	}
+
+	claims, ok := parsed.Claims.(*Claims)
+	if !ok || !parsed.Valid {
+		return nil, errors.New("invalid token")
+	}
+	return claims, nil
+}
+
 func AuthRequired() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		h := c.GetHeader("Authorization")
 		if h == "" || !strings.HasPrefix(strings.ToLower(h), "bearer ") {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "missing bearer token"})
-			c.Abort()
+			AbortWithError(c, apperr.Newf(apperr.CodeUnauthorized, "missing bearer token"))
 			return
 		}
 
 		raw := strings.TrimSpace(h[len("Bearer "):])
 		if raw == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "missing bearer token"})
-			c.Abort()
+			AbortWithError(c, apperr.Newf(apperr.CodeUnauthorized, "missing bearer token"))
 			return
 		}
 
-		secret, err := JwtSecret()
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-			c.Abort()
+		if _, err := JwtSecret(); err != nil {
+			AbortWithError(c, err)
 			return
 		}
 
-		parsed, err := jwt.ParseWithClaims(raw, &Claims{}, func(token *jwt.Token) (any, error) {
-			if token.Method != jwt.SigningMethodHS256 {
-				return nil, errors.New("unexpected signing method")
-			}
-			return []byte(secret), nil
-		}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Name}))
+		claims, err := ParseToken(raw)
 		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
-			c.Abort()
-			return
-		}
-
-		claims, ok := parsed.Claims.(*Claims)
-		if !ok || !parsed.Valid {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
-			c.Abort()
+			AbortWithError(c, apperr.Newf(apperr.CodeUnauthorized, "invalid token"))
 			return
 		}
 
@@ -125,13 +150,64 @@ func RequireRole(roles ...Role) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		claims, ok := GetClaims(c)
 		if !ok {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "missing auth claims"})
-			c.Abort()
+			AbortWithError(c, apperr.Newf(apperr.CodeUnauthorized, "missing auth claims"))
 			return
 		}
 		if _, ok := allowed[claims.Role]; !ok {
-			c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
-			c.Abort()
+			AbortWithError(c, apperr.New(apperr.CodeForbidden))
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireAdminRole 在 RequireRole(RoleAdmin) 之后使用，限制管理员内部角色
+func RequireAdminRole(roles ...string) gin.HandlerFunc {
+	allowed := map[string]struct{}{}
+	for _, r := range roles {
+		allowed[r] = struct{}{}
+	}
+
+	return func(c *gin.Context) {
+		claims, ok := GetClaims(c)
+		if !ok {
+			AbortWithError(c, apperr.Newf(apperr.CodeUnauthorized, "missing auth claims"))
+			return
+		}
+		if _, ok := allowed[claims.AdminRole]; !ok {
+			AbortWithError(c, apperr.New(apperr.CodeForbidden))
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequirePasswordFresh 拒绝密码已过期的管理员 token，提示其先修改密码
+func RequirePasswordFresh() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims, ok := GetClaims(c)
+		if !ok {
+			AbortWithError(c, apperr.Newf(apperr.CodeUnauthorized, "missing auth claims"))
+			return
+		}
+		if claims.PasswordExpired {
+			AbortWithError(c, apperr.New(apperr.CodePasswordExpired))
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireMFASatisfied 拒绝尚未按系统要求绑定 MFA 的管理员 token
+func RequireMFASatisfied() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims, ok := GetClaims(c)
+		if !ok {
+			AbortWithError(c, apperr.Newf(apperr.CodeUnauthorized, "missing auth claims"))
+			return
+		}
+		if claims.MFASetupRequired {
+			AbortWithError(c, apperr.New(apperr.CodeMFASetupRequired))
 			return
 		}
 		c.Next()
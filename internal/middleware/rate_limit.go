@@ -0,0 +1,199 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"campus-logistics/internal/apperr"
+	"campus-logistics/internal/validation"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+)
+
+// RateLimitStore 令牌桶存储：每次请求消耗一个令牌。
+// ratePerSec 为补充速率，burst 为桶容量；被拒绝时返回还需等待的时间。
+type RateLimitStore interface {
+	Take(key string, ratePerSec float64, burst int) (allowed bool, retryAfter time.Duration, err error)
+}
+
+// 限流维度（configs/config.yaml 中 rate_limit.groups.<name>.keys 的取值）
+const (
+	RateKeyIP          = "ip"           // 客户端 IP
+	RateKeyPhone       = "phone"        // 请求体中的 phone
+	RateKeyUsername    = "username"     // 请求体中的 username
+	RateKeyCourierCode = "courier_code" // 请求体中的 courier_code，或快递员 token 中的 courier_code
+	RateKeyUser        = "user"         // token 中的学生 user_id
+)
+
+// maxRateLimitBody 为读取请求体字段时的上限，避免大请求体被整体读入内存
+const maxRateLimitBody = 64 << 10
+
+type rateLimitRule struct {
+	group      string
+	ratePerSec float64
+	burst      int
+	keys       []string
+}
+
+func loadRateLimitRule(group string) rateLimitRule {
+	prefix := "rate_limit.groups." + group + "."
+	r := rateLimitRule{
+		group:      group,
+		ratePerSec: viper.GetFloat64(prefix+"rate_per_minute") / 60,
+		burst:      viper.GetInt(prefix + "burst"),
+		keys:       viper.GetStringSlice(prefix + "keys"),
+	}
+	if r.burst <= 0 {
+		r.burst = 1
+	}
+	if len(r.keys) == 0 {
+		r.keys = []string{RateKeyIP}
+	}
+	return r
+}
+
+// RateLimit 按配置组对请求限流；每个维度各自一个令牌桶，任一维度耗尽即返回 429。
+// 未配置 rate_per_minute 的组不限流。store 出错时放行并记录日志，避免限流故障拖垮业务。
+// 使用 user / courier_code（token）维度时需放在 AuthRequired 之后。
+func RateLimit(store RateLimitStore, group string) gin.HandlerFunc {
+	rule := loadRateLimitRule(group)
+	if store == nil || rule.ratePerSec <= 0 {
+		return func(c *gin.Context) { c.Next() }
+	}
+
+	return func(c *gin.Context) {
+		var body map[string]any
+		for _, kind := range rule.keys {
+			value := rateLimitKeyValue(c, kind, &body)
+			if value == "" {
+				continue
+			}
+
+			key := "rl:" + rule.group + ":" + kind + ":" + value
+			allowed, retryAfter, err := store.Take(key, rule.ratePerSec, rule.burst)
+			if err != nil {
+				log.Printf("rate limit store failed (group=%s): %v", rule.group, err)
+				continue
+			}
+			if !allowed {
+				seconds := int(math.Ceil(retryAfter.Seconds()))
+				if seconds < 1 {
+					seconds = 1
+				}
+				c.Header("Retry-After", strconv.Itoa(seconds))
+				c.JSON(http.StatusTooManyRequests, gin.H{
+					"error":       apperr.Message(apperr.CodeRateLimited, apperr.ParseLang(c.GetHeader("Accept-Language"))),
+					"code":        apperr.CodeRateLimited,
+					"retry_after": seconds,
+				})
+				c.Abort()
+				return
+			}
+		}
+		c.Next()
+	}
+}
+
+func rateLimitKeyValue(c *gin.Context, kind string, body *map[string]any) string {
+	switch kind {
+	case RateKeyIP:
+		return c.ClientIP()
+	case RateKeyUser:
+		if claims, ok := GetClaims(c); ok && claims.UserID != 0 {
+			return strconv.FormatInt(claims.UserID, 10)
+		}
+		return ""
+	case RateKeyCourierCode:
+		if claims, ok := GetClaims(c); ok && claims.CourierCode != "" {
+			return claims.CourierCode
+		}
+		return validation.Code(bodyField(c, body, "courier_code"))
+	case RateKeyPhone:
+		// 与登录时的规范化一致，避免通过 +86、空格等变体绕过限流
+		return validation.Phone(bodyField(c, body, "phone"))
+	case RateKeyUsername:
+		return strings.ToLower(bodyField(c, body, "username"))
+	default:
+		return ""
+	}
+}
+
+// bodyField 读取 JSON 请求体中的字符串字段，并把请求体还原供后续 handler 绑定
+func bodyField(c *gin.Context, body *map[string]any, field string) string {
+	if *body == nil {
+		*body = map[string]any{}
+		if c.Request.Body != nil {
+			raw, err := io.ReadAll(io.LimitReader(c.Request.Body, maxRateLimitBody))
+			if err == nil {
+				c.Request.Body = io.NopCloser(io.MultiReader(bytes.NewReader(raw), c.Request.Body))
+				_ = json.Unmarshal(raw, body)
+			}
+		}
+	}
+	v, ok := (*body)[field]
+	if !ok || v == nil {
+		return ""
+	}
+	return strings.TrimSpace(fmt.Sprint(v))
+}
+
+// MemoryRateLimitStore 进程内令牌桶，适合单副本部署
+type MemoryRateLimitStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+}
+
+type tokenBucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// rateLimitIdleTTL 超过该时间未访问的桶会被清理（此时桶早已补满，清理不影响结果）
+const rateLimitIdleTTL = 30 * time.Minute
+
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{buckets: map[string]*tokenBucket{}, lastSweep: time.Now()}
+}
+
+func (s *MemoryRateLimitStore) Take(key string, ratePerSec float64, burst int) (bool, time.Duration, error) {
+	now := time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if now.Sub(s.lastSweep) > time.Minute {
+		for k, b := range s.buckets {
+			if now.Sub(b.updated) > rateLimitIdleTTL {
+				delete(s.buckets, k)
+			}
+		}
+		s.lastSweep = now
+	}
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &tokenBucket{tokens: float64(burst), updated: now}
+		s.buckets[key] = b
+	}
+
+	b.tokens = math.Min(float64(burst), b.tokens+now.Sub(b.updated).Seconds()*ratePerSec)
+	b.updated = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0, nil
+	}
+	wait := time.Duration((1 - b.tokens) / ratePerSec * float64(time.Second))
+	return false, wait, nil
+}
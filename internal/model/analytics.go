@@ -0,0 +1,90 @@
+package model
+
+import "time"
+
+// Analytics 管理员运营分析：时间范围 [From, To) 内按 Granularity（hour / day）分桶的统计
+type Analytics struct {
+	From        time.Time `json:"from"`
+	To          time.Time `json:"to"`
+	Granularity string    `json:"granularity"`
+	// GeneratedAt 统计生成时间；命中缓存时早于请求时间
+	GeneratedAt time.Time `json:"generated_at"`
+
+	Throughput    []ThroughputPoint    `json:"throughput"`
+	Dwell         DwellStats           `json:"dwell"`
+	Couriers      []CourierVolume      `json:"couriers"`
+	ZoneOccupancy []ZoneOccupancyPoint `json:"zone_occupancy"`
+	Retention     []RetentionBucket    `json:"retention"`
+	Heatmap       []HeatmapCell        `json:"heatmap"`
+}
+
+// ThroughputPoint 单个时间桶内的入库量、取件量及已取件包裹的滞留时长（小时）
+type ThroughputPoint struct {
+	Bucket           time.Time `db:"bucket" json:"bucket"`
+	Inbound          int       `db:"inbound" json:"inbound"`
+	PickedUp         int       `db:"picked_up" json:"picked_up"`
+	MedianDwellHours *float64  `db:"median_dwell_hours" json:"median_dwell_hours"`
+	P90DwellHours    *float64  `db:"p90_dwell_hours" json:"p90_dwell_hours"`
+}
+
+// DwellStats 范围内已取件包裹从入库（created_at）到取件（picked_up_at）的时长（小时）
+type DwellStats struct {
+	PickedUp    int      `db:"picked_up" json:"picked_up"`
+	MedianHours *float64 `db:"median_hours" json:"median_hours"`
+	P90Hours    *float64 `db:"p90_hours" json:"p90_hours"`
+	AvgHours    *float64 `db:"avg_hours" json:"avg_hours"`
+}
+
+// CourierVolume 各快递公司在范围内的入库量与取件量
+type CourierVolume struct {
+	CourierCode string `db:"courier_code" json:"courier_code"`
+	CourierName string `db:"courier_name" json:"courier_name"`
+	Inbound     int    `db:"inbound" json:"inbound"`
+	PickedUp    int    `db:"picked_up" json:"picked_up"`
+}
+
+// ZoneOccupancyPoint 货架区域在时间桶结束时（不晚于当前时间）的在架包裹数；Capacity 为当前总容量
+type ZoneOccupancyPoint struct {
+	Bucket   time.Time `db:"bucket" json:"bucket"`
+	Zone     string    `db:"zone" json:"zone"`
+	Occupied int       `db:"occupied" json:"occupied"`
+	Capacity int       `db:"capacity" json:"capacity"`
+}
+
+// RetentionBucket 当前待取件包裹按已存放时长分段的数量
+type RetentionBucket struct {
+	// Age 0-1d / 1-3d / 3-7d / 7-14d / 14d+
+	Age        string `db:"age" json:"age"`
+	Count      int    `db:"count" json:"count"`
+	Perishable int    `db:"perishable" json:"perishable"`
+}
+
+// HeatmapCell 星期（1 为周一）与小时的入库/取件量，只返回非零格
+type HeatmapCell struct {
+	Weekday  int `db:"weekday" json:"weekday"`
+	Hour     int `db:"hour" json:"hour"`
+	Inbound  int `db:"inbound" json:"inbound"`
+	PickedUp int `db:"picked_up" json:"picked_up"`
+}
+
+// DailyStat 每日统计汇总（daily_parcel_stats）按日期及可选维度聚合后的一行
+type DailyStat struct {
+	Date        string `db:"stat_date" json:"date"`
+	CourierCode string `db:"courier_code" json:"courier_code,omitempty"`
+	Zone        string `db:"zone" json:"zone,omitempty"`
+	Inbound     int    `db:"inbound" json:"inbound"`
+	PickedUp    int    `db:"picked_up" json:"picked_up"`
+	Returned    int    `db:"returned" json:"returned"`
+	Exceptions  int    `db:"exceptions" json:"exceptions"`
+	// AvgDwellHours 当天取件包裹的平均滞留时长，无取件时为 null
+	AvgDwellHours *float64 `db:"avg_dwell_hours" json:"avg_dwell_hours"`
+	// DwellHistogram 滞留时长分布：<1h, 1-6h, 6-24h, 1-3d, 3-7d, >=7d
+	DwellHistogram []int `db:"-" json:"dwell_histogram"`
+
+	Dwell1 int `db:"dwell_1" json:"-"`
+	Dwell2 int `db:"dwell_2" json:"-"`
+	Dwell3 int `db:"dwell_3" json:"-"`
+	Dwell4 int `db:"dwell_4" json:"-"`
+	Dwell5 int `db:"dwell_5" json:"-"`
+	Dwell6 int `db:"dwell_6" json:"-"`
+}
@@ -0,0 +1,32 @@
+package model
+
+import "time"
+
+// ParcelAttachment 包裹附件：入库照片、取件照片或签名，文件本身保存在对象存储中
+type ParcelAttachment struct {
+	ID          int64     `db:"id" json:"id"`
+	ParcelID    int64     `db:"parcel_id" json:"-"`
+	Kind        string    `db:"kind" json:"kind"`
+	StorageKey  string    `db:"storage_key" json:"-"`
+	ThumbKey    string    `db:"thumb_key" json:"-"`
+	ContentType string    `db:"content_type" json:"content_type"`
+	SizeBytes   int64     `db:"size_bytes" json:"size_bytes"`
+	Width       int       `db:"width" json:"width"`
+	Height      int       `db:"height" json:"height"`
+	SHA256      string    `db:"sha256" json:"sha256"`
+	UploadedBy  string    `db:"uploaded_by" json:"uploaded_by"`
+	CreatedAt   time.Time `db:"created_at" json:"created_at"`
+
+	// 带签名的短期下载地址，查询时填写
+	URL          string `db:"-" json:"url,omitempty"`
+	ThumbnailURL string `db:"-" json:"thumbnail_url,omitempty"`
+}
+
+// ParcelRef 包裹的归属信息，用于附件上传与查看的权限判断
+type ParcelRef struct {
+	ID        int64  `db:"id"`
+	UserID    int64  `db:"user_id"`
+	CourierID int64  `db:"courier_id"`
+	StationID int64  `db:"station_id"`
+	Status    string `db:"status"`
+}
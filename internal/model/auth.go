@@ -1,10 +1,22 @@
 package model
 
+import "time"
+
 type Admin struct {
-	ID           int64  `db:"id" json:"id"`
-	Username     string `db:"username" json:"username"`
-	PasswordHash string `db:"password_hash" json:"-"`
-	Role         string `db:"role" json:"role"`
+	ID                int64      `db:"id" json:"id"`
+	Username          string     `db:"username" json:"username"`
+	PasswordHash      string     `db:"password_hash" json:"-"`
+	Role              string     `db:"role" json:"role"`
+	StationID         *int64     `db:"station_id" json:"station_id"`
+	LastLoginAt       *time.Time `db:"last_login_at" json:"last_login_at"`
+	FailedAttempts    int        `db:"failed_attempts" json:"failed_attempts"`
+	LockedUntil       *time.Time `db:"locked_until" json:"locked_until"`
+	PasswordChangedAt *time.Time `db:"password_changed_at" json:"password_changed_at"`
+	TOTPSecret        *string    `db:"totp_secret" json:"-"`
+	MFAEnabled        bool       `db:"mfa_enabled" json:"mfa_enabled"`
+	MFAEnrolledAt     *time.Time `db:"mfa_enrolled_at" json:"mfa_enrolled_at"`
+	TOTPLastStep      int64      `db:"totp_last_step" json:"-"`
+	CreatedAt         time.Time  `db:"created_at" json:"created_at"`
 }
 
 type User struct {
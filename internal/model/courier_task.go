@@ -3,8 +3,38 @@ package model
 import "time"
 
 type CourierTask struct {
-	TrackingNumber string    `db:"tracking_number" json:"tracking_number"`
-	Phone          string    `db:"phone" json:"phone"`
-	Status         string    `db:"status" json:"status"`
-	CreatedAt      time.Time `db:"created_at" json:"created_at"`
+	TrackingNumber string `db:"tracking_number" json:"tracking_number"`
+	Phone          string `db:"phone" json:"phone"`
+	Status         string `db:"status" json:"status"`
+	ParcelAttributes
+	StationCode string `db:"station_code" json:"station_code"`
+	StationName string `db:"station_name" json:"station_name"`
+	// 货架位置，未上架时为空
+	ShelfCode  string     `db:"shelf_code" json:"shelf_code"`
+	ShelfZone  string     `db:"shelf_zone" json:"shelf_zone"`
+	CreatedAt  time.Time  `db:"created_at" json:"created_at"`
+	PickedUpAt *time.Time `db:"picked_up_at" json:"picked_up_at,omitempty"`
+	// DwellHours 滞留小时数：未取件的计到当前，已取件/退回的计到取件或最后更新时间
+	DwellHours float64 `db:"dwell_hours" json:"dwell_hours"`
+}
+
+// CourierTaskDetail 单个包裹详情及状态记录
+type CourierTaskDetail struct {
+	CourierTask
+	History []CourierTaskEvent `json:"history"`
+}
+
+// CourierTaskEvent 包裹的一次状态记录（来自审计日志）
+type CourierTaskEvent struct {
+	Action    string    `db:"action" json:"action"`
+	Status    string    `db:"status" json:"status"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+}
+
+// CourierTaskSummary 快递员名下包裹按状态的数量
+type CourierTaskSummary struct {
+	ByStatus map[string]int `json:"by_status"`
+	Total    int            `json:"total"`
+	// TodayInbound 今天入库的数量
+	TodayInbound int `json:"today_inbound"`
 }
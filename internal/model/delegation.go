@@ -0,0 +1,16 @@
+package model
+
+import "time"
+
+// PickupDelegation 代取授权：包裹所有人授权某个手机号在时间窗口内代取单个或全部包裹
+type PickupDelegation struct {
+	ID            int64  `db:"id" json:"id"`
+	OwnerName     string `db:"owner_name" json:"owner_name"`
+	DelegatePhone string `db:"delegate_phone" json:"delegate_phone"`
+	// TrackingNumber 授权的单个包裹；为空表示全部包裹
+	TrackingNumber string     `db:"tracking_number" json:"tracking_number"`
+	StartsAt       time.Time  `db:"starts_at" json:"starts_at"`
+	ExpiresAt      time.Time  `db:"expires_at" json:"expires_at"`
+	RevokedAt      *time.Time `db:"revoked_at" json:"revoked_at,omitempty"`
+	CreatedAt      time.Time  `db:"created_at" json:"created_at"`
+}
@@ -0,0 +1,10 @@
+package model
+
+// IdempotencyRecord 幂等键记录：同一个 Idempotency-Key 的首个请求哈希与其响应
+type IdempotencyRecord struct {
+	RequestHash string `db:"request_hash"`
+	Completed   bool   `db:"completed"`
+	StatusCode  int    `db:"status_code"`
+	ContentType string `db:"content_type"`
+	Body        []byte `db:"response_body"`
+}
@@ -0,0 +1,38 @@
+package model
+
+import "time"
+
+// ImportJob 批量导入任务（学生 / 快递公司）
+type ImportJob struct {
+	ID       int64  `db:"id" json:"id"`
+	Kind     string `db:"kind" json:"kind"`
+	FileName string `db:"file_name" json:"file_name"`
+	DryRun   bool   `db:"dry_run" json:"dry_run"`
+	// Status running / completed / failed
+	Status    string `db:"status" json:"status"`
+	Total     int    `db:"total" json:"total"`
+	Processed int    `db:"processed" json:"processed"`
+	Created   int    `db:"created" json:"created"`
+	Updated   int    `db:"updated" json:"updated"`
+	Unchanged int    `db:"unchanged" json:"unchanged"`
+	Failed    int    `db:"failed" json:"failed"`
+	// Message 任务整体失败的原因
+	Message    string     `db:"message" json:"message,omitempty"`
+	CreatedBy  string     `db:"created_by" json:"created_by"`
+	CreatedAt  time.Time  `db:"created_at" json:"created_at"`
+	UpdatedAt  time.Time  `db:"updated_at" json:"updated_at"`
+	FinishedAt *time.Time `db:"finished_at" json:"finished_at,omitempty"`
+
+	ErrorsJSON []byte           `db:"errors" json:"-"`
+	Errors     []ImportRowError `db:"-" json:"errors,omitempty"`
+}
+
+// ImportRowError 某一行未通过校验或无法写入；Row 为表格中的行号（表头为第 1 行）
+type ImportRowError struct {
+	Row   int    `json:"row"`
+	Field string `json:"field"`
+	Rule  string `json:"rule"`
+	Param string `json:"param,omitempty"`
+	// Message 本地化提示，查询时按请求语言填写
+	Message string `json:"message,omitempty"`
+}
@@ -50,6 +50,26 @@ type Parcel struct {
 	UpdatedAt time.Time `db:"updated_at" json:"updated_at"`
 }
 
+// ParcelAttributes 包裹物理属性，入库时登记，用于货架分配与滞留提醒
+type ParcelAttributes struct {
+	// 尺寸等级：document / small / medium / large / bulky
+	SizeClass string `db:"size_class" json:"size_class"`
+
+	// 重量（克），0 表示未登记
+	WeightGrams int `db:"weight_grams" json:"weight_grams,omitempty"`
+
+	Fragile bool `db:"fragile" json:"fragile"`
+
+	// 生鲜/易腐，滞留提醒提前
+	Perishable bool `db:"perishable" json:"perishable"`
+
+	// 需冷藏，只能放冷藏货架
+	ColdChain bool `db:"cold_chain" json:"cold_chain"`
+
+	// 声明价值（分），0 表示未声明
+	DeclaredValueCents int64 `db:"declared_value_cents" json:"declared_value_cents,omitempty"`
+}
+
 // Courier 结构体表示快递公司实体，对应数据库中的couriers表
 // 用于存储和管理快递公司信息
 type Courier struct {
@@ -65,6 +85,16 @@ type Courier struct {
 	// 快递公司联系电话，用于联系快递公司
 	ContactPhone string `db:"contact_phone" json:"contact_phone"`
 
+	// 运单号格式（正则，整串匹配），入库时校验；为空表示不限制
+	TrackingPattern string `db:"tracking_pattern" json:"tracking_pattern"`
+
+	// 运单号长度范围，0 表示不限制
+	TrackingMinLength int `db:"tracking_min_length" json:"tracking_min_length"`
+	TrackingMaxLength int `db:"tracking_max_length" json:"tracking_max_length"`
+
+	// 校验位算法（s10、ems），为空表示不校验
+	TrackingChecksum string `db:"tracking_checksum" json:"tracking_checksum"`
+
 	// 创建时间，记录快递公司信息创建的时间戳
 	CreatedAt time.Time `db:"created_at" json:"created_at"`
 }
@@ -87,9 +117,18 @@ type ParcelViewStudent struct {
 	// 货架区域，学生需要知道的包裹存储位置
 	ShelfZone string `db:"shelf_zone" json:"shelf_zone"`
 
+	// 站点名称，多站点时学生需要知道去哪个驿站取件
+	StationName string `db:"station_name" json:"station_name"`
+
 	// 包裹状态，学生需要知道的包裹当前状态
 	Status string `db:"status" json:"status"`
 
+	// 代取包裹的所有人姓名；本人包裹为空
+	DelegatedBy string `db:"delegated_by" json:"delegated_by,omitempty"`
+
+	// 尺寸、易碎、生鲜、冷链等属性
+	ParcelAttributes
+
 	// 创建时间（入库时间参考）
 	CreatedAt time.Time `db:"created_at" json:"created_at"`
 
@@ -97,10 +136,32 @@ type ParcelViewStudent struct {
 	UpdatedAt time.Time `db:"updated_at" json:"updated_at"`
 }
 
+// RetentionParcel 滞留包裹，附带收件人手机号（按 privacy 配置脱敏），便于驿站联系收件人
+type RetentionParcel struct {
+	ParcelViewStudent
+	RecipientPhone string `db:"recipient_phone" json:"recipient_phone"`
+}
+
+// CounterPickupParcel 柜台扫码取件时一次取走的包裹，供管理员到货架取件
+type CounterPickupParcel struct {
+	TrackingNumber string `db:"tracking_number" json:"tracking_number"`
+	CourierName    string `db:"courier_name" json:"courier_name"`
+	PickupCode     string `db:"pickup_code" json:"pickup_code"`
+	ShelfCode      string `db:"shelf_code" json:"shelf_code"`
+	ShelfID        int64  `db:"shelf_id" json:"-"`
+}
+
 // AdminDashboard 表示管理员仪表盘视图的数据结构
 // 对应数据库视图 v_admin_dashboard 的查询结果
 type AdminDashboard struct {
 	WaitingPickup int `db:"waiting_pickup" json:"waiting_pickup"`
-	FullShelves   int `db:"full_shelves" json:"full_shelves"`
-	TodayOps      int `db:"today_ops" json:"today_ops"`
+	// 待取件中的生鲜/易腐包裹数
+	WaitingPerishable int `db:"waiting_perishable" json:"waiting_perishable"`
+	FullShelves       int `db:"full_shelves" json:"full_shelves"`
+	// 以下为当天（数据库时区）的作业量，来自每日统计汇总，随后台汇总任务更新
+	TodayOps        int `db:"today_ops" json:"today_ops"`
+	TodayInbound    int `db:"today_inbound" json:"today_inbound"`
+	TodayPickedUp   int `db:"today_picked_up" json:"today_picked_up"`
+	TodayReturned   int `db:"today_returned" json:"today_returned"`
+	TodayExceptions int `db:"today_exceptions" json:"today_exceptions"`
 }
@@ -0,0 +1,32 @@
+package model
+
+import "time"
+
+// ReturnBatch 退件批次：某快递公司需从某站点取回的一组包裹
+type ReturnBatch struct {
+	ID          int64  `db:"id" json:"id"`
+	CourierCode string `db:"courier_code" json:"courier_code"`
+	StationCode string `db:"station_code" json:"station_code"`
+	StationName string `db:"station_name" json:"station_name"`
+	// Status open（待交接）/ completed（已全部交接）/ cancelled（已取消）
+	Status string `db:"status" json:"status"`
+	// Source manual（管理员创建）/ retention（滞留策略生成）
+	Source     string       `db:"source" json:"source"`
+	CreatedBy  string       `db:"created_by" json:"created_by"`
+	ItemCount  int          `db:"item_count" json:"item_count"`
+	HandedOver int          `db:"handed_over" json:"handed_over"`
+	CreatedAt  time.Time    `db:"created_at" json:"created_at"`
+	ClosedAt   *time.Time   `db:"closed_at" json:"closed_at,omitempty"`
+	Items      []ReturnItem `db:"-" json:"items,omitempty"`
+}
+
+// ReturnItem 退件批次中的单个包裹
+type ReturnItem struct {
+	BatchID        int64      `db:"batch_id" json:"-"`
+	TrackingNumber string     `db:"tracking_number" json:"tracking_number"`
+	ParcelStatus   string     `db:"parcel_status" json:"parcel_status"`
+	SizeClass      string     `db:"size_class" json:"size_class"`
+	ShelfCode      string     `db:"shelf_code" json:"shelf_code"`
+	HandedOverAt   *time.Time `db:"handed_over_at" json:"handed_over_at,omitempty"`
+	HandedOverBy   string     `db:"handed_over_by" json:"handed_over_by,omitempty"`
+}
@@ -3,10 +3,14 @@ package model
 import "time"
 
 type Shelf struct {
-	ID          int64     `db:"id" json:"id"`
-	Zone        string    `db:"zone" json:"zone"`
-	Code        string    `db:"code" json:"code"`
-	Capacity    int       `db:"capacity" json:"capacity"`
-	CurrentLoad int       `db:"current_load" json:"current_load"`
+	ID          int64  `db:"id" json:"id"`
+	StationID   int64  `db:"station_id" json:"station_id"`
+	Zone        string `db:"zone" json:"zone"`
+	Code        string `db:"code" json:"code"`
+	Capacity    int    `db:"capacity" json:"capacity"`
+	CurrentLoad int    `db:"current_load" json:"current_load"`
+	// MaxSize 可存放的最大尺寸等级，Temperature 为 ambient（常温）/ chilled（冷藏）
+	MaxSize     string    `db:"max_size" json:"max_size"`
+	Temperature string    `db:"temperature" json:"temperature"`
 	UpdatedAt   time.Time `db:"updated_at" json:"updated_at"`
 }
@@ -0,0 +1,55 @@
+package model
+
+import "time"
+
+// ShippingRate 快递公司在某个区域的寄件运费：首重价格 + 每个续重单位的价格
+type ShippingRate struct {
+	CourierCode           string    `db:"courier_code" json:"courier_code"`
+	CourierName           string    `db:"courier_name" json:"courier_name"`
+	Zone                  string    `db:"zone" json:"zone"`
+	FirstWeightGrams      int       `db:"first_weight_grams" json:"first_weight_grams"`
+	FirstPriceCents       int       `db:"first_price_cents" json:"first_price_cents"`
+	AdditionalWeightGrams int       `db:"additional_weight_grams" json:"additional_weight_grams"`
+	AdditionalPriceCents  int       `db:"additional_price_cents" json:"additional_price_cents"`
+	UpdatedAt             time.Time `db:"updated_at" json:"updated_at"`
+}
+
+// Shipment 寄件订单
+type Shipment struct {
+	OrderNo     string `db:"order_no" json:"order_no"`
+	CourierCode string `db:"courier_code" json:"courier_code"`
+	CourierName string `db:"courier_name" json:"courier_name"`
+	StationCode string `db:"station_code" json:"station_code"`
+	StationName string `db:"station_name" json:"station_name"`
+	// Status created / dropped_off / collected / cancelled
+	Status string `db:"status" json:"status"`
+
+	SenderName        string `db:"sender_name" json:"sender_name"`
+	SenderPhone       string `db:"sender_phone" json:"sender_phone"`
+	RecipientName     string `db:"recipient_name" json:"recipient_name"`
+	RecipientPhone    string `db:"recipient_phone" json:"recipient_phone"`
+	RecipientProvince string `db:"recipient_province" json:"recipient_province"`
+	RecipientAddress  string `db:"recipient_address" json:"recipient_address"`
+
+	Zone           string `db:"zone" json:"zone"`
+	WeightGrams    int    `db:"weight_grams" json:"weight_grams"`
+	PriceCents     int    `db:"price_cents" json:"price_cents"`
+	TrackingNumber string `db:"tracking_number" json:"tracking_number,omitempty"`
+
+	CreatedAt    time.Time  `db:"created_at" json:"created_at"`
+	UpdatedAt    time.Time  `db:"updated_at" json:"updated_at"`
+	DroppedOffAt *time.Time `db:"dropped_off_at" json:"dropped_off_at,omitempty"`
+	CollectedAt  *time.Time `db:"collected_at" json:"collected_at,omitempty"`
+
+	// Events 状态变更记录，仅详情接口返回
+	Events []ShipmentEvent `db:"-" json:"events,omitempty"`
+}
+
+// ShipmentEvent 寄件订单审计记录；Operator 只对管理员返回
+type ShipmentEvent struct {
+	Action    string    `db:"action" json:"action"`
+	OldStatus *string   `db:"old_status" json:"old_status"`
+	NewStatus string    `db:"new_status" json:"new_status"`
+	Operator  string    `db:"operator" json:"operator,omitempty"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+}
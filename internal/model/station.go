@@ -0,0 +1,12 @@
+package model
+
+import "time"
+
+// Station 驿站/取件点，货架、包裹与站点管理员都归属于某个站点
+type Station struct {
+	ID        int64     `db:"id" json:"id"`
+	Code      string    `db:"code" json:"code"`
+	Name      string    `db:"name" json:"name"`
+	Address   string    `db:"address" json:"address"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+}
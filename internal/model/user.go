@@ -0,0 +1,72 @@
+package model
+
+import "time"
+
+// UserProfile 学生资料
+type UserProfile struct {
+	ID        int64  `db:"id" json:"id"`
+	Phone     string `db:"phone" json:"phone"`
+	StudentID string `db:"student_id" json:"student_id"`
+	Name      string `db:"name" json:"name"`
+	Dorm      string `db:"dorm" json:"dorm"`
+	// 通知偏好
+	NotifyInbound   bool      `db:"notify_inbound" json:"notify_inbound"`
+	NotifyRetention bool      `db:"notify_retention" json:"notify_retention"`
+	CreatedAt       time.Time `db:"created_at" json:"created_at"`
+}
+
+// AdminUser 管理员查询用户时的结果，附带包裹数量，便于判断重复账号
+type AdminUser struct {
+	UserProfile
+	Parcels       int `db:"parcels" json:"parcels"`
+	ActiveParcels int `db:"active_parcels" json:"active_parcels"`
+}
+
+// PhoneChange 待验证的手机号变更
+type PhoneChange struct {
+	NewPhone  string    `json:"new_phone"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// UserMergeResult 合并重复用户的结果
+type UserMergeResult struct {
+	User         UserProfile `json:"user"`
+	ParcelsMoved int         `json:"parcels_moved"`
+}
+
+// UserErasureResult 删除学生个人信息的结果
+type UserErasureResult struct {
+	UserID            int64 `json:"user_id"`
+	ParcelsAnonymized int   `json:"parcels_anonymized"`
+}
+
+// ExportParcel 个人数据导出中的包裹记录（含入库时登记的收件人快照）
+type ExportParcel struct {
+	TrackingNumber string     `db:"tracking_number" json:"tracking_number"`
+	CourierName    string     `db:"courier_name" json:"courier_name"`
+	StationName    string     `db:"station_name" json:"station_name"`
+	Status         string     `db:"status" json:"status"`
+	RecipientName  string     `db:"recipient_name" json:"recipient_name"`
+	RecipientPhone string     `db:"recipient_phone" json:"recipient_phone"`
+	CreatedAt      time.Time  `db:"created_at" json:"created_at"`
+	PickedUpAt     *time.Time `db:"picked_up_at" json:"picked_up_at,omitempty"`
+}
+
+// UserChangeLog 手机号变更 / 账号合并记录
+type UserChangeLog struct {
+	Action       string    `db:"action" json:"action"`
+	OldPhone     string    `db:"old_phone" json:"old_phone"`
+	NewPhone     string    `db:"new_phone" json:"new_phone"`
+	ParcelsMoved int       `db:"parcels_moved" json:"parcels_moved"`
+	CreatedAt    time.Time `db:"created_at" json:"created_at"`
+}
+
+// UserDataExport 学生本人的全部记录（个人数据导出）
+type UserDataExport struct {
+	Profile     UserProfile        `json:"profile"`
+	Parcels     []ExportParcel     `json:"parcels"`
+	Delegations []PickupDelegation `json:"delegations"`
+	Shipments   []Shipment         `json:"shipments"`
+	ChangeLogs  []UserChangeLog    `json:"change_logs"`
+	ExportedAt  time.Time          `json:"exported_at"`
+}
@@ -0,0 +1,74 @@
+package report
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// ErrTooManyRows 数据行数超过上限
+var ErrTooManyRows = errors.New("too many rows")
+
+// ReadAll 读取 CSV / XLSX（第一个工作表）的全部行，第一行为表头；
+// 数据行超过 maxRows 时返回 ErrTooManyRows。CSV 开头的 UTF-8 BOM 会被去掉，各行列数可以不同
+func ReadAll(format string, r io.Reader, maxRows int) ([][]string, error) {
+	var rows [][]string
+	add := func(record []string) error {
+		if len(rows) > maxRows {
+			return ErrTooManyRows
+		}
+		rows = append(rows, record)
+		return nil
+	}
+
+	switch format {
+	case "csv":
+		cr := csv.NewReader(r)
+		cr.FieldsPerRecord = -1
+		for {
+			record, err := cr.Read()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return nil, err
+			}
+			if len(rows) == 0 && len(record) > 0 {
+				record[0] = strings.TrimPrefix(record[0], "\uFEFF")
+			}
+			if err := add(record); err != nil {
+				return nil, err
+			}
+		}
+	case "xlsx":
+		f, err := excelize.OpenReader(r)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		it, err := f.Rows(f.GetSheetName(0))
+		if err != nil {
+			return nil, err
+		}
+		defer it.Close()
+		for it.Next() {
+			record, err := it.Columns()
+			if err != nil {
+				return nil, err
+			}
+			if err := add(record); err != nil {
+				return nil, err
+			}
+		}
+		if err := it.Error(); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported spreadsheet format %q", format)
+	}
+	return rows, nil
+}
@@ -0,0 +1,84 @@
+package report
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Writer 逐行输出报表，第一行为表头；Close 后输出完整文件
+type Writer interface {
+	Row(values ...any) error
+	Close() error
+}
+
+// Formats 支持的导出格式
+var Formats = []string{"csv", "xlsx"}
+
+// New 按格式创建报表输出，sheet 为 XLSX 工作表名（CSV 忽略）
+func New(format string, w io.Writer, sheet string) (Writer, error) {
+	switch format {
+	case "csv":
+		return NewCSV(w), nil
+	case "xlsx":
+		return NewXLSX(w, sheet)
+	}
+	return nil, fmt.Errorf("unsupported report format %q", format)
+}
+
+// ContentType 导出格式对应的 MIME 类型
+func ContentType(format string) string {
+	if format == "xlsx" {
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	}
+	return "text/csv; charset=utf-8"
+}
+
+// csvWriter CSV 输出：写入 UTF-8 BOM，便于 Excel 正确识别中文
+type csvWriter struct {
+	w       io.Writer
+	cw      *csv.Writer
+	started bool
+}
+
+// NewCSV 创建 CSV 报表输出；数据经缓冲后写入 w
+func NewCSV(w io.Writer) Writer {
+	return &csvWriter{w: w, cw: csv.NewWriter(w)}
+}
+
+func (c *csvWriter) Row(values ...any) error {
+	if !c.started {
+		if _, err := io.WriteString(c.w, "\uFEFF"); err != nil {
+			return err
+		}
+		c.started = true
+	}
+	record := make([]string, len(values))
+	for i, v := range values {
+		record[i] = formatCell(v)
+	}
+	return c.cw.Write(record)
+}
+
+func (c *csvWriter) Close() error {
+	c.cw.Flush()
+	return c.cw.Error()
+}
+
+// formatCell 单元格的文本形式：时间输出为 2006-01-02 15:04:05，小数保留两位，nil 为空
+func formatCell(v any) string {
+	switch x := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return x
+	case []byte:
+		return string(x)
+	case time.Time:
+		return x.Format("2006-01-02 15:04:05")
+	case float64:
+		return fmt.Sprintf("%.2f", x)
+	}
+	return fmt.Sprint(v)
+}
@@ -0,0 +1,71 @@
+package report
+
+import (
+	"io"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// xlsxWriter XLSX 输出：使用流式写入，行数据不常驻内存，Close 时打包输出
+type xlsxWriter struct {
+	w    io.Writer
+	f    *excelize.File
+	sw   *excelize.StreamWriter
+	row  int
+	bold int
+}
+
+// NewXLSX 创建 XLSX 报表输出，表头行加粗
+func NewXLSX(w io.Writer, sheet string) (Writer, error) {
+	f := excelize.NewFile()
+	if sheet != "" && sheet != "Sheet1" {
+		if err := f.SetSheetName("Sheet1", sheet); err != nil {
+			f.Close()
+			return nil, err
+		}
+	} else {
+		sheet = "Sheet1"
+	}
+	sw, err := f.NewStreamWriter(sheet)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	bold, err := f.NewStyle(&excelize.Style{Font: &excelize.Font{Bold: true}})
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &xlsxWriter{w: w, f: f, sw: sw, bold: bold}, nil
+}
+
+func (x *xlsxWriter) Row(values ...any) error {
+	x.row++
+	cells := make([]any, len(values))
+	for i, v := range values {
+		switch t := v.(type) {
+		case []byte:
+			v = string(t)
+		case time.Time:
+			v = t.Format("2006-01-02 15:04:05")
+		}
+		if x.row == 1 {
+			v = excelize.Cell{StyleID: x.bold, Value: v}
+		}
+		cells[i] = v
+	}
+	cell, err := excelize.CoordinatesToCellName(1, x.row)
+	if err != nil {
+		return err
+	}
+	return x.sw.SetRow(cell, cells)
+}
+
+func (x *xlsxWriter) Close() error {
+	defer x.f.Close()
+	if err := x.sw.Flush(); err != nil {
+		return err
+	}
+	return x.f.Write(x.w)
+}
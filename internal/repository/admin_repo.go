@@ -0,0 +1,227 @@
+package repository
+
+import (
+	"campus-logistics/internal/model"
+	"database/sql"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+const adminColumns = `id, username, password_hash, COALESCE(role, 'super_admin') AS role, station_id, last_login_at,
+	failed_attempts, locked_until, password_changed_at, totp_secret, mfa_enabled, mfa_enrolled_at,
+	totp_last_step, created_at`
+
+func GetAdminByID(adminID int64) (*model.Admin, error) {
+	var a model.Admin
+	query := `SELECT ` + adminColumns + ` FROM admins WHERE id = $1`
+	if err := DB.Get(&a, query, adminID); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &a, nil
+}
+
+func ListAdmins(limit, offset int) ([]model.Admin, error) {
+	admins := []model.Admin{}
+	query := `SELECT ` + adminColumns + ` FROM admins ORDER BY id ASC LIMIT $1 OFFSET $2`
+	if err := DB.Select(&admins, query, limit, offset); err != nil {
+		return nil, fmt.Errorf("list admins failed: %w", err)
+	}
+	return admins, nil
+}
+
+// CreateAdmin 新建管理员；password_changed_at 置空，首次登录需修改初始密码。
+// stationID 为 nil 表示可管理全部站点。
+func CreateAdmin(username, passwordHash, role string, stationID *int64) (*model.Admin, error) {
+	var a model.Admin
+	query := `
+		INSERT INTO admins (username, password_hash, role, station_id, password_changed_at)
+		VALUES ($1, $2, $3, $4, NULL)
+		RETURNING ` + adminColumns
+	if err := DB.Get(&a, query, username, passwordHash, role, stationID); err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+// UpdateAdminRole 修改管理员角色；该管理员原为 keepRole 且是最后一个时返回 ErrLastOfRole
+func UpdateAdminRole(adminID int64, role, keepRole string) error {
+	return keepLastOfRole(adminID, keepRole, func(tx *sqlx.Tx) (sql.Result, error) {
+		return tx.Exec(`UPDATE admins SET role = $1 WHERE id = $2`, role, adminID)
+	})
+}
+
+// UpdateAdminStation 调整管理员所属站点；nil 表示全部站点
+func UpdateAdminStation(adminID int64, stationID *int64) error {
+	result, err := DB.Exec(`UPDATE admins SET station_id = $1 WHERE id = $2`, stationID, adminID)
+	if err != nil {
+		return err
+	}
+	return expectOneRow(result)
+}
+
+func UnlockAdmin(adminID int64) error {
+	result, err := DB.Exec(`UPDATE admins SET failed_attempts = 0, locked_until = NULL WHERE id = $1`, adminID)
+	if err != nil {
+		return err
+	}
+	return expectOneRow(result)
+}
+
+// DeleteAdmin 删除管理员；该管理员为 keepRole 且是最后一个时返回 ErrLastOfRole
+func DeleteAdmin(adminID int64, keepRole string) error {
+	return keepLastOfRole(adminID, keepRole, func(tx *sqlx.Tx) (sql.Result, error) {
+		return tx.Exec(`DELETE FROM admins WHERE id = $1`, adminID)
+	})
+}
+
+// keepLastOfRole 在一个事务内锁定 keepRole 角色的全部管理员后再执行 write，
+// 并发降级/删除不同的管理员时后到的事务等待前者提交并看到其结果，不会把该角色删空。
+// 角色为空的旧账号与 adminColumns 一致按 super_admin 计
+func keepLastOfRole(adminID int64, keepRole string, write func(tx *sqlx.Tx) (sql.Result, error)) error {
+	tx, err := DB.Beginx()
+	if err != nil {
+		return fmt.Errorf("transaction begin failed: %w", err)
+	}
+	defer tx.Rollback()
+
+	var ids []int64
+	if err := tx.Select(&ids, `SELECT id FROM admins WHERE COALESCE(role, 'super_admin') = $1 FOR UPDATE`, keepRole); err != nil {
+		return err
+	}
+	if len(ids) <= 1 && slices.Contains(ids, adminID) {
+		return ErrLastOfRole
+	}
+
+	result, err := write(tx)
+	if err != nil {
+		return err
+	}
+	if err := expectOneRow(result); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// RecordAdminLoginFailure 累加连续失败次数，达到 maxAttempts 时锁定 lockFor 时长。
+// 上一次锁定已过期的账号从 1 重新计数。返回累计次数与锁定截止时间（未锁定为 nil）。
+func RecordAdminLoginFailure(adminID int64, maxAttempts int, lockFor time.Duration) (int, *time.Time, error) {
+	var r struct {
+		FailedAttempts int        `db:"failed_attempts"`
+		LockedUntil    *time.Time `db:"locked_until"`
+	}
+	query := `
+		WITH next AS (
+			SELECT id,
+				CASE WHEN locked_until IS NOT NULL AND locked_until <= NOW() THEN 1
+				     ELSE failed_attempts + 1
+				END AS attempts
+			FROM admins
+			WHERE id = $1
+		)
+		UPDATE admins a
+		SET failed_attempts = next.attempts,
+			locked_until = CASE WHEN next.attempts >= $2 THEN NOW() + ($3 * INTERVAL '1 second') ELSE NULL END
+		FROM next
+		WHERE a.id = next.id
+		RETURNING a.failed_attempts, a.locked_until
+	`
+	if err := DB.Get(&r, query, adminID, maxAttempts, int64(lockFor.Seconds())); err != nil {
+		if err == sql.ErrNoRows {
+			return 0, nil, ErrNotFound
+		}
+		return 0, nil, fmt.Errorf("record login failure failed: %w", err)
+	}
+	return r.FailedAttempts, r.LockedUntil, nil
+}
+
+// RehashAdminPassword 替换为新的哈希（同一密码的升级，不计入历史、不刷新修改时间）
+func RehashAdminPassword(adminID int64, newHash string) error {
+	result, err := DB.Exec(`UPDATE admins SET password_hash = $1 WHERE id = $2`, newHash, adminID)
+	if err != nil {
+		return err
+	}
+	return expectOneRow(result)
+}
+
+// UpdateAdminPassword 修改密码：旧哈希写入历史表，仅保留最近 keepHistory 条。
+// forceChange 为 true 时清空 password_changed_at，下次登录即视为密码过期（用于超级管理员重置）。
+func UpdateAdminPassword(adminID int64, newHash string, keepHistory int, forceChange bool) error {
+	tx, err := DB.Beginx()
+	if err != nil {
+		return fmt.Errorf("transaction begin failed: %w", err)
+	}
+	defer tx.Rollback()
+
+	var oldHash string
+	if err := tx.Get(&oldHash, `SELECT password_hash FROM admins WHERE id = $1 FOR UPDATE`, adminID); err != nil {
+		if err == sql.ErrNoRows {
+			return ErrNotFound
+		}
+		return err
+	}
+
+	if _, err := tx.Exec(`INSERT INTO admin_password_history (admin_id, password_hash) VALUES ($1, $2)`, adminID, oldHash); err != nil {
+		return fmt.Errorf("insert password history failed: %w", err)
+	}
+
+	if _, err := tx.Exec(`
+		UPDATE admins
+		SET password_hash = $1,
+			password_changed_at = CASE WHEN $3 THEN NULL ELSE NOW() END,
+			failed_attempts = 0,
+			locked_until = NULL
+		WHERE id = $2
+	`, newHash, adminID, forceChange); err != nil {
+		return fmt.Errorf("update password failed: %w", err)
+	}
+
+	if _, err := tx.Exec(`
+		DELETE FROM admin_password_history
+		WHERE admin_id = $1
+		  AND id NOT IN (
+			SELECT id FROM admin_password_history
+			WHERE admin_id = $1
+			ORDER BY created_at DESC, id DESC
+			LIMIT $2
+		  )
+	`, adminID, keepHistory); err != nil {
+		return fmt.Errorf("trim password history failed: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("transaction commit failed: %w", err)
+	}
+	return nil
+}
+
+// GetAdminPasswordHistory 返回最近 limit 条历史密码哈希（新到旧）
+func GetAdminPasswordHistory(adminID int64, limit int) ([]string, error) {
+	hashes := []string{}
+	query := `
+		SELECT password_hash FROM admin_password_history
+		WHERE admin_id = $1
+		ORDER BY created_at DESC, id DESC
+		LIMIT $2
+	`
+	if err := DB.Select(&hashes, query, adminID, limit); err != nil {
+		return nil, err
+	}
+	return hashes, nil
+}
+
+func expectOneRow(result sql.Result) error {
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
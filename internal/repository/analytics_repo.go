@@ -0,0 +1,169 @@
+package repository
+
+import (
+	"campus-logistics/internal/model"
+	"fmt"
+	"time"
+)
+
+// 以下查询的参数约定：$1 / $2 为时间范围 [from, to)，$3 为分桶粒度（hour / day），$4 为站点 ID（0 表示全部站点）。
+// 分桶按数据库会话时区进行。
+
+// analyticsBuckets 时间桶序列
+const analyticsBuckets = `
+        buckets AS (
+            SELECT generate_series($1::timestamptz, $2::timestamptz - ('1 ' || $3::text)::interval, ('1 ' || $3::text)::interval) AS bucket
+        )`
+
+// GetThroughputSeries 每个时间桶的入库量、取件量以及该桶内取件包裹的滞留时长中位数 / P90（小时）
+func GetThroughputSeries(from, to time.Time, granularity string, stationID int64) ([]model.ThroughputPoint, error) {
+	points := []model.ThroughputPoint{}
+	query := `
+        WITH` + analyticsBuckets + `,
+        inbound AS (
+            SELECT date_trunc($3, created_at) AS bucket, COUNT(*) AS n
+            FROM parcels
+            WHERE created_at >= $1 AND created_at < $2 AND ($4 = 0 OR station_id = $4)
+            GROUP BY 1
+        ),
+        picked AS (
+            SELECT date_trunc($3, picked_up_at) AS bucket, COUNT(*) AS n,
+                   percentile_cont(0.5) WITHIN GROUP (ORDER BY EXTRACT(EPOCH FROM picked_up_at - created_at)) / 3600 AS median_hours,
+                   percentile_cont(0.9) WITHIN GROUP (ORDER BY EXTRACT(EPOCH FROM picked_up_at - created_at)) / 3600 AS p90_hours
+            FROM parcels
+            WHERE picked_up_at >= $1 AND picked_up_at < $2 AND ($4 = 0 OR station_id = $4)
+            GROUP BY 1
+        )
+        SELECT b.bucket, COALESCE(i.n, 0) AS inbound, COALESCE(p.n, 0) AS picked_up,
+               p.median_hours AS median_dwell_hours, p.p90_hours AS p90_dwell_hours
+        FROM buckets b
+        LEFT JOIN inbound i ON i.bucket = b.bucket
+        LEFT JOIN picked p ON p.bucket = b.bucket
+        ORDER BY b.bucket
+    `
+	if err := DB.Select(&points, query, from, to, granularity, stationID); err != nil {
+		return nil, fmt.Errorf("query throughput series failed: %w", err)
+	}
+	return points, nil
+}
+
+// GetDwellStats 范围内取件的包裹从入库到取件的时长统计（小时）
+func GetDwellStats(from, to time.Time, stationID int64) (*model.DwellStats, error) {
+	var stats model.DwellStats
+	query := `
+        SELECT COUNT(*) AS picked_up,
+               percentile_cont(0.5) WITHIN GROUP (ORDER BY EXTRACT(EPOCH FROM picked_up_at - created_at)) / 3600 AS median_hours,
+               percentile_cont(0.9) WITHIN GROUP (ORDER BY EXTRACT(EPOCH FROM picked_up_at - created_at)) / 3600 AS p90_hours,
+               AVG(EXTRACT(EPOCH FROM picked_up_at - created_at)) / 3600 AS avg_hours
+        FROM parcels
+        WHERE picked_up_at >= $1 AND picked_up_at < $2 AND ($3 = 0 OR station_id = $3)
+    `
+	if err := DB.Get(&stats, query, from, to, stationID); err != nil {
+		return nil, fmt.Errorf("query dwell stats failed: %w", err)
+	}
+	return &stats, nil
+}
+
+// GetCourierVolumes 各快递公司在范围内的入库量与取件量（按入库量倒序）
+func GetCourierVolumes(from, to time.Time, stationID int64) ([]model.CourierVolume, error) {
+	volumes := []model.CourierVolume{}
+	query := `
+        SELECT c.code AS courier_code, c.name AS courier_name,
+               COUNT(*) FILTER (WHERE p.created_at >= $1 AND p.created_at < $2) AS inbound,
+               COUNT(*) FILTER (WHERE p.picked_up_at >= $1 AND p.picked_up_at < $2) AS picked_up
+        FROM parcels p
+        JOIN couriers c ON c.id = p.courier_id
+        WHERE ((p.created_at >= $1 AND p.created_at < $2) OR (p.picked_up_at >= $1 AND p.picked_up_at < $2))
+          AND ($3 = 0 OR p.station_id = $3)
+        GROUP BY c.code, c.name
+        ORDER BY inbound DESC, c.code
+    `
+	if err := DB.Select(&volumes, query, from, to, stationID); err != nil {
+		return nil, fmt.Errorf("query courier volumes failed: %w", err)
+	}
+	return volumes, nil
+}
+
+// GetZoneOccupancySeries 各货架区域在每个时间桶结束时的在架包裹数。
+// 包裹在架区间为入库时间到取件时间（已退回的包裹以最后更新时间为准），据此回推历史占用；容量取当前货架配置
+func GetZoneOccupancySeries(from, to time.Time, granularity string, stationID int64) ([]model.ZoneOccupancyPoint, error) {
+	points := []model.ZoneOccupancyPoint{}
+	query := `
+        WITH` + analyticsBuckets + `,
+        zones AS (
+            SELECT zone, COALESCE(SUM(capacity), 0) AS capacity
+            FROM shelves
+            WHERE ($4 = 0 OR station_id = $4)
+            GROUP BY zone
+        ),
+        stays AS (
+            SELECT s.zone, p.created_at AS since,
+                   COALESCE(p.picked_up_at, CASE WHEN p.status = 'returned' THEN p.updated_at END) AS until
+            FROM parcels p
+            JOIN shelves s ON s.id = p.shelf_id
+            WHERE ($4 = 0 OR p.station_id = $4) AND p.created_at < $2
+        )
+        SELECT b.bucket, z.zone, z.capacity, COUNT(st.zone) AS occupied
+        FROM buckets b
+        CROSS JOIN zones z
+        LEFT JOIN stays st ON st.zone = z.zone
+             AND st.since < LEAST(b.bucket + ('1 ' || $3::text)::interval, NOW())
+             AND (st.until IS NULL OR st.until >= LEAST(b.bucket + ('1 ' || $3::text)::interval, NOW()))
+        GROUP BY b.bucket, z.zone, z.capacity
+        ORDER BY b.bucket, z.zone
+    `
+	if err := DB.Select(&points, query, from, to, granularity, stationID); err != nil {
+		return nil, fmt.Errorf("query zone occupancy failed: %w", err)
+	}
+	return points, nil
+}
+
+// GetRetentionAgeBuckets 当前待取件包裹按已存放时长分段计数，只返回有包裹的分段
+func GetRetentionAgeBuckets(stationID int64) ([]model.RetentionBucket, error) {
+	buckets := []model.RetentionBucket{}
+	query := `
+        SELECT CASE
+                   WHEN age < INTERVAL '1 day' THEN '0-1d'
+                   WHEN age < INTERVAL '3 days' THEN '1-3d'
+                   WHEN age < INTERVAL '7 days' THEN '3-7d'
+                   WHEN age < INTERVAL '14 days' THEN '7-14d'
+                   ELSE '14d+'
+               END AS age,
+               COUNT(*) AS count,
+               COUNT(*) FILTER (WHERE perishable OR cold_chain) AS perishable
+        FROM (
+            SELECT NOW() - created_at AS age, perishable, cold_chain
+            FROM parcels
+            WHERE status = 'stored' AND ($1 = 0 OR station_id = $1)
+        ) t
+        GROUP BY 1
+    `
+	if err := DB.Select(&buckets, query, stationID); err != nil {
+		return nil, fmt.Errorf("query retention buckets failed: %w", err)
+	}
+	return buckets, nil
+}
+
+// GetPeakHourHeatmap 范围内按星期与小时统计的入库/取件量，只返回非零格
+func GetPeakHourHeatmap(from, to time.Time, stationID int64) ([]model.HeatmapCell, error) {
+	cells := []model.HeatmapCell{}
+	query := `
+        SELECT EXTRACT(ISODOW FROM ts)::int AS weekday, EXTRACT(HOUR FROM ts)::int AS hour,
+               SUM(inbound) AS inbound, SUM(picked_up) AS picked_up
+        FROM (
+            SELECT created_at AS ts, 1 AS inbound, 0 AS picked_up
+            FROM parcels
+            WHERE created_at >= $1 AND created_at < $2 AND ($3 = 0 OR station_id = $3)
+            UNION ALL
+            SELECT picked_up_at, 0, 1
+            FROM parcels
+            WHERE picked_up_at >= $1 AND picked_up_at < $2 AND ($3 = 0 OR station_id = $3)
+        ) t
+        GROUP BY 1, 2
+        ORDER BY 1, 2
+    `
+	if err := DB.Select(&cells, query, from, to, stationID); err != nil {
+		return nil, fmt.Errorf("query heatmap failed: %w", err)
+	}
+	return cells, nil
+}
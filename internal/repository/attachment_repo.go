@@ -0,0 +1,60 @@
+package repository
+
+import (
+	"campus-logistics/internal/model"
+	"database/sql"
+	"fmt"
+)
+
+const attachmentColumns = `id, parcel_id, kind, storage_key, COALESCE(thumb_key, '') AS thumb_key, content_type,
+		size_bytes, width, height, sha256, uploaded_by, created_at`
+
+// GetParcelRef 按运单号查询包裹归属（所有人、快递公司、站点），不存在返回 ErrNotFound
+func GetParcelRef(trackingNum string) (*model.ParcelRef, error) {
+	var p model.ParcelRef
+	query := `SELECT id, user_id, courier_id, station_id, status::text AS status FROM parcels WHERE tracking_number = $1`
+	if err := DB.Get(&p, query, trackingNum); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("query parcel failed: %w", err)
+	}
+	return &p, nil
+}
+
+func CreateAttachment(a *model.ParcelAttachment) error {
+	query := `
+		INSERT INTO parcel_attachments (parcel_id, kind, storage_key, thumb_key, content_type, size_bytes, width, height, sha256, uploaded_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		RETURNING id, created_at
+	`
+	err := DB.QueryRowx(query, a.ParcelID, a.Kind, a.StorageKey,
+		sql.NullString{String: a.ThumbKey, Valid: a.ThumbKey != ""},
+		a.ContentType, a.SizeBytes, a.Width, a.Height, a.SHA256, a.UploadedBy).Scan(&a.ID, &a.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("create attachment failed: %w", err)
+	}
+	return nil
+}
+
+// ListAttachmentsByParcel 包裹的全部附件，按上传时间排序
+func ListAttachmentsByParcel(parcelID int64) ([]model.ParcelAttachment, error) {
+	attachments := []model.ParcelAttachment{}
+	query := `SELECT ` + attachmentColumns + ` FROM parcel_attachments WHERE parcel_id = $1 ORDER BY created_at ASC, id ASC`
+	if err := DB.Select(&attachments, query, parcelID); err != nil {
+		return nil, fmt.Errorf("list attachments failed: %w", err)
+	}
+	return attachments, nil
+}
+
+func GetAttachment(id int64) (*model.ParcelAttachment, error) {
+	var a model.ParcelAttachment
+	query := `SELECT ` + attachmentColumns + ` FROM parcel_attachments WHERE id = $1`
+	if err := DB.Get(&a, query, id); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &a, nil
+}
@@ -0,0 +1,32 @@
+package repository
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// auditContext 写入事务级设置，供审计触发器 func_audit_parcel_change 记录操作人、动作与代取人；
+// 设置只在当前事务内有效，空值表示使用触发器的默认值
+type auditContext struct {
+	Operator       string
+	Action         string
+	DelegateUserID int64
+}
+
+func (a auditContext) apply(tx *sqlx.Tx) error {
+	delegate := ""
+	if a.DelegateUserID != 0 {
+		delegate = strconv.FormatInt(a.DelegateUserID, 10)
+	}
+	_, err := tx.Exec(`
+        SELECT set_config('app.audit_operator', $1, true),
+               set_config('app.audit_action', $2, true),
+               set_config('app.audit_delegate_user_id', $3, true)
+    `, a.Operator, a.Action, delegate)
+	if err != nil {
+		return fmt.Errorf("set audit context failed: %w", err)
+	}
+	return nil
+}
@@ -3,12 +3,11 @@ package repository
 import (
 	"campus-logistics/internal/model"
 	"database/sql"
-	"fmt"
 )
 
 func GetAdminByUsername(username string) (*model.Admin, error) {
 	var a model.Admin
-	query := `SELECT id, username, password_hash, role FROM admins WHERE username = $1`
+	query := `SELECT ` + adminColumns + ` FROM admins WHERE username = $1`
 	if err := DB.Get(&a, query, username); err != nil {
 		if err == sql.ErrNoRows {
 			return nil, ErrNotFound
@@ -18,30 +17,38 @@ func GetAdminByUsername(username string) (*model.Admin, error) {
 	return &a, nil
 }
 
+// GetUserByPhone 按手机号（盲索引）查找用户
 func GetUserByPhone(phone string) (*model.User, error) {
 	var u model.User
-	query := `SELECT id, phone, name FROM users WHERE phone = $1`
-	if err := DB.Get(&u, query, phone); err != nil {
+	query := `SELECT id, name FROM users WHERE phone_hash = $1`
+	if err := DB.Get(&u, query, phoneHash(phone)); err != nil {
 		if err == sql.ErrNoRows {
 			return nil, ErrNotFound
 		}
 		return nil, err
 	}
+	u.Phone = phone
 	return &u, nil
 }
 
+// CreateUser 新建用户，手机号加密保存
 func CreateUser(phone, name string) (*model.User, error) {
+	enc, hash, err := sealPhone(phone)
+	if err != nil {
+		return nil, err
+	}
 	var u model.User
-	query := `INSERT INTO users (phone, name) VALUES ($1, $2) RETURNING id, phone, name`
-	if err := DB.Get(&u, query, phone, name); err != nil {
+	query := `INSERT INTO users (phone_enc, phone_hash, name) VALUES ($1, $2, $3) RETURNING id, name`
+	if err := DB.Get(&u, query, enc, hash, name); err != nil {
 		return nil, err
 	}
+	u.Phone = phone
 	return &u, nil
 }
 
 func GetCourierByCode(code string) (*model.Courier, error) {
 	var c model.Courier
-	query := `SELECT id, name, code FROM couriers WHERE code = $1`
+	query := `SELECT ` + courierColumns + ` FROM couriers WHERE code = $1`
 	if err := DB.Get(&c, query, code); err != nil {
 		if err == sql.ErrNoRows {
 			return nil, ErrNotFound
@@ -51,23 +58,9 @@ func GetCourierByCode(code string) (*model.Courier, error) {
 	return &c, nil
 }
 
+// TouchAdminLastLogin 记录成功登录时间，并清零连续失败次数与锁定状态
 func TouchAdminLastLogin(adminID int64) error {
-	query := `UPDATE admins SET last_login_at = NOW() WHERE id = $1`
+	query := `UPDATE admins SET last_login_at = NOW(), failed_attempts = 0, locked_until = NULL WHERE id = $1`
 	_, err := DB.Exec(query, adminID)
 	return err
 }
-
-func GetCourierTasks(courierID int64, limit, offset int) ([]model.CourierTask, error) {
-	tasks := []model.CourierTask{}
-	query := `
-		SELECT tracking_number, phone, status, created_at
-		FROM v_courier_tasks
-		WHERE courier_id = $1
-		ORDER BY created_at DESC
-		LIMIT $2 OFFSET $3
-	`
-	if err := DB.Select(&tasks, query, courierID, limit, offset); err != nil {
-		return nil, fmt.Errorf("query courier tasks failed: %w", err)
-	}
-	return tasks, nil
-}
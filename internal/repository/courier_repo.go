@@ -6,10 +6,15 @@ import (
 	"fmt"
 )
 
+// courierColumns 快递公司查询列（含运单号规则）
+const courierColumns = `id, name, code, COALESCE(contact_phone, '') AS contact_phone,
+		COALESCE(tracking_pattern, '') AS tracking_pattern, tracking_min_length, tracking_max_length,
+		COALESCE(tracking_checksum, '') AS tracking_checksum, created_at`
+
 func ListCouriers(limit, offset int) ([]model.Courier, error) {
 	couriers := []model.Courier{}
 	query := `
-		SELECT id, name, code, COALESCE(contact_phone, '') AS contact_phone, created_at
+		SELECT ` + courierColumns + `
 		FROM couriers
 		ORDER BY id ASC
 		LIMIT $1 OFFSET $2
@@ -20,14 +25,47 @@ func ListCouriers(limit, offset int) ([]model.Courier, error) {
 	return couriers, nil
 }
 
-func CreateCourier(name, code, contactPhone string) (*model.Courier, error) {
+// ListCourierTrackingRules 全部快递公司及其运单号规则，用于加载规则缓存
+func ListCourierTrackingRules() ([]model.Courier, error) {
+	couriers := []model.Courier{}
+	query := `SELECT ` + courierColumns + ` FROM couriers ORDER BY id ASC`
+	if err := DB.Select(&couriers, query); err != nil {
+		return nil, fmt.Errorf("list courier tracking rules failed: %w", err)
+	}
+	return couriers, nil
+}
+
+func CreateCourier(name, code, contactPhone, trackingPattern string) (*model.Courier, error) {
+	var c model.Courier
+	query := `
+		INSERT INTO couriers (name, code, contact_phone, tracking_pattern)
+		VALUES ($1, $2, $3, $4)
+		RETURNING ` + courierColumns + `
+	`
+	if err := DB.Get(&c, query, name, code,
+		sql.NullString{String: contactPhone, Valid: contactPhone != ""},
+		sql.NullString{String: trackingPattern, Valid: trackingPattern != ""}); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// UpdateCourierTrackingRule 整体替换运单号规则；pattern / checksum 为空表示不限制
+func UpdateCourierTrackingRule(code, pattern string, minLength, maxLength int, checksum string) (*model.Courier, error) {
 	var c model.Courier
 	query := `
-		INSERT INTO couriers (name, code, contact_phone)
-		VALUES ($1, $2, $3)
-		RETURNING id, name, code, COALESCE(contact_phone, '') AS contact_phone, created_at
+		UPDATE couriers
+		SET tracking_pattern = $2, tracking_min_length = $3, tracking_max_length = $4, tracking_checksum = $5
+		WHERE code = $1
+		RETURNING ` + courierColumns + `
 	`
-	if err := DB.Get(&c, query, name, code, sql.NullString{String: contactPhone, Valid: contactPhone != ""}); err != nil {
+	if err := DB.Get(&c, query, code,
+		sql.NullString{String: pattern, Valid: pattern != ""},
+		minLength, maxLength,
+		sql.NullString{String: checksum, Valid: checksum != ""}); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNotFound
+		}
 		return nil, err
 	}
 	return &c, nil
@@ -0,0 +1,126 @@
+package repository
+
+import (
+	"campus-logistics/internal/model"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// CourierTaskFilter 快递员任务筛选条件，零值表示不限
+type CourierTaskFilter struct {
+	Statuses []string
+	// From / To 入库时间范围 [From, To)
+	From *time.Time
+	To   *time.Time
+	// Query 运单号片段；PhoneSuffix 为 true 时同时按收件人手机号尾号匹配（脱敏形式保留后 4 位）
+	Query       string
+	PhoneSuffix bool
+}
+
+const courierTaskColumns = `tracking_number, COALESCE(phone, '') AS phone, status,
+        size_class, COALESCE(weight_grams, 0) AS weight_grams, fragile, perishable, cold_chain,
+        station_code, station_name, COALESCE(shelf_code, '') AS shelf_code, COALESCE(shelf_zone, '') AS shelf_zone,
+        created_at, picked_up_at, ROUND(dwell_seconds / 3600.0, 1)::float8 AS dwell_hours`
+
+const courierTaskWhere = `
+        WHERE courier_id = $1
+          AND ($2::text[] IS NULL OR status::text = ANY($2))
+          AND ($3::timestamptz IS NULL OR created_at >= $3)
+          AND ($4::timestamptz IS NULL OR created_at < $4)
+          AND ($5::text = '' OR tracking_number LIKE '%' || $5 || '%' OR ($6 AND phone_masked LIKE '%' || $5))`
+
+func (f CourierTaskFilter) args(courierID int64) []any {
+	var statuses any
+	if len(f.Statuses) > 0 {
+		statuses = pq.Array(f.Statuses)
+	}
+	return []any{courierID, statuses, f.From, f.To, f.Query, f.PhoneSuffix}
+}
+
+// GetCourierTasks 快递员名下的包裹（按入库时间倒序）及符合条件的总数
+func GetCourierTasks(courierID int64, f CourierTaskFilter, limit, offset int) ([]model.CourierTask, int, error) {
+	tasks := []model.CourierTask{}
+	args := f.args(courierID)
+	query := `SELECT ` + courierTaskColumns + ` FROM v_courier_tasks` + courierTaskWhere + `
+        ORDER BY created_at DESC
+        LIMIT $7 OFFSET $8`
+	if err := DB.Select(&tasks, query, append(args, limit, offset)...); err != nil {
+		return nil, 0, fmt.Errorf("query courier tasks failed: %w", err)
+	}
+	for i := range tasks {
+		phone, err := openPhone(tasks[i].Phone)
+		if err != nil {
+			return nil, 0, err
+		}
+		tasks[i].Phone = phone
+	}
+
+	var total int
+	if err := DB.Get(&total, `SELECT COUNT(*) FROM v_courier_tasks`+courierTaskWhere, args...); err != nil {
+		return nil, 0, fmt.Errorf("count courier tasks failed: %w", err)
+	}
+	return tasks, total, nil
+}
+
+// GetCourierTaskSummary 快递员名下包裹在入库时间范围内按状态的数量，以及今天入库的数量
+func GetCourierTaskSummary(courierID int64, from, to *time.Time) (*model.CourierTaskSummary, error) {
+	var rows []struct {
+		Status string `db:"status"`
+		Count  int    `db:"count"`
+		Today  int    `db:"today"`
+	}
+	err := DB.Select(&rows, `
+        SELECT status::text AS status, COUNT(*) AS count, COUNT(*) FILTER (WHERE created_at >= CURRENT_DATE) AS today
+        FROM parcels
+        WHERE courier_id = $1
+          AND ($2::timestamptz IS NULL OR created_at >= $2)
+          AND ($3::timestamptz IS NULL OR created_at < $3)
+        GROUP BY status
+    `, courierID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("query courier task summary failed: %w", err)
+	}
+
+	s := &model.CourierTaskSummary{ByStatus: map[string]int{
+		"inbound": 0, "stored": 0, "pending": 0, "picked_up": 0, "returned": 0, "exception": 0,
+	}}
+	for _, r := range rows {
+		s.ByStatus[r.Status] = r.Count
+		s.Total += r.Count
+		s.TodayInbound += r.Today
+	}
+	return s, nil
+}
+
+// GetCourierTask 快递员名下单个包裹的详情及状态记录；不属于该快递公司时返回 ErrNotFound
+func GetCourierTask(courierID int64, trackingNumber string) (*model.CourierTaskDetail, error) {
+	var d model.CourierTaskDetail
+	query := `SELECT ` + courierTaskColumns + ` FROM v_courier_tasks WHERE courier_id = $1 AND tracking_number = $2`
+	if err := DB.Get(&d.CourierTask, query, courierID, trackingNumber); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	phone, err := openPhone(d.Phone)
+	if err != nil {
+		return nil, err
+	}
+	d.Phone = phone
+
+	d.History = []model.CourierTaskEvent{}
+	err = DB.Select(&d.History, `
+        SELECT l.action, COALESCE(l.new_status::text, '') AS status, l.created_at
+        FROM parcel_audit_logs l
+        JOIN parcels p ON p.id = l.parcel_id
+        WHERE p.tracking_number = $1
+        ORDER BY l.created_at, l.id
+    `, trackingNumber)
+	if err != nil {
+		return nil, fmt.Errorf("query parcel history failed: %w", err)
+	}
+	return &d, nil
+}
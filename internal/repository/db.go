@@ -4,6 +4,7 @@ package repository
 
 // 导入所需的包
 import (
+    "context" // 上下文，用于控制数据库健康检查的超时
     "fmt"     // 格式化字符串，用于构建连接字符串和错误信息
     "log"     // 标准日志库，用于记录数据库连接状态
 
@@ -73,4 +74,22 @@ func InitDB() error {
     
     // 返回 nil 表示初始化成功
     return nil
-}
\ No newline at end of file
+}
+
+// PingDB 检查数据库连接是否可用，用于就绪检查（/readyz）
+// 参数 ctx 控制超时，避免数据库无响应时检查请求一直挂起
+func PingDB(ctx context.Context) error {
+    if DB == nil {
+        return fmt.Errorf("db not initialized")
+    }
+    return DB.PingContext(ctx)
+}
+
+// CloseDB 关闭数据库连接池
+// 在优雅停机的最后一步调用：HTTP 请求已排空、后台任务已停止，之后不能再访问数据库
+func CloseDB() error {
+    if DB == nil {
+        return nil
+    }
+    return DB.Close()
+}
@@ -0,0 +1,137 @@
+package repository
+
+import (
+	"campus-logistics/internal/model"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// delegationColumns 代取授权查询列，d 为 pickup_delegations，o 为所有人，p 为授权的单个包裹
+const delegationColumns = `d.id, COALESCE(o.name, '') AS owner_name, d.delegate_phone,
+		COALESCE(p.tracking_number, '') AS tracking_number, d.starts_at, d.expires_at, d.revoked_at, d.created_at`
+
+const delegationFrom = `
+		FROM pickup_delegations d
+		JOIN users o ON o.id = d.owner_id
+		LEFT JOIN parcels p ON p.id = d.parcel_id`
+
+// CreateDelegation 创建代取授权；trackingNum 为空表示授权全部包裹。
+// 单个包裹不存在（或不属于 ownerID）返回 ErrNotFound，包裹已取走或已退回返回 ErrConflict
+func CreateDelegation(ownerID int64, delegatePhone, trackingNum string, startsAt, expiresAt time.Time) (*model.PickupDelegation, error) {
+	var parcelID sql.NullInt64
+	if trackingNum != "" {
+		var p struct {
+			ID     int64  `db:"id"`
+			Status string `db:"status"`
+		}
+		err := DB.Get(&p, `SELECT id, status::text AS status FROM parcels WHERE tracking_number = $1 AND user_id = $2`, trackingNum, ownerID)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return nil, ErrNotFound
+			}
+			return nil, fmt.Errorf("query parcel failed: %w", err)
+		}
+		if p.Status == "picked_up" || p.Status == "returned" {
+			return nil, ErrConflict
+		}
+		parcelID = sql.NullInt64{Int64: p.ID, Valid: true}
+	}
+
+	enc, hash, err := sealPhone(delegatePhone)
+	if err != nil {
+		return nil, err
+	}
+	var id int64
+	err = DB.Get(&id, `
+        INSERT INTO pickup_delegations (owner_id, delegate_phone, delegate_phone_hash, parcel_id, starts_at, expires_at)
+        VALUES ($1, $2, $3, $4, $5, $6)
+        RETURNING id
+    `, ownerID, enc, hash, parcelID, startsAt, expiresAt)
+	if err != nil {
+		return nil, fmt.Errorf("create delegation failed: %w", err)
+	}
+	return getDelegation(id)
+}
+
+func getDelegation(id int64) (*model.PickupDelegation, error) {
+	var d model.PickupDelegation
+	query := `SELECT ` + delegationColumns + delegationFrom + ` WHERE d.id = $1`
+	if err := DB.Get(&d, query, id); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	phone, err := openPhone(d.DelegatePhone)
+	if err != nil {
+		return nil, err
+	}
+	d.DelegatePhone = phone
+	return &d, nil
+}
+
+// openDelegationPhones 解密被授权人手机号
+func openDelegationPhones(delegations []model.PickupDelegation) error {
+	for i := range delegations {
+		phone, err := openPhone(delegations[i].DelegatePhone)
+		if err != nil {
+			return err
+		}
+		delegations[i].DelegatePhone = phone
+	}
+	return nil
+}
+
+// ListDelegationsByOwner 本人发出、未撤销且未过期的代取授权
+func ListDelegationsByOwner(ownerID int64) ([]model.PickupDelegation, error) {
+	delegations := []model.PickupDelegation{}
+	query := `SELECT ` + delegationColumns + delegationFrom + `
+		WHERE d.owner_id = $1 AND d.revoked_at IS NULL AND d.expires_at > NOW()
+		ORDER BY d.starts_at ASC, d.id ASC
+	`
+	if err := DB.Select(&delegations, query, ownerID); err != nil {
+		return nil, fmt.Errorf("list delegations failed: %w", err)
+	}
+	if err := openDelegationPhones(delegations); err != nil {
+		return nil, err
+	}
+	return delegations, nil
+}
+
+// ListDelegationsToUser 他人授权给本人（按本人当前手机号匹配）、未撤销且未过期的代取授权
+func ListDelegationsToUser(userID int64) ([]model.PickupDelegation, error) {
+	delegations := []model.PickupDelegation{}
+	query := `SELECT ` + delegationColumns + delegationFrom + `
+		JOIN users du ON du.phone_hash = d.delegate_phone_hash
+		WHERE du.id = $1 AND d.revoked_at IS NULL AND d.expires_at > NOW()
+		ORDER BY d.starts_at ASC, d.id ASC
+	`
+	if err := DB.Select(&delegations, query, userID); err != nil {
+		return nil, fmt.Errorf("list received delegations failed: %w", err)
+	}
+	if err := openDelegationPhones(delegations); err != nil {
+		return nil, err
+	}
+	return delegations, nil
+}
+
+// RevokeDelegation 撤销本人发出的代取授权；不存在或已撤销返回 ErrNotFound
+func RevokeDelegation(id, ownerID int64) error {
+	result, err := DB.Exec(`
+        UPDATE pickup_delegations
+        SET revoked_at = NOW()
+        WHERE id = $1 AND owner_id = $2 AND revoked_at IS NULL
+    `, id, ownerID)
+	if err != nil {
+		return fmt.Errorf("revoke delegation failed: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
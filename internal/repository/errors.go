@@ -5,4 +5,18 @@ import "errors"
 var (
 	ErrNotFound = errors.New("not found")
 	ErrConflict = errors.New("conflict")
+	// ErrCodeMismatch 记录存在但提交的校验码（如取件码）不匹配
+	ErrCodeMismatch = errors.New("code mismatch")
+	// ErrExpired 验证码已过期或失败次数过多
+	ErrExpired = errors.New("expired")
+	// ErrAlreadyUsed 一次性凭证已被使用
+	ErrAlreadyUsed = errors.New("already used")
+	// ErrDuplicate 唯一值（如运单号）已被使用
+	ErrDuplicate = errors.New("duplicate")
+	// ErrBatchClosed 退件批次已完成或已取消
+	ErrBatchClosed = errors.New("batch closed")
+	// ErrParcelNotInBatch 包裹不在该退件批次中
+	ErrParcelNotInBatch = errors.New("parcel not in batch")
+	// ErrLastOfRole 操作会移除某角色的最后一个管理员
+	ErrLastOfRole = errors.New("last admin of role")
 )
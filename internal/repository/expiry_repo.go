@@ -3,20 +3,23 @@ package repository
 import "fmt"
 
 // InsertExpiredAuditLogs writes an immutable audit log record for parcels that are older than expiryDays
-// and still in stored/pending state. This does NOT change parcel_status (no DB schema changes).
+// (perishable parcels: perishableHours) and still in stored/pending state. This does NOT change parcel_status.
 //
 // Idempotency: it will not insert duplicates for the same parcel if an EXPIRED log already exists.
-func InsertExpiredAuditLogs(expiryDays int) (int64, error) {
+func InsertExpiredAuditLogs(expiryDays, perishableHours int) (int64, error) {
 	if expiryDays <= 0 {
 		expiryDays = 3
 	}
+	if perishableHours <= 0 {
+		perishableHours = 24
+	}
 
 	query := `
 		INSERT INTO parcel_audit_logs (parcel_id, action, old_status, new_status, operator)
 		SELECT p.id, 'EXPIRED', p.status, p.status, 'SYSTEM'
 		FROM parcels p
 		WHERE p.status IN ('stored', 'pending')
-		  AND p.created_at < NOW() - ($1 * INTERVAL '1 day')
+		  AND p.created_at < NOW() - CASE WHEN p.perishable THEN $2 * INTERVAL '1 hour' ELSE $1 * INTERVAL '1 day' END
 		  AND NOT EXISTS (
 			SELECT 1
 			FROM parcel_audit_logs l
@@ -24,7 +27,7 @@ func InsertExpiredAuditLogs(expiryDays int) (int64, error) {
 		  )
 	`
 
-	result, err := DB.Exec(query, expiryDays)
+	result, err := DB.Exec(query, expiryDays, perishableHours)
 	if err != nil {
 		return 0, fmt.Errorf("insert expired audit logs failed: %w", err)
 	}
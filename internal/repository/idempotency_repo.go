@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"campus-logistics/internal/model"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// PostgresIdempotencyStore 基于 idempotency_keys 表保存首个请求的响应。
+// 实现 middleware.IdempotencyStore。
+type PostgresIdempotencyStore struct{}
+
+// Reserve 尝试占用幂等键。占用成功返回 (nil, true)；键已存在返回已有记录与 false。
+// 超过 ttl 的旧记录视为过期，会被替换；未完成且占用超过 lease 的记录（进程崩溃、停机或写入响应失败遗留）由本次请求接管。
+func (PostgresIdempotencyStore) Reserve(key, requestHash string, ttl, lease time.Duration) (*model.IdempotencyRecord, bool, error) {
+	if _, err := DB.Exec(`
+		DELETE FROM idempotency_keys
+		WHERE key = $1 AND created_at < NOW() - ($2 * INTERVAL '1 second')
+	`, key, int64(ttl.Seconds())); err != nil {
+		return nil, false, fmt.Errorf("expire idempotency key failed: %w", err)
+	}
+
+	result, err := DB.Exec(`
+		INSERT INTO idempotency_keys AS k (key, request_hash)
+		VALUES ($1, $2)
+		ON CONFLICT (key) DO UPDATE
+		SET request_hash = EXCLUDED.request_hash, created_at = NOW(), locked_at = NOW()
+		WHERE NOT k.completed AND k.locked_at < NOW() - ($3 * INTERVAL '1 second')
+	`, key, requestHash, int64(lease.Seconds()))
+	if err != nil {
+		return nil, false, fmt.Errorf("reserve idempotency key failed: %w", err)
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return nil, false, err
+	} else if n == 1 {
+		return nil, true, nil
+	}
+
+	var rec model.IdempotencyRecord
+	if err := DB.Get(&rec, `
+		SELECT request_hash, completed, status_code, content_type, COALESCE(response_body, ''::bytea) AS response_body
+		FROM idempotency_keys
+		WHERE key = $1
+	`, key); err != nil {
+		if err == sql.ErrNoRows {
+			// 并发的首个请求失败后刚刚释放了该键：按处理中返回，客户端稍后重试即可重新占用
+			return &model.IdempotencyRecord{RequestHash: requestHash}, false, nil
+		}
+		return nil, false, err
+	}
+	return &rec, false, nil
+}
+
+// Complete 保存首个请求的响应，后续同键重放直接返回
+func (PostgresIdempotencyStore) Complete(key string, statusCode int, contentType string, body []byte) error {
+	_, err := DB.Exec(`
+		UPDATE idempotency_keys
+		SET completed = TRUE, status_code = $2, content_type = $3, response_body = $4, completed_at = NOW()
+		WHERE key = $1
+	`, key, statusCode, contentType, body)
+	return err
+}
+
+// Release 删除未完成的占用（首个请求出现 5xx 时，允许客户端用同一个键重试）
+func (PostgresIdempotencyStore) Release(key string) error {
+	_, err := DB.Exec(`DELETE FROM idempotency_keys WHERE key = $1 AND completed = FALSE`, key)
+	return err
+}
+
+// PurgeExpiredIdempotencyKeys 清理超过 ttl 的幂等键
+func PurgeExpiredIdempotencyKeys(ttl time.Duration) (int64, error) {
+	result, err := DB.Exec(`DELETE FROM idempotency_keys WHERE created_at < NOW() - ($1 * INTERVAL '1 second')`, int64(ttl.Seconds()))
+	if err != nil {
+		return 0, fmt.Errorf("purge idempotency keys failed: %w", err)
+	}
+	return result.RowsAffected()
+}
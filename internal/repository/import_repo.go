@@ -0,0 +1,222 @@
+package repository
+
+import (
+	"campus-logistics/internal/model"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// 导入结果：新建 / 更新 / 与现有数据相同
+const (
+	ImportCreated   = "created"
+	ImportUpdated   = "updated"
+	ImportUnchanged = "unchanged"
+)
+
+const importJobColumns = `id, kind, file_name, dry_run, status, total, processed, created, updated, unchanged, failed,
+        COALESCE(message, '') AS message, created_by, created_at, updated_at, finished_at`
+
+// CreateImportJob 创建导入任务（状态为 running）
+func CreateImportJob(kind, fileName string, dryRun bool, total int, createdBy string) (*model.ImportJob, error) {
+	var job model.ImportJob
+	query := `
+        INSERT INTO import_jobs (kind, file_name, dry_run, total, created_by)
+        VALUES ($1, $2, $3, $4, $5)
+        RETURNING ` + importJobColumns
+	if err := DB.Get(&job, query, kind, fileName, dryRun, total, createdBy); err != nil {
+		return nil, fmt.Errorf("create import job failed: %w", err)
+	}
+	return &job, nil
+}
+
+// UpdateImportJobProgress 刷新处理进度与各项计数；任务已不是 running（如被 FailStaleImportJobs 判定为中断）时返回 ErrConflict
+func UpdateImportJobProgress(job *model.ImportJob) error {
+	result, err := DB.Exec(`
+        UPDATE import_jobs
+        SET processed = $2, created = $3, updated = $4, unchanged = $5, failed = $6, updated_at = NOW()
+        WHERE id = $1 AND status = 'running'
+    `, job.ID, job.Processed, job.Created, job.Updated, job.Unchanged, job.Failed)
+	if err != nil {
+		return fmt.Errorf("update import job failed: %w", err)
+	}
+	return expectRunningJob(result)
+}
+
+// expectRunningJob 只更新 running 任务的条件更新未命中时返回 ErrConflict
+func expectRunningJob(result sql.Result) error {
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrConflict
+	}
+	return nil
+}
+
+// FinishImportJob 结束导入任务：写入最终计数、逐行错误（JSON）与状态。
+// 只结束 running 的任务；已被 FailStaleImportJobs 标记为失败时保持失败状态并返回 ErrConflict
+func FinishImportJob(job *model.ImportJob, errorsJSON []byte) error {
+	result, err := DB.Exec(`
+        UPDATE import_jobs
+        SET status = $2, processed = $3, created = $4, updated = $5, unchanged = $6, failed = $7,
+            errors = $8, message = NULLIF($9, ''), updated_at = NOW(), finished_at = NOW()
+        WHERE id = $1 AND status = 'running'
+    `, job.ID, job.Status, job.Processed, job.Created, job.Updated, job.Unchanged, job.Failed, string(errorsJSON), job.Message)
+	if err != nil {
+		return fmt.Errorf("finish import job failed: %w", err)
+	}
+	return expectRunningJob(result)
+}
+
+// GetImportJob 查询导入任务（含逐行错误）；不存在返回 ErrNotFound
+func GetImportJob(id int64) (*model.ImportJob, error) {
+	var job model.ImportJob
+	query := `SELECT ` + importJobColumns + `, errors FROM import_jobs WHERE id = $1`
+	if err := DB.Get(&job, query, id); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &job, nil
+}
+
+// ListImportJobs 最近的导入任务（不含逐行错误）
+func ListImportJobs(limit, offset int) ([]model.ImportJob, error) {
+	jobs := []model.ImportJob{}
+	query := `SELECT ` + importJobColumns + ` FROM import_jobs ORDER BY id DESC LIMIT $1 OFFSET $2`
+	if err := DB.Select(&jobs, query, limit, offset); err != nil {
+		return nil, fmt.Errorf("list import jobs failed: %w", err)
+	}
+	return jobs, nil
+}
+
+// FailStaleImportJobs 把超过 idle 未刷新进度的 running 任务标记为失败（进程重启等导致中断）
+func FailStaleImportJobs(idle time.Duration) (int64, error) {
+	result, err := DB.Exec(`
+        UPDATE import_jobs
+        SET status = 'failed', message = 'interrupted', finished_at = NOW()
+        WHERE status = 'running' AND updated_at < NOW() - $1 * INTERVAL '1 second'
+    `, int64(idle.Seconds()))
+	if err != nil {
+		return 0, fmt.Errorf("fail stale import jobs failed: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// UpsertImportedStudent 按手机号新建或更新学生；文件中为空的字段保留原值（新建时姓名默认为“同学”）。
+// 学号已属于其他手机号时返回 ErrDuplicate；dryRun 时只判断结果不写入
+func UpsertImportedStudent(phone, studentID, name, dorm string, dryRun bool) (string, error) {
+	if studentID != "" {
+		var taken bool
+		err := DB.Get(&taken, `SELECT EXISTS (SELECT 1 FROM users WHERE student_id = $1 AND phone_hash IS DISTINCT FROM $2)`,
+			studentID, phoneHash(phone))
+		if err != nil {
+			return "", fmt.Errorf("query student failed: %w", err)
+		}
+		if taken {
+			return "", ErrDuplicate
+		}
+	}
+
+	var cur struct {
+		StudentID string `db:"student_id"`
+		Name      string `db:"name"`
+		Dorm      string `db:"dorm"`
+	}
+	outcome := ImportUnchanged
+	err := DB.Get(&cur, `
+        SELECT COALESCE(student_id, '') AS student_id, COALESCE(name, '') AS name, COALESCE(dorm, '') AS dorm
+        FROM users WHERE phone_hash = $1
+    `, phoneHash(phone))
+	switch {
+	case err == sql.ErrNoRows:
+		outcome = ImportCreated
+	case err != nil:
+		return "", fmt.Errorf("query student failed: %w", err)
+	case (studentID != "" && studentID != cur.StudentID) || (name != "" && name != cur.Name) || (dorm != "" && dorm != cur.Dorm):
+		outcome = ImportUpdated
+	}
+	if dryRun || outcome == ImportUnchanged {
+		return outcome, nil
+	}
+
+	enc, hash, err := sealPhone(phone)
+	if err != nil {
+		return "", err
+	}
+	_, err = DB.Exec(`
+        INSERT INTO users (phone_hash, phone_enc, student_id, name, dorm)
+        VALUES ($1, $5, NULLIF($2, ''), COALESCE(NULLIF($3, ''), '同学'), NULLIF($4, ''))
+        ON CONFLICT (phone_hash) DO UPDATE
+        SET student_id = COALESCE(EXCLUDED.student_id, users.student_id),
+            name = CASE WHEN $3 = '' THEN users.name ELSE EXCLUDED.name END,
+            dorm = COALESCE(EXCLUDED.dorm, users.dorm)
+    `, hash, studentID, name, dorm, enc)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		return "", ErrDuplicate
+	}
+	if err != nil {
+		return "", fmt.Errorf("upsert student failed: %w", err)
+	}
+	return outcome, nil
+}
+
+// UpsertImportedCourier 按编码新建或更新快递公司；联系电话、运单号格式为空时保留原值。
+// 名称已属于其他快递公司时返回 ErrDuplicate；dryRun 时只判断结果不写入
+func UpsertImportedCourier(code, name, contactPhone, trackingPattern string, dryRun bool) (string, error) {
+	var taken bool
+	err := DB.Get(&taken, `SELECT EXISTS (SELECT 1 FROM couriers WHERE name = $1 AND code <> $2)`, name, code)
+	if err != nil {
+		return "", fmt.Errorf("query courier failed: %w", err)
+	}
+	if taken {
+		return "", ErrDuplicate
+	}
+
+	var cur struct {
+		Name            string `db:"name"`
+		ContactPhone    string `db:"contact_phone"`
+		TrackingPattern string `db:"tracking_pattern"`
+	}
+	outcome := ImportUnchanged
+	err = DB.Get(&cur, `
+        SELECT name, COALESCE(contact_phone, '') AS contact_phone, COALESCE(tracking_pattern, '') AS tracking_pattern
+        FROM couriers WHERE code = $1
+    `, code)
+	switch {
+	case err == sql.ErrNoRows:
+		outcome = ImportCreated
+	case err != nil:
+		return "", fmt.Errorf("query courier failed: %w", err)
+	case name != cur.Name || (contactPhone != "" && contactPhone != cur.ContactPhone) ||
+		(trackingPattern != "" && trackingPattern != cur.TrackingPattern):
+		outcome = ImportUpdated
+	}
+	if dryRun || outcome == ImportUnchanged {
+		return outcome, nil
+	}
+
+	_, err = DB.Exec(`
+        INSERT INTO couriers (code, name, contact_phone, tracking_pattern)
+        VALUES ($1, $2, NULLIF($3, ''), NULLIF($4, ''))
+        ON CONFLICT (code) DO UPDATE
+        SET name = EXCLUDED.name,
+            contact_phone = COALESCE(EXCLUDED.contact_phone, couriers.contact_phone),
+            tracking_pattern = COALESCE(EXCLUDED.tracking_pattern, couriers.tracking_pattern)
+    `, code, name, contactPhone, trackingPattern)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		return "", ErrDuplicate
+	}
+	if err != nil {
+		return "", fmt.Errorf("upsert courier failed: %w", err)
+	}
+	return outcome, nil
+}
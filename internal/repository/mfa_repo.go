@@ -0,0 +1,152 @@
+package repository
+
+import (
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// SetAdminPendingTOTPSecret 写入待激活的 TOTP 密钥；已启用 MFA 的账号不会被覆盖
+func SetAdminPendingTOTPSecret(adminID int64, secret string) error {
+	result, err := DB.Exec(`
+		UPDATE admins SET totp_secret = $1
+		WHERE id = $2 AND mfa_enabled = FALSE
+	`, secret, adminID)
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrConflict
+	}
+	return nil
+}
+
+// ActivateAdminMFA 启用 MFA，记录首个验证码的时间步，并写入新的恢复码哈希
+func ActivateAdminMFA(adminID, step int64, codeHashes []string) error {
+	tx, err := DB.Beginx()
+	if err != nil {
+		return fmt.Errorf("transaction begin failed: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
+		UPDATE admins
+		SET mfa_enabled = TRUE, mfa_enrolled_at = NOW(), totp_last_step = $2
+		WHERE id = $1 AND mfa_enabled = FALSE AND totp_secret IS NOT NULL
+	`, adminID, step)
+	if err != nil {
+		return fmt.Errorf("activate mfa failed: %w", err)
+	}
+	if err := expectOneRow(result); err != nil {
+		return ErrConflict
+	}
+
+	if err := replaceRecoveryCodes(tx, adminID, codeHashes); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("transaction commit failed: %w", err)
+	}
+	return nil
+}
+
+// DisableAdminMFA 关闭 MFA 并清除密钥与全部恢复码
+func DisableAdminMFA(adminID int64) error {
+	tx, err := DB.Beginx()
+	if err != nil {
+		return fmt.Errorf("transaction begin failed: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
+		UPDATE admins
+		SET mfa_enabled = FALSE, mfa_enrolled_at = NULL, totp_secret = NULL, totp_last_step = 0
+		WHERE id = $1
+	`, adminID)
+	if err != nil {
+		return fmt.Errorf("disable mfa failed: %w", err)
+	}
+	if err := expectOneRow(result); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(`DELETE FROM admin_recovery_codes WHERE admin_id = $1`, adminID); err != nil {
+		return fmt.Errorf("delete recovery codes failed: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("transaction commit failed: %w", err)
+	}
+	return nil
+}
+
+// AdvanceAdminTOTPStep 原子地推进已使用的时间步；step 不大于已记录值时返回 ErrConflict（验证码重放）
+func AdvanceAdminTOTPStep(adminID, step int64) error {
+	result, err := DB.Exec(`UPDATE admins SET totp_last_step = $2 WHERE id = $1 AND totp_last_step < $2`, adminID, step)
+	if err != nil {
+		return err
+	}
+	if err := expectOneRow(result); err != nil {
+		return ErrConflict
+	}
+	return nil
+}
+
+// ConsumeAdminRecoveryCode 将匹配的未使用恢复码标记为已使用；不存在时返回 ErrNotFound
+func ConsumeAdminRecoveryCode(adminID int64, codeHash string) error {
+	result, err := DB.Exec(`
+		UPDATE admin_recovery_codes SET used_at = NOW()
+		WHERE id = (
+			SELECT id FROM admin_recovery_codes
+			WHERE admin_id = $1 AND code_hash = $2 AND used_at IS NULL
+			LIMIT 1
+		)
+	`, adminID, codeHash)
+	if err != nil {
+		return err
+	}
+	return expectOneRow(result)
+}
+
+// ReplaceAdminRecoveryCodes 作废旧恢复码并写入新的一组
+func ReplaceAdminRecoveryCodes(adminID int64, codeHashes []string) error {
+	tx, err := DB.Beginx()
+	if err != nil {
+		return fmt.Errorf("transaction begin failed: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := replaceRecoveryCodes(tx, adminID, codeHashes); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("transaction commit failed: %w", err)
+	}
+	return nil
+}
+
+func CountUnusedAdminRecoveryCodes(adminID int64) (int, error) {
+	var n int
+	if err := DB.Get(&n, `SELECT COUNT(1) FROM admin_recovery_codes WHERE admin_id = $1 AND used_at IS NULL`, adminID); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+func replaceRecoveryCodes(tx *sqlx.Tx, adminID int64, codeHashes []string) error {
+	if _, err := tx.Exec(`DELETE FROM admin_recovery_codes WHERE admin_id = $1`, adminID); err != nil {
+		return fmt.Errorf("delete recovery codes failed: %w", err)
+	}
+	for _, h := range codeHashes {
+		if _, err := tx.Exec(`INSERT INTO admin_recovery_codes (admin_id, code_hash) VALUES ($1, $2)`, adminID, h); err != nil {
+			return fmt.Errorf("insert recovery code failed: %w", err)
+		}
+	}
+	return nil
+}
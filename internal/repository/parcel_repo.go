@@ -7,6 +7,11 @@ import (
 	"campus-logistics/internal/model" // 项目内部数据模型
 	"database/sql"                    // 标准库SQL错误类型
 	"fmt"                             // 格式化字符串，用于构建错误信息
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 )
 
 // CreateParcelInbound 创建包裹入库记录（通过存储过程）
@@ -17,9 +22,11 @@ import (
 //   - phone: 收件人手机号
 //   - courierCode: 快递公司代码
 //   - userName: 入库操作员名称
+//   - stationCode: 入库站点编号，为空时仅在只有一个站点时使用该站点，否则返回 INVALID_STATION
+//   - attrs: 尺寸、重量、易碎/生鲜/冷链标记与声明价值，决定可用的货架
 //
 // 返回值：error - 成功返回nil，失败返回具体错误
-func CreateParcelInbound(trackingNum, phone, courierCode, userName string) error {
+func CreateParcelInbound(trackingNum, phone, courierCode, userName, stationCode string, attrs model.ParcelAttributes) error {
 	// 1. 开始数据库事务
 	// Beginx() 返回一个 sqlx.Tx 事务对象，支持命名参数等高级特性
 	tx, err := DB.Beginx()
@@ -34,12 +41,22 @@ func CreateParcelInbound(trackingNum, phone, courierCode, userName string) error
 
 	// 3. 调用存储过程执行入库操作
 	// 使用PostgreSQL存储过程（或函数）sp_parcel_inbound
-	// $1, $2, $3, $4 是位置参数占位符
-	query := `CALL sp_parcel_inbound($1, $2, $3, $4)`
+	// $1 ~ $13 是位置参数占位符；重量与声明价值为 0 时写入 NULL
+	// 手机号以盲索引、密文与脱敏形式传入，存储过程不接触明文
+	query := `CALL sp_parcel_inbound($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)`
+	enc, hash, err := sealPhone(phone)
+	if err != nil {
+		return err
+	}
 
 	// 执行存储过程调用
 	// Exec 方法用于执行不返回结果集的SQL语句
-	_, err = tx.Exec(query, trackingNum, phone, courierCode, userName)
+	_, err = tx.Exec(query, trackingNum, hash, enc, maskedPhone(phone), courierCode, userName,
+		sql.NullString{String: stationCode, Valid: stationCode != ""},
+		attrs.SizeClass,
+		sql.NullInt64{Int64: int64(attrs.WeightGrams), Valid: attrs.WeightGrams > 0},
+		attrs.Fragile, attrs.Perishable, attrs.ColdChain,
+		sql.NullInt64{Int64: attrs.DeclaredValueCents, Valid: attrs.DeclaredValueCents > 0})
 	if err != nil {
 		// 存储过程执行失败，返回具体错误
 		// 可能的原因：参数错误、业务规则违反（如重复运单号）、数据库约束违反等
@@ -109,20 +126,24 @@ func GetParcelByPhone(phone string, limit, offset int) ([]model.ParcelViewStuden
 				ELSE '待上架'
 			END AS pickup_code,
 			s.zone AS shelf_zone,
+			st.name AS station_name,
 			p.status,
+			p.size_class, COALESCE(p.weight_grams, 0) AS weight_grams, p.fragile, p.perishable, p.cold_chain,
+			COALESCE(p.declared_value_cents, 0) AS declared_value_cents,
 			p.created_at,
 			p.updated_at
 		FROM parcels p
 		JOIN couriers c ON p.courier_id = c.id
+		JOIN stations st ON p.station_id = st.id
 		LEFT JOIN shelves s ON p.shelf_id = s.id
 		WHERE p.user_id = (
-			SELECT id FROM users WHERE phone = $1
+			SELECT id FROM users WHERE phone_hash = $1
 		)
 		ORDER BY p.updated_at DESC
 		LIMIT $2 OFFSET $3
 	`
 
-	if err := DB.Select(&parcels, query, phone, limit, offset); err != nil {
+	if err := DB.Select(&parcels, query, phoneHash(phone), limit, offset); err != nil {
 		return nil, err
 	}
 	return parcels, nil
@@ -130,6 +151,7 @@ func GetParcelByPhone(phone string, limit, offset int) ([]model.ParcelViewStuden
 
 // GetParcelByUserID 根据 user_id 查询用户的包裹列表（学生视图）
 // 这是鉴权后的推荐路径，避免使用 phone 造成越权查询
+// 列表同时包含他人授权本人代取、仍待取件的包裹（delegated_by 为所有人姓名）
 func GetParcelByUserID(userID int64, limit, offset int) ([]model.ParcelViewStudent, error) {
 	parcels := []model.ParcelViewStudent{}
 
@@ -142,13 +164,27 @@ func GetParcelByUserID(userID int64, limit, offset int) ([]model.ParcelViewStude
 				ELSE '待上架'
 			END AS pickup_code,
 			s.zone AS shelf_zone,
+			st.name AS station_name,
 			p.status,
+			p.size_class, COALESCE(p.weight_grams, 0) AS weight_grams, p.fragile, p.perishable, p.cold_chain,
+			COALESCE(p.declared_value_cents, 0) AS declared_value_cents,
+			CASE WHEN p.user_id = $1 THEN '' ELSE COALESCE(u.name, '') END AS delegated_by,
 			p.created_at,
 			p.updated_at
 		FROM parcels p
 		JOIN couriers c ON p.courier_id = c.id
+		JOIN stations st ON p.station_id = st.id
+		JOIN users u ON p.user_id = u.id
 		LEFT JOIN shelves s ON p.shelf_id = s.id
 		WHERE p.user_id = $1
+		   OR (p.status IN ('stored', 'pending')
+		       AND p.user_id IN (
+		           SELECT d.owner_id
+		           FROM pickup_delegations d
+		           JOIN users du ON du.phone_hash = d.delegate_phone_hash
+		           WHERE du.id = $1 AND d.revoked_at IS NULL AND d.expires_at > NOW()
+		       )
+		       AND func_active_delegation(p.id, $1) IS NOT NULL)
 		ORDER BY p.updated_at DESC
 		LIMIT $2 OFFSET $3
 	`
@@ -159,12 +195,188 @@ func GetParcelByUserID(userID int64, limit, offset int) ([]model.ParcelViewStude
 	return parcels, nil
 }
 
+// GetStudentParcelPickupCode 查询学生本人包裹的取件码与状态，用于生成取件凭证
+func GetStudentParcelPickupCode(userID int64, trackingNum string) (pickupCode, status string, err error) {
+	var p struct {
+		PickupCode sql.NullString `db:"pickup_code"`
+		Status     string         `db:"status"`
+	}
+	query := `
+		SELECT pickup_code, status::text AS status
+		FROM parcels
+		WHERE tracking_number = $1 AND user_id = $2
+	`
+	if err := DB.Get(&p, query, trackingNum, userID); err != nil {
+		if err == sql.ErrNoRows {
+			return "", "", ErrNotFound
+		}
+		return "", "", err
+	}
+	return p.PickupCode.String, p.Status, nil
+}
+
+// CountStudentReadyParcels 学生本人已上架、可取件的包裹数
+func CountStudentReadyParcels(userID int64) (int, error) {
+	var n int
+	query := `SELECT COUNT(*) FROM parcels WHERE user_id = $1 AND status = 'stored'`
+	if err := DB.Get(&n, query, userID); err != nil {
+		return 0, fmt.Errorf("count ready parcels failed: %w", err)
+	}
+	return n, nil
+}
+
+// CounterPickupParcels 柜台扫码取件：在一个事务内登记凭证（防重放）、
+// 取走学生在本站点全部已上架的包裹并扣减货架负载；审计日志记录操作人 operator。
+// 凭证已使用返回 ErrAlreadyUsed，没有可取件的包裹返回 ErrNotFound（凭证不会被消耗）；
+// stationID 为 0 表示不限站点
+func CounterPickupParcels(userID int64, nonce string, expiresAt time.Time, operator string, stationID int64) ([]model.CounterPickupParcel, error) {
+	tx, err := DB.Beginx()
+	if err != nil {
+		return nil, fmt.Errorf("transaction begin failed: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
+        INSERT INTO pickup_token_uses (nonce, user_id, operator, expires_at)
+        VALUES ($1, $2, $3, $4)
+        ON CONFLICT (nonce) DO NOTHING
+    `, nonce, userID, operator, expiresAt)
+	if err != nil {
+		return nil, fmt.Errorf("record pickup token failed: %w", err)
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return nil, err
+	} else if n == 0 {
+		return nil, ErrAlreadyUsed
+	}
+
+	if err := (auditContext{Operator: operator, Action: "COUNTER_PICKUP"}).apply(tx); err != nil {
+		return nil, err
+	}
+
+	parcels := []model.CounterPickupParcel{}
+	query := `
+        WITH picked AS (
+            UPDATE parcels
+            SET status = 'picked_up', picked_up_at = NOW()
+            WHERE user_id = $1
+              AND status = 'stored'
+              AND ($2 = 0 OR station_id = $2)
+            RETURNING tracking_number, pickup_code, courier_id, shelf_id
+        )
+        SELECT picked.tracking_number,
+               COALESCE(c.name, '') AS courier_name,
+               COALESCE(picked.pickup_code, '') AS pickup_code,
+               COALESCE(s.code, '') AS shelf_code,
+               COALESCE(picked.shelf_id, 0) AS shelf_id
+        FROM picked
+        LEFT JOIN couriers c ON c.id = picked.courier_id
+        LEFT JOIN shelves s ON s.id = picked.shelf_id
+        ORDER BY shelf_code, picked.tracking_number
+    `
+	if err := tx.Select(&parcels, query, userID, stationID); err != nil {
+		return nil, fmt.Errorf("counter pickup failed: %w", err)
+	}
+	if len(parcels) == 0 {
+		return nil, ErrNotFound
+	}
+
+	// 按货架汇总后扣减负载
+	loads := map[int64]int{}
+	for _, p := range parcels {
+		if p.ShelfID != 0 {
+			loads[p.ShelfID]++
+		}
+	}
+	for shelfID, n := range loads {
+		if _, err := tx.Exec(`
+            UPDATE shelves
+            SET current_load = GREATEST(current_load - $2, 0), updated_at = NOW()
+            WHERE id = $1
+        `, shelfID, n); err != nil {
+			return nil, fmt.Errorf("update shelf load failed: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("transaction commit failed: %w", err)
+	}
+	return parcels, nil
+}
+
+// CounterPickupSingleParcel 柜台扫描单个包裹的取件凭证（PC1）取件：运单号与取件码须匹配且包裹仍在架上，
+// 同时扣减货架负载。取件后包裹不再是 stored，同一凭证无法重复使用；
+// 不存在、已取件或不在本站点返回 ErrNotFound。stationID 为 0 表示不限站点
+func CounterPickupSingleParcel(trackingNum, pickupCode, operator string, stationID int64) (*model.CounterPickupParcel, error) {
+	tx, err := DB.Beginx()
+	if err != nil {
+		return nil, fmt.Errorf("transaction begin failed: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := (auditContext{Operator: operator, Action: "COUNTER_PICKUP"}).apply(tx); err != nil {
+		return nil, err
+	}
+
+	var parcel model.CounterPickupParcel
+	err = tx.Get(&parcel, `
+        WITH picked AS (
+            UPDATE parcels
+            SET status = 'picked_up', picked_up_at = NOW()
+            WHERE tracking_number = $1
+              AND pickup_code = $2
+              AND status = 'stored'
+              AND ($3 = 0 OR station_id = $3)
+            RETURNING tracking_number, pickup_code, courier_id, shelf_id
+        )
+        SELECT picked.tracking_number,
+               COALESCE(c.name, '') AS courier_name,
+               COALESCE(picked.pickup_code, '') AS pickup_code,
+               COALESCE(s.code, '') AS shelf_code,
+               COALESCE(picked.shelf_id, 0) AS shelf_id
+        FROM picked
+        LEFT JOIN couriers c ON c.id = picked.courier_id
+        LEFT JOIN shelves s ON s.id = picked.shelf_id
+    `, trackingNum, pickupCode, stationID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("counter pickup failed: %w", err)
+	}
+
+	if parcel.ShelfID != 0 {
+		if _, err := tx.Exec(`
+            UPDATE shelves
+            SET current_load = GREATEST(current_load - 1, 0), updated_at = NOW()
+            WHERE id = $1
+        `, parcel.ShelfID); err != nil {
+			return nil, fmt.Errorf("update shelf load failed: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("transaction commit failed: %w", err)
+	}
+	return &parcel, nil
+}
+
+// PurgeExpiredPickupTokenUses 清理已过期凭证的使用记录（过期凭证本身已无法通过校验）
+func PurgeExpiredPickupTokenUses() (int64, error) {
+	result, err := DB.Exec(`DELETE FROM pickup_token_uses WHERE expires_at < NOW()`)
+	if err != nil {
+		return 0, fmt.Errorf("purge pickup token uses failed: %w", err)
+	}
+	return result.RowsAffected()
+}
+
 // PickupParcel 包裹取件操作
 // 功能：根据运单号和取件码更新包裹状态为"已取件"
 // 使用原子更新操作确保并发安全性，避免重复取件
 // 参数：
 //   - trackingNum: 运单号
 //   - pickupCode: 取件码
+//   - userID: 取件人，可以是包裹所有人，也可以是持有生效代取授权的被授权人
 // 返回值：error - 成功返回nil，失败返回具体错误
 
 func PickupParcel(trackingNum, pickupCode string, userID int64) error {
@@ -175,6 +387,33 @@ func PickupParcel(trackingNum, pickupCode string, userID int64) error {
 	}
 	defer tx.Rollback()
 
+	// 代取时以包裹所有人的身份取件，审计日志同时记录所有人与代取人
+	var owner struct {
+		UserID       int64         `db:"user_id"`
+		DelegationID sql.NullInt64 `db:"delegation_id"`
+	}
+	err = tx.Get(&owner, `
+        SELECT user_id, func_active_delegation(id, $2) AS delegation_id
+        FROM parcels
+        WHERE tracking_number = $1
+    `, trackingNum, userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return ErrNotFound
+		}
+		return fmt.Errorf("query parcel owner failed: %w", err)
+	}
+	if owner.UserID != userID {
+		if !owner.DelegationID.Valid {
+			return ErrNotFound
+		}
+		audit := auditContext{Operator: fmt.Sprintf("user:%d", userID), Action: "DELEGATED_PICKUP", DelegateUserID: userID}
+		if err := audit.apply(tx); err != nil {
+			return err
+		}
+		userID = owner.UserID
+	}
+
 	type pickedParcel struct {
 		ShelfID int64 `db:"shelf_id"`
 	}
@@ -193,9 +432,9 @@ func PickupParcel(trackingNum, pickupCode string, userID int64) error {
 		`
 
 	if err := tx.Get(&p, updateQuery, trackingNum, pickupCode, userID); err != nil {
-		// 可能是未找到符合条件的记录，或其他数据库错误
+		// 未更新任何记录时，区分包裹不存在、状态不可取件与取件码错误
 		if err == sql.ErrNoRows {
-			return fmt.Errorf("pickup failed: invalid code or parcel status")
+			return pickupFailureReason(tx, trackingNum, pickupCode, userID)
 		}
 		return fmt.Errorf("db execution failed: %w", err)
 	}
@@ -219,58 +458,121 @@ func PickupParcel(trackingNum, pickupCode string, userID int64) error {
 	return nil
 }
 
+// pickupFailureReason 取件失败的原因：
+// 包裹不存在（或不属于该学生）返回 ErrNotFound，状态不是已上架返回 ErrConflict，取件码不符返回 ErrCodeMismatch
+func pickupFailureReason(tx *sqlx.Tx, trackingNum, pickupCode string, userID int64) error {
+	var cur struct {
+		Status     string         `db:"status"`
+		PickupCode sql.NullString `db:"pickup_code"`
+	}
+	err := tx.Get(&cur, `
+        SELECT status::text AS status, pickup_code
+        FROM parcels
+        WHERE tracking_number = $1 AND user_id = $2
+    `, trackingNum, userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return ErrNotFound
+		}
+		return fmt.Errorf("query parcel failed: %w", err)
+	}
+	if cur.Status != "stored" {
+		return ErrConflict
+	}
+	if cur.PickupCode.String != pickupCode {
+		return ErrCodeMismatch
+	}
+	// 并发取件等情况下状态刚被修改
+	return ErrConflict
+}
+
 // GetAdminDashboard 查询管理员仪表盘统计数据
-// 数据来源：数据库视图 v_admin_dashboard
-func GetAdminDashboard() (*model.AdminDashboard, error) {
+// 数据来源：stationID 为 0 时使用全局视图 v_admin_dashboard，否则使用 v_station_dashboard
+func GetAdminDashboard(stationID int64) (*model.AdminDashboard, error) {
 	dashboard := &model.AdminDashboard{}
-	query := `
-        SELECT waiting_pickup, full_shelves, today_ops
+	if stationID == 0 {
+		query := `
+        SELECT waiting_pickup, waiting_perishable, full_shelves,
+               today_ops, today_inbound, today_picked_up, today_returned, today_exceptions
         FROM v_admin_dashboard
         LIMIT 1
     `
-	if err := DB.Get(dashboard, query); err != nil {
+		if err := DB.Get(dashboard, query); err != nil {
+			return nil, err
+		}
+		return dashboard, nil
+	}
+
+	query := `
+        SELECT waiting_pickup, waiting_perishable, full_shelves,
+               today_ops, today_inbound, today_picked_up, today_returned, today_exceptions
+        FROM v_station_dashboard
+        WHERE station_id = $1
+    `
+	if err := DB.Get(dashboard, query, stationID); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNotFound
+		}
 		return nil, err
 	}
 	return dashboard, nil
 }
 
 // GetRetentionParcels 查询滞留包裹列表
-// days 参数表示滞留天数阈值，例如 7 表示滞留超过 7 天
-// 结果按创建时间升序排列，并支持 limit/offset 分页
-func GetRetentionParcels(days, limit, offset int) ([]model.ParcelViewStudent, error) {
-	parcels := []model.ParcelViewStudent{}
+// days 参数表示滞留天数阈值，例如 7 表示滞留超过 7 天；
+// 生鲜/冷链包裹改用 perishableHours 小时阈值，提前进入滞留名单
+// stationID 为 0 表示全部站点
+// 结果生鲜优先、再按创建时间升序排列，并支持 limit/offset 分页
+func GetRetentionParcels(days, perishableHours int, stationID int64, limit, offset int) ([]model.RetentionParcel, error) {
+	parcels := []model.RetentionParcel{}
 	query := `
         SELECT 
             p.tracking_number,
             c.name AS courier_name,
             p.pickup_code,
             s.zone AS shelf_zone,
+            st.name AS station_name,
             p.status,
+            p.size_class, COALESCE(p.weight_grams, 0) AS weight_grams, p.fragile, p.perishable, p.cold_chain,
+            COALESCE(p.declared_value_cents, 0) AS declared_value_cents,
 			p.created_at,
-            p.updated_at
+            p.updated_at,
+            COALESCE(p.recipient_phone_enc, p.recipient_phone_snapshot, p.recipient_phone_masked, '') AS recipient_phone
         FROM parcels p
         LEFT JOIN couriers c ON p.courier_id = c.id
         LEFT JOIN shelves s ON p.shelf_id = s.id
+        JOIN stations st ON p.station_id = st.id
         WHERE p.status IN ('stored', 'pending')
-          AND p.created_at < NOW() - ($1 * INTERVAL '1 day')
-        ORDER BY p.created_at ASC
-        LIMIT $2 OFFSET $3
+          AND p.created_at < NOW() - CASE WHEN p.perishable THEN $2 * INTERVAL '1 hour' ELSE $1 * INTERVAL '1 day' END
+          AND ($3 = 0 OR p.station_id = $3)
+        ORDER BY p.perishable DESC, p.created_at ASC
+        LIMIT $4 OFFSET $5
     `
-	if err := DB.Select(&parcels, query, days, limit, offset); err != nil {
+	if err := DB.Select(&parcels, query, days, perishableHours, stationID, limit, offset); err != nil {
 		return nil, err
 	}
+	for i := range parcels {
+		phone, err := openPhone(parcels[i].RecipientPhone)
+		if err != nil {
+			return nil, err
+		}
+		parcels[i].RecipientPhone = phone
+	}
 	return parcels, nil
 }
 
 // UpdateParcelStatus 管理员更新包裹状态（不含 picked_up 流转）
-// 用于处理待取、异常、退回等状态
-func UpdateParcelStatus(trackingNum, newStatus string) error {
+// 用于处理待取、异常、退回等状态；只有当前状态在 fromStatuses 中才会更新，
+// stationID 非 0 时只能修改本站点的包裹
+func UpdateParcelStatus(trackingNum, newStatus string, fromStatuses []string, stationID int64) error {
 	query := `
         UPDATE parcels
         SET status = $1
         WHERE tracking_number = $2
+          AND ($3 = 0 OR station_id = $3)
+          AND status::text = ANY($4)
     `
-	result, err := DB.Exec(query, newStatus, trackingNum)
+	result, err := DB.Exec(query, newStatus, trackingNum, stationID, pq.Array(fromStatuses))
 	if err != nil {
 		return fmt.Errorf("update parcel status failed: %w", err)
 	}
@@ -278,8 +580,108 @@ func UpdateParcelStatus(trackingNum, newStatus string) error {
 	if err != nil {
 		return err
 	}
-	if rows == 0 {
-		return fmt.Errorf("parcel not found")
+	if rows > 0 {
+		return nil
 	}
-	return nil
+
+	// 未更新：包裹不存在（或不在管理范围内）返回 ErrNotFound，当前状态不允许变更返回 ErrConflict
+	var exists bool
+	err = DB.Get(&exists, `
+        SELECT EXISTS (
+            SELECT 1 FROM parcels
+            WHERE tracking_number = $1 AND ($2 = 0 OR station_id = $2)
+        )
+    `, trackingNum, stationID)
+	if err != nil {
+		return fmt.Errorf("query parcel failed: %w", err)
+	}
+	if !exists {
+		return ErrNotFound
+	}
+	return ErrConflict
+}
+
+// RevealParcelPhone 返回包裹收件人的完整手机号并写入查看记录。
+// courierID / stationID 非 0 时分别限定快递公司与站点；不存在或不在范围内返回 ErrNotFound，
+// 包裹已不在库（已取件、退回、异常）时返回 ErrConflict
+func RevealParcelPhone(trackingNumber string, courierID, stationID int64, operator, role string) (string, error) {
+	tx, err := DB.Beginx()
+	if err != nil {
+		return "", fmt.Errorf("transaction begin failed: %w", err)
+	}
+	defer tx.Rollback()
+
+	var p struct {
+		ID     int64  `db:"id"`
+		Phone  string `db:"phone"`
+		Active bool   `db:"active"`
+	}
+	err = tx.Get(&p, `
+        SELECT id, COALESCE(recipient_phone_enc, recipient_phone_snapshot, '') AS phone,
+               status IN ('inbound', 'stored', 'pending') AS active
+        FROM parcels
+        WHERE tracking_number = $1
+          AND ($2 = 0 OR courier_id = $2)
+          AND ($3 = 0 OR station_id = $3)
+    `, trackingNumber, courierID, stationID)
+	if err == sql.ErrNoRows {
+		return "", ErrNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("query parcel phone failed: %w", err)
+	}
+	phone, err := openPhone(p.Phone)
+	if err != nil {
+		return "", err
+	}
+	// 已清理为脱敏形式的号码不能再查看
+	if !p.Active || phone == "" || strings.Contains(phone, "*") {
+		return "", ErrConflict
+	}
+
+	if _, err := tx.Exec(`INSERT INTO phone_access_logs (parcel_id, operator, role) VALUES ($1, $2, $3)`,
+		p.ID, operator, role); err != nil {
+		return "", fmt.Errorf("insert phone access log failed: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return "", fmt.Errorf("transaction commit failed: %w", err)
+	}
+	return phone, nil
+}
+
+// RedactPickedUpPhones 删除取件时间早于 before 的包裹上的手机号密文（及加密迁移前的明文快照），
+// 只保留脱敏形式（前 3 位与后 4 位），返回处理的包裹数。只修改快照列，不触发状态审计，不修改 updated_at
+func RedactPickedUpPhones(before time.Time) (int64, error) {
+	tx, err := DB.Beginx()
+	if err != nil {
+		return 0, fmt.Errorf("transaction begin failed: %w", err)
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec(`SELECT set_config('app.keep_updated_at', 'on', true)`); err != nil {
+		return 0, err
+	}
+
+	res, err := tx.Exec(`
+        UPDATE parcels
+        SET recipient_phone_masked = COALESCE(recipient_phone_masked,
+                CASE WHEN LENGTH(recipient_phone_snapshot) > 7
+                     THEN LEFT(recipient_phone_snapshot, 3) || '****' || RIGHT(recipient_phone_snapshot, 4)
+                     ELSE '****' END),
+            recipient_phone_enc = NULL,
+            recipient_phone_snapshot = NULL
+        WHERE status = 'picked_up'
+          AND picked_up_at < $1
+          AND (recipient_phone_enc IS NOT NULL OR recipient_phone_snapshot IS NOT NULL)
+    `, before)
+	if err != nil {
+		return 0, fmt.Errorf("redact parcel phones failed: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("transaction commit failed: %w", err)
+	}
+	return n, nil
 }
@@ -0,0 +1,315 @@
+package repository
+
+import (
+	"campus-logistics/internal/fieldcrypt"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// 手机号在 users、parcels、代取授权、寄件订单、手机号变更请求与用户变更记录中加密保存（见 internal/fieldcrypt）。
+// 本包的函数对调用方保持明文接口：写入时加密并计算盲索引，读取时解密。
+
+// sealPhone 加密手机号，返回密文与盲索引
+func sealPhone(phone string) (enc, hash string, err error) {
+	enc, err = fieldcrypt.Default.Encrypt(phone)
+	if err != nil {
+		return "", "", fmt.Errorf("encrypt phone failed: %w", err)
+	}
+	return enc, fieldcrypt.Default.BlindIndex(phone), nil
+}
+
+// sealOptionalPhone 加密可能为空的手机号（如已删除个人信息的用户），空值返回 NULL
+func sealOptionalPhone(phone string) (sql.NullString, error) {
+	if phone == "" {
+		return sql.NullString{}, nil
+	}
+	enc, _, err := sealPhone(phone)
+	return sql.NullString{String: enc, Valid: err == nil}, err
+}
+
+// phoneHash 手机号盲索引，用于按手机号查找
+func phoneHash(phone string) string {
+	return fieldcrypt.Default.BlindIndex(phone)
+}
+
+// openPhone 还原手机号列值：密文解密；加密迁移前的明文与已清理的脱敏形式原样返回
+func openPhone(s string) (string, error) {
+	if !fieldcrypt.IsCiphertext(s) {
+		return s, nil
+	}
+	phone, err := fieldcrypt.Default.Decrypt(s)
+	if err != nil {
+		return "", fmt.Errorf("decrypt phone failed: %w", err)
+	}
+	return phone, nil
+}
+
+// maskedPhone 入库时随密文保存的脱敏形式（保留前 3 位与后 4 位），用于尾号搜索与清理后的展示
+func maskedPhone(phone string) string {
+	if len(phone) <= 7 {
+		return "****"
+	}
+	return phone[:3] + "****" + phone[len(phone)-4:]
+}
+
+// phoneRow 加密迁移 / 密钥轮换时读取的一行：value 为旧版明文或密文
+type phoneRow struct {
+	ID    int64  `db:"id"`
+	Value string `db:"value"`
+}
+
+// ReencryptUserPhones 加密迁移与密钥轮换：把 users 中仍为明文、或不是用当前密钥加密的手机号
+// 用当前密钥重新加密并写入盲索引，最多处理 limit 行，返回处理的行数。不修改 updated_at
+func ReencryptUserPhones(limit int) (int, error) {
+	tx, err := DB.Beginx()
+	if err != nil {
+		return 0, fmt.Errorf("transaction begin failed: %w", err)
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec(`SELECT set_config('app.keep_updated_at', 'on', true)`); err != nil {
+		return 0, err
+	}
+
+	rows := []phoneRow{}
+	err = tx.Select(&rows, `
+        SELECT id, COALESCE(phone, phone_enc) AS value
+        FROM users
+        WHERE phone IS NOT NULL OR (phone_enc IS NOT NULL AND split_part(phone_enc, ':', 1) <> $1)
+        ORDER BY id
+        LIMIT $2
+        FOR UPDATE SKIP LOCKED
+    `, fieldcrypt.Default.ActiveKeyID(), limit)
+	if err != nil {
+		return 0, fmt.Errorf("query user phones failed: %w", err)
+	}
+	for _, r := range rows {
+		phone, err := openPhone(r.Value)
+		if err != nil {
+			return 0, fmt.Errorf("user %d: %w", r.ID, err)
+		}
+		enc, hash, err := sealPhone(phone)
+		if err != nil {
+			return 0, err
+		}
+		if _, err := tx.Exec(`UPDATE users SET phone = NULL, phone_enc = $2, phone_hash = $3 WHERE id = $1`,
+			r.ID, enc, hash); err != nil {
+			return 0, fmt.Errorf("update user %d phone failed: %w", r.ID, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("transaction commit failed: %w", err)
+	}
+	return len(rows), nil
+}
+
+// ReencryptParcelPhones 加密迁移与密钥轮换：把 parcels 中的明文手机号快照、或不是用当前密钥加密的密文
+// 用当前密钥重新加密并补齐脱敏形式，最多处理 limit 行，返回处理的行数。
+// 已清理为脱敏形式的旧快照只保留脱敏形式。不修改 updated_at
+func ReencryptParcelPhones(limit int) (int, error) {
+	tx, err := DB.Beginx()
+	if err != nil {
+		return 0, fmt.Errorf("transaction begin failed: %w", err)
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec(`SELECT set_config('app.keep_updated_at', 'on', true)`); err != nil {
+		return 0, err
+	}
+
+	rows := []phoneRow{}
+	err = tx.Select(&rows, `
+        SELECT id, COALESCE(recipient_phone_snapshot, recipient_phone_enc) AS value
+        FROM parcels
+        WHERE recipient_phone_snapshot IS NOT NULL
+           OR (recipient_phone_enc IS NOT NULL AND split_part(recipient_phone_enc, ':', 1) <> $1)
+        ORDER BY id
+        LIMIT $2
+        FOR UPDATE SKIP LOCKED
+    `, fieldcrypt.Default.ActiveKeyID(), limit)
+	if err != nil {
+		return 0, fmt.Errorf("query parcel phones failed: %w", err)
+	}
+	for _, r := range rows {
+		phone, err := openPhone(r.Value)
+		if err != nil {
+			return 0, fmt.Errorf("parcel %d: %w", r.ID, err)
+		}
+		var enc sql.NullString
+		masked := phone
+		if !strings.Contains(phone, "*") {
+			if enc.String, err = fieldcrypt.Default.Encrypt(phone); err != nil {
+				return 0, fmt.Errorf("encrypt phone failed: %w", err)
+			}
+			enc.Valid = true
+			masked = maskedPhone(phone)
+		}
+		if _, err := tx.Exec(`
+            UPDATE parcels
+            SET recipient_phone_snapshot = NULL, recipient_phone_enc = $2,
+                recipient_phone_masked = COALESCE(recipient_phone_masked, $3)
+            WHERE id = $1
+        `, r.ID, enc, masked); err != nil {
+			return 0, fmt.Errorf("update parcel %d phone failed: %w", r.ID, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("transaction commit failed: %w", err)
+	}
+	return len(rows), nil
+}
+
+// ReencryptDelegationPhones 加密迁移与密钥轮换：把代取授权中仍为明文、或不是用当前密钥加密的被授权人手机号
+// 用当前密钥重新加密并补齐盲索引，最多处理 limit 行，返回处理的行数
+func ReencryptDelegationPhones(limit int) (int, error) {
+	tx, err := DB.Beginx()
+	if err != nil {
+		return 0, fmt.Errorf("transaction begin failed: %w", err)
+	}
+	defer tx.Rollback()
+
+	rows := []phoneRow{}
+	err = tx.Select(&rows, `
+        SELECT id, delegate_phone AS value
+        FROM pickup_delegations
+        WHERE delegate_phone_hash IS NULL OR split_part(delegate_phone, ':', 1) <> $1
+        ORDER BY id
+        LIMIT $2
+        FOR UPDATE SKIP LOCKED
+    `, fieldcrypt.Default.ActiveKeyID(), limit)
+	if err != nil {
+		return 0, fmt.Errorf("query delegations failed: %w", err)
+	}
+	for _, r := range rows {
+		phone, err := openPhone(r.Value)
+		if err != nil {
+			return 0, fmt.Errorf("delegation %d: %w", r.ID, err)
+		}
+		enc, hash, err := sealPhone(phone)
+		if err != nil {
+			return 0, err
+		}
+		if _, err := tx.Exec(`UPDATE pickup_delegations SET delegate_phone = $2, delegate_phone_hash = $3 WHERE id = $1`,
+			r.ID, enc, hash); err != nil {
+			return 0, fmt.Errorf("update delegation %d failed: %w", r.ID, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("transaction commit failed: %w", err)
+	}
+	return len(rows), nil
+}
+
+// ReencryptChangeLogPhones 加密迁移与密钥轮换：把用户变更记录中仍为明文、或不是用当前密钥加密的手机号
+// 用当前密钥重新加密，最多处理 limit 行，返回处理的行数
+func ReencryptChangeLogPhones(limit int) (int, error) {
+	tx, err := DB.Beginx()
+	if err != nil {
+		return 0, fmt.Errorf("transaction begin failed: %w", err)
+	}
+	defer tx.Rollback()
+
+	rows := []struct {
+		ID       int64          `db:"id"`
+		OldPhone sql.NullString `db:"old_phone"`
+		NewPhone sql.NullString `db:"new_phone"`
+	}{}
+	err = tx.Select(&rows, `
+        SELECT id, old_phone, new_phone
+        FROM user_change_logs
+        WHERE split_part(old_phone, ':', 1) <> $1 OR split_part(new_phone, ':', 1) <> $1
+        ORDER BY id
+        LIMIT $2
+        FOR UPDATE SKIP LOCKED
+    `, fieldcrypt.Default.ActiveKeyID(), limit)
+	if err != nil {
+		return 0, fmt.Errorf("query user change logs failed: %w", err)
+	}
+	reseal := func(v sql.NullString) (sql.NullString, error) {
+		if !v.Valid {
+			return v, nil
+		}
+		phone, err := openPhone(v.String)
+		if err != nil {
+			return v, err
+		}
+		return sealOptionalPhone(phone)
+	}
+	for _, r := range rows {
+		oldEnc, err := reseal(r.OldPhone)
+		if err != nil {
+			return 0, fmt.Errorf("user change log %d: %w", r.ID, err)
+		}
+		newEnc, err := reseal(r.NewPhone)
+		if err != nil {
+			return 0, fmt.Errorf("user change log %d: %w", r.ID, err)
+		}
+		if _, err := tx.Exec(`UPDATE user_change_logs SET old_phone = $2, new_phone = $3 WHERE id = $1`,
+			r.ID, oldEnc, newEnc); err != nil {
+			return 0, fmt.Errorf("update user change log %d failed: %w", r.ID, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("transaction commit failed: %w", err)
+	}
+	return len(rows), nil
+}
+
+// ReencryptShipmentPhones 加密迁移与密钥轮换：把寄件订单中仍为明文、或不是用当前密钥加密的寄件人 / 收件人手机号
+// 用当前密钥重新加密，最多处理 limit 行，返回处理的行数。删除个人信息后的 '-' 保持不变。不修改 updated_at
+func ReencryptShipmentPhones(limit int) (int, error) {
+	tx, err := DB.Beginx()
+	if err != nil {
+		return 0, fmt.Errorf("transaction begin failed: %w", err)
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec(`SELECT set_config('app.keep_updated_at', 'on', true)`); err != nil {
+		return 0, err
+	}
+
+	rows := []struct {
+		ID             int64  `db:"id"`
+		SenderPhone    string `db:"sender_phone"`
+		RecipientPhone string `db:"recipient_phone"`
+	}{}
+	err = tx.Select(&rows, `
+        SELECT id, sender_phone, recipient_phone
+        FROM shipments
+        WHERE (sender_phone <> '-' AND split_part(sender_phone, ':', 1) <> $1)
+           OR (recipient_phone <> '-' AND split_part(recipient_phone, ':', 1) <> $1)
+        ORDER BY id
+        LIMIT $2
+        FOR UPDATE SKIP LOCKED
+    `, fieldcrypt.Default.ActiveKeyID(), limit)
+	if err != nil {
+		return 0, fmt.Errorf("query shipment phones failed: %w", err)
+	}
+	reseal := func(v string) (string, error) {
+		if v == "-" {
+			return v, nil
+		}
+		phone, err := openPhone(v)
+		if err != nil {
+			return "", err
+		}
+		enc, _, err := sealPhone(phone)
+		return enc, err
+	}
+	for _, r := range rows {
+		sender, err := reseal(r.SenderPhone)
+		if err != nil {
+			return 0, fmt.Errorf("shipment %d: %w", r.ID, err)
+		}
+		recipient, err := reseal(r.RecipientPhone)
+		if err != nil {
+			return 0, fmt.Errorf("shipment %d: %w", r.ID, err)
+		}
+		if _, err := tx.Exec(`UPDATE shipments SET sender_phone = $2, recipient_phone = $3 WHERE id = $1`,
+			r.ID, sender, recipient); err != nil {
+			return 0, fmt.Errorf("update shipment %d phones failed: %w", r.ID, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("transaction commit failed: %w", err)
+	}
+	return len(rows), nil
+}
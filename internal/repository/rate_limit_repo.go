@@ -0,0 +1,51 @@
+package repository
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+)
+
+// PostgresRateLimitStore 基于 rate_limit_buckets 表的令牌桶，多副本部署时共享限流状态。
+// 实现 middleware.RateLimitStore。
+type PostgresRateLimitStore struct{}
+
+// Take 在一条 UPSERT 中完成"补充令牌 + 尝试扣减"，由行锁保证并发安全。
+// 键按 SHA-256 十六进制保存：长度固定（用户名、手机号等维度值再长也不会超出列宽），且不落库明文手机号
+func (PostgresRateLimitStore) Take(key string, ratePerSec float64, burst int) (bool, time.Duration, error) {
+	sum := sha256.Sum256([]byte(key))
+	var r struct {
+		Tokens  float64 `db:"tokens"`
+		Allowed bool    `db:"allowed"`
+	}
+	query := `
+		INSERT INTO rate_limit_buckets AS b (key, tokens, allowed, updated_at)
+		VALUES ($1, $3::float8 - 1, TRUE, NOW())
+		ON CONFLICT (key) DO UPDATE SET
+			tokens = CASE
+				WHEN LEAST($3::float8, b.tokens + EXTRACT(EPOCH FROM (NOW() - b.updated_at))::float8 * $2::float8) >= 1
+				THEN LEAST($3::float8, b.tokens + EXTRACT(EPOCH FROM (NOW() - b.updated_at))::float8 * $2::float8) - 1
+				ELSE LEAST($3::float8, b.tokens + EXTRACT(EPOCH FROM (NOW() - b.updated_at))::float8 * $2::float8)
+			END,
+			allowed = LEAST($3::float8, b.tokens + EXTRACT(EPOCH FROM (NOW() - b.updated_at))::float8 * $2::float8) >= 1,
+			updated_at = NOW()
+		RETURNING tokens, allowed
+	`
+	if err := DB.Get(&r, query, hex.EncodeToString(sum[:]), ratePerSec, float64(burst)); err != nil {
+		return false, 0, fmt.Errorf("take rate limit token failed: %w", err)
+	}
+	if r.Allowed {
+		return true, 0, nil
+	}
+	return false, time.Duration((1 - r.Tokens) / ratePerSec * float64(time.Second)), nil
+}
+
+// PurgeStaleRateLimitBuckets 清理长时间未访问的桶（此时桶早已补满，删除不影响限流结果）
+func PurgeStaleRateLimitBuckets(idle time.Duration) (int64, error) {
+	result, err := DB.Exec(`DELETE FROM rate_limit_buckets WHERE updated_at < NOW() - ($1 * INTERVAL '1 second')`, int64(idle.Seconds()))
+	if err != nil {
+		return 0, fmt.Errorf("purge rate limit buckets failed: %w", err)
+	}
+	return result.RowsAffected()
+}
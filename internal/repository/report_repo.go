@@ -0,0 +1,94 @@
+package repository
+
+import "fmt"
+
+// 报表查询逐行回调 fn，不在内存中保留整个结果集；日期参数为 YYYY-MM-DD（包含两端），按数据库时区解释
+
+// streamRows 执行查询并逐行回调，文本列转换为 string
+func streamRows(fn func(values []any) error, query string, args ...any) error {
+	rows, err := DB.Queryx(query, args...)
+	if err != nil {
+		return fmt.Errorf("report query failed: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		values, err := rows.SliceScan()
+		if err != nil {
+			return fmt.Errorf("report scan failed: %w", err)
+		}
+		for i, v := range values {
+			if b, ok := v.([]byte); ok {
+				values[i] = string(b)
+			}
+		}
+		if err := fn(values); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// StreamDailySummary 每天的入库、取件、退回、异常数量及平均滞留时长（来自每日统计汇总）
+// 列：日期、入库、取件、退回、异常、平均滞留小时
+func StreamDailySummary(from, to string, stationID int64, fn func(values []any) error) error {
+	return streamRows(fn, `
+        SELECT to_char(d::date, 'YYYY-MM-DD'),
+               COALESCE(SUM(s.inbound), 0)::int8, COALESCE(SUM(s.picked_up), 0)::int8,
+               COALESCE(SUM(s.returned), 0)::int8, COALESCE(SUM(s.exceptions), 0)::int8,
+               ROUND(SUM(s.dwell_seconds_total) / NULLIF(SUM(s.picked_up), 0) / 3600.0, 2)::float8
+        FROM generate_series($1::date, $2::date, INTERVAL '1 day') d
+        LEFT JOIN daily_parcel_stats s ON s.stat_date = d::date AND ($3 = 0 OR s.station_id = $3)
+        GROUP BY d
+        ORDER BY d
+    `, from, to, stationID)
+}
+
+// StreamCourierSummary 各快递公司在范围内的入库、取件、退回、异常数量（来自每日统计汇总，按入库量倒序）
+// 列：快递公司编码、名称、入库、取件、退回、异常、平均滞留小时
+func StreamCourierSummary(from, to string, stationID int64, fn func(values []any) error) error {
+	return streamRows(fn, `
+        SELECT c.code, c.name,
+               SUM(s.inbound)::int8, SUM(s.picked_up)::int8, SUM(s.returned)::int8, SUM(s.exceptions)::int8,
+               ROUND(SUM(s.dwell_seconds_total) / NULLIF(SUM(s.picked_up), 0) / 3600.0, 2)::float8
+        FROM daily_parcel_stats s
+        JOIN couriers c ON c.id = s.courier_id
+        WHERE s.stat_date BETWEEN $1::date AND $2::date AND ($3 = 0 OR s.station_id = $3)
+        GROUP BY c.code, c.name
+        ORDER BY SUM(s.inbound) DESC, c.code
+    `, from, to, stationID)
+}
+
+// StreamRetentionList 当前滞留包裹（普通包裹超过 days 天，生鲜/冷链超过 perishableHours 小时）
+// 列：运单号、快递公司、站点、货架、状态、生鲜、入库时间、滞留天数
+func StreamRetentionList(days, perishableHours int, stationID int64, fn func(values []any) error) error {
+	return streamRows(fn, `
+        SELECT p.tracking_number, c.name, st.name, COALESCE(s.code, ''), p.status::text,
+               CASE WHEN p.perishable THEN '是' ELSE '' END,
+               p.created_at, (EXTRACT(EPOCH FROM NOW() - p.created_at) / 86400)::int8
+        FROM parcels p
+        JOIN couriers c ON c.id = p.courier_id
+        JOIN stations st ON st.id = p.station_id
+        LEFT JOIN shelves s ON s.id = p.shelf_id
+        WHERE p.status IN ('stored', 'pending')
+          AND p.created_at < NOW() - CASE WHEN p.perishable THEN $2 * INTERVAL '1 hour' ELSE $1 * INTERVAL '1 day' END
+          AND ($3 = 0 OR p.station_id = $3)
+        ORDER BY p.created_at ASC
+    `, days, perishableHours, stationID)
+}
+
+// StreamExceptionList 范围内被标记为异常的包裹及其当前状态
+// 列：运单号、快递公司、站点、标记时间、操作人、当前状态
+func StreamExceptionList(from, to string, stationID int64, fn func(values []any) error) error {
+	return streamRows(fn, `
+        SELECT p.tracking_number, c.name, st.name, l.created_at, COALESCE(l.operator, ''), p.status::text
+        FROM parcel_audit_logs l
+        JOIN parcels p ON p.id = l.parcel_id
+        JOIN couriers c ON c.id = p.courier_id
+        JOIN stations st ON st.id = p.station_id
+        WHERE l.new_status = 'exception' AND l.old_status IS DISTINCT FROM l.new_status
+          AND l.created_at >= $1::date AND l.created_at < $2::date + 1
+          AND ($3 = 0 OR p.station_id = $3)
+        ORDER BY l.created_at ASC, l.id ASC
+    `, from, to, stationID)
+}
@@ -0,0 +1,332 @@
+package repository
+
+import (
+	"campus-logistics/internal/model"
+	"database/sql"
+	"fmt"
+
+	"github.com/lib/pq"
+)
+
+// returnableStatuses 可以退回快递公司的包裹状态（仍在驿站货架上）
+var returnableStatuses = pq.Array([]string{"stored", "pending", "exception"})
+
+// returnBatchColumns 退件批次查询列，b 为 return_batches
+const returnBatchColumns = `b.id, c.code AS courier_code, st.code AS station_code, st.name AS station_name,
+		b.status, b.source, b.created_by,
+		(SELECT COUNT(*) FROM return_batch_items i WHERE i.batch_id = b.id) AS item_count,
+		(SELECT COUNT(*) FROM return_batch_items i WHERE i.batch_id = b.id AND i.handed_over_at IS NOT NULL) AS handed_over,
+		b.created_at, b.closed_at`
+
+const returnBatchFrom = `
+		FROM return_batches b
+		JOIN couriers c ON c.id = b.courier_id
+		JOIN stations st ON st.id = b.station_id`
+
+// CreateReturnBatch 为指定快递公司与站点创建退件批次。
+// 运单号须属于该快递公司与站点、仍在架上且不在其他未交接的批次中；
+// 否则返回不符合条件的运单号与 ErrConflict，不创建批次
+func CreateReturnBatch(courierID, stationID int64, trackingNums []string, createdBy string) (int64, []string, error) {
+	tx, err := DB.Beginx()
+	if err != nil {
+		return 0, nil, fmt.Errorf("transaction begin failed: %w", err)
+	}
+	defer tx.Rollback()
+
+	var parcels []struct {
+		ID             int64  `db:"id"`
+		TrackingNumber string `db:"tracking_number"`
+	}
+	err = tx.Select(&parcels, `
+        SELECT p.id, p.tracking_number
+        FROM parcels p
+        WHERE p.tracking_number = ANY($1)
+          AND p.courier_id = $2
+          AND p.station_id = $3
+          AND p.status::text = ANY($4)
+          AND NOT EXISTS (
+              SELECT 1 FROM return_batch_items i
+              WHERE i.parcel_id = p.id AND i.handed_over_at IS NULL
+          )
+        FOR UPDATE OF p
+    `, pq.Array(trackingNums), courierID, stationID, returnableStatuses)
+	if err != nil {
+		return 0, nil, fmt.Errorf("query returnable parcels failed: %w", err)
+	}
+
+	found := make(map[string]bool, len(parcels))
+	ids := make([]int64, 0, len(parcels))
+	for _, p := range parcels {
+		found[p.TrackingNumber] = true
+		ids = append(ids, p.ID)
+	}
+	var ineligible []string
+	for _, tn := range trackingNums {
+		if !found[tn] {
+			ineligible = append(ineligible, tn)
+		}
+	}
+	if len(ineligible) > 0 {
+		return 0, ineligible, ErrConflict
+	}
+
+	var batchID int64
+	err = tx.Get(&batchID, `
+        INSERT INTO return_batches (courier_id, station_id, source, created_by)
+        VALUES ($1, $2, 'manual', $3)
+        RETURNING id
+    `, courierID, stationID, createdBy)
+	if err != nil {
+		return 0, nil, fmt.Errorf("create return batch failed: %w", err)
+	}
+	_, err = tx.Exec(`
+        INSERT INTO return_batch_items (batch_id, parcel_id)
+        SELECT $1, unnest($2::bigint[])
+    `, batchID, pq.Array(ids))
+	if err != nil {
+		return 0, nil, fmt.Errorf("add return items failed: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, nil, fmt.Errorf("transaction commit failed: %w", err)
+	}
+	return batchID, nil, nil
+}
+
+// CreateRetentionReturnBatches 按滞留策略生成退件批次：滞留超过 days 天（生鲜/冷链超过 perishableHours 小时）
+// 且尚未在退件批次中的包裹，按快递公司与站点各生成一个批次。
+// stationID 为 0 表示全部站点；返回新建的批次数与包裹数
+func CreateRetentionReturnBatches(days, perishableHours int, stationID int64, createdBy string) (int, int, error) {
+	var batchIDs []int64
+	err := DB.Select(&batchIDs, `
+        WITH eligible AS (
+            SELECT p.id, p.courier_id, p.station_id
+            FROM parcels p
+            WHERE p.status IN ('stored', 'pending')
+              AND p.created_at < NOW() - CASE WHEN p.perishable THEN $2 * INTERVAL '1 hour' ELSE $1 * INTERVAL '1 day' END
+              AND ($3 = 0 OR p.station_id = $3)
+              AND NOT EXISTS (
+                  SELECT 1 FROM return_batch_items i
+                  WHERE i.parcel_id = p.id AND i.handed_over_at IS NULL
+              )
+            FOR UPDATE OF p SKIP LOCKED
+        ),
+        batches AS (
+            INSERT INTO return_batches (courier_id, station_id, source, created_by)
+            SELECT DISTINCT courier_id, station_id, 'retention', $4 FROM eligible
+            RETURNING id, courier_id, station_id
+        )
+        INSERT INTO return_batch_items (batch_id, parcel_id)
+        SELECT b.id, e.id
+        FROM eligible e
+        JOIN batches b ON b.courier_id = e.courier_id AND b.station_id = e.station_id
+        RETURNING batch_id
+    `, days, perishableHours, stationID, createdBy)
+	if err != nil {
+		return 0, 0, fmt.Errorf("create retention return batches failed: %w", err)
+	}
+
+	batches := make(map[int64]bool)
+	for _, id := range batchIDs {
+		batches[id] = true
+	}
+	return len(batches), len(batchIDs), nil
+}
+
+// ListReturnBatches 查询退件批次（按创建时间倒序）。
+// courierID / stationID 为 0 表示不限，status 为空表示全部状态
+func ListReturnBatches(courierID, stationID int64, status string, limit, offset int) ([]model.ReturnBatch, error) {
+	batches := []model.ReturnBatch{}
+	query := `SELECT ` + returnBatchColumns + returnBatchFrom + `
+		WHERE ($1 = 0 OR b.courier_id = $1)
+		  AND ($2 = 0 OR b.station_id = $2)
+		  AND ($3 = '' OR b.status = $3)
+		ORDER BY b.created_at DESC, b.id DESC
+		LIMIT $4 OFFSET $5
+	`
+	if err := DB.Select(&batches, query, courierID, stationID, status, limit, offset); err != nil {
+		return nil, fmt.Errorf("list return batches failed: %w", err)
+	}
+	return batches, nil
+}
+
+// GetReturnBatch 查询单个退件批次；courierID / stationID 非 0 时只查对应范围，不存在返回 ErrNotFound
+func GetReturnBatch(id, courierID, stationID int64) (*model.ReturnBatch, error) {
+	var b model.ReturnBatch
+	query := `SELECT ` + returnBatchColumns + returnBatchFrom + `
+		WHERE b.id = $1 AND ($2 = 0 OR b.courier_id = $2) AND ($3 = 0 OR b.station_id = $3)
+	`
+	if err := DB.Get(&b, query, id, courierID, stationID); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &b, nil
+}
+
+// ListReturnItems 查询批次中的包裹，按货架编号排序便于拣货
+func ListReturnItems(batchIDs []int64) ([]model.ReturnItem, error) {
+	items := []model.ReturnItem{}
+	if len(batchIDs) == 0 {
+		return items, nil
+	}
+	query := `
+		SELECT i.batch_id, p.tracking_number, p.status::text AS parcel_status, p.size_class::text AS size_class,
+		       COALESCE(s.code, '') AS shelf_code, i.handed_over_at, COALESCE(i.handed_over_by, '') AS handed_over_by
+		FROM return_batch_items i
+		JOIN parcels p ON p.id = i.parcel_id
+		LEFT JOIN shelves s ON s.id = p.shelf_id
+		WHERE i.batch_id = ANY($1)
+		ORDER BY i.batch_id, s.code NULLS LAST, p.tracking_number
+	`
+	if err := DB.Select(&items, query, pq.Array(batchIDs)); err != nil {
+		return nil, fmt.Errorf("list return items failed: %w", err)
+	}
+	return items, nil
+}
+
+// CancelReturnBatch 取消未完成的退件批次：移除尚未交接的包裹；已有交接记录的批次标记为 completed。
+// stationID 非 0 时只能取消本站点的批次；不存在返回 ErrNotFound，已结束返回 ErrBatchClosed
+func CancelReturnBatch(id, stationID int64) error {
+	tx, err := DB.Beginx()
+	if err != nil {
+		return fmt.Errorf("transaction begin failed: %w", err)
+	}
+	defer tx.Rollback()
+
+	var status string
+	err = tx.Get(&status, `
+        SELECT status FROM return_batches
+        WHERE id = $1 AND ($2 = 0 OR station_id = $2)
+        FOR UPDATE
+    `, id, stationID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return ErrNotFound
+		}
+		return fmt.Errorf("query return batch failed: %w", err)
+	}
+	if status != "open" {
+		return ErrBatchClosed
+	}
+
+	if _, err := tx.Exec(`DELETE FROM return_batch_items WHERE batch_id = $1 AND handed_over_at IS NULL`, id); err != nil {
+		return fmt.Errorf("remove return items failed: %w", err)
+	}
+	_, err = tx.Exec(`
+        UPDATE return_batches
+        SET status = CASE WHEN EXISTS (SELECT 1 FROM return_batch_items WHERE batch_id = $1)
+                          THEN 'completed' ELSE 'cancelled' END,
+            closed_at = NOW()
+        WHERE id = $1
+    `, id)
+	if err != nil {
+		return fmt.Errorf("close return batch failed: %w", err)
+	}
+	return tx.Commit()
+}
+
+// HandoverReturnParcel 快递员扫码确认交接批次中的一个包裹：包裹状态变为 returned 并释放货架，
+// 审计日志记录动作 RETURN_HANDOVER 与操作人 operator（快递公司及工作人员）。
+// 批次中剩余包裹均已交接（或已不在架上）时批次自动完成。
+// 批次不存在返回 ErrNotFound，批次已结束返回 ErrBatchClosed，
+// 包裹不在批次中返回 ErrParcelNotInBatch，已交接或已不在架上返回 ErrConflict
+func HandoverReturnParcel(batchID, courierID int64, trackingNum, staff, operator string) error {
+	tx, err := DB.Beginx()
+	if err != nil {
+		return fmt.Errorf("transaction begin failed: %w", err)
+	}
+	defer tx.Rollback()
+
+	// 锁住批次，同一批次的交接串行执行，保证完成判断准确
+	var status string
+	err = tx.Get(&status, `SELECT status FROM return_batches WHERE id = $1 AND courier_id = $2 FOR UPDATE`, batchID, courierID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return ErrNotFound
+		}
+		return fmt.Errorf("query return batch failed: %w", err)
+	}
+	if status != "open" {
+		return ErrBatchClosed
+	}
+
+	var item struct {
+		ParcelID int64 `db:"parcel_id"`
+		Handed   bool  `db:"handed"`
+	}
+	err = tx.Get(&item, `
+        SELECT i.parcel_id, i.handed_over_at IS NOT NULL AS handed
+        FROM return_batch_items i
+        JOIN parcels p ON p.id = i.parcel_id
+        WHERE i.batch_id = $1 AND p.tracking_number = $2
+    `, batchID, trackingNum)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return ErrParcelNotInBatch
+		}
+		return fmt.Errorf("query return item failed: %w", err)
+	}
+	if item.Handed {
+		return ErrConflict
+	}
+
+	audit := auditContext{Operator: operator, Action: "RETURN_HANDOVER"}
+	if err := audit.apply(tx); err != nil {
+		return err
+	}
+
+	var shelfID sql.NullInt64
+	err = tx.Get(&shelfID, `
+        UPDATE parcels
+        SET status = 'returned'
+        WHERE id = $1 AND status::text = ANY($2)
+        RETURNING shelf_id
+    `, item.ParcelID, returnableStatuses)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return ErrConflict
+		}
+		return fmt.Errorf("update parcel status failed: %w", err)
+	}
+
+	if shelfID.Valid {
+		_, err = tx.Exec(`
+            UPDATE shelves
+            SET current_load = current_load - 1, updated_at = NOW()
+            WHERE id = $1 AND current_load > 0
+        `, shelfID.Int64)
+		if err != nil {
+			return fmt.Errorf("update shelf load failed: %w", err)
+		}
+	}
+
+	_, err = tx.Exec(`
+        UPDATE return_batch_items
+        SET handed_over_at = NOW(), handed_over_by = $3
+        WHERE batch_id = $1 AND parcel_id = $2
+    `, batchID, item.ParcelID, staff)
+	if err != nil {
+		return fmt.Errorf("update return item failed: %w", err)
+	}
+
+	_, err = tx.Exec(`
+        UPDATE return_batches b
+        SET status = 'completed', closed_at = NOW()
+        WHERE b.id = $1 AND NOT EXISTS (
+            SELECT 1
+            FROM return_batch_items i
+            JOIN parcels p ON p.id = i.parcel_id
+            WHERE i.batch_id = b.id AND i.handed_over_at IS NULL AND p.status::text = ANY($2)
+        )
+    `, batchID, returnableStatuses)
+	if err != nil {
+		return fmt.Errorf("complete return batch failed: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("transaction commit failed: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,31 @@
+package repository
+
+import "database/sql"
+
+// 系统设置键
+const (
+	SettingAdminMFARequired = "admin_mfa_required"
+)
+
+// GetSetting 读取系统设置；不存在时返回 ErrNotFound
+func GetSetting(key string) (string, error) {
+	var v string
+	if err := DB.Get(&v, `SELECT value FROM system_settings WHERE key = $1`, key); err != nil {
+		if err == sql.ErrNoRows {
+			return "", ErrNotFound
+		}
+		return "", err
+	}
+	return v, nil
+}
+
+// UpsertSetting 写入系统设置并记录修改人
+func UpsertSetting(key, value, updatedBy string) error {
+	_, err := DB.Exec(`
+		INSERT INTO system_settings (key, value, updated_by, updated_at)
+		VALUES ($1, $2, $3, NOW())
+		ON CONFLICT (key) DO UPDATE
+		SET value = EXCLUDED.value, updated_by = EXCLUDED.updated_by, updated_at = NOW()
+	`, key, value, updatedBy)
+	return err
+}
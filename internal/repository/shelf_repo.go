@@ -6,28 +6,66 @@ import (
 	"fmt"
 )
 
-func ListShelves(limit, offset int) ([]model.Shelf, error) {
+// shelfColumns 货架查询列（含可存放的最大尺寸与温控类型）
+const shelfColumns = `id, station_id, zone, code, capacity, current_load, max_size::text AS max_size, temperature, updated_at`
+
+// ListShelves 查询货架列表；stationID 为 0 表示全部站点
+func ListShelves(stationID int64, limit, offset int) ([]model.Shelf, error) {
 	shelves := []model.Shelf{}
 	query := `
-		SELECT id, zone, code, capacity, current_load, updated_at
+		SELECT ` + shelfColumns + `
 		FROM shelves
+		WHERE ($1 = 0 OR station_id = $1)
 		ORDER BY id ASC
-		LIMIT $1 OFFSET $2
+		LIMIT $2 OFFSET $3
 	`
-	if err := DB.Select(&shelves, query, limit, offset); err != nil {
+	if err := DB.Select(&shelves, query, stationID, limit, offset); err != nil {
 		return nil, fmt.Errorf("list shelves failed: %w", err)
 	}
 	return shelves, nil
 }
 
-func CreateShelf(zone, code string, capacity int) (*model.Shelf, error) {
+// GetShelfByCode 按编号查询货架；stationID 非 0 时只查本站点
+func GetShelfByCode(code string, stationID int64) (*model.Shelf, error) {
+	var s model.Shelf
+	query := `
+		SELECT ` + shelfColumns + `
+		FROM shelves
+		WHERE code = $1 AND ($2 = 0 OR station_id = $2)
+	`
+	if err := DB.Get(&s, query, code, stationID); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &s, nil
+}
+
+// ListShelvesByZone 查询某个区域的全部货架（按编号排序，用于打印标签）；stationID 为 0 表示全部站点
+func ListShelvesByZone(stationID int64, zone string) ([]model.Shelf, error) {
+	shelves := []model.Shelf{}
+	query := `
+		SELECT ` + shelfColumns + `
+		FROM shelves
+		WHERE zone = $1 AND ($2 = 0 OR station_id = $2)
+		ORDER BY code ASC
+	`
+	if err := DB.Select(&shelves, query, zone, stationID); err != nil {
+		return nil, fmt.Errorf("list shelves by zone failed: %w", err)
+	}
+	return shelves, nil
+}
+
+// CreateShelf 创建货架；maxSize 为可存放的最大尺寸等级，temperature 为 ambient / chilled
+func CreateShelf(stationID int64, zone, code string, capacity int, maxSize, temperature string) (*model.Shelf, error) {
 	var s model.Shelf
 	query := `
-		INSERT INTO shelves (zone, code, capacity)
-		VALUES ($1, $2, $3)
-		RETURNING id, zone, code, capacity, current_load, updated_at
+		INSERT INTO shelves (station_id, zone, code, capacity, max_size, temperature)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING ` + shelfColumns + `
 	`
-	if err := DB.Get(&s, query, zone, code, capacity); err != nil {
+	if err := DB.Get(&s, query, stationID, zone, code, capacity, maxSize, temperature); err != nil {
 		return nil, err
 	}
 	return &s, nil
@@ -38,10 +76,11 @@ type shelfForDelete struct {
 	CurrentLoad int   `db:"current_load"`
 }
 
-func DeleteEmptyShelfByCode(code string) error {
+// DeleteEmptyShelfByCode 删除空货架；stationID 非 0 时只能删除本站点的货架
+func DeleteEmptyShelfByCode(code string, stationID int64) error {
 	var s shelfForDelete
-	getQuery := `SELECT id, current_load FROM shelves WHERE code = $1`
-	if err := DB.Get(&s, getQuery, code); err != nil {
+	getQuery := `SELECT id, current_load FROM shelves WHERE code = $1 AND ($2 = 0 OR station_id = $2)`
+	if err := DB.Get(&s, getQuery, code, stationID); err != nil {
 		if err == sql.ErrNoRows {
 			return ErrNotFound
 		}
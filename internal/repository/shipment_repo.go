@@ -0,0 +1,273 @@
+package repository
+
+import (
+	"campus-logistics/internal/model"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/lib/pq"
+)
+
+const shippingRateColumns = `c.code AS courier_code, c.name AS courier_name, r.zone,
+		r.first_weight_grams, r.first_price_cents, r.additional_weight_grams, r.additional_price_cents, r.updated_at`
+
+// ListShippingRates 查询寄件运费表；courierCode / zone 为空表示不限
+func ListShippingRates(courierCode, zone string) ([]model.ShippingRate, error) {
+	rates := []model.ShippingRate{}
+	query := `
+		SELECT ` + shippingRateColumns + `
+		FROM shipping_rates r
+		JOIN couriers c ON c.id = r.courier_id
+		WHERE ($1 = '' OR c.code = $1) AND ($2 = '' OR r.zone = $2)
+		ORDER BY c.code, r.zone
+	`
+	if err := DB.Select(&rates, query, courierCode, zone); err != nil {
+		return nil, fmt.Errorf("list shipping rates failed: %w", err)
+	}
+	return rates, nil
+}
+
+// UpsertShippingRate 新增或覆盖快递公司在某个区域的运费
+func UpsertShippingRate(courierID int64, zone string, firstGrams, firstCents, addGrams, addCents int) error {
+	_, err := DB.Exec(`
+        INSERT INTO shipping_rates (courier_id, zone, first_weight_grams, first_price_cents, additional_weight_grams, additional_price_cents)
+        VALUES ($1, $2, $3, $4, $5, $6)
+        ON CONFLICT (courier_id, zone) DO UPDATE
+        SET first_weight_grams = EXCLUDED.first_weight_grams,
+            first_price_cents = EXCLUDED.first_price_cents,
+            additional_weight_grams = EXCLUDED.additional_weight_grams,
+            additional_price_cents = EXCLUDED.additional_price_cents,
+            updated_at = NOW()
+    `, courierID, zone, firstGrams, firstCents, addGrams, addCents)
+	if err != nil {
+		return fmt.Errorf("upsert shipping rate failed: %w", err)
+	}
+	return nil
+}
+
+// DeleteShippingRate 删除运费；不存在返回 ErrNotFound
+func DeleteShippingRate(courierID int64, zone string) error {
+	result, err := DB.Exec(`DELETE FROM shipping_rates WHERE courier_id = $1 AND zone = $2`, courierID, zone)
+	if err != nil {
+		return fmt.Errorf("delete shipping rate failed: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// shipmentColumns 寄件订单查询列，s 为 shipments
+const shipmentColumns = `s.order_no, c.code AS courier_code, c.name AS courier_name,
+		st.code AS station_code, st.name AS station_name, s.status::text AS status,
+		s.sender_name, s.sender_phone, s.recipient_name, s.recipient_phone, s.recipient_province, s.recipient_address,
+		s.zone, s.weight_grams, s.price_cents, COALESCE(s.tracking_number, '') AS tracking_number,
+		s.created_at, s.updated_at, s.dropped_off_at, s.collected_at`
+
+const shipmentFrom = `
+		FROM shipments s
+		JOIN couriers c ON c.id = s.courier_id
+		JOIN stations st ON st.id = s.station_id`
+
+// ShipmentScope 寄件订单的访问范围：学生只能访问本人的订单，快递员只能访问本公司的，站点管理员只能访问本站点的；
+// 字段为 0 表示不限
+type ShipmentScope struct {
+	UserID    int64
+	CourierID int64
+	StationID int64
+}
+
+// shipmentScopeWhere 订单号与访问范围条件，占用 $1 ~ $4
+const shipmentScopeWhere = `s.order_no = $1 AND ($2 = 0 OR s.user_id = $2) AND ($3 = 0 OR s.courier_id = $3) AND ($4 = 0 OR s.station_id = $4)`
+
+// NewShipment 创建寄件订单所需的数据，运费由 service 层报价
+type NewShipment struct {
+	UserID            int64
+	CourierID         int64
+	StationID         int64
+	SenderName        string
+	SenderPhone       string
+	RecipientName     string
+	RecipientPhone    string
+	RecipientProvince string
+	RecipientAddress  string
+	Zone              string
+	WeightGrams       int
+	PriceCents        int
+}
+
+// CreateShipment 创建寄件订单，返回订单号；审计日志记录操作人 operator
+func CreateShipment(s NewShipment, operator string) (string, error) {
+	tx, err := DB.Beginx()
+	if err != nil {
+		return "", fmt.Errorf("transaction begin failed: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := (auditContext{Operator: operator, Action: "CREATE"}).apply(tx); err != nil {
+		return "", err
+	}
+
+	senderEnc, _, err := sealPhone(s.SenderPhone)
+	if err != nil {
+		return "", err
+	}
+	recipientEnc, _, err := sealPhone(s.RecipientPhone)
+	if err != nil {
+		return "", err
+	}
+
+	var orderNo string
+	err = tx.Get(&orderNo, `
+        INSERT INTO shipments (user_id, courier_id, station_id, sender_name, sender_phone,
+                               recipient_name, recipient_phone, recipient_province, recipient_address,
+                               zone, weight_grams, price_cents)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+        RETURNING order_no
+    `, s.UserID, s.CourierID, s.StationID, s.SenderName, senderEnc,
+		s.RecipientName, recipientEnc, s.RecipientProvince, s.RecipientAddress,
+		s.Zone, s.WeightGrams, s.PriceCents)
+	if err != nil {
+		return "", fmt.Errorf("create shipment failed: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return "", fmt.Errorf("transaction commit failed: %w", err)
+	}
+	return orderNo, nil
+}
+
+// GetShipment 按订单号查询寄件订单；不存在或不在访问范围内返回 ErrNotFound
+func GetShipment(orderNo string, scope ShipmentScope) (*model.Shipment, error) {
+	var s model.Shipment
+	query := `SELECT ` + shipmentColumns + shipmentFrom + ` WHERE ` + shipmentScopeWhere
+	if err := DB.Get(&s, query, orderNo, scope.UserID, scope.CourierID, scope.StationID); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	if err := openShipmentPhones(&s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// openShipmentPhones 解密寄件人与收件人手机号
+func openShipmentPhones(s *model.Shipment) error {
+	var err error
+	if s.SenderPhone, err = openPhone(s.SenderPhone); err != nil {
+		return err
+	}
+	s.RecipientPhone, err = openPhone(s.RecipientPhone)
+	return err
+}
+
+// ListShipments 查询访问范围内的寄件订单（按创建时间倒序）；status 为空表示全部状态
+func ListShipments(scope ShipmentScope, status string, limit, offset int) ([]model.Shipment, error) {
+	shipments := []model.Shipment{}
+	query := `SELECT ` + shipmentColumns + shipmentFrom + `
+		WHERE ($1 = 0 OR s.user_id = $1) AND ($2 = 0 OR s.courier_id = $2) AND ($3 = 0 OR s.station_id = $3)
+		  AND ($4 = '' OR s.status::text = $4)
+		ORDER BY s.created_at DESC, s.id DESC
+		LIMIT $5 OFFSET $6
+	`
+	if err := DB.Select(&shipments, query, scope.UserID, scope.CourierID, scope.StationID, status, limit, offset); err != nil {
+		return nil, fmt.Errorf("list shipments failed: %w", err)
+	}
+	for i := range shipments {
+		if err := openShipmentPhones(&shipments[i]); err != nil {
+			return nil, err
+		}
+	}
+	return shipments, nil
+}
+
+// ListShipmentEvents 寄件订单的审计记录（按时间升序）
+func ListShipmentEvents(orderNo string) ([]model.ShipmentEvent, error) {
+	events := []model.ShipmentEvent{}
+	query := `
+		SELECT l.action, l.old_status::text AS old_status, l.new_status::text AS new_status,
+		       COALESCE(l.operator, '') AS operator, l.created_at
+		FROM shipment_audit_logs l
+		JOIN shipments s ON s.id = l.shipment_id
+		WHERE s.order_no = $1
+		ORDER BY l.created_at ASC, l.id ASC
+	`
+	if err := DB.Select(&events, query, orderNo); err != nil {
+		return nil, fmt.Errorf("list shipment events failed: %w", err)
+	}
+	return events, nil
+}
+
+// CancelShipment 取消尚未交寄的订单
+func CancelShipment(orderNo string, scope ShipmentScope, operator string) error {
+	return updateShipment(auditContext{Operator: operator, Action: "CANCEL"}, orderNo, scope, `
+        UPDATE shipments s
+        SET status = 'cancelled'
+        WHERE `+shipmentScopeWhere+` AND s.status = 'created'
+    `)
+}
+
+// DropOffShipment 驿站收件：按称重结果更新重量与运费
+func DropOffShipment(orderNo string, scope ShipmentScope, weightGrams, priceCents int, operator string) error {
+	return updateShipment(auditContext{Operator: operator, Action: "DROP_OFF"}, orderNo, scope, `
+        UPDATE shipments s
+        SET status = 'dropped_off', weight_grams = $5, price_cents = $6, dropped_off_at = NOW()
+        WHERE `+shipmentScopeWhere+` AND s.status = 'created'
+    `, weightGrams, priceCents)
+}
+
+// CollectShipment 快递员揽收并登记运单号；运单号已被其他订单使用返回 ErrDuplicate
+func CollectShipment(orderNo string, scope ShipmentScope, trackingNum, operator string) error {
+	err := updateShipment(auditContext{Operator: operator, Action: "COLLECT"}, orderNo, scope, `
+        UPDATE shipments s
+        SET status = 'collected', tracking_number = $5, collected_at = NOW()
+        WHERE `+shipmentScopeWhere+` AND s.status = 'dropped_off'
+    `, trackingNum)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		return ErrDuplicate
+	}
+	return err
+}
+
+// updateShipment 在事务中执行带状态条件的更新（$1 ~ $4 为订单号与访问范围，其余参数从 $5 开始），
+// 审计触发器记录 audit 中的操作人与动作。未更新时，订单不存在返回 ErrNotFound，当前状态不允许返回 ErrConflict
+func updateShipment(audit auditContext, orderNo string, scope ShipmentScope, query string, args ...any) error {
+	tx, err := DB.Beginx()
+	if err != nil {
+		return fmt.Errorf("transaction begin failed: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := audit.apply(tx); err != nil {
+		return err
+	}
+	result, err := tx.Exec(query, append([]any{orderNo, scope.UserID, scope.CourierID, scope.StationID}, args...)...)
+	if err != nil {
+		return fmt.Errorf("update shipment failed: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		var exists bool
+		err := tx.Get(&exists, `SELECT EXISTS (SELECT 1 FROM shipments s WHERE `+shipmentScopeWhere+`)`,
+			orderNo, scope.UserID, scope.CourierID, scope.StationID)
+		if err != nil {
+			return fmt.Errorf("query shipment failed: %w", err)
+		}
+		if !exists {
+			return ErrNotFound
+		}
+		return ErrConflict
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"campus-logistics/internal/model"
+	"database/sql"
+	"fmt"
+)
+
+const stationColumns = `id, code, name, COALESCE(address, '') AS address, created_at`
+
+func ListStations() ([]model.Station, error) {
+	stations := []model.Station{}
+	query := `SELECT ` + stationColumns + ` FROM stations ORDER BY id ASC`
+	if err := DB.Select(&stations, query); err != nil {
+		return nil, fmt.Errorf("list stations failed: %w", err)
+	}
+	return stations, nil
+}
+
+func GetStationByCode(code string) (*model.Station, error) {
+	var s model.Station
+	query := `SELECT ` + stationColumns + ` FROM stations WHERE code = $1`
+	if err := DB.Get(&s, query, code); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &s, nil
+}
+
+func GetStationByID(id int64) (*model.Station, error) {
+	var s model.Station
+	query := `SELECT ` + stationColumns + ` FROM stations WHERE id = $1`
+	if err := DB.Get(&s, query, id); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &s, nil
+}
+
+func CreateStation(code, name, address string) (*model.Station, error) {
+	var s model.Station
+	query := `
+		INSERT INTO stations (code, name, address)
+		VALUES ($1, $2, $3)
+		RETURNING ` + stationColumns
+	if err := DB.Get(&s, query, code, name, sql.NullString{String: address, Valid: address != ""}); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// DeleteStationByCode 删除站点；仍有货架、包裹或管理员引用时由外键拒绝（23503）
+func DeleteStationByCode(code string) error {
+	result, err := DB.Exec(`DELETE FROM stations WHERE code = $1`, code)
+	if err != nil {
+		return err
+	}
+	return expectOneRow(result)
+}
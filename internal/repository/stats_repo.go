@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"campus-logistics/internal/model"
+	"fmt"
+)
+
+// 日期参数均为 YYYY-MM-DD，按数据库时区解释
+
+// RollupDailyStats 重算某一天的每日统计汇总（可重复执行），返回写入的汇总行数
+func RollupDailyStats(day string) (int, error) {
+	var rows int
+	if err := DB.Get(&rows, `SELECT func_rollup_daily_stats($1::date)`, day); err != nil {
+		return 0, fmt.Errorf("rollup daily stats %s failed: %w", day, err)
+	}
+	return rows, nil
+}
+
+// ListStaleRollupDays 最近 lookbackDays 天到今天之间尚未汇总、或汇总时当天尚未结束的日期（今天总是包含在内）
+func ListStaleRollupDays(lookbackDays int) ([]string, error) {
+	days := []string{}
+	query := `
+        SELECT to_char(d, 'YYYY-MM-DD')
+        FROM generate_series(CURRENT_DATE - $1::int, CURRENT_DATE, INTERVAL '1 day') d
+        LEFT JOIN daily_stats_runs r ON r.stat_date = d::date
+        WHERE r.stat_date IS NULL OR r.rolled_up_at < d::date + 1
+        ORDER BY d
+    `
+	if err := DB.Select(&days, query, lookbackDays); err != nil {
+		return nil, fmt.Errorf("list stale rollup days failed: %w", err)
+	}
+	return days, nil
+}
+
+// ListMissingRollupDays [from, to] 中从未汇总过的日期
+func ListMissingRollupDays(from, to string) ([]string, error) {
+	days := []string{}
+	query := `
+        SELECT to_char(d, 'YYYY-MM-DD')
+        FROM generate_series($1::date, $2::date, INTERVAL '1 day') d
+        WHERE NOT EXISTS (SELECT 1 FROM daily_stats_runs r WHERE r.stat_date = d::date)
+        ORDER BY d
+    `
+	if err := DB.Select(&days, query, from, to); err != nil {
+		return nil, fmt.Errorf("list missing rollup days failed: %w", err)
+	}
+	return days, nil
+}
+
+// GetDailyStats 查询 [from, to] 的每日统计汇总；groupBy 为 courier / zone 时按该维度拆分，为空时每天一行
+func GetDailyStats(from, to string, stationID int64, groupBy string) ([]model.DailyStat, error) {
+	stats := []model.DailyStat{}
+	query := `
+        SELECT to_char(d.stat_date, 'YYYY-MM-DD') AS stat_date,
+               CASE WHEN $4::text = 'courier' THEN c.code ELSE '' END AS courier_code,
+               CASE WHEN $4::text = 'zone' THEN d.zone ELSE '' END AS zone,
+               SUM(d.inbound) AS inbound, SUM(d.picked_up) AS picked_up,
+               SUM(d.returned) AS returned, SUM(d.exceptions) AS exceptions,
+               SUM(d.dwell_seconds_total)::float8 / NULLIF(SUM(d.picked_up), 0) / 3600 AS avg_dwell_hours,
+               SUM(d.dwell_histogram[1]) AS dwell_1, SUM(d.dwell_histogram[2]) AS dwell_2,
+               SUM(d.dwell_histogram[3]) AS dwell_3, SUM(d.dwell_histogram[4]) AS dwell_4,
+               SUM(d.dwell_histogram[5]) AS dwell_5, SUM(d.dwell_histogram[6]) AS dwell_6
+        FROM daily_parcel_stats d
+        JOIN couriers c ON c.id = d.courier_id
+        WHERE d.stat_date BETWEEN $1::date AND $2::date AND ($3 = 0 OR d.station_id = $3)
+        GROUP BY 1, 2, 3
+        ORDER BY 1, 2, 3
+    `
+	if err := DB.Select(&stats, query, from, to, stationID, groupBy); err != nil {
+		return nil, fmt.Errorf("query daily stats failed: %w", err)
+	}
+	return stats, nil
+}
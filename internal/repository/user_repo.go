@@ -0,0 +1,357 @@
+package repository
+
+import (
+	"campus-logistics/internal/model"
+	"context"
+	"crypto/subtle"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// userProfileColumns 资料查询列；phone 为密文（加密迁移前为明文），由 openProfilePhone 解密
+const userProfileColumns = `id, COALESCE(phone_enc, phone, '') AS phone, COALESCE(student_id, '') AS student_id, COALESCE(name, '') AS name,
+        COALESCE(dorm, '') AS dorm, notify_inbound, notify_retention, created_at`
+
+// GetUserProfile 查询学生资料；不存在或个人信息已删除返回 ErrNotFound
+func GetUserProfile(userID int64) (*model.UserProfile, error) {
+	return getUserProfile(DB, userID)
+}
+
+func getUserProfile(q sqlx.Queryer, userID int64) (*model.UserProfile, error) {
+	var u model.UserProfile
+	if err := sqlx.Get(q, &u, `SELECT `+userProfileColumns+` FROM users WHERE id = $1 AND erased_at IS NULL`, userID); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	if err := openProfilePhone(&u); err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
+
+func openProfilePhone(u *model.UserProfile) error {
+	phone, err := openPhone(u.Phone)
+	if err != nil {
+		return fmt.Errorf("user %d: %w", u.ID, err)
+	}
+	u.Phone = phone
+	return nil
+}
+
+// UserProfileUpdate 修改学生资料，nil 表示不修改；学号、宿舍为空字符串时清空
+type UserProfileUpdate struct {
+	Name            *string
+	StudentID       *string
+	Dorm            *string
+	NotifyInbound   *bool
+	NotifyRetention *bool
+}
+
+// UpdateUserProfile 修改学生资料；学号已被其他用户登记时返回 ErrDuplicate
+func UpdateUserProfile(userID int64, u UserProfileUpdate) (*model.UserProfile, error) {
+	var p model.UserProfile
+	err := DB.Get(&p, `
+        UPDATE users
+        SET name = COALESCE($2, name),
+            student_id = CASE WHEN $3::text IS NULL THEN student_id ELSE NULLIF($3, '') END,
+            dorm = CASE WHEN $4::text IS NULL THEN dorm ELSE NULLIF($4, '') END,
+            notify_inbound = COALESCE($5, notify_inbound),
+            notify_retention = COALESCE($6, notify_retention)
+        WHERE id = $1 AND erased_at IS NULL
+        RETURNING `+userProfileColumns,
+		userID, u.Name, u.StudentID, u.Dorm, u.NotifyInbound, u.NotifyRetention)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		return nil, ErrDuplicate
+	}
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("update user profile failed: %w", err)
+	}
+	if err := openProfilePhone(&p); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// SavePhoneChangeRequest 保存手机号变更验证码（覆盖同一用户之前的请求），新手机号加密保存
+func SavePhoneChangeRequest(userID int64, newPhone, codeHash string, expiresAt time.Time) error {
+	enc, _, err := sealPhone(newPhone)
+	if err != nil {
+		return err
+	}
+	_, err = DB.Exec(`
+        INSERT INTO phone_change_requests (user_id, new_phone, code_hash, expires_at)
+        VALUES ($1, $2, $3, $4)
+        ON CONFLICT (user_id) DO UPDATE
+        SET new_phone = EXCLUDED.new_phone, code_hash = EXCLUDED.code_hash, attempts = 0,
+            expires_at = EXCLUDED.expires_at, created_at = NOW()
+    `, userID, enc, codeHash, expiresAt)
+	if err != nil {
+		return fmt.Errorf("save phone change request failed: %w", err)
+	}
+	return nil
+}
+
+// ConfirmPhoneChange 校验验证码并把手机号改为待验证的新号码，返回更新后的资料与合并转移的包裹数。
+// 没有待验证的请求返回 ErrNotFound；已过期或失败次数达到 maxAttempts 返回 ErrExpired；
+// 验证码错误时累计失败次数并返回 ErrCodeMismatch。
+// 新号码已属于其他用户（如入库时自动创建）时先把该用户合并到当前用户；双方都已登记学号时返回 ErrConflict
+func ConfirmPhoneChange(userID int64, codeHash string, maxAttempts int) (*model.UserProfile, int, error) {
+	tx, err := DB.Beginx()
+	if err != nil {
+		return nil, 0, fmt.Errorf("transaction begin failed: %w", err)
+	}
+	defer tx.Rollback()
+
+	var req struct {
+		NewPhone string `db:"new_phone"`
+		CodeHash string `db:"code_hash"`
+		Attempts int    `db:"attempts"`
+		Active   bool   `db:"active"`
+	}
+	err = tx.Get(&req, `
+        SELECT new_phone, code_hash, attempts, expires_at > NOW() AS active
+        FROM phone_change_requests WHERE user_id = $1
+        FOR UPDATE
+    `, userID)
+	if err == sql.ErrNoRows {
+		return nil, 0, ErrNotFound
+	}
+	if err != nil {
+		return nil, 0, fmt.Errorf("query phone change request failed: %w", err)
+	}
+	if !req.Active || req.Attempts >= maxAttempts {
+		return nil, 0, ErrExpired
+	}
+	if subtle.ConstantTimeCompare([]byte(req.CodeHash), []byte(codeHash)) != 1 {
+		if _, err := tx.Exec(`UPDATE phone_change_requests SET attempts = attempts + 1 WHERE user_id = $1`, userID); err != nil {
+			return nil, 0, fmt.Errorf("update phone change request failed: %w", err)
+		}
+		if err := tx.Commit(); err != nil {
+			return nil, 0, fmt.Errorf("transaction commit failed: %w", err)
+		}
+		return nil, 0, ErrCodeMismatch
+	}
+	newPhone, err := openPhone(req.NewPhone)
+	if err != nil {
+		return nil, 0, err
+	}
+	enc, hash, err := sealPhone(newPhone)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	cur, err := getUserProfile(tx, userID)
+	if err != nil {
+		return nil, 0, err
+	}
+	oldEnc, err := sealOptionalPhone(cur.Phone)
+	if err != nil {
+		return nil, 0, err
+	}
+	operator := fmt.Sprintf("user:%d", userID)
+	moved := 0
+	var other struct {
+		ID        int64  `db:"id"`
+		StudentID string `db:"student_id"`
+	}
+	err = tx.Get(&other, `SELECT id, COALESCE(student_id, '') AS student_id FROM users WHERE phone_hash = $1 AND id <> $2`,
+		hash, userID)
+	switch {
+	case err == sql.ErrNoRows:
+	case err != nil:
+		return nil, 0, fmt.Errorf("query user failed: %w", err)
+	case other.StudentID != "" && cur.StudentID != "":
+		return nil, 0, ErrConflict
+	default:
+		if err := tx.Get(&moved, `SELECT func_merge_users($1, $2, $3, 'phone change', $4)`, other.ID, userID, operator, enc); err != nil {
+			return nil, 0, err
+		}
+	}
+
+	if _, err := tx.Exec(`UPDATE users SET phone = NULL, phone_enc = $2, phone_hash = $3 WHERE id = $1`, userID, enc, hash); err != nil {
+		return nil, 0, fmt.Errorf("update phone failed: %w", err)
+	}
+	if _, err := tx.Exec(`
+        INSERT INTO user_change_logs (action, user_id, old_phone, new_phone, parcels_moved, operator)
+        VALUES ('PHONE_CHANGE', $1, $2, $3, $4, $5)
+    `, userID, oldEnc, enc, moved, operator); err != nil {
+		return nil, 0, fmt.Errorf("insert user change log failed: %w", err)
+	}
+	if _, err := tx.Exec(`DELETE FROM phone_change_requests WHERE user_id = $1`, userID); err != nil {
+		return nil, 0, fmt.Errorf("delete phone change request failed: %w", err)
+	}
+
+	profile, err := getUserProfile(tx, userID)
+	if err != nil {
+		return nil, 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, 0, fmt.Errorf("transaction commit failed: %w", err)
+	}
+	return profile, moved, nil
+}
+
+// SearchUsers 按手机号、学号或姓名查询用户（精确匹配手机号 / 学号，姓名模糊匹配），附带包裹数量
+func SearchUsers(q string, limit int) ([]model.AdminUser, error) {
+	users := []model.AdminUser{}
+	query := `
+        SELECT ` + userProfileColumns + `,
+               (SELECT COUNT(*) FROM parcels p WHERE p.user_id = users.id) AS parcels,
+               (SELECT COUNT(*) FROM parcels p WHERE p.user_id = users.id AND p.status IN ('stored', 'pending')) AS active_parcels
+        FROM users
+        WHERE phone_hash = $3 OR student_id = $1 OR name LIKE '%' || $1 || '%'
+        ORDER BY id
+        LIMIT $2
+    `
+	if err := DB.Select(&users, query, q, limit, phoneHash(q)); err != nil {
+		return nil, fmt.Errorf("search users failed: %w", err)
+	}
+	for i := range users {
+		if err := openProfilePhone(&users[i].UserProfile); err != nil {
+			return nil, err
+		}
+	}
+	return users, nil
+}
+
+// MergeUsers 把 sourceID（手机号 sourcePhone，记入变更记录）合并到 targetID（转移包裹、代取授权、寄件订单并删除 sourceID），
+// 返回转移的包裹数
+func MergeUsers(sourceID, targetID int64, sourcePhone, operator, reason string) (int, error) {
+	enc, err := sealOptionalPhone(sourcePhone)
+	if err != nil {
+		return 0, err
+	}
+	var moved int
+	if err := DB.Get(&moved, `SELECT func_merge_users($1, $2, $3, NULLIF($4, ''), $5)`,
+		sourceID, targetID, operator, reason, enc); err != nil {
+		return 0, err
+	}
+	return moved, nil
+}
+
+// EraseUser 删除学生个人信息（见 func_erase_user），返回匿名化的包裹数。
+// source 为 request（学生申请）或 retention（超过保留期限）
+func EraseUser(userID int64, operator, source, reason string) (int, error) {
+	var n int
+	if err := DB.Get(&n, `SELECT func_erase_user($1, $2, $3, NULLIF($4, ''))`, userID, operator, source, reason); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// ListInactiveUsers 自 before 起没有任何活动（注册、修改资料、包裹入库 / 取件、寄件）且个人信息尚未删除的用户，最多 limit 个
+func ListInactiveUsers(before time.Time, limit int) ([]int64, error) {
+	ids := []int64{}
+	err := DB.Select(&ids, `
+        SELECT u.id
+        FROM users u
+        WHERE u.erased_at IS NULL
+          AND u.created_at < $1
+          AND u.updated_at < $1
+          AND NOT EXISTS (
+              SELECT 1 FROM parcels p
+              WHERE p.user_id = u.id
+                AND (p.created_at >= $1 OR p.picked_up_at >= $1 OR p.status IN ('inbound', 'stored', 'pending'))
+          )
+          AND NOT EXISTS (
+              SELECT 1 FROM shipments s
+              WHERE s.user_id = u.id
+                AND (s.updated_at >= $1 OR s.status IN ('created', 'dropped_off'))
+          )
+        ORDER BY u.id
+        LIMIT $2
+    `, before, limit)
+	if err != nil {
+		return nil, fmt.Errorf("query inactive users failed: %w", err)
+	}
+	return ids, nil
+}
+
+// ExportUserData 学生本人的全部记录：资料、包裹（含收件人快照）、发出的代取授权（含已撤销 / 过期）、寄件订单与变更记录。
+// 用户不存在或个人信息已删除返回 ErrNotFound
+func ExportUserData(userID int64) (*model.UserDataExport, error) {
+	tx, err := DB.BeginTxx(context.Background(), &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return nil, fmt.Errorf("transaction begin failed: %w", err)
+	}
+	defer tx.Rollback()
+
+	profile, err := getUserProfile(tx, userID)
+	if err != nil {
+		return nil, err
+	}
+	e := &model.UserDataExport{
+		Profile:     *profile,
+		Parcels:     []model.ExportParcel{},
+		Delegations: []model.PickupDelegation{},
+		Shipments:   []model.Shipment{},
+		ChangeLogs:  []model.UserChangeLog{},
+	}
+	if err := tx.Select(&e.Parcels, `
+        SELECT p.tracking_number, c.name AS courier_name, st.name AS station_name, p.status::text AS status,
+               COALESCE(p.recipient_name_snapshot, '') AS recipient_name,
+               COALESCE(p.recipient_phone_enc, p.recipient_phone_snapshot, p.recipient_phone_masked, '') AS recipient_phone,
+               p.created_at, p.picked_up_at
+        FROM parcels p
+        JOIN couriers c ON c.id = p.courier_id
+        JOIN stations st ON st.id = p.station_id
+        WHERE p.user_id = $1
+        ORDER BY p.created_at DESC
+    `, userID); err != nil {
+		return nil, fmt.Errorf("export parcels failed: %w", err)
+	}
+	for i := range e.Parcels {
+		if e.Parcels[i].RecipientPhone, err = openPhone(e.Parcels[i].RecipientPhone); err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Select(&e.Delegations, `SELECT `+delegationColumns+delegationFrom+`
+        WHERE d.owner_id = $1
+        ORDER BY d.created_at DESC
+    `, userID); err != nil {
+		return nil, fmt.Errorf("export delegations failed: %w", err)
+	}
+	if err := openDelegationPhones(e.Delegations); err != nil {
+		return nil, err
+	}
+	if err := tx.Select(&e.Shipments, `SELECT `+shipmentColumns+shipmentFrom+`
+        WHERE s.user_id = $1
+        ORDER BY s.created_at DESC
+    `, userID); err != nil {
+		return nil, fmt.Errorf("export shipments failed: %w", err)
+	}
+	for i := range e.Shipments {
+		if err := openShipmentPhones(&e.Shipments[i]); err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Select(&e.ChangeLogs, `
+        SELECT action, COALESCE(old_phone, '') AS old_phone, COALESCE(new_phone, '') AS new_phone, parcels_moved, created_at
+        FROM user_change_logs
+        WHERE user_id = $1
+        ORDER BY created_at DESC
+    `, userID); err != nil {
+		return nil, fmt.Errorf("export change logs failed: %w", err)
+	}
+	for i := range e.ChangeLogs {
+		if e.ChangeLogs[i].OldPhone, err = openPhone(e.ChangeLogs[i].OldPhone); err != nil {
+			return nil, err
+		}
+		if e.ChangeLogs[i].NewPhone, err = openPhone(e.ChangeLogs[i].NewPhone); err != nil {
+			return nil, err
+		}
+	}
+	e.ExportedAt = time.Now()
+	return e, nil
+}
@@ -0,0 +1,301 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"campus-logistics/internal/apperr"
+	"campus-logistics/internal/middleware"
+	"campus-logistics/internal/model"
+	"campus-logistics/internal/repository"
+
+	"github.com/spf13/viper"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	ErrAccountLocked      = errors.New("account locked")
+	ErrWeakPassword       = errors.New("password does not meet policy")
+	ErrPasswordReused     = errors.New("password was used recently")
+	ErrInvalidAdminRole   = errors.New("invalid admin role")
+	ErrLastSuperAdmin     = errors.New("cannot remove the last super admin")
+	ErrCannotModifySelf   = errors.New("cannot delete or demote yourself")
+	ErrEnvManagedAccount  = errors.New("account is managed via environment variables")
+	ErrWrongPassword      = errors.New("current password is incorrect")
+	ErrInvalidAdminParams = errors.New("invalid admin parameters")
+)
+
+// PasswordPolicy 管理员密码策略，来自 configs/config.yaml 的 admin.password_policy
+type PasswordPolicy struct {
+	MinLength  int
+	History    int
+	MaxAgeDays int
+}
+
+// LockoutPolicy 登录失败锁定策略，来自 admin.lockout
+type LockoutPolicy struct {
+	MaxFailedAttempts int
+	Duration          time.Duration
+}
+
+func loadPasswordPolicy() PasswordPolicy {
+	p := PasswordPolicy{
+		MinLength:  viper.GetInt("admin.password_policy.min_length"),
+		History:    viper.GetInt("admin.password_policy.history"),
+		MaxAgeDays: viper.GetInt("admin.password_policy.max_age_days"),
+	}
+	if p.MinLength <= 0 {
+		p.MinLength = 8
+	}
+	if p.History < 0 {
+		p.History = 0
+	}
+	if p.MaxAgeDays < 0 {
+		p.MaxAgeDays = 0
+	}
+	return p
+}
+
+func loadLockoutPolicy() LockoutPolicy {
+	p := LockoutPolicy{
+		MaxFailedAttempts: viper.GetInt("admin.lockout.max_failed_attempts"),
+		Duration:          time.Duration(viper.GetInt("admin.lockout.duration_minutes")) * time.Minute,
+	}
+	if p.MaxFailedAttempts <= 0 {
+		p.MaxFailedAttempts = 5
+	}
+	if p.Duration <= 0 {
+		p.Duration = 15 * time.Minute
+	}
+	return p
+}
+
+// validatePassword 检查长度与字符组成；bcrypt 只使用前 72 字节，超过即拒绝
+func (p PasswordPolicy) validatePassword(password string) error {
+	if utf8.RuneCountInString(password) < p.MinLength {
+		return weakPassword("at least %d characters", p.MinLength)
+	}
+	if len(password) > 72 {
+		return weakPassword("at most 72 bytes")
+	}
+	var hasLetter, hasDigit bool
+	for _, r := range password {
+		switch {
+		case r >= '0' && r <= '9':
+			hasDigit = true
+		case (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z'):
+			hasLetter = true
+		}
+	}
+	if !hasLetter || !hasDigit {
+		return weakPassword("must contain letters and digits")
+	}
+	return nil
+}
+
+// weakPassword 密码不符合策略：说明返回给客户端，errors.Is(err, ErrWeakPassword) 仍成立
+func weakPassword(format string, args ...any) error {
+	return &apperr.Error{Code: apperr.CodeWeakPassword, Detail: fmt.Sprintf(format, args...), Err: ErrWeakPassword}
+}
+
+// expired 判断密码是否超过有效期；password_changed_at 为空表示被重置，需要立即修改
+func (p PasswordPolicy) expired(a *model.Admin) bool {
+	if a.PasswordChangedAt == nil {
+		return true
+	}
+	if p.MaxAgeDays == 0 {
+		return false
+	}
+	return time.Since(*a.PasswordChangedAt) > time.Duration(p.MaxAgeDays)*24*time.Hour
+}
+
+func hashPassword(password string) (string, error) {
+	h, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(h), nil
+}
+
+// needsRehash 明文种子或 cost 低于当前默认值的 bcrypt 哈希都需要升级
+func needsRehash(storedHash string) bool {
+	if !isBcryptHash(storedHash) {
+		return true
+	}
+	cost, err := bcrypt.Cost([]byte(storedHash))
+	if err != nil {
+		return true
+	}
+	return cost < bcrypt.DefaultCost
+}
+
+func isBcryptHash(storedHash string) bool {
+	return strings.HasPrefix(storedHash, "$2a$") || strings.HasPrefix(storedHash, "$2b$") || strings.HasPrefix(storedHash, "$2y$")
+}
+
+func validAdminRole(role string) bool {
+	return role == middleware.AdminRoleSuper || role == middleware.AdminRoleOperator
+}
+
+// ListAdminAccounts 超级管理员查看管理员列表（含最近登录时间、失败次数、锁定状态）
+func ListAdminAccounts(page, pageSize int) ([]model.Admin, error) {
+	if page < 1 {
+		page = 1
+	}
+	if pageSize <= 0 || pageSize > 100 {
+		pageSize = 20
+	}
+	offset := (page - 1) * pageSize
+	return repository.ListAdmins(pageSize, offset)
+}
+
+// CreateAdminAccount 创建管理员，密码需满足策略；新账号首次登录必须修改密码。
+// stationCode 为空表示可管理全部站点，否则为该站点的站点管理员。
+func CreateAdminAccount(username, password, role, stationCode string) (*model.Admin, error) {
+	username = strings.TrimSpace(username)
+	if username == "" || len(username) > 50 {
+		return nil, ErrInvalidAdminParams
+	}
+	if role == "" {
+		role = middleware.AdminRoleOperator
+	}
+	if !validAdminRole(role) {
+		return nil, ErrInvalidAdminRole
+	}
+	if err := loadPasswordPolicy().validatePassword(password); err != nil {
+		return nil, err
+	}
+	stationID, err := lookupStationID(stationCode)
+	if err != nil {
+		return nil, err
+	}
+
+	hash, err := hashPassword(password)
+	if err != nil {
+		return nil, err
+	}
+	return repository.CreateAdmin(username, hash, role, stationID)
+}
+
+// UpdateAdminAccountRequest 超级管理员修改他人账号；字段为空表示不修改
+// StationCode 为 nil 表示不修改，空字符串表示改为全部站点
+type UpdateAdminAccountRequest struct {
+	Role          string  `json:"role"`
+	StationCode   *string `json:"station_code"`
+	Unlock        bool    `json:"unlock"`
+	ResetPassword string  `json:"reset_password"`
+}
+
+func UpdateAdminAccount(actorID int64, username string, req UpdateAdminAccountRequest) (*model.Admin, error) {
+	a, err := repository.GetAdminByUsername(strings.TrimSpace(username))
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Role != "" && req.Role != a.Role {
+		if !validAdminRole(req.Role) {
+			return nil, ErrInvalidAdminRole
+		}
+		if a.ID == actorID {
+			return nil, ErrCannotModifySelf
+		}
+		if err := repository.UpdateAdminRole(a.ID, req.Role, middleware.AdminRoleSuper); err != nil {
+			if errors.Is(err, repository.ErrLastOfRole) {
+				return nil, ErrLastSuperAdmin
+			}
+			return nil, err
+		}
+	}
+
+	if req.StationCode != nil {
+		stationID, err := lookupStationID(*req.StationCode)
+		if err != nil {
+			return nil, err
+		}
+		if err := repository.UpdateAdminStation(a.ID, stationID); err != nil {
+			return nil, err
+		}
+	}
+
+	if req.Unlock {
+		if err := repository.UnlockAdmin(a.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	if req.ResetPassword != "" {
+		policy := loadPasswordPolicy()
+		if err := policy.validatePassword(req.ResetPassword); err != nil {
+			return nil, err
+		}
+		hash, err := hashPassword(req.ResetPassword)
+		if err != nil {
+			return nil, err
+		}
+		// 重置的密码视为临时密码，强制对方下次登录时修改
+		if err := repository.UpdateAdminPassword(a.ID, hash, policy.History, true); err != nil {
+			return nil, err
+		}
+	}
+
+	return repository.GetAdminByID(a.ID)
+}
+
+func DeleteAdminAccount(actorID int64, username string) error {
+	a, err := repository.GetAdminByUsername(strings.TrimSpace(username))
+	if err != nil {
+		return err
+	}
+	if a.ID == actorID {
+		return ErrCannotModifySelf
+	}
+	err = repository.DeleteAdmin(a.ID, middleware.AdminRoleSuper)
+	if errors.Is(err, repository.ErrLastOfRole) {
+		return ErrLastSuperAdmin
+	}
+	return err
+}
+
+// ChangeAdminPassword 管理员修改自己的密码：校验旧密码、策略、最近 N 次历史
+func ChangeAdminPassword(adminID int64, oldPassword, newPassword string) error {
+	if adminID == 0 {
+		return ErrEnvManagedAccount
+	}
+
+	a, err := repository.GetAdminByID(adminID)
+	if err != nil {
+		return err
+	}
+	if !verifyPassword(a.PasswordHash, oldPassword) {
+		return ErrWrongPassword
+	}
+
+	policy := loadPasswordPolicy()
+	if err := policy.validatePassword(newPassword); err != nil {
+		return err
+	}
+
+	if verifyPassword(a.PasswordHash, newPassword) {
+		return ErrPasswordReused
+	}
+	if policy.History > 0 {
+		history, err := repository.GetAdminPasswordHistory(adminID, policy.History)
+		if err != nil {
+			return err
+		}
+		for _, h := range history {
+			if verifyPassword(h, newPassword) {
+				return ErrPasswordReused
+			}
+		}
+	}
+
+	hash, err := hashPassword(newPassword)
+	if err != nil {
+		return err
+	}
+	return repository.UpdateAdminPassword(adminID, hash, policy.History, false)
+}
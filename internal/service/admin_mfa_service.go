@@ -0,0 +1,318 @@
+package service
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"campus-logistics/internal/middleware"
+	"campus-logistics/internal/model"
+	"campus-logistics/internal/repository"
+
+	"github.com/spf13/viper"
+)
+
+var (
+	ErrMFAAlreadyEnabled = errors.New("mfa already enabled")
+	ErrMFANotEnabled     = errors.New("mfa not enabled")
+	ErrMFAEnforced       = errors.New("mfa is required for all admins")
+	ErrInvalidMFACode    = errors.New("invalid mfa code")
+	ErrInvalidMFAToken   = errors.New("invalid or expired mfa token")
+)
+
+// mfaTokenTTL 中间态 token 的有效期：足够输入验证码，又不至于长期有效
+const mfaTokenTTL = 5 * time.Minute
+
+// MFAEnrollment 绑定第一步的返回：密钥与可渲染为二维码的 otpauth URI
+type MFAEnrollment struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"`
+}
+
+type MFAStatus struct {
+	Enabled                bool       `json:"enabled"`
+	EnrolledAt             *time.Time `json:"enrolled_at"`
+	RecoveryCodesRemaining int        `json:"recovery_codes_remaining"`
+	Required               bool       `json:"required"`
+}
+
+type SecuritySettings struct {
+	AdminMFARequired bool `json:"admin_mfa_required"`
+}
+
+func mfaIssuer() string {
+	if s := strings.TrimSpace(viper.GetString("admin.mfa.issuer")); s != "" {
+		return s
+	}
+	return "Campus Logistics"
+}
+
+// IsAdminMFARequired 读取系统设置 admin_mfa_required；未配置视为不强制
+func IsAdminMFARequired() (bool, error) {
+	v, err := repository.GetSetting(repository.SettingAdminMFARequired)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	required, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, nil
+	}
+	return required, nil
+}
+
+func GetSecuritySettings() (*SecuritySettings, error) {
+	required, err := IsAdminMFARequired()
+	if err != nil {
+		return nil, err
+	}
+	return &SecuritySettings{AdminMFARequired: required}, nil
+}
+
+// UpdateSecuritySettings 超级管理员开启/关闭"所有管理员必须启用 MFA"
+func UpdateSecuritySettings(settings SecuritySettings, updatedBy string) error {
+	return repository.UpsertSetting(repository.SettingAdminMFARequired, strconv.FormatBool(settings.AdminMFARequired), updatedBy)
+}
+
+func GetMFAStatus(adminID int64) (*MFAStatus, error) {
+	if adminID == 0 {
+		return nil, ErrEnvManagedAccount
+	}
+	a, err := repository.GetAdminByID(adminID)
+	if err != nil {
+		return nil, err
+	}
+	required, err := IsAdminMFARequired()
+	if err != nil {
+		return nil, err
+	}
+	remaining := 0
+	if a.MFAEnabled {
+		if remaining, err = repository.CountUnusedAdminRecoveryCodes(adminID); err != nil {
+			return nil, err
+		}
+	}
+	return &MFAStatus{
+		Enabled:                a.MFAEnabled,
+		EnrolledAt:             a.MFAEnrolledAt,
+		RecoveryCodesRemaining: remaining,
+		Required:               required,
+	}, nil
+}
+
+// BeginMFAEnrollment 生成新的待激活密钥；重复调用会覆盖之前未激活的密钥
+func BeginMFAEnrollment(adminID int64) (*MFAEnrollment, error) {
+	if adminID == 0 {
+		return nil, ErrEnvManagedAccount
+	}
+	a, err := repository.GetAdminByID(adminID)
+	if err != nil {
+		return nil, err
+	}
+	if a.MFAEnabled {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	secret, err := generateTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+	if err := repository.SetAdminPendingTOTPSecret(adminID, secret); err != nil {
+		if errors.Is(err, repository.ErrConflict) {
+			return nil, ErrMFAAlreadyEnabled
+		}
+		return nil, err
+	}
+
+	return &MFAEnrollment{
+		Secret:          secret,
+		ProvisioningURI: totpProvisioningURI(mfaIssuer(), a.Username, secret),
+	}, nil
+}
+
+// ActivateMFA 用验证器 App 上的首个验证码确认绑定，返回只展示一次的恢复码
+func ActivateMFA(adminID int64, code string) ([]string, error) {
+	if adminID == 0 {
+		return nil, ErrEnvManagedAccount
+	}
+	a, err := repository.GetAdminByID(adminID)
+	if err != nil {
+		return nil, err
+	}
+	if a.MFAEnabled {
+		return nil, ErrMFAAlreadyEnabled
+	}
+	if a.TOTPSecret == nil {
+		return nil, ErrMFANotEnabled
+	}
+
+	step, ok := verifyTOTP(*a.TOTPSecret, code, time.Now())
+	if !ok {
+		return nil, ErrInvalidMFACode
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := repository.ActivateAdminMFA(adminID, step, hashes); err != nil {
+		if errors.Is(err, repository.ErrConflict) {
+			return nil, ErrMFAAlreadyEnabled
+		}
+		return nil, err
+	}
+	return codes, nil
+}
+
+// DisableMFA 关闭 MFA，需要当前密码以及当前验证码或一次性恢复码（仅凭被盗的会话与密码不能关闭）；
+// 系统强制 MFA 时不允许关闭
+func DisableMFA(adminID int64, password, code, recoveryCode string) error {
+	if adminID == 0 {
+		return ErrEnvManagedAccount
+	}
+	required, err := IsAdminMFARequired()
+	if err != nil {
+		return err
+	}
+	if required {
+		return ErrMFAEnforced
+	}
+
+	a, err := repository.GetAdminByID(adminID)
+	if err != nil {
+		return err
+	}
+	if !a.MFAEnabled {
+		return ErrMFANotEnabled
+	}
+	if !verifyPassword(a.PasswordHash, password) {
+		return ErrWrongPassword
+	}
+	if a.TOTPSecret == nil {
+		return ErrMFANotEnabled
+	}
+	if err := consumeSecondFactor(a, code, recoveryCode); err != nil {
+		return err
+	}
+	return repository.DisableAdminMFA(adminID)
+}
+
+// RegenerateRecoveryCodes 使用当前验证码换取一组新的恢复码，旧的全部作废
+func RegenerateRecoveryCodes(adminID int64, code string) ([]string, error) {
+	if adminID == 0 {
+		return nil, ErrEnvManagedAccount
+	}
+	a, err := repository.GetAdminByID(adminID)
+	if err != nil {
+		return nil, err
+	}
+	if !a.MFAEnabled || a.TOTPSecret == nil {
+		return nil, ErrMFANotEnabled
+	}
+	if err := consumeTOTP(a, code); err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := repository.ReplaceAdminRecoveryCodes(adminID, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// CompleteAdminMFA 登录第二步：校验中间态 token 与验证码（或恢复码），签发正式 token。
+// 失败计入与密码相同的连续失败次数。
+func CompleteAdminMFA(mfaToken, code, recoveryCode string) (*TokenResponse, error) {
+	claims, err := middleware.ParseToken(strings.TrimSpace(mfaToken))
+	if err != nil || claims.Role != middleware.RoleAdminMFAPending || claims.AdminID == 0 {
+		return nil, ErrInvalidMFAToken
+	}
+
+	a, err := repository.GetAdminByID(claims.AdminID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrInvalidMFAToken
+		}
+		return nil, err
+	}
+	if a.LockedUntil != nil && a.LockedUntil.After(time.Now()) {
+		return nil, ErrAccountLocked
+	}
+	if !a.MFAEnabled || a.TOTPSecret == nil {
+		return nil, ErrInvalidMFAToken
+	}
+
+	if err := consumeSecondFactor(a, code, recoveryCode); err != nil {
+		if !errors.Is(err, ErrInvalidMFACode) {
+			return nil, err
+		}
+		lockout := loadLockoutPolicy()
+		_, lockedUntil, lerr := repository.RecordAdminLoginFailure(a.ID, lockout.MaxFailedAttempts, lockout.Duration)
+		if lerr != nil {
+			return nil, lerr
+		}
+		if lockedUntil != nil {
+			return nil, ErrAccountLocked
+		}
+		return nil, ErrInvalidMFACode
+	}
+
+	_ = repository.TouchAdminLastLogin(a.ID)
+	return issueAdminToken(a, false)
+}
+
+// consumeSecondFactor 校验验证码或（未提供验证码时）一次性恢复码，两者都为空或不正确时返回 ErrInvalidMFACode
+func consumeSecondFactor(a *model.Admin, code, recoveryCode string) error {
+	switch {
+	case strings.TrimSpace(code) != "":
+		return consumeTOTP(a, code)
+	case strings.TrimSpace(recoveryCode) != "":
+		err := repository.ConsumeAdminRecoveryCode(a.ID, hashRecoveryCode(recoveryCode))
+		if errors.Is(err, repository.ErrNotFound) {
+			return ErrInvalidMFACode
+		}
+		return err
+	default:
+		return ErrInvalidMFACode
+	}
+}
+
+// consumeTOTP 校验验证码并推进已使用的时间步，同一验证码不能使用两次
+func consumeTOTP(a *model.Admin, code string) error {
+	step, ok := verifyTOTP(*a.TOTPSecret, code, time.Now())
+	if !ok || step <= a.TOTPLastStep {
+		return ErrInvalidMFACode
+	}
+	if err := repository.AdvanceAdminTOTPStep(a.ID, step); err != nil {
+		if errors.Is(err, repository.ErrConflict) {
+			return ErrInvalidMFACode
+		}
+		return err
+	}
+	return nil
+}
+
+// issueMFAChallenge 密码校验通过但需要二次验证时，签发短期中间态 token
+func issueMFAChallenge(a *model.Admin) (*TokenResponse, error) {
+	tok, err := middleware.IssueToken(middleware.Claims{
+		Role:     middleware.RoleAdminMFAPending,
+		AdminID:  a.ID,
+		Username: a.Username,
+	}, mfaTokenTTL)
+	if err != nil {
+		return nil, err
+	}
+	return &TokenResponse{
+		TokenType:   "Bearer",
+		ExpiresIn:   int64(mfaTokenTTL.Seconds()),
+		Role:        string(middleware.RoleAdmin),
+		MFARequired: true,
+		MFAToken:    tok,
+	}, nil
+}
@@ -1,21 +1,35 @@
 package service
 
 import (
-	"fmt"
+	"errors"
 
+	"github.com/spf13/viper"
+
+	"campus-logistics/internal/apperr"
 	"campus-logistics/internal/model"
 	"campus-logistics/internal/repository"
 )
 
 // GetAdminDashboardService 获取管理员仪表盘统计数据
-func GetAdminDashboardService() (*model.AdminDashboard, error) {
-	return repository.GetAdminDashboard()
+// stationID 为 0 表示全部站点
+func GetAdminDashboardService(stationID int64) (*model.AdminDashboard, error) {
+	return repository.GetAdminDashboard(stationID)
+}
+
+// perishableRetentionHours 生鲜/冷链包裹的滞留阈值，来自 retention.perishable_hours，默认 24 小时
+func perishableRetentionHours() int {
+	if h := viper.GetInt("retention.perishable_hours"); h > 0 {
+		return h
+	}
+	return 24
 }
 
 // GetRetentionParcelsService 查询滞留包裹列表
-// days: 滞留天数阈值
+// days: 滞留天数阈值（生鲜/冷链包裹按 perishableRetentionHours 提前列入）
+// stationID: 站点范围，0 表示全部站点
 // page/pageSize: 分页参数
-func GetRetentionParcelsService(days, page, pageSize int) ([]model.ParcelViewStudent, error) {
+// 收件人手机号按 privacy 配置脱敏
+func GetRetentionParcelsService(days int, stationID int64, page, pageSize int) ([]model.RetentionParcel, error) {
 	if days <= 0 {
 		days = 7
 	}
@@ -27,18 +41,39 @@ func GetRetentionParcelsService(days, page, pageSize int) ([]model.ParcelViewStu
 	}
 
 	offset := (page - 1) * pageSize
-	return repository.GetRetentionParcels(days, pageSize, offset)
+	parcels, err := repository.GetRetentionParcels(days, perishableRetentionHours(), stationID, pageSize, offset)
+	if err != nil {
+		return nil, err
+	}
+	for i := range parcels {
+		parcels[i].RecipientPhone = maskPhone(parcels[i].RecipientPhone)
+	}
+	return parcels, nil
+}
+
+// parcelStatusTransitions 管理员可执行的状态流转：目标状态 -> 允许的当前状态。
+// picked_up 只能通过取件接口产生，已取走的包裹不允许再变更。
+var parcelStatusTransitions = map[string][]string{
+	"stored":    {"inbound", "pending", "exception"},
+	"pending":   {"stored", "exception"},
+	"exception": {"inbound", "stored", "pending"},
+	"returned":  {"stored", "pending", "exception"},
 }
 
 // UpdateParcelStatusService 管理员更新包裹状态
-// 这里只允许部分业务状态，防止非法值传入
-func UpdateParcelStatusService(trackingNum, newStatus string) error {
-	switch newStatus {
-	case "pending", "returned", "exception", "stored":
-		// 合法状态，继续
-	default:
-		return fmt.Errorf("invalid status: %s", newStatus)
+// 这里只允许部分业务状态，防止非法值传入；stationID 非 0 时只能修改本站点包裹
+func UpdateParcelStatusService(trackingNum, newStatus string, stationID int64) error {
+	from, ok := parcelStatusTransitions[newStatus]
+	if !ok {
+		return apperr.Newf(apperr.CodeInvalidStatus, "%s", newStatus)
 	}
 
-	return repository.UpdateParcelStatus(trackingNum, newStatus)
+	err := repository.UpdateParcelStatus(trackingNum, newStatus, from, stationID)
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		return apperr.New(apperr.CodeParcelNotFound)
+	case errors.Is(err, repository.ErrConflict):
+		return apperr.Newf(apperr.CodeIllegalTransition, "-> %s", newStatus)
+	}
+	return err
 }
@@ -0,0 +1,157 @@
+package service
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"campus-logistics/internal/apperr"
+	"campus-logistics/internal/model"
+	"campus-logistics/internal/repository"
+)
+
+// analyticsMaxDays 各分桶粒度允许查询的最大天数，避免生成过多时间桶
+var analyticsMaxDays = map[string]int{
+	"hour": 7,
+	"day":  366,
+}
+
+// analyticsCache 运营分析结果缓存，按站点、时间范围与粒度区分；多副本部署时各副本独立缓存
+var (
+	analyticsCache   = map[string]analyticsEntry{}
+	analyticsCacheMu sync.Mutex
+)
+
+type analyticsEntry struct {
+	data      *model.Analytics
+	expiresAt time.Time
+}
+
+// analyticsCacheTTL 缓存有效期，来自 analytics.cache_ttl_seconds，未配置时默认 60 秒，配置为 0 时不缓存
+func analyticsCacheTTL() time.Duration {
+	if !viper.IsSet("analytics.cache_ttl_seconds") {
+		return time.Minute
+	}
+	return time.Duration(max(viper.GetInt("analytics.cache_ttl_seconds"), 0)) * time.Second
+}
+
+// parseAnalyticsRange 解析日期范围（YYYY-MM-DD，包含结束日期），返回 [from, to)；
+// 默认结束日期为今天，开始日期为结束日期前 6 天
+func parseAnalyticsRange(fromStr, toStr, granularity string) (time.Time, time.Time, error) {
+	maxDays, ok := analyticsMaxDays[granularity]
+	if !ok {
+		return time.Time{}, time.Time{}, apperr.Invalid("granularity", "oneof").WithParam("hour day")
+	}
+
+	now := time.Now()
+	to := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	if toStr != "" {
+		t, err := time.ParseInLocation("2006-01-02", toStr, time.Local)
+		if err != nil {
+			return time.Time{}, time.Time{}, apperr.Invalid("to", "date")
+		}
+		to = t
+	}
+	from := to.AddDate(0, 0, -6)
+	if fromStr != "" {
+		t, err := time.ParseInLocation("2006-01-02", fromStr, time.Local)
+		if err != nil {
+			return time.Time{}, time.Time{}, apperr.Invalid("from", "date")
+		}
+		from = t
+	}
+	if to.Before(from) {
+		return time.Time{}, time.Time{}, apperr.Invalid("to", "after_from")
+	}
+	to = to.AddDate(0, 0, 1)
+	if to.After(from.AddDate(0, 0, maxDays)) {
+		return time.Time{}, time.Time{}, apperr.Invalid("to", "analytics_range").WithParam(strconv.Itoa(maxDays))
+	}
+	return from, to, nil
+}
+
+// GetAnalytics 管理员运营分析
+// from/to: 日期范围（包含两端），granularity: hour / day，stationID: 0 表示全部站点
+func GetAnalytics(fromStr, toStr, granularity string, stationID int64) (*model.Analytics, error) {
+	if granularity == "" {
+		granularity = "day"
+	}
+	from, to, err := parseAnalyticsRange(fromStr, toStr, granularity)
+	if err != nil {
+		return nil, err
+	}
+
+	key := fmt.Sprintf("%d|%s|%s|%s", stationID, from.Format(time.RFC3339), to.Format(time.RFC3339), granularity)
+	ttl := analyticsCacheTTL()
+	if ttl > 0 {
+		analyticsCacheMu.Lock()
+		entry, ok := analyticsCache[key]
+		analyticsCacheMu.Unlock()
+		if ok && time.Now().Before(entry.expiresAt) {
+			return entry.data, nil
+		}
+	}
+
+	data, err := loadAnalytics(from, to, granularity, stationID)
+	if err != nil {
+		return nil, err
+	}
+
+	if ttl > 0 {
+		now := time.Now()
+		analyticsCacheMu.Lock()
+		for k, e := range analyticsCache {
+			if now.After(e.expiresAt) {
+				delete(analyticsCache, k)
+			}
+		}
+		analyticsCache[key] = analyticsEntry{data: data, expiresAt: now.Add(ttl)}
+		analyticsCacheMu.Unlock()
+	}
+	return data, nil
+}
+
+// retentionAges 滞留时长分段，按此顺序返回（无包裹的分段补 0）
+var retentionAges = []string{"0-1d", "1-3d", "3-7d", "7-14d", "14d+"}
+
+func loadAnalytics(from, to time.Time, granularity string, stationID int64) (*model.Analytics, error) {
+	data := &model.Analytics{From: from, To: to, Granularity: granularity, GeneratedAt: time.Now()}
+
+	var err error
+	if data.Throughput, err = repository.GetThroughputSeries(from, to, granularity, stationID); err != nil {
+		return nil, err
+	}
+	dwell, err := repository.GetDwellStats(from, to, stationID)
+	if err != nil {
+		return nil, err
+	}
+	data.Dwell = *dwell
+	if data.Couriers, err = repository.GetCourierVolumes(from, to, stationID); err != nil {
+		return nil, err
+	}
+	if data.ZoneOccupancy, err = repository.GetZoneOccupancySeries(from, to, granularity, stationID); err != nil {
+		return nil, err
+	}
+	if data.Heatmap, err = repository.GetPeakHourHeatmap(from, to, stationID); err != nil {
+		return nil, err
+	}
+
+	buckets, err := repository.GetRetentionAgeBuckets(stationID)
+	if err != nil {
+		return nil, err
+	}
+	byAge := make(map[string]model.RetentionBucket, len(buckets))
+	for _, b := range buckets {
+		byAge[b.Age] = b
+	}
+	data.Retention = make([]model.RetentionBucket, 0, len(retentionAges))
+	for _, age := range retentionAges {
+		b := byAge[age]
+		b.Age = age
+		data.Retention = append(data.Retention, b)
+	}
+	return data, nil
+}
@@ -0,0 +1,92 @@
+package service
+
+import (
+	"bytes"
+	"image"
+	"image/jpeg"
+	_ "image/png" // 注册 PNG 解码（签名通常为 PNG）
+	"net/http"
+)
+
+// 允许上传的图片类型：按文件内容识别，不信任客户端声明的 Content-Type
+var attachmentContentTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+}
+
+// maxAttachmentPixels 图片像素上限，防止小文件解码出超大图片耗尽内存
+// （解码后每像素约 4 字节，1200 万像素约 48MB）
+const maxAttachmentPixels = 12_000_000
+
+// inspectImage 识别图片类型并读取尺寸；不是允许的类型或无法解析时 ok 为 false
+func inspectImage(data []byte) (contentType string, width, height int, ok bool) {
+	contentType = http.DetectContentType(data)
+	if !attachmentContentTypes[contentType] {
+		return contentType, 0, 0, false
+	}
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil || cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width*cfg.Height > maxAttachmentPixels {
+		return contentType, 0, 0, false
+	}
+	return contentType, cfg.Width, cfg.Height, true
+}
+
+// makeThumbnail 生成长边不超过 maxSide 的 JPEG 缩略图；透明背景（如签名）铺白底
+func makeThumbnail(data []byte, maxSide int) ([]byte, error) {
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, downscale(src, maxSide), &jpeg.Options{Quality: 80}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// downscale 按区域平均缩小图片，使长边不超过 maxSide，同时把透明部分铺成白底；
+// 直接读取解码结果，只分配缩略图大小的内存
+func downscale(src image.Image, maxSide int) *image.RGBA {
+	b := src.Bounds()
+	sw, sh := b.Dx(), b.Dy()
+	dw, dh := sw, sh
+	if sw > maxSide || sh > maxSide {
+		dw, dh = maxSide, sh*maxSide/sw
+		if sh > sw {
+			dw, dh = sw*maxSide/sh, maxSide
+		}
+	}
+	dw, dh = max(dw, 1), max(dh, 1)
+
+	// 常见的解码结果（YCbCr、NRGBA、Paletted 等）都实现了 RGBA64Image，逐像素读取不产生分配
+	at := func(x, y int) (r, g, bl, a uint32) { return src.At(x, y).RGBA() }
+	if fast, ok := src.(image.RGBA64Image); ok {
+		at = func(x, y int) (r, g, bl, a uint32) {
+			c := fast.RGBA64At(x, y)
+			return uint32(c.R), uint32(c.G), uint32(c.B), uint32(c.A)
+		}
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		y0, y1 := y*sh/dh, max((y+1)*sh/dh, y*sh/dh+1)
+		for x := 0; x < dw; x++ {
+			x0, x1 := x*sw/dw, max((x+1)*sw/dw, x*sw/dw+1)
+			var r, g, bl, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					pr, pg, pb, pa := at(b.Min.X+sx, b.Min.Y+sy)
+					// 预乘 alpha 的颜色叠加到白底上
+					white := 0xffff - pa
+					r += uint64(pr + white)
+					g += uint64(pg + white)
+					bl += uint64(pb + white)
+					n++
+				}
+			}
+			i := y*dst.Stride + x*4
+			dst.Pix[i], dst.Pix[i+1], dst.Pix[i+2], dst.Pix[i+3] = uint8(r/n>>8), uint8(g/n>>8), uint8(bl/n>>8), 0xff
+		}
+	}
+	return dst
+}
@@ -0,0 +1,255 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/url"
+	"strconv"
+	"time"
+
+	"campus-logistics/internal/apperr"
+	"campus-logistics/internal/model"
+	"campus-logistics/internal/repository"
+	"campus-logistics/internal/storage"
+
+	"github.com/spf13/viper"
+)
+
+// 附件类型
+const (
+	AttachmentInboundPhoto = "inbound_photo" // 快递员入库时拍摄的包裹照片
+	AttachmentPickupPhoto  = "pickup_photo"  // 驿站取件时拍摄的照片
+	AttachmentSignature    = "signature"     // 取件人签名
+)
+
+// 附件下载地址的签名前缀：AT1.<附件ID>.<original|thumb>.<过期时间戳>.<签名>
+const attachmentURLPrefix = "AT1"
+
+// 下载变体
+const (
+	VariantOriginal = "original"
+	VariantThumb    = "thumb"
+)
+
+// MaxAttachmentBytes 单个附件大小上限，来自 attachments.max_size_mb，默认 5MB
+func MaxAttachmentBytes() int64 {
+	mb := viper.GetInt64("attachments.max_size_mb")
+	if mb <= 0 {
+		mb = 5
+	}
+	return mb << 20
+}
+
+func thumbnailSize() int {
+	if n := viper.GetInt("attachments.thumbnail_size"); n > 0 {
+		return n
+	}
+	return 320
+}
+
+// attachmentURLTTL 下载地址有效期，来自 attachments.url_ttl_minutes，默认 15 分钟
+func attachmentURLTTL() time.Duration {
+	if m := viper.GetInt("attachments.url_ttl_minutes"); m > 0 {
+		return time.Duration(m) * time.Minute
+	}
+	return 15 * time.Minute
+}
+
+// UploadCourierAttachment 快递员上传入库照片，只能上传本快递公司的包裹
+func UploadCourierAttachment(courierID int64, courierCode, trackingNum string, data []byte) (*model.ParcelAttachment, error) {
+	p, err := repository.GetParcelRef(trackingNum)
+	if err != nil {
+		return nil, parcelRefError(err)
+	}
+	if p.CourierID != courierID {
+		return nil, apperr.New(apperr.CodeParcelNotFound)
+	}
+	return saveAttachment(p, AttachmentInboundPhoto, "courier:"+courierCode, data)
+}
+
+// UploadStationAttachment 驿站管理员上传取件照片或签名；stationID 为 0 表示不限站点
+func UploadStationAttachment(stationID int64, username, trackingNum, kind string, data []byte) (*model.ParcelAttachment, error) {
+	if kind != AttachmentPickupPhoto && kind != AttachmentSignature {
+		return nil, apperr.Invalid("kind", "oneof").WithParam(AttachmentPickupPhoto + " " + AttachmentSignature)
+	}
+	p, err := repository.GetParcelRef(trackingNum)
+	if err != nil {
+		return nil, parcelRefError(err)
+	}
+	if stationID != 0 && p.StationID != stationID {
+		return nil, apperr.New(apperr.CodeParcelNotFound)
+	}
+	return saveAttachment(p, kind, truncateOperator("admin:"+username), data)
+}
+
+// ListStudentAttachments 学生查看本人包裹的附件
+func ListStudentAttachments(userID int64, trackingNum string) ([]model.ParcelAttachment, error) {
+	p, err := repository.GetParcelRef(trackingNum)
+	if err != nil {
+		return nil, parcelRefError(err)
+	}
+	if p.UserID != userID {
+		return nil, apperr.New(apperr.CodeParcelNotFound)
+	}
+	return listAttachments(p.ID)
+}
+
+// ListStationAttachments 管理员查看包裹附件；stationID 为 0 表示不限站点
+func ListStationAttachments(stationID int64, trackingNum string) ([]model.ParcelAttachment, error) {
+	p, err := repository.GetParcelRef(trackingNum)
+	if err != nil {
+		return nil, parcelRefError(err)
+	}
+	if stationID != 0 && p.StationID != stationID {
+		return nil, apperr.New(apperr.CodeParcelNotFound)
+	}
+	return listAttachments(p.ID)
+}
+
+// OpenAttachment 校验下载地址签名后读取附件，返回内容类型与内容；调用方负责关闭
+func OpenAttachment(ctx context.Context, id int64, variant, expires, signature string) (string, io.ReadCloser, error) {
+	payload := fmt.Sprintf("%s.%d.%s.%s.%s", attachmentURLPrefix, id, variant, expires, signature)
+	if _, err := verifyCredential(attachmentURLPrefix, payload, 4); err != nil {
+		if errors.Is(err, errInvalidCredential) {
+			return "", nil, apperr.New(apperr.CodeInvalidDownloadLink)
+		}
+		return "", nil, err
+	}
+	exp, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || time.Now().Unix() > exp {
+		return "", nil, apperr.New(apperr.CodeInvalidDownloadLink)
+	}
+
+	a, err := repository.GetAttachment(id)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return "", nil, apperr.New(apperr.CodeNotFound)
+		}
+		return "", nil, err
+	}
+	key, contentType := a.StorageKey, a.ContentType
+	if variant == VariantThumb && a.ThumbKey != "" {
+		key, contentType = a.ThumbKey, "image/jpeg"
+	}
+	rc, err := storage.Default.Open(ctx, key)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return "", nil, apperr.New(apperr.CodeNotFound)
+		}
+		return "", nil, err
+	}
+	return contentType, rc, nil
+}
+
+func parcelRefError(err error) error {
+	if errors.Is(err, repository.ErrNotFound) {
+		return apperr.New(apperr.CodeParcelNotFound)
+	}
+	return err
+}
+
+// saveAttachment 校验图片、生成缩略图并写入存储，最后登记元数据；登记失败时删除已写入的文件
+func saveAttachment(p *model.ParcelRef, kind, uploadedBy string, data []byte) (*model.ParcelAttachment, error) {
+	if int64(len(data)) > MaxAttachmentBytes() {
+		return nil, apperr.Newf(apperr.CodeAttachmentTooLarge, "max %d MB", MaxAttachmentBytes()>>20)
+	}
+	contentType, width, height, ok := inspectImage(data)
+	if !ok {
+		return nil, apperr.Newf(apperr.CodeUnsupportedMedia, "jpeg or png only")
+	}
+	thumb, err := makeThumbnail(data, thumbnailSize())
+	if err != nil {
+		return nil, apperr.Newf(apperr.CodeUnsupportedMedia, "jpeg or png only")
+	}
+
+	name := make([]byte, 12)
+	if _, err := rand.Read(name); err != nil {
+		return nil, err
+	}
+	ext := ".jpg"
+	if contentType == "image/png" {
+		ext = ".png"
+	}
+	base := fmt.Sprintf("parcels/%d/%s/%s", p.ID, kind, hex.EncodeToString(name))
+	sum := sha256.Sum256(data)
+	a := &model.ParcelAttachment{
+		ParcelID:    p.ID,
+		Kind:        kind,
+		StorageKey:  base + ext,
+		ThumbKey:    base + "-thumb.jpg",
+		ContentType: contentType,
+		SizeBytes:   int64(len(data)),
+		Width:       width,
+		Height:      height,
+		SHA256:      hex.EncodeToString(sum[:]),
+		UploadedBy:  uploadedBy,
+	}
+
+	ctx := context.Background()
+	if err := storage.Default.Put(ctx, a.StorageKey, bytes.NewReader(data), a.SizeBytes, contentType); err != nil {
+		return nil, fmt.Errorf("store attachment failed: %w", err)
+	}
+	if err := storage.Default.Put(ctx, a.ThumbKey, bytes.NewReader(thumb), int64(len(thumb)), "image/jpeg"); err != nil {
+		removeObjects(ctx, a.StorageKey)
+		return nil, fmt.Errorf("store thumbnail failed: %w", err)
+	}
+	if err := repository.CreateAttachment(a); err != nil {
+		removeObjects(ctx, a.StorageKey, a.ThumbKey)
+		return nil, err
+	}
+	if err := signAttachmentURLs(a); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+func removeObjects(ctx context.Context, keys ...string) {
+	for _, key := range keys {
+		if err := storage.Default.Delete(ctx, key); err != nil {
+			log.Printf("delete attachment object %s failed: %v", key, err)
+		}
+	}
+}
+
+func listAttachments(parcelID int64) ([]model.ParcelAttachment, error) {
+	attachments, err := repository.ListAttachmentsByParcel(parcelID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range attachments {
+		if err := signAttachmentURLs(&attachments[i]); err != nil {
+			return nil, err
+		}
+	}
+	return attachments, nil
+}
+
+// signAttachmentURLs 填写原图与缩略图的签名下载地址（相对路径）
+func signAttachmentURLs(a *model.ParcelAttachment) error {
+	exp := strconv.FormatInt(time.Now().Add(attachmentURLTTL()).Unix(), 10)
+	for _, v := range []struct {
+		variant string
+		dst     *string
+	}{{VariantOriginal, &a.URL}, {VariantThumb, &a.ThumbnailURL}} {
+		if v.variant == VariantThumb && a.ThumbKey == "" {
+			continue
+		}
+		signed, err := signCredential(attachmentURLPrefix, attachmentURLPrefix, strconv.FormatInt(a.ID, 10), v.variant, exp)
+		if err != nil {
+			return err
+		}
+		q := url.Values{}
+		q.Set("variant", v.variant)
+		q.Set("expires", exp)
+		q.Set("signature", credentialSignature(signed))
+		*v.dst = fmt.Sprintf("/api/v1/attachments/%d?%s", a.ID, q.Encode())
+	}
+	return nil
+}
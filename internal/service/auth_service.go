@@ -3,6 +3,7 @@ package service
 import (
 	"crypto/subtle"
 	"errors"
+	"log"
 	"os"
 	"strings"
 	"time"
@@ -19,10 +20,17 @@ var ErrInvalidCredentials = errors.New("invalid credentials")
 const defaultTokenTTL = 24 * time.Hour
 
 type TokenResponse struct {
-	AccessToken string `json:"access_token"`
+	AccessToken string `json:"access_token,omitempty"`
 	TokenType   string `json:"token_type"`
 	ExpiresIn   int64  `json:"expires_in"`
 	Role        string `json:"role"`
+	// MustChangePassword 管理员密码过期或被重置，需先调用修改密码接口
+	MustChangePassword bool `json:"must_change_password,omitempty"`
+	// MFASetupRequired 系统要求 MFA 而该管理员尚未绑定
+	MFASetupRequired bool `json:"mfa_setup_required,omitempty"`
+	// MFARequired 为 true 时没有 access_token，需携带 MFAToken 调用 /api/v1/auth/admin/mfa
+	MFARequired bool   `json:"mfa_required,omitempty"`
+	MFAToken    string `json:"mfa_token,omitempty"`
 }
 
 func AdminLogin(username, password string) (*TokenResponse, error) {
@@ -45,11 +53,19 @@ func AdminLogin(username, password string) (*TokenResponse, error) {
 		if !verifyPassword(envPass, password) {
 			return nil, ErrInvalidCredentials
 		}
+		// 环境变量账号无法绑定 MFA，系统强制 MFA 时拒绝其登录
+		if required, err := IsAdminMFARequired(); err != nil {
+			return nil, err
+		} else if required {
+			log.Printf("env admin %s rejected: admin MFA is required", envUser)
+			return nil, ErrInvalidCredentials
+		}
 
 		tok, err := middleware.IssueToken(middleware.Claims{
-			Role:     middleware.RoleAdmin,
-			AdminID:  0,
-			Username: envUser,
+			Role:      middleware.RoleAdmin,
+			AdminID:   0,
+			Username:  envUser,
+			AdminRole: middleware.AdminRoleSuper,
 		}, defaultTokenTTL)
 		if err != nil {
 			return nil, err
@@ -71,26 +87,72 @@ func AdminLogin(username, password string) (*TokenResponse, error) {
 		return nil, err
 	}
 
+	if a.LockedUntil != nil && a.LockedUntil.After(time.Now()) {
+		return nil, ErrAccountLocked
+	}
+
 	if !verifyPassword(a.PasswordHash, password) {
+		lockout := loadLockoutPolicy()
+		attempts, lockedUntil, err := repository.RecordAdminLoginFailure(a.ID, lockout.MaxFailedAttempts, lockout.Duration)
+		if err != nil {
+			return nil, err
+		}
+		if lockedUntil != nil {
+			log.Printf("admin %s locked until %s after %d failed attempts", a.Username, lockedUntil.Format(time.RFC3339), attempts)
+			return nil, ErrAccountLocked
+		}
 		return nil, ErrInvalidCredentials
 	}
 
+	// 明文种子或旧 cost 的哈希在登录成功时自动升级为 bcrypt
+	if needsRehash(a.PasswordHash) {
+		if hash, err := hashPassword(password); err == nil {
+			if err := repository.RehashAdminPassword(a.ID, hash); err != nil {
+				log.Printf("rehash password for admin %s failed: %v", a.Username, err)
+			}
+		}
+	}
+
+	if a.MFAEnabled {
+		return issueMFAChallenge(a)
+	}
+
+	mfaRequired, err := IsAdminMFARequired()
+	if err != nil {
+		return nil, err
+	}
+
 	_ = repository.TouchAdminLastLogin(a.ID)
+	return issueAdminToken(a, mfaRequired)
+}
 
+// issueAdminToken 签发正式管理员 token；密码过期或需绑定 MFA 时 token 仅能访问账号自助接口
+func issueAdminToken(a *model.Admin, mfaSetupRequired bool) (*TokenResponse, error) {
+	expired := loadPasswordPolicy().expired(a)
+	var stationID int64
+	if a.StationID != nil {
+		stationID = *a.StationID
+	}
 	tok, err := middleware.IssueToken(middleware.Claims{
-		Role:     middleware.RoleAdmin,
-		AdminID:  a.ID,
-		Username: a.Username,
+		Role:             middleware.RoleAdmin,
+		AdminID:          a.ID,
+		Username:         a.Username,
+		AdminRole:        a.Role,
+		StationID:        stationID,
+		PasswordExpired:  expired,
+		MFASetupRequired: mfaSetupRequired,
 	}, defaultTokenTTL)
 	if err != nil {
 		return nil, err
 	}
 
 	return &TokenResponse{
-		AccessToken: tok,
-		TokenType:   "Bearer",
-		ExpiresIn:   int64(defaultTokenTTL.Seconds()),
-		Role:        string(middleware.RoleAdmin),
+		AccessToken:        tok,
+		TokenType:          "Bearer",
+		ExpiresIn:          int64(defaultTokenTTL.Seconds()),
+		Role:               string(middleware.RoleAdmin),
+		MustChangePassword: expired,
+		MFASetupRequired:   mfaSetupRequired,
 	}, nil
 }
 
@@ -113,11 +175,15 @@ func StudentLogin(phone, name string) (*TokenResponse, error) {
 	if err != nil {
 		return nil, err
 	}
+	return issueStudentToken(u.ID, u.Phone)
+}
 
+// issueStudentToken 签发学生 token；手机号变更后需重新签发
+func issueStudentToken(userID int64, phone string) (*TokenResponse, error) {
 	tok, err := middleware.IssueToken(middleware.Claims{
 		Role:   middleware.RoleStudent,
-		UserID: u.ID,
-		Phone:  u.Phone,
+		UserID: userID,
+		Phone:  phone,
 	}, defaultTokenTTL)
 	if err != nil {
 		return nil, err
@@ -168,21 +234,10 @@ func verifyPassword(storedHash, password string) bool {
 		return false
 	}
 	// If it looks like a bcrypt hash, verify using bcrypt.
-	if strings.HasPrefix(storedHash, "$2a$") || strings.HasPrefix(storedHash, "$2b$") || strings.HasPrefix(storedHash, "$2y$") {
+	if isBcryptHash(storedHash) {
 		return bcrypt.CompareHashAndPassword([]byte(storedHash), []byte(password)) == nil
 	}
-	// Demo fallback: constant-time compare for plain-text seed values.
+	// Legacy fallback: constant-time compare for plain-text seed values.
+	// Database accounts are re-hashed to bcrypt on their next successful login.
 	return subtle.ConstantTimeCompare([]byte(storedHash), []byte(password)) == 1
 }
-
-// Expose models for handlers that may want to return profile later.
-func GetCourierTasksForCourier(courierID int64, page, pageSize int) ([]model.CourierTask, error) {
-	if page < 1 {
-		page = 1
-	}
-	if pageSize <= 0 || pageSize > 100 {
-		pageSize = 20
-	}
-	offset := (page - 1) * pageSize
-	return repository.GetCourierTasks(courierID, pageSize, offset)
-}
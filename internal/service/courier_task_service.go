@@ -0,0 +1,135 @@
+package service
+
+import (
+	"errors"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"campus-logistics/internal/apperr"
+	"campus-logistics/internal/model"
+	"campus-logistics/internal/repository"
+	"campus-logistics/internal/validation"
+)
+
+// parcelStatuses 包裹状态（与数据库枚举 parcel_status 一致）
+var parcelStatuses = []string{"inbound", "stored", "pending", "picked_up", "returned", "exception"}
+
+// taskQueryRe 搜索关键字：运单号片段或手机号尾号，至少 4 位
+var taskQueryRe = regexp.MustCompile(`^[A-Z0-9]{4,32}$`)
+
+// CourierTaskQuery 快递员任务列表的筛选参数
+type CourierTaskQuery struct {
+	// Status 逗号分隔的状态，如 stored,pending
+	Status string
+	// From / To 入库日期 YYYY-MM-DD，包含两端
+	From string
+	To   string
+	// Q 运单号片段；4 位数字时同时按收件人手机号尾号匹配
+	Q        string
+	Page     int
+	PageSize int
+}
+
+// CourierTaskPage 一页任务及符合条件的总数
+type CourierTaskPage struct {
+	Tasks    []model.CourierTask
+	Total    int
+	Page     int
+	PageSize int
+}
+
+// parseTaskDay 解析可选的日期参数，end 为 true 时返回次日零点（范围右端不含）
+func parseTaskDay(field, s string, end bool) (*time.Time, error) {
+	if s == "" {
+		return nil, nil
+	}
+	t, err := time.ParseInLocation("2006-01-02", s, time.Local)
+	if err != nil {
+		return nil, apperr.Invalid(field, "date")
+	}
+	if end {
+		t = t.AddDate(0, 0, 1)
+	}
+	return &t, nil
+}
+
+// parseTaskRange 解析入库日期范围 [from, to+1)
+func parseTaskRange(fromStr, toStr string) (*time.Time, *time.Time, error) {
+	from, err := parseTaskDay("from", fromStr, false)
+	if err != nil {
+		return nil, nil, err
+	}
+	to, err := parseTaskDay("to", toStr, true)
+	if err != nil {
+		return nil, nil, err
+	}
+	if from != nil && to != nil && !to.After(*from) {
+		return nil, nil, apperr.Invalid("to", "after_from")
+	}
+	return from, to, nil
+}
+
+// ListCourierTasks 快递员名下的包裹，支持按状态、入库日期、运单号或手机号尾号筛选；手机号按 privacy 配置脱敏
+func ListCourierTasks(courierID int64, q CourierTaskQuery) (*CourierTaskPage, error) {
+	var f repository.CourierTaskFilter
+	if q.Status != "" {
+		for _, s := range strings.Split(q.Status, ",") {
+			s = strings.ToLower(strings.TrimSpace(s))
+			if !slices.Contains(parcelStatuses, s) {
+				return nil, apperr.Invalid("status", "oneof").WithParam(strings.Join(parcelStatuses, " "))
+			}
+			f.Statuses = append(f.Statuses, s)
+		}
+	}
+	var err error
+	if f.From, f.To, err = parseTaskRange(q.From, q.To); err != nil {
+		return nil, err
+	}
+	if q.Q != "" {
+		f.Query = validation.Code(q.Q)
+		if !taskQueryRe.MatchString(f.Query) {
+			return nil, apperr.Invalid("q", "task_query")
+		}
+		// 手机号加密保存，只能按脱敏形式中保留的后 4 位匹配
+		f.PhoneSuffix = len(f.Query) == 4 && strings.Trim(f.Query, "0123456789") == ""
+	}
+
+	if q.Page < 1 {
+		q.Page = 1
+	}
+	if q.PageSize <= 0 || q.PageSize > 100 {
+		q.PageSize = 20
+	}
+	tasks, total, err := repository.GetCourierTasks(courierID, f, q.PageSize, (q.Page-1)*q.PageSize)
+	if err != nil {
+		return nil, err
+	}
+	for i := range tasks {
+		tasks[i].Phone = maskPhone(tasks[i].Phone)
+	}
+	return &CourierTaskPage{Tasks: tasks, Total: total, Page: q.Page, PageSize: q.PageSize}, nil
+}
+
+// GetCourierTaskSummary 快递员名下包裹按状态的数量（可按入库日期范围），以及今天入库的数量
+func GetCourierTaskSummary(courierID int64, fromStr, toStr string) (*model.CourierTaskSummary, error) {
+	from, to, err := parseTaskRange(fromStr, toStr)
+	if err != nil {
+		return nil, err
+	}
+	return repository.GetCourierTaskSummary(courierID, from, to)
+}
+
+// GetCourierTask 单个包裹详情：货架位置、滞留时长与状态记录（手机号按 privacy 配置脱敏）；不属于本快递公司时返回 PARCEL_NOT_FOUND
+func GetCourierTask(courierID int64, trackingNumber string) (*model.CourierTaskDetail, error) {
+	d, err := repository.GetCourierTask(courierID, trackingNumber)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, apperr.New(apperr.CodeParcelNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+	d.Phone = maskPhone(d.Phone)
+	return d, nil
+}
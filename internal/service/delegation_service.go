@@ -0,0 +1,89 @@
+package service
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"campus-logistics/internal/apperr"
+	"campus-logistics/internal/model"
+	"campus-logistics/internal/repository"
+	"campus-logistics/internal/validation"
+
+	"github.com/spf13/viper"
+)
+
+// CreateDelegationRequest 代取授权请求
+type CreateDelegationRequest struct {
+	// 被授权人手机号，对方用该手机号登录后即可在包裹列表中看到并代取
+	DelegatePhone string `json:"delegate_phone" binding:"required,cnmobile"`
+	// 授权的单个包裹；为空表示授权时间窗口内的全部包裹（包括之后入库的）
+	TrackingNumber string `json:"tracking_number" binding:"omitempty,tracking_number"`
+	// 生效时间，缺省为立即生效
+	StartsAt  *time.Time `json:"starts_at"`
+	ExpiresAt time.Time  `json:"expires_at" binding:"required"`
+}
+
+func (r *CreateDelegationRequest) Normalize() {
+	r.DelegatePhone = validation.Phone(r.DelegatePhone)
+	r.TrackingNumber = validation.Code(r.TrackingNumber)
+}
+
+// delegationMaxWindow 授权时间窗口上限，来自 pickup.delegation_max_days，默认 7 天
+func delegationMaxWindow() time.Duration {
+	days := viper.GetInt("pickup.delegation_max_days")
+	if days <= 0 {
+		days = 7
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// CreateDelegation 包裹所有人授权他人代取；不能授权给本人，时间窗口不超过 pickup.delegation_max_days
+func CreateDelegation(ownerID int64, ownerPhone string, req CreateDelegationRequest) (*model.PickupDelegation, error) {
+	if req.DelegatePhone == ownerPhone {
+		return nil, apperr.Invalid("delegate_phone", "delegate_self")
+	}
+
+	now := time.Now()
+	startsAt := now
+	if req.StartsAt != nil && req.StartsAt.After(now) {
+		startsAt = *req.StartsAt
+	}
+	switch {
+	case !req.ExpiresAt.After(now):
+		return nil, apperr.Invalid("expires_at", "future")
+	case !req.ExpiresAt.After(startsAt):
+		return nil, apperr.Invalid("expires_at", "after_starts_at")
+	case req.ExpiresAt.Sub(startsAt) > delegationMaxWindow():
+		return nil, apperr.Invalid("expires_at", "delegation_window").
+			WithParam(strconv.Itoa(int(delegationMaxWindow().Hours() / 24)))
+	}
+
+	d, err := repository.CreateDelegation(ownerID, req.DelegatePhone, req.TrackingNumber, startsAt, req.ExpiresAt)
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		return nil, apperr.New(apperr.CodeParcelNotFound)
+	case errors.Is(err, repository.ErrConflict):
+		return nil, apperr.New(apperr.CodeParcelNotPickable)
+	}
+	return d, err
+}
+
+// ListDelegations received 为 false 时返回本人发出的授权，为 true 时返回他人授权给本人的
+func ListDelegations(userID int64, received bool) ([]model.PickupDelegation, error) {
+	if received {
+		return repository.ListDelegationsToUser(userID)
+	}
+	return repository.ListDelegationsByOwner(userID)
+}
+
+// RevokeDelegation 撤销本人发出的授权，撤销后立即失效
+func RevokeDelegation(id, ownerID int64) error {
+	if err := repository.RevokeDelegation(id, ownerID); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return apperr.New(apperr.CodeNotFound)
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,373 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/spf13/viper"
+
+	"campus-logistics/internal/apperr"
+	"campus-logistics/internal/model"
+	"campus-logistics/internal/report"
+	"campus-logistics/internal/repository"
+	"campus-logistics/internal/validation"
+)
+
+// 导入种类
+const (
+	ImportStudents = "students"
+	ImportCouriers = "couriers"
+)
+
+const (
+	// importProgressEvery 每处理这么多行刷新一次进度
+	importProgressEvery = 200
+	// importProgressInterval 处理较慢时至少按该间隔刷新进度，避免被 FailStaleImportJobs 判定为中断
+	importProgressInterval = 30 * time.Second
+	// maxImportErrors 任务中最多保存的逐行错误数，其余只计入 failed
+	maxImportErrors = 1000
+)
+
+// importRow 一行规范化并校验后的数据
+type importRow interface {
+	Normalize()
+	// key 文件内去重使用的键（学生为手机号，快递公司为编码）
+	key() string
+	upsert(dryRun bool) (string, error)
+}
+
+// StudentImportRow 学生导入的一行；以手机号为键，学号、姓名、宿舍为空时保留原值
+type StudentImportRow struct {
+	StudentID string `json:"student_id" binding:"max=20"`
+	Phone     string `json:"phone" binding:"required,cnmobile"`
+	Name      string `json:"name" binding:"max=50"`
+	Dorm      string `json:"dorm" binding:"max=100"`
+}
+
+func (r *StudentImportRow) Normalize() {
+	r.StudentID = validation.Code(r.StudentID)
+	r.Phone = validation.Phone(r.Phone)
+	r.Name = validation.Text(r.Name)
+	r.Dorm = validation.Text(r.Dorm)
+}
+
+func (r *StudentImportRow) key() string { return r.Phone }
+
+func (r *StudentImportRow) upsert(dryRun bool) (string, error) {
+	outcome, err := repository.UpsertImportedStudent(r.Phone, r.StudentID, r.Name, r.Dorm, dryRun)
+	if errors.Is(err, repository.ErrDuplicate) {
+		return "", apperr.Invalid("student_id", "taken")
+	}
+	return outcome, err
+}
+
+// CourierImportRow 快递公司导入的一行；以编码为键
+type CourierImportRow struct {
+	Code         string `json:"code" binding:"required,max=20"`
+	Name         string `json:"name" binding:"required,max=50"`
+	ContactPhone string `json:"contact_phone" binding:"max=20"`
+	// TrackingPattern 运单号格式（正则，整串匹配），为空时保留原值
+	TrackingPattern string `json:"tracking_pattern" binding:"omitempty,max=200,regexp"`
+}
+
+func (r *CourierImportRow) Normalize() {
+	r.Code = validation.Code(r.Code)
+	r.Name = validation.Text(r.Name)
+	r.ContactPhone = validation.Text(r.ContactPhone)
+	r.TrackingPattern = strings.TrimSpace(r.TrackingPattern)
+}
+
+func (r *CourierImportRow) key() string { return r.Code }
+
+func (r *CourierImportRow) upsert(dryRun bool) (string, error) {
+	outcome, err := repository.UpsertImportedCourier(r.Code, r.Name, r.ContactPhone, r.TrackingPattern, dryRun)
+	if errors.Is(err, repository.ErrDuplicate) {
+		return "", apperr.Invalid("name", "taken")
+	}
+	return outcome, err
+}
+
+// importKind 一种导入：表头别名（中英文）、必需的列（第一列为去重键）与行转换
+type importKind struct {
+	columns  map[string]string
+	required []string
+	row      func(values map[string]string) importRow
+}
+
+var importKinds = map[string]importKind{
+	ImportStudents: {
+		columns: map[string]string{
+			"student_id": "student_id", "学号": "student_id",
+			"phone": "phone", "手机号": "phone", "手机": "phone",
+			"name": "name", "姓名": "name",
+			"dorm": "dorm", "宿舍": "dorm",
+		},
+		required: []string{"phone"},
+		row: func(v map[string]string) importRow {
+			return &StudentImportRow{StudentID: v["student_id"], Phone: v["phone"], Name: v["name"], Dorm: v["dorm"]}
+		},
+	},
+	ImportCouriers: {
+		columns: map[string]string{
+			"code": "code", "编码": "code", "快递公司编码": "code",
+			"name": "name", "名称": "name", "快递公司": "name",
+			"contact_phone": "contact_phone", "联系电话": "contact_phone",
+			"tracking_pattern": "tracking_pattern", "运单号格式": "tracking_pattern",
+		},
+		required: []string{"code", "name"},
+		row: func(v map[string]string) importRow {
+			return &CourierImportRow{Code: v["code"], Name: v["name"], ContactPhone: v["contact_phone"], TrackingPattern: v["tracking_pattern"]}
+		},
+	},
+}
+
+// MaxImportBytes 导入文件大小上限，来自 imports.max_size_mb，默认 10MB
+func MaxImportBytes() int64 {
+	mb := viper.GetInt64("imports.max_size_mb")
+	if mb <= 0 {
+		mb = 10
+	}
+	return mb << 20
+}
+
+// maxImportRows 单个文件的数据行上限，来自 imports.max_rows，默认 50000
+func maxImportRows() int {
+	if n := viper.GetInt("imports.max_rows"); n > 0 {
+		return n
+	}
+	return 50000
+}
+
+// importRecord 文件中的一条数据：行号（表头为第 1 行）与按列名取值
+type importRecord struct {
+	row    int
+	values map[string]string
+}
+
+// parseImportFile 解析表头并把数据行转换为按列名取值，跳过空行
+func parseImportFile(kind importKind, fileName string, data []byte) ([]importRecord, error) {
+	format := strings.ToLower(strings.TrimPrefix(filepath.Ext(fileName), "."))
+	if format != "csv" && format != "xlsx" {
+		return nil, apperr.Newf(apperr.CodeUnsupportedMedia, "only .csv and .xlsx files are accepted")
+	}
+	rows, err := report.ReadAll(format, bytes.NewReader(data), maxImportRows())
+	if errors.Is(err, report.ErrTooManyRows) {
+		return nil, apperr.Newf(apperr.CodeBatchTooLarge, "max %d rows", maxImportRows())
+	}
+	if err != nil {
+		return nil, apperr.Invalid("file", "import_file")
+	}
+	if len(rows) == 0 {
+		return nil, apperr.Invalid("file", "required")
+	}
+
+	header := make([]string, len(rows[0]))
+	seen := map[string]bool{}
+	for i, h := range rows[0] {
+		col := kind.columns[strings.ToLower(validation.Text(h))]
+		if col != "" && !seen[col] {
+			header[i] = col
+			seen[col] = true
+		}
+	}
+	var missing []string
+	for _, col := range kind.required {
+		if !seen[col] {
+			missing = append(missing, col)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, apperr.Invalid("file", "import_columns").WithParam(strings.Join(missing, ", "))
+	}
+
+	records := make([]importRecord, 0, len(rows)-1)
+	for i, cells := range rows[1:] {
+		values := map[string]string{}
+		blank := true
+		for j, cell := range cells {
+			if j < len(header) && header[j] != "" {
+				values[header[j]] = cell
+				if strings.TrimSpace(cell) != "" {
+					blank = false
+				}
+			}
+		}
+		if !blank {
+			records = append(records, importRecord{row: i + 2, values: values})
+		}
+	}
+	return records, nil
+}
+
+// 后台导入任务使用服务生命周期的 context（停机时取消），importJobs 跟踪运行中的任务，停机时在关闭数据库前等待
+var (
+	importCtx  = context.Background()
+	importJobs sync.WaitGroup
+)
+
+// SetImportContext 设置后台导入任务的 context；启动时调用一次，ctx 取消后任务在当前行处理完后中止
+func SetImportContext(ctx context.Context) {
+	importCtx = ctx
+}
+
+// WaitImports 等待运行中的导入任务退出；停机时在 HTTP 服务停止后调用
+func WaitImports() {
+	importJobs.Wait()
+}
+
+// StartImport 校验文件并创建导入任务，随后在后台逐行处理；dryRun 时只校验并统计将新建/更新的数量，不写入
+func StartImport(kind, fileName string, data []byte, dryRun bool, operator string) (*model.ImportJob, error) {
+	k, ok := importKinds[kind]
+	if !ok {
+		return nil, apperr.Invalid("kind", "oneof").WithParam(ImportStudents + " " + ImportCouriers)
+	}
+	records, err := parseImportFile(k, fileName, data)
+	if err != nil {
+		return nil, err
+	}
+
+	job, err := repository.CreateImportJob(kind, filepath.Base(fileName), dryRun, len(records), operator)
+	if err != nil {
+		return nil, err
+	}
+	// 后台任务使用副本，返回值不会被并发修改
+	running := *job
+	importJobs.Add(1)
+	go func() {
+		defer importJobs.Done()
+		runImport(importCtx, k, &running, records)
+	}()
+	return job, nil
+}
+
+// runImport 逐行校验并写入；文件内重复的键只处理第一次出现的行。数据库错误或 ctx 取消（服务停机）会中止任务
+func runImport(ctx context.Context, k importKind, job *model.ImportJob, records []importRecord) {
+	var rowErrors []model.ImportRowError
+	addError := func(row int, fields []apperr.FieldError) {
+		job.Failed++
+		for _, f := range fields {
+			if len(rowErrors) < maxImportErrors {
+				rowErrors = append(rowErrors, model.ImportRowError{Row: row, Field: f.Field, Rule: f.Rule, Param: f.Param})
+			}
+		}
+	}
+
+	firstRow := map[string]int{}
+	job.Status = "completed"
+	lastProgress := time.Now()
+	abandoned := false
+	for _, rec := range records {
+		if ctx.Err() != nil {
+			log.Printf("import job %d interrupted at row %d: server shutting down", job.ID, rec.row)
+			job.Status = "failed"
+			job.Message = fmt.Sprintf("interrupted by server shutdown before row %d", rec.row)
+			break
+		}
+		outcome, err := importOne(k, rec, firstRow, job.DryRun)
+		switch {
+		case err == nil:
+			switch outcome {
+			case repository.ImportCreated:
+				job.Created++
+			case repository.ImportUpdated:
+				job.Updated++
+			default:
+				job.Unchanged++
+			}
+		case apperr.CodeOf(err) == apperr.CodeValidationFailed:
+			addError(rec.row, apperr.Translate(err).Fields)
+		default:
+			log.Printf("import job %d failed at row %d: %v", job.ID, rec.row, err)
+			job.Status = "failed"
+			job.Message = fmt.Sprintf("row %d: database error", rec.row)
+		}
+		if job.Status == "failed" {
+			break
+		}
+		job.Processed++
+		if job.Processed%importProgressEvery == 0 || time.Since(lastProgress) >= importProgressInterval {
+			err := repository.UpdateImportJobProgress(job)
+			if errors.Is(err, repository.ErrConflict) {
+				// 已被判定为中断并标记失败，不再继续写入
+				log.Printf("import job %d is no longer running; stopping at row %d", job.ID, rec.row)
+				abandoned = true
+				break
+			}
+			if err != nil {
+				log.Printf("import job %d progress: %v", job.ID, err)
+			}
+			lastProgress = time.Now()
+		}
+	}
+
+	if job.Kind == ImportCouriers && !job.DryRun && job.Created+job.Updated > 0 {
+		if err := refreshTrackingRules(true); err != nil {
+			log.Printf("reload tracking rules after import: %v", err)
+		}
+	}
+
+	if abandoned {
+		return
+	}
+	if rowErrors == nil {
+		rowErrors = []model.ImportRowError{}
+	}
+	errorsJSON, _ := json.Marshal(rowErrors)
+	if err := repository.FinishImportJob(job, errorsJSON); errors.Is(err, repository.ErrConflict) {
+		log.Printf("import job %d was already marked failed; keeping that status", job.ID)
+	} else if err != nil {
+		log.Printf("import job %d finish: %v", job.ID, err)
+	}
+}
+
+// importOne 规范化、校验并写入一行；与前面某行的键重复时不写入，返回 duplicate_in_file
+func importOne(k importKind, rec importRecord, firstRow map[string]int, dryRun bool) (string, error) {
+	row := k.row(rec.values)
+	row.Normalize()
+	if err := binding.Validator.ValidateStruct(row); err != nil {
+		return "", err
+	}
+	if first, dup := firstRow[row.key()]; dup {
+		return "", apperr.Invalid(k.required[0], "duplicate_in_file").WithParam(strconv.Itoa(first))
+	}
+	firstRow[row.key()] = rec.row
+	return row.upsert(dryRun)
+}
+
+// GetImportJob 查询导入任务及逐行错误
+func GetImportJob(id int64) (*model.ImportJob, error) {
+	job, err := repository.GetImportJob(id)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, apperr.New(apperr.CodeImportJobNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(job.ErrorsJSON) > 0 {
+		if err := json.Unmarshal(job.ErrorsJSON, &job.Errors); err != nil {
+			return nil, fmt.Errorf("decode import errors: %w", err)
+		}
+	}
+	return job, nil
+}
+
+// ListImportJobs 最近的导入任务（不含逐行错误）
+func ListImportJobs(page, pageSize int) ([]model.ImportJob, error) {
+	if page < 1 {
+		page = 1
+	}
+	if pageSize <= 0 || pageSize > 100 {
+		pageSize = 20
+	}
+	return repository.ListImportJobs(pageSize, (page-1)*pageSize)
+}
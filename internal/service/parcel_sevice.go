@@ -5,8 +5,13 @@ package service
 
 // 导入所需的包
 import (
+	"campus-logistics/internal/apperr"     // 业务错误码
 	"campus-logistics/internal/model"      // 数据模型
 	"campus-logistics/internal/repository" // 数据访问层
+	"campus-logistics/internal/validation" // 请求规范化与格式校验
+	"errors"
+	"log"
+	"strings"
 )
 
 // InboundRequest 入库请求结构体
@@ -17,54 +22,194 @@ import (
 type InboundRequest struct {
 	// 运单号/快递单号，入库包裹的唯一标识符
 	// binding:"required" 表示该字段为必填项，如果请求中缺失，Gin会返回验证错误
-	TrackingNumber string `json:"tracking_number" binding:"required"`
+	// tracking_number 校验通用格式，各快递公司的格式、长度与校验位由运单号规则另行校验
+	TrackingNumber string `json:"tracking_number" binding:"required,tracking_number"`
 
 	// 收件人手机号，用于关联用户信息
-	// 必填项，确保每个包裹都有对应的收件人
-	Phone string `json:"phone" binding:"required"`
+	// 必填项，确保每个包裹都有对应的收件人；需为大陆手机号
+	Phone string `json:"phone" binding:"required,cnmobile"`
 
 	// 快递公司代码，用于标识快递公司
 	// 例如：SF（顺丰）、YT（圆通）、ZT（中通）
 	// 对于快递员入库接口：courier_code 由 JWT claims 决定，客户端不应提交
-	// 对于兼容旧调用方式：仍允许从请求体中读取该字段
+	// 对于驿站代收入库接口：可选，未提供时根据运单号识别快递公司
 	CourierCode string `json:"courier_code"`
 
 	// 操作员名称，执行入库操作的人员名称
 	// 非必填项（没有binding:"required"标签），可以为空
 	// 用于记录操作日志和责任追踪
-	UserName string `json:"user_name"`
+	UserName string `json:"user_name" binding:"max=64"`
+
+	// 入库站点编号，决定在哪个站点分配货架
+	// 只有一个站点时可为空；存在多个站点时必填
+	StationCode string `json:"station_code"`
+
+	// 尺寸等级，决定可用货架的最大尺寸；为空时按 small 处理
+	SizeClass string `json:"size_class" binding:"omitempty,oneof=document small medium large bulky"`
+
+	// 重量（克），可选
+	WeightGrams int `json:"weight_grams" binding:"omitempty,min=1,max=100000"`
+
+	// 易碎、生鲜、冷链标记；冷链包裹只能分配到冷藏货架，并视为生鲜
+	Fragile    bool `json:"fragile"`
+	Perishable bool `json:"perishable"`
+	ColdChain  bool `json:"cold_chain"`
+
+	// 声明价值（分），可选
+	DeclaredValueCents int64 `json:"declared_value_cents" binding:"omitempty,min=0,max=100000000"`
 }
 
-// Inbound 包裹入库服务函数
-// 功能：处理包裹入库的核心业务逻辑，协调相关操作
-// 参数：req - InboundRequest结构体，包含入库所需的所有信息
-// 返回值：error - 成功返回nil，失败返回具体错误
-func Inbound(req InboundRequest) error {
-	// 兼容旧调用方式：仍允许从 req.CourierCode 读取
-	return repository.CreateParcelInbound(req.TrackingNumber, req.Phone, req.CourierCode, req.UserName)
+// Normalize 校验前规范化：运单号、编号转大写并全角转半角，手机号去掉分隔符与 +86 前缀
+func (r *InboundRequest) Normalize() {
+	r.TrackingNumber = validation.Code(r.TrackingNumber)
+	r.Phone = validation.Phone(r.Phone)
+	r.CourierCode = validation.Code(r.CourierCode)
+	r.UserName = validation.Text(r.UserName)
+	r.StationCode = validation.Code(r.StationCode)
+	r.SizeClass = strings.ToLower(strings.TrimSpace(r.SizeClass))
+}
+
+// attributes 入库请求中的包裹属性；未指定尺寸时为 small，冷链包裹同时标记为生鲜
+func (r InboundRequest) attributes() model.ParcelAttributes {
+	attrs := model.ParcelAttributes{
+		SizeClass:          r.SizeClass,
+		WeightGrams:        r.WeightGrams,
+		Fragile:            r.Fragile,
+		Perishable:         r.Perishable || r.ColdChain,
+		ColdChain:          r.ColdChain,
+		DeclaredValueCents: r.DeclaredValueCents,
+	}
+	if attrs.SizeClass == "" {
+		attrs.SizeClass = "small"
+	}
+	return attrs
 }
 
 // InboundByCourier 入库（快递员鉴权版）：courierCode 由 JWT 决定，不允许客户端伪造
 func InboundByCourier(req InboundRequest, courierCode string) error {
-	return repository.CreateParcelInbound(req.TrackingNumber, req.Phone, courierCode, req.UserName)
+	if err := checkTrackingNumber(courierCode, req.TrackingNumber); err != nil {
+		return err
+	}
+	return repository.CreateParcelInbound(req.TrackingNumber, req.Phone, courierCode, req.UserName, strings.ToUpper(strings.TrimSpace(req.StationCode)), req.attributes())
+}
+
+// StationInbound 驿站代收入库（快递员未自行入库、由驿站工作人员扫码入库）：
+// courier_code 未提供时根据运单号识别快递公司；stationID 非 0 时入库到该站点（绑定站点的管理员只能入库到本站）。
+// 返回实际使用的快递公司代码
+func StationInbound(req InboundRequest, stationID int64) (string, error) {
+	courierCode := req.CourierCode
+	if courierCode == "" {
+		detected, err := DetectCourier(req.TrackingNumber)
+		if err != nil {
+			return "", err
+		}
+		courierCode = detected
+	}
+	if stationID != 0 {
+		st, err := repository.GetStationByID(stationID)
+		if errors.Is(err, repository.ErrNotFound) {
+			return "", apperr.New(apperr.CodeInvalidStation)
+		}
+		if err != nil {
+			return "", err
+		}
+		req.StationCode = st.Code
+	}
+	return courierCode, InboundByCourier(req, courierCode)
+}
+
+// MaxBatchInboundSize 单次批量入库的最大条数
+const MaxBatchInboundSize = 100
+
+// BatchInboundRequest 批量入库请求：扫码枪离线缓存后一次性上传
+type BatchInboundRequest struct {
+	Items []InboundRequest `json:"items" binding:"required,min=1,dive"`
+}
+
+func (r *BatchInboundRequest) Normalize() {
+	for i := range r.Items {
+		r.Items[i].Normalize()
+	}
+}
+
+// BatchInboundResult 单条入库结果，批量入库中各条互不影响
+type BatchInboundResult struct {
+	TrackingNumber string `json:"tracking_number"`
+	// CourierCode 驿站代收入库时实际使用（或识别出）的快递公司代码
+	CourierCode string      `json:"courier_code,omitempty"`
+	Status      string      `json:"status"` // stored / failed
+	Code        apperr.Code `json:"code,omitempty"`
+	// Error 本地化的失败提示，由 handler 按请求语言填写
+	Error string `json:"error,omitempty"`
+}
+
+// BatchInboundByCourier 逐条入库，每条在各自的事务中执行；返回每条结果与成功条数
+func BatchInboundByCourier(items []InboundRequest, courierCode string) ([]BatchInboundResult, int) {
+	return batchInbound(items, func(item InboundRequest) (string, error) {
+		return "", InboundByCourier(item, courierCode)
+	})
+}
+
+// BatchStationInbound 驿站代收批量入库，每条分别识别快递公司
+func BatchStationInbound(items []InboundRequest, stationID int64) ([]BatchInboundResult, int) {
+	return batchInbound(items, func(item InboundRequest) (string, error) {
+		return StationInbound(item, stationID)
+	})
+}
+
+// batchInbound 逐条调用 inbound，记录每条结果；inbound 返回的快递公司代码写入结果
+func batchInbound(items []InboundRequest, inbound func(InboundRequest) (string, error)) ([]BatchInboundResult, int) {
+	results := make([]BatchInboundResult, 0, len(items))
+	stored := 0
+	for _, item := range items {
+		r := BatchInboundResult{TrackingNumber: item.TrackingNumber, Status: "stored"}
+		courierCode, err := inbound(item)
+		r.CourierCode = courierCode
+		if err != nil {
+			r.Status = "failed"
+			r.Code = apperr.CodeOf(err)
+			if r.Code == apperr.CodeInternal {
+				log.Printf("batch inbound %s failed: %v", item.TrackingNumber, err)
+			}
+		} else {
+			stored++
+		}
+		results = append(results, r)
+	}
+	return results, stored
 }
 
 // PickupRequest 取件请求结构体
 // 定义接收取件请求时需要的数据格式
 type PickupRequest struct {
 	// 运单号，取件时用于定位具体包裹
-	TrackingNumber string `json:"tracking_number" binding:"required"`
+	TrackingNumber string `json:"tracking_number" binding:"required,tracking_number"`
 
 	// 取件码，取件时用于验证用户身份的凭证
 	PickupCode string `json:"pickup_code" binding:"required"`
 }
 
+// Normalize 取件码由货架编号生成（如 A01-123），同样全角转半角并转大写
+func (r *PickupRequest) Normalize() {
+	r.TrackingNumber = validation.Code(r.TrackingNumber)
+	r.PickupCode = validation.Code(r.PickupCode)
+}
+
 // Pickup 包裹取件服务函数
 // 功能：处理包裹取件的核心业务逻辑
 // 参数：req - PickupRequest结构体，包含取件所需的所有信息
 // 返回值：error - 成功返回nil，失败返回具体错误
 func Pickup(req PickupRequest, userID int64) error {
-	return repository.PickupParcel(req.TrackingNumber, req.PickupCode, userID)
+	err := repository.PickupParcel(req.TrackingNumber, req.PickupCode, userID)
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		return apperr.New(apperr.CodeParcelNotFound)
+	case errors.Is(err, repository.ErrConflict):
+		return apperr.New(apperr.CodeParcelNotPickable)
+	case errors.Is(err, repository.ErrCodeMismatch):
+		return apperr.New(apperr.CodeInvalidPickupCode)
+	}
+	return err
 }
 
 // GetMyParcels 查询我的包裹服务函数
@@ -0,0 +1,82 @@
+package service
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"campus-logistics/internal/repository"
+)
+
+// phoneEncryptionBatch 加密迁移 / 密钥轮换每批处理的行数，来自 encryption.batch_size，默认 500
+func phoneEncryptionBatch() int {
+	if n := viper.GetInt("encryption.batch_size"); n > 0 {
+		return n
+	}
+	return 500
+}
+
+// drainBatches 反复执行 batch 直到处理行数为 0、超过 budget（0 表示不限）或 ctx 取消，返回处理的总行数
+func drainBatches(ctx context.Context, batch func(int) (int, error), budget time.Duration) (int, error) {
+	size := phoneEncryptionBatch()
+	start := time.Now()
+	total := 0
+	for {
+		n, err := batch(size)
+		total += n
+		if err != nil || n == 0 {
+			return total, err
+		}
+		if (budget > 0 && time.Since(start) > budget) || ctx.Err() != nil {
+			return total, nil
+		}
+	}
+}
+
+// MigrateUserPhones 启动时执行：加密 users 与代取授权中的旧版明文手机号并补齐盲索引。
+// 按手机号查找只走盲索引，因此必须在开始处理请求前完成；包裹快照由后台任务 RunPhoneEncryption 分批处理
+func MigrateUserPhones() error {
+	users, err := drainBatches(context.Background(), repository.ReencryptUserPhones, 0)
+	if err != nil {
+		return err
+	}
+	delegations, err := drainBatches(context.Background(), repository.ReencryptDelegationPhones, 0)
+	if err != nil {
+		return err
+	}
+	if users > 0 || delegations > 0 {
+		log.Printf("phone encryption: migrated %d users, %d delegations", users, delegations)
+	}
+	return nil
+}
+
+// RunPhoneEncryption 后台任务：分批加密包裹、寄件订单与用户变更记录中的旧版明文手机号，并在密钥轮换（encryption.active_key 变更）后
+// 用新密钥重新加密 users、parcels、代取授权、寄件订单与用户变更记录中的旧密文；每类数据每次最多运行 1 分钟，停机时在当前批次结束后退出，剩余的下次继续
+func RunPhoneEncryption(ctx context.Context) {
+	users, err := drainBatches(ctx, repository.ReencryptUserPhones, time.Minute)
+	if err != nil {
+		log.Printf("phone encryption (users) failed: %v", err)
+	}
+	parcels, err := drainBatches(ctx, repository.ReencryptParcelPhones, time.Minute)
+	if err != nil {
+		log.Printf("phone encryption (parcels) failed: %v", err)
+	}
+	delegations, err := drainBatches(ctx, repository.ReencryptDelegationPhones, time.Minute)
+	if err != nil {
+		log.Printf("phone encryption (delegations) failed: %v", err)
+	}
+	shipments, err := drainBatches(ctx, repository.ReencryptShipmentPhones, time.Minute)
+	if err != nil {
+		log.Printf("phone encryption (shipments) failed: %v", err)
+	}
+	logs, err := drainBatches(ctx, repository.ReencryptChangeLogPhones, time.Minute)
+	if err != nil {
+		log.Printf("phone encryption (user change logs) failed: %v", err)
+	}
+	if users > 0 || parcels > 0 || delegations > 0 || shipments > 0 || logs > 0 {
+		log.Printf("phone encryption re-encrypted %d users, %d parcels, %d delegations, %d shipments, %d user change logs",
+			users, parcels, delegations, shipments, logs)
+	}
+}
@@ -0,0 +1,147 @@
+package service
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"campus-logistics/internal/apperr"
+	"campus-logistics/internal/middleware"
+	"campus-logistics/internal/model"
+	"campus-logistics/internal/repository"
+
+	"github.com/spf13/viper"
+)
+
+// 取件凭证前缀，带版本号便于以后调整格式
+const pickupCredentialPrefix = "PC1"
+
+// PickupCredential 学生出示的取件凭证，Payload 即二维码内容：
+// PC1.<运单号>.<取件码>.<过期时间戳>.<签名>，驿站扫码后由 verifyPickupCredential 核验
+type PickupCredential struct {
+	TrackingNumber string    `json:"tracking_number"`
+	PickupCode     string    `json:"pickup_code"`
+	ExpiresAt      time.Time `json:"expires_at"`
+	Payload        string    `json:"payload"`
+}
+
+// pickupCredentialTTL 凭证有效期，来自 pickup.credential_ttl_minutes，默认 10 分钟
+func pickupCredentialTTL() time.Duration {
+	if m := viper.GetInt("pickup.credential_ttl_minutes"); m > 0 {
+		return time.Duration(m) * time.Minute
+	}
+	return 10 * time.Minute
+}
+
+// credentialKey 凭证签名密钥：优先使用 pickup.credential_secret，否则由 JWT 密钥按用途派生，
+// 不同用途的凭证互相不能通用
+func credentialKey(purpose string) ([]byte, error) {
+	secret := strings.TrimSpace(viper.GetString("pickup.credential_secret"))
+	if secret == "" {
+		s, err := middleware.JwtSecret()
+		if err != nil {
+			return nil, err
+		}
+		secret = s
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(purpose))
+	return mac.Sum(nil), nil
+}
+
+// signCredential 把各字段用 "." 连接并附上 HMAC-SHA256 签名（截取 128 位，控制二维码密度）
+func signCredential(purpose string, fields ...string) (string, error) {
+	key, err := credentialKey(purpose)
+	if err != nil {
+		return "", err
+	}
+	body := strings.Join(fields, ".")
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(body))
+	return body + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil)[:16]), nil
+}
+
+// credentialSignature 取出 signCredential 结果中的签名部分
+func credentialSignature(signed string) string {
+	return signed[strings.LastIndexByte(signed, '.')+1:]
+}
+
+// errInvalidCredential 凭证格式或签名不正确
+var errInvalidCredential = errors.New("invalid credential")
+
+// verifyCredential 校验 signCredential 生成的凭证并返回除签名外的各字段（首个字段为 purpose）；
+// nfields 为期望的字段数，格式不符或签名不正确时返回 errInvalidCredential
+func verifyCredential(purpose, payload string, nfields int) ([]string, error) {
+	parts := strings.Split(payload, ".")
+	if len(parts) != nfields+1 || parts[0] != purpose {
+		return nil, errInvalidCredential
+	}
+	fields, sig := parts[:nfields], parts[nfields]
+	expected, err := signCredential(purpose, fields...)
+	if err != nil {
+		return nil, err
+	}
+	if !hmac.Equal([]byte(credentialSignature(expected)), []byte(sig)) {
+		return nil, errInvalidCredential
+	}
+	return fields, nil
+}
+
+// IssuePickupCredential 为学生本人已上架的包裹签发取件凭证
+func IssuePickupCredential(userID int64, trackingNum string) (*PickupCredential, error) {
+	pickupCode, status, err := repository.GetStudentParcelPickupCode(userID, trackingNum)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, apperr.New(apperr.CodeParcelNotFound)
+		}
+		return nil, err
+	}
+	if status != "stored" || pickupCode == "" {
+		return nil, apperr.New(apperr.CodeParcelNotPickable)
+	}
+
+	expiresAt := time.Now().Add(pickupCredentialTTL()).Truncate(time.Second)
+	payload, err := signCredential(pickupCredentialPrefix,
+		pickupCredentialPrefix, trackingNum, pickupCode, strconv.FormatInt(expiresAt.Unix(), 10))
+	if err != nil {
+		return nil, err
+	}
+	return &PickupCredential{
+		TrackingNumber: trackingNum,
+		PickupCode:     pickupCode,
+		ExpiresAt:      expiresAt,
+		Payload:        payload,
+	}, nil
+}
+
+// verifyPickupCredential 核验单个包裹的取件凭证：签名错误 INVALID_PICKUP_TOKEN，过期 PICKUP_TOKEN_EXPIRED，
+// 包裹已取件、取件码已变更或不在本站点 NO_PARCELS_READY；通过后取走该包裹
+func verifyPickupCredential(payload, operator string, stationID int64) (*CounterPickupResult, error) {
+	fields, err := verifyCredential(pickupCredentialPrefix, payload, 4)
+	if err != nil {
+		if errors.Is(err, errInvalidCredential) {
+			return nil, apperr.New(apperr.CodeInvalidPickupToken)
+		}
+		return nil, err
+	}
+	exp, err := strconv.ParseInt(fields[3], 10, 64)
+	if err != nil {
+		return nil, apperr.New(apperr.CodeInvalidPickupToken)
+	}
+	if time.Now().After(time.Unix(exp, 0)) {
+		return nil, apperr.New(apperr.CodePickupTokenExpired)
+	}
+
+	parcel, err := repository.CounterPickupSingleParcel(fields[1], fields[2], truncateOperator(operator), stationID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, apperr.New(apperr.CodeNoParcelsReady)
+		}
+		return nil, err
+	}
+	return &CounterPickupResult{Parcels: []model.CounterPickupParcel{*parcel}, Count: 1}, nil
+}
@@ -0,0 +1,121 @@
+package service
+
+import (
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"campus-logistics/internal/apperr"
+	"campus-logistics/internal/repository"
+)
+
+// phoneMaskEnabled 快递员与滞留列表中的手机号是否脱敏，来自 privacy.mask_phone，未配置时默认开启
+func phoneMaskEnabled() bool {
+	if !viper.IsSet("privacy.mask_phone") {
+		return true
+	}
+	return viper.GetBool("privacy.mask_phone")
+}
+
+// maskPhone 手机号脱敏：保留前 privacy.mask_keep_prefix（默认 3）位与后 privacy.mask_keep_suffix（默认 4）位，
+// 中间替换为 ****，如 138****1234；号码太短时整体替换。已脱敏的号码原样返回
+func maskPhone(phone string) string {
+	if phone == "" || !phoneMaskEnabled() || strings.Contains(phone, "*") {
+		return phone
+	}
+	prefix, suffix := 3, 4
+	if viper.IsSet("privacy.mask_keep_prefix") {
+		prefix = max(viper.GetInt("privacy.mask_keep_prefix"), 0)
+	}
+	if viper.IsSet("privacy.mask_keep_suffix") {
+		suffix = max(viper.GetInt("privacy.mask_keep_suffix"), 0)
+	}
+	if prefix+suffix >= len(phone) {
+		return "****"
+	}
+	return phone[:prefix] + "****" + phone[len(phone)-suffix:]
+}
+
+// RevealedPhone 查看完整手机号的结果
+type RevealedPhone struct {
+	TrackingNumber string `json:"tracking_number"`
+	Phone          string `json:"phone"`
+}
+
+// RevealParcelPhone 查看包裹收件人的完整手机号并记录查看人，仅限未取件的包裹。
+// courierID / stationID 非 0 时分别限定快递公司与站点，范围外的包裹按不存在处理
+func RevealParcelPhone(trackingNumber string, courierID, stationID int64, operator, role string) (*RevealedPhone, error) {
+	phone, err := repository.RevealParcelPhone(trackingNumber, courierID, stationID, operator, role)
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		return nil, apperr.New(apperr.CodeParcelNotFound)
+	case errors.Is(err, repository.ErrConflict):
+		return nil, apperr.New(apperr.CodePhoneNotAvailable)
+	case err != nil:
+		return nil, err
+	}
+	return &RevealedPhone{TrackingNumber: trackingNumber, Phone: phone}, nil
+}
+
+// phoneRedactAfter 已取件包裹保留完整手机号快照的时长，来自 privacy.redact_after_days，默认 30 天；0 表示不清理
+func phoneRedactAfter() time.Duration {
+	days := 30
+	if viper.IsSet("privacy.redact_after_days") {
+		days = viper.GetInt("privacy.redact_after_days")
+	}
+	if days <= 0 {
+		return 0
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// RunPhoneRedaction 后台任务：把取件超过 privacy.redact_after_days 天的包裹上的手机号快照改为脱敏形式
+func RunPhoneRedaction() {
+	after := phoneRedactAfter()
+	if after == 0 {
+		return
+	}
+	n, err := repository.RedactPickedUpPhones(time.Now().Add(-after))
+	if err != nil {
+		log.Printf("phone redaction failed: %v", err)
+		return
+	}
+	if n > 0 {
+		log.Printf("phone redaction masked %d parcels", n)
+	}
+}
+
+// userRetention 用户无任何活动后保留个人信息的时长，来自 privacy.user_retention_days；未配置或 0 表示不自动删除
+func userRetention() time.Duration {
+	if days := viper.GetInt("privacy.user_retention_days"); days > 0 {
+		return time.Duration(days) * 24 * time.Hour
+	}
+	return 0
+}
+
+// RunUserRetention 后台任务：删除超过 privacy.user_retention_days 天没有任何活动的用户的个人信息，每次最多 200 个
+func RunUserRetention() {
+	retention := userRetention()
+	if retention == 0 {
+		return
+	}
+	ids, err := repository.ListInactiveUsers(time.Now().Add(-retention), 200)
+	if err != nil {
+		log.Printf("user retention failed: %v", err)
+		return
+	}
+	erased := 0
+	for _, id := range ids {
+		if _, err := repository.EraseUser(id, "SYSTEM", "retention", "retention period exceeded"); err != nil {
+			log.Printf("user retention: erase user %d failed: %v", id, err)
+			continue
+		}
+		erased++
+	}
+	if erased > 0 {
+		log.Printf("user retention erased %d users", erased)
+	}
+}
@@ -0,0 +1,350 @@
+package service
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"campus-logistics/internal/apperr"
+	"campus-logistics/internal/model"
+	"campus-logistics/internal/report"
+	"campus-logistics/internal/repository"
+)
+
+// reportKind 一种报表：XLSX 工作表名、表头与逐行查询
+type reportKind struct {
+	sheet  string
+	header []string
+	stream func(r *Report, stationID int64, fn func([]any) error) error
+}
+
+var reportKinds = map[string]reportKind{
+	"summary": {
+		sheet:  "入库取件汇总",
+		header: []string{"日期", "入库", "取件", "退回", "异常", "平均滞留(小时)"},
+		stream: func(r *Report, stationID int64, fn func([]any) error) error {
+			return repository.StreamDailySummary(r.fromDay(), r.toDay(), stationID, fn)
+		},
+	},
+	"couriers": {
+		sheet:  "快递公司统计",
+		header: []string{"快递公司编码", "快递公司", "入库", "取件", "退回", "异常", "平均滞留(小时)"},
+		stream: func(r *Report, stationID int64, fn func([]any) error) error {
+			return repository.StreamCourierSummary(r.fromDay(), r.toDay(), stationID, fn)
+		},
+	},
+	"retention": {
+		sheet:  "滞留包裹",
+		header: []string{"运单号", "快递公司", "站点", "货架", "状态", "生鲜", "入库时间", "滞留天数"},
+		stream: func(r *Report, stationID int64, fn func([]any) error) error {
+			return repository.StreamRetentionList(r.RetentionDays, perishableRetentionHours(), stationID, fn)
+		},
+	},
+	"exceptions": {
+		sheet:  "异常包裹",
+		header: []string{"运单号", "快递公司", "站点", "标记时间", "操作人", "当前状态"},
+		stream: func(r *Report, stationID int64, fn func([]any) error) error {
+			return repository.StreamExceptionList(r.fromDay(), r.toDay(), stationID, fn)
+		},
+	},
+}
+
+// reportKindNames 报表种类，按此顺序生成定时报表
+var reportKindNames = []string{"summary", "couriers", "retention", "exceptions"}
+
+// Report 一次报表导出：种类、格式与统计周期 [From, To)
+type Report struct {
+	Kind   string
+	Format string
+	// Period daily / weekly / monthly
+	Period string
+	From   time.Time
+	To     time.Time
+	// Label 周期标识：2026-10-18 / 2026-W42 / 2026-10
+	Label string
+	// RetentionDays 滞留包裹报表的天数阈值（生鲜/冷链按 retention.perishable_hours）
+	RetentionDays int
+}
+
+// NewReport 校验报表参数并计算统计周期；date 为周期内任意一天（YYYY-MM-DD），默认昨天
+func NewReport(kind, format, period, date string) (*Report, error) {
+	if _, ok := reportKinds[kind]; !ok {
+		return nil, apperr.Invalid("kind", "oneof").WithParam(strings.Join(reportKindNames, " "))
+	}
+	if format == "" {
+		format = "xlsx"
+	}
+	if !slices.Contains(report.Formats, format) {
+		return nil, apperr.Invalid("format", "oneof").WithParam(strings.Join(report.Formats, " "))
+	}
+	if period == "" {
+		period = "daily"
+	}
+
+	now := time.Now()
+	day := time.Date(now.Year(), now.Month(), now.Day()-1, 0, 0, 0, 0, time.Local)
+	if date != "" {
+		t, err := time.ParseInLocation("2006-01-02", date, time.Local)
+		if err != nil {
+			return nil, apperr.Invalid("date", "date")
+		}
+		day = t
+	}
+
+	r := &Report{Kind: kind, Format: format, Period: period, RetentionDays: 7}
+	switch period {
+	case "daily":
+		r.From, r.To = day, day.AddDate(0, 0, 1)
+		r.Label = day.Format("2006-01-02")
+	case "weekly":
+		// ISO 周：周一至周日
+		offset := (int(day.Weekday()) + 6) % 7
+		r.From = day.AddDate(0, 0, -offset)
+		r.To = r.From.AddDate(0, 0, 7)
+		year, week := r.From.ISOWeek()
+		r.Label = fmt.Sprintf("%d-W%02d", year, week)
+	case "monthly":
+		r.From = time.Date(day.Year(), day.Month(), 1, 0, 0, 0, 0, time.Local)
+		r.To = r.From.AddDate(0, 1, 0)
+		r.Label = r.From.Format("2006-01")
+	default:
+		return nil, apperr.Invalid("period", "oneof").WithParam("daily weekly monthly")
+	}
+	return r, nil
+}
+
+func (r *Report) fromDay() string { return r.From.Format("2006-01-02") }
+
+func (r *Report) toDay() string { return r.To.AddDate(0, 0, -1).Format("2006-01-02") }
+
+// FileName 报表文件名：<种类>_<周期>_<周期标识>_<站点编号或 ALL>.<格式>
+func (r *Report) FileName(stationCode string) string {
+	if stationCode == "" {
+		stationCode = "ALL"
+	}
+	return fmt.Sprintf("%s_%s_%s_%s.%s", r.Kind, r.Period, r.Label, stationCode, r.Format)
+}
+
+// Write 从数据库逐行读取并输出报表；stationID 为 0 表示全部站点
+func (r *Report) Write(w io.Writer, stationID int64) error {
+	kind := reportKinds[r.Kind]
+	out, err := report.New(r.Format, w, kind.sheet)
+	if err != nil {
+		return err
+	}
+	header := make([]any, len(kind.header))
+	for i, h := range kind.header {
+		header[i] = h
+	}
+	if err := out.Row(header...); err != nil {
+		return err
+	}
+	if err := kind.stream(r, stationID, func(values []any) error {
+		return out.Row(values...)
+	}); err != nil {
+		return err
+	}
+	return out.Close()
+}
+
+// ReportStationCode 文件名中的站点编号，stationID 为 0 时为空（全部站点）
+func ReportStationCode(stationID int64) (string, error) {
+	if stationID == 0 {
+		return "", nil
+	}
+	st, err := repository.GetStationByID(stationID)
+	if err != nil {
+		return "", err
+	}
+	return st.Code, nil
+}
+
+// reportsDir 定时报表的保存目录，来自 reports.dir，默认 data/reports
+func reportsDir() string {
+	if d := viper.GetString("reports.dir"); d != "" {
+		return d
+	}
+	return "data/reports"
+}
+
+// reportsKeepDays 定时报表的保留天数，来自 reports.keep_days，默认 90 天
+func reportsKeepDays() int {
+	if d := viper.GetInt("reports.keep_days"); d > 0 {
+		return d
+	}
+	return 90
+}
+
+// RunScheduledReports 后台任务：为昨天（日报）、上周（周报）、上月（月报）生成全部站点及各站点的报表，
+// 已存在的文件跳过；随后清理超过保留天数的文件
+func RunScheduledReports() {
+	if !viper.GetBool("reports.schedule.enabled") {
+		return
+	}
+	dir := reportsDir()
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		log.Printf("scheduled reports failed: %v", err)
+		return
+	}
+
+	periods := viper.GetStringSlice("reports.schedule.periods")
+	if len(periods) == 0 {
+		periods = []string{"daily", "weekly", "monthly"}
+	}
+	formats := viper.GetStringSlice("reports.schedule.formats")
+	if len(formats) == 0 {
+		formats = []string{"xlsx"}
+	}
+	stations, err := repository.ListStations()
+	if err != nil {
+		log.Printf("scheduled reports failed: %v", err)
+		return
+	}
+	scopes := append([]model.Station{{}}, stations...)
+
+	now := time.Now()
+	for _, period := range periods {
+		// 取上一个完整周期内的某一天
+		var day time.Time
+		switch period {
+		case "daily":
+			day = now.AddDate(0, 0, -1)
+		case "weekly":
+			day = now.AddDate(0, 0, -7)
+		case "monthly":
+			day = time.Date(now.Year(), now.Month(), 0, 0, 0, 0, 0, time.Local)
+		default:
+			log.Printf("scheduled reports: unknown period %q", period)
+			continue
+		}
+		for _, kind := range reportKindNames {
+			for _, format := range formats {
+				r, err := NewReport(kind, format, period, day.Format("2006-01-02"))
+				if err != nil {
+					log.Printf("scheduled reports: %s %s %s: %v", kind, period, format, err)
+					continue
+				}
+				for _, st := range scopes {
+					if err := saveReport(dir, r, st); err != nil {
+						log.Printf("scheduled reports failed: %v", err)
+					}
+				}
+			}
+		}
+	}
+	purgeReports(dir, time.Duration(reportsKeepDays())*24*time.Hour)
+}
+
+// saveReport 生成报表文件；先写临时文件再改名，避免留下不完整的文件
+func saveReport(dir string, r *Report, st model.Station) error {
+	path := filepath.Join(dir, r.FileName(st.Code))
+	if _, err := os.Stat(path); err == nil {
+		return nil
+	}
+	tmp, err := os.CreateTemp(dir, ".report-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := r.Write(tmp, st.ID); err != nil {
+		tmp.Close()
+		return fmt.Errorf("generate %s: %w", filepath.Base(path), err)
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// purgeReports 删除修改时间早于保留期限的报表文件
+func purgeReports(dir string, keep time.Duration) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		log.Printf("report purge failed: %v", err)
+		return
+	}
+	cutoff := time.Now().Add(-keep)
+	for _, e := range entries {
+		info, err := e.Info()
+		if err != nil || e.IsDir() || !info.ModTime().Before(cutoff) {
+			continue
+		}
+		if err := os.Remove(filepath.Join(dir, e.Name())); err != nil {
+			log.Printf("report purge failed: %v", err)
+		}
+	}
+}
+
+// ReportFile 已保存的定时报表文件
+type ReportFile struct {
+	Name        string    `json:"name"`
+	StationCode string    `json:"station_code"`
+	Size        int64     `json:"size"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// reportFileStation 从文件名中解析站点编号；ALL 表示全部站点，返回 ok=false 表示不是报表文件
+func reportFileStation(name string) (string, bool) {
+	parts := strings.SplitN(name, "_", 4)
+	if len(parts) != 4 {
+		return "", false
+	}
+	ext := filepath.Ext(parts[3])
+	if !slices.Contains(report.Formats, strings.TrimPrefix(ext, ".")) {
+		return "", false
+	}
+	code := strings.TrimSuffix(parts[3], ext)
+	if code == "ALL" {
+		code = ""
+	}
+	return code, true
+}
+
+// ListReportFiles 列出已保存的报表（按文件名倒序）；stationCode 非空时只列出该站点的报表
+func ListReportFiles(stationCode string) ([]ReportFile, error) {
+	files := []ReportFile{}
+	entries, err := os.ReadDir(reportsDir())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return files, nil
+		}
+		return nil, err
+	}
+	for _, e := range entries {
+		code, ok := reportFileStation(e.Name())
+		if !ok || e.IsDir() || (stationCode != "" && code != stationCode) {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		files = append(files, ReportFile{Name: e.Name(), StationCode: code, Size: info.Size(), CreatedAt: info.ModTime()})
+	}
+	slices.Reverse(files)
+	return files, nil
+}
+
+// OpenReportFile 打开已保存的报表；文件不存在或不属于 stationCode（非空时）返回 404
+func OpenReportFile(name, stationCode string) (*os.File, error) {
+	code, ok := reportFileStation(name)
+	if !ok || name != filepath.Base(name) || (stationCode != "" && code != stationCode) {
+		return nil, apperr.New(apperr.CodeNotFound)
+	}
+	f, err := os.Open(filepath.Join(reportsDir(), name))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, apperr.New(apperr.CodeNotFound)
+		}
+		return nil, err
+	}
+	return f, nil
+}
@@ -0,0 +1,266 @@
+package service
+
+import (
+	"errors"
+	"log"
+	"strings"
+
+	"campus-logistics/internal/apperr"
+	"campus-logistics/internal/model"
+	"campus-logistics/internal/repository"
+	"campus-logistics/internal/validation"
+
+	"github.com/spf13/viper"
+)
+
+// MaxReturnBatchSize 单个退件批次（及单次交接扫码）的最大包裹数
+const MaxReturnBatchSize = 200
+
+// CreateReturnBatchRequest 管理员创建退件批次
+type CreateReturnBatchRequest struct {
+	CourierCode string `json:"courier_code" binding:"required,max=20"`
+	// StationCode 退件站点；站点管理员可省略（使用本站点）
+	StationCode     string   `json:"station_code"`
+	TrackingNumbers []string `json:"tracking_numbers" binding:"required,min=1,dive,tracking_number"`
+}
+
+func (r *CreateReturnBatchRequest) Normalize() {
+	r.CourierCode = validation.Code(r.CourierCode)
+	r.StationCode = validation.Code(r.StationCode)
+	for i := range r.TrackingNumbers {
+		r.TrackingNumbers[i] = validation.Code(r.TrackingNumbers[i])
+	}
+}
+
+// RetentionReturnRequest 管理员按滞留策略生成退件批次
+type RetentionReturnRequest struct {
+	// Days 滞留天数阈值，缺省为 returns.auto_days（未配置时 14 天）
+	Days int `json:"days" binding:"omitempty,min=1,max=365"`
+}
+
+// RetentionReturnResult 按滞留策略生成的批次数与包裹数
+type RetentionReturnResult struct {
+	Batches int `json:"batches"`
+	Parcels int `json:"parcels"`
+}
+
+// HandoverRequest 快递员扫码交接退件；StaffName / StaffID 为现场交接的快递员工作人员
+type HandoverRequest struct {
+	TrackingNumbers []string `json:"tracking_numbers" binding:"required,min=1,dive,tracking_number"`
+	StaffName       string   `json:"staff_name" binding:"required,max=32"`
+	StaffID         string   `json:"staff_id" binding:"max=20"`
+}
+
+func (r *HandoverRequest) Normalize() {
+	for i := range r.TrackingNumbers {
+		r.TrackingNumbers[i] = validation.Code(r.TrackingNumbers[i])
+	}
+	r.StaffName = validation.Text(r.StaffName)
+	r.StaffID = validation.Code(r.StaffID)
+}
+
+// staff 交接人标识：姓名，填写工号时为 "姓名/工号"
+func (r HandoverRequest) staff() string {
+	if r.StaffID == "" {
+		return r.StaffName
+	}
+	return r.StaffName + "/" + r.StaffID
+}
+
+// HandoverResult 单个包裹的交接结果，各包裹互不影响
+type HandoverResult struct {
+	TrackingNumber string      `json:"tracking_number"`
+	Status         string      `json:"status"` // returned / failed
+	Code           apperr.Code `json:"code,omitempty"`
+	// Error 本地化的失败提示，由 handler 按请求语言填写
+	Error string `json:"error,omitempty"`
+}
+
+// ReturnAutoDays 滞留策略的退件阈值（天），来自 returns.auto_days，默认 14 天
+func ReturnAutoDays() int {
+	if d := viper.GetInt("returns.auto_days"); d > 0 {
+		return d
+	}
+	return 14
+}
+
+// CreateReturnBatch 管理员为指定快递公司创建退件批次；operator 为管理员用户名。
+// 有不符合条件的运单号时整批不创建，返回 PARCEL_NOT_RETURNABLE 并列出这些运单号
+func CreateReturnBatch(req CreateReturnBatchRequest, stationID int64, operator string) (*model.ReturnBatch, error) {
+	if len(req.TrackingNumbers) > MaxReturnBatchSize {
+		return nil, apperr.Newf(apperr.CodeBatchTooLarge, "max %d", MaxReturnBatchSize)
+	}
+	courierID, err := CourierIDByCode(req.CourierCode)
+	if err != nil {
+		return nil, err
+	}
+
+	id, ineligible, err := repository.CreateReturnBatch(courierID, stationID, dedupe(req.TrackingNumbers), truncateOperator("admin:"+operator))
+	if err != nil {
+		if errors.Is(err, repository.ErrConflict) {
+			return nil, apperr.Newf(apperr.CodeParcelNotReturnable, "%s", strings.Join(ineligible, ","))
+		}
+		return nil, err
+	}
+	return GetReturnBatch(id, 0, stationID)
+}
+
+// CourierIDByCode 快递公司代码转换为 ID；不存在返回 INVALID_COURIER
+func CourierIDByCode(code string) (int64, error) {
+	courier, err := repository.GetCourierByCode(validation.Code(code))
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return 0, apperr.New(apperr.CodeInvalidCourier)
+		}
+		return 0, err
+	}
+	return courier.ID, nil
+}
+
+// CreateRetentionReturnBatches 按滞留策略为全部快递公司生成退件批次；days 为 0 时使用 ReturnAutoDays
+func CreateRetentionReturnBatches(days int, stationID int64, operator string) (*RetentionReturnResult, error) {
+	if days <= 0 {
+		days = ReturnAutoDays()
+	}
+	batches, parcels, err := repository.CreateRetentionReturnBatches(days, perishableRetentionHours(), stationID, operator)
+	if err != nil {
+		return nil, err
+	}
+	return &RetentionReturnResult{Batches: batches, Parcels: parcels}, nil
+}
+
+// RunRetentionReturns 后台任务：returns.auto_enabled 开启时，按滞留策略为全部站点生成退件批次
+func RunRetentionReturns() {
+	if !viper.GetBool("returns.auto_enabled") {
+		return
+	}
+	result, err := CreateRetentionReturnBatches(0, 0, "SYSTEM")
+	if err != nil {
+		log.Printf("retention returns failed: %v", err)
+		return
+	}
+	if result.Batches > 0 {
+		log.Printf("retention returns created %d batches (%d parcels)", result.Batches, result.Parcels)
+	}
+}
+
+// ListReturnBatches 查询退件批次；withItems 为 true 时附带包裹明细（快递员视图）
+func ListReturnBatches(courierID, stationID int64, status string, page, pageSize int, withItems bool) ([]model.ReturnBatch, error) {
+	if page < 1 {
+		page = 1
+	}
+	if pageSize <= 0 || pageSize > 100 {
+		pageSize = 20
+	}
+	batches, err := repository.ListReturnBatches(courierID, stationID, status, pageSize, (page-1)*pageSize)
+	if err != nil || !withItems {
+		return batches, err
+	}
+	return batches, attachReturnItems(batches)
+}
+
+// GetReturnBatch 查询单个退件批次及其包裹明细；不存在返回 RETURN_BATCH_NOT_FOUND
+func GetReturnBatch(id, courierID, stationID int64) (*model.ReturnBatch, error) {
+	b, err := repository.GetReturnBatch(id, courierID, stationID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, apperr.New(apperr.CodeReturnBatchNotFound)
+		}
+		return nil, err
+	}
+	batches := []model.ReturnBatch{*b}
+	if err := attachReturnItems(batches); err != nil {
+		return nil, err
+	}
+	return &batches[0], nil
+}
+
+func attachReturnItems(batches []model.ReturnBatch) error {
+	ids := make([]int64, len(batches))
+	for i, b := range batches {
+		ids[i] = b.ID
+	}
+	items, err := repository.ListReturnItems(ids)
+	if err != nil {
+		return err
+	}
+	byBatch := make(map[int64][]model.ReturnItem, len(batches))
+	for _, it := range items {
+		byBatch[it.BatchID] = append(byBatch[it.BatchID], it)
+	}
+	for i := range batches {
+		batches[i].Items = byBatch[batches[i].ID]
+	}
+	return nil
+}
+
+// CancelReturnBatch 管理员取消退件批次，未交接的包裹留在原货架
+func CancelReturnBatch(id, stationID int64) error {
+	err := repository.CancelReturnBatch(id, stationID)
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		return apperr.New(apperr.CodeReturnBatchNotFound)
+	case errors.Is(err, repository.ErrBatchClosed):
+		return apperr.New(apperr.CodeReturnBatchClosed)
+	}
+	return err
+}
+
+// HandoverReturns 快递员逐个交接扫码的包裹，每个包裹在各自的事务中执行；返回每个包裹的结果与成功数。
+// 批次不存在或已结束时整体返回错误
+func HandoverReturns(batchID, courierID int64, courierCode string, req HandoverRequest) ([]HandoverResult, int, error) {
+	if len(req.TrackingNumbers) > MaxReturnBatchSize {
+		return nil, 0, apperr.Newf(apperr.CodeBatchTooLarge, "max %d", MaxReturnBatchSize)
+	}
+	b, err := repository.GetReturnBatch(batchID, courierID, 0)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, 0, apperr.New(apperr.CodeReturnBatchNotFound)
+		}
+		return nil, 0, err
+	}
+	if b.Status != "open" {
+		return nil, 0, apperr.New(apperr.CodeReturnBatchClosed)
+	}
+
+	staff := req.staff()
+	operator := truncateOperator("courier:" + courierCode + ":" + staff)
+	trackingNums := dedupe(req.TrackingNumbers)
+	results := make([]HandoverResult, 0, len(trackingNums))
+	returned := 0
+	for _, tn := range trackingNums {
+		r := HandoverResult{TrackingNumber: tn, Status: "returned"}
+		err := repository.HandoverReturnParcel(batchID, courierID, tn, staff, operator)
+		switch {
+		case err == nil:
+			returned++
+		case errors.Is(err, repository.ErrParcelNotInBatch):
+			r.Code = apperr.CodeParcelNotFound
+		case errors.Is(err, repository.ErrConflict):
+			r.Code = apperr.CodeParcelNotReturnable
+		case errors.Is(err, repository.ErrBatchClosed), errors.Is(err, repository.ErrNotFound):
+			r.Code = apperr.CodeReturnBatchClosed
+		default:
+			r.Code = apperr.CodeOf(err)
+			log.Printf("return handover %s failed: %v", tn, err)
+		}
+		if r.Code != "" {
+			r.Status = "failed"
+		}
+		results = append(results, r)
+	}
+	return results, returned, nil
+}
+
+// dedupe 去掉重复的运单号（扫码枪可能重复扫描），保持原有顺序
+func dedupe(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	out := make([]string, 0, len(values))
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			out = append(out, v)
+		}
+	}
+	return out
+}
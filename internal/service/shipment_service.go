@@ -0,0 +1,367 @@
+package service
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+
+	"campus-logistics/internal/apperr"
+	"campus-logistics/internal/model"
+	"campus-logistics/internal/repository"
+	"campus-logistics/internal/validation"
+
+	"github.com/spf13/viper"
+)
+
+// provinceSuffixes 省级行政区名称的后缀，匹配区域前去掉（"浙江省" 与 "浙江" 视为相同）
+var provinceSuffixes = []string{"维吾尔自治区", "壮族自治区", "回族自治区", "特别行政区", "自治区", "省", "市"}
+
+// normalizeProvince 去掉空白与行政区后缀
+func normalizeProvince(p string) string {
+	p = validation.Text(p)
+	for _, suffix := range provinceSuffixes {
+		if trimmed := strings.TrimSuffix(p, suffix); trimmed != p && trimmed != "" {
+			return trimmed
+		}
+	}
+	return p
+}
+
+// shippingZone 收件省份所属的运费区域，来自 shipping.zones（区域 -> 省份列表）；
+// 未列出的省份使用 shipping.default_zone，为空时返回 ""
+func shippingZone(province string) string {
+	province = normalizeProvince(province)
+	zones := viper.GetStringMapStringSlice("shipping.zones")
+	names := make([]string, 0, len(zones))
+	for name := range zones {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, p := range zones[name] {
+			if normalizeProvince(p) == province {
+				return name
+			}
+		}
+	}
+	return viper.GetString("shipping.default_zone")
+}
+
+// shippingZones 配置中的全部区域（含默认区域），按名称排序
+func shippingZones() []string {
+	zones := viper.GetStringMapStringSlice("shipping.zones")
+	names := make([]string, 0, len(zones)+1)
+	for name := range zones {
+		names = append(names, name)
+	}
+	if d := viper.GetString("shipping.default_zone"); d != "" && zones[d] == nil {
+		names = append(names, d)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// shippingPrice 运费 = 首重价格 + 超出首重部分按续重单位向上取整计价
+func shippingPrice(rate model.ShippingRate, weightGrams int) int {
+	price := rate.FirstPriceCents
+	if extra := weightGrams - rate.FirstWeightGrams; extra > 0 && rate.AdditionalWeightGrams > 0 {
+		units := (extra + rate.AdditionalWeightGrams - 1) / rate.AdditionalWeightGrams
+		price += units * rate.AdditionalPriceCents
+	}
+	return price
+}
+
+// QuoteRequest 运费试算请求；不指定快递公司时返回全部可寄快递公司的报价
+type QuoteRequest struct {
+	CourierCode string `json:"courier_code" binding:"max=20"`
+	Province    string `json:"province" binding:"required,max=20"`
+	WeightGrams int    `json:"weight_grams" binding:"required,min=1,max=50000"`
+}
+
+func (r *QuoteRequest) Normalize() {
+	r.CourierCode = validation.Code(r.CourierCode)
+	r.Province = normalizeProvince(r.Province)
+}
+
+// ShippingQuote 单个快递公司的报价
+type ShippingQuote struct {
+	CourierCode string `json:"courier_code"`
+	CourierName string `json:"courier_name"`
+	Zone        string `json:"zone"`
+	WeightGrams int    `json:"weight_grams"`
+	PriceCents  int    `json:"price_cents"`
+}
+
+// QuoteShipping 运费试算，按价格从低到高排序；没有可用报价时返回 SHIPPING_UNAVAILABLE
+func QuoteShipping(req QuoteRequest) ([]ShippingQuote, error) {
+	zone := shippingZone(req.Province)
+	if zone == "" {
+		return nil, apperr.Newf(apperr.CodeShippingUnavailable, "%s", req.Province)
+	}
+	rates, err := repository.ListShippingRates(req.CourierCode, zone)
+	if err != nil {
+		return nil, err
+	}
+	if len(rates) == 0 {
+		return nil, apperr.Newf(apperr.CodeShippingUnavailable, "%s", req.Province)
+	}
+	quotes := make([]ShippingQuote, 0, len(rates))
+	for _, r := range rates {
+		quotes = append(quotes, ShippingQuote{
+			CourierCode: r.CourierCode,
+			CourierName: r.CourierName,
+			Zone:        zone,
+			WeightGrams: req.WeightGrams,
+			PriceCents:  shippingPrice(r, req.WeightGrams),
+		})
+	}
+	sort.SliceStable(quotes, func(i, j int) bool { return quotes[i].PriceCents < quotes[j].PriceCents })
+	return quotes, nil
+}
+
+// quoteFor 指定快递公司的报价
+func quoteFor(courierCode, province string, weightGrams int) (*ShippingQuote, error) {
+	quotes, err := QuoteShipping(QuoteRequest{CourierCode: courierCode, Province: province, WeightGrams: weightGrams})
+	if err != nil {
+		return nil, err
+	}
+	return &quotes[0], nil
+}
+
+// ShippingRateRequest 管理员设置运费（单位：克、分）
+type ShippingRateRequest struct {
+	FirstWeightGrams      int `json:"first_weight_grams" binding:"required,min=1,max=50000"`
+	FirstPriceCents       int `json:"first_price_cents" binding:"min=0,max=1000000"`
+	AdditionalWeightGrams int `json:"additional_weight_grams" binding:"required,min=1,max=50000"`
+	AdditionalPriceCents  int `json:"additional_price_cents" binding:"min=0,max=1000000"`
+}
+
+// ListShippingRates 查询运费表；courierCode 为空表示全部快递公司
+func ListShippingRates(courierCode string) ([]model.ShippingRate, error) {
+	return repository.ListShippingRates(validation.Code(courierCode), "")
+}
+
+// SetShippingRate 设置快递公司在某个区域的运费；区域须在 shipping.zones 或 shipping.default_zone 中
+func SetShippingRate(courierCode, zone string, req ShippingRateRequest) error {
+	zone = strings.ToLower(strings.TrimSpace(zone))
+	known := false
+	for _, z := range shippingZones() {
+		known = known || z == zone
+	}
+	if !known {
+		return apperr.Invalid("zone", "oneof").WithParam(strings.Join(shippingZones(), " "))
+	}
+	courierID, err := CourierIDByCode(courierCode)
+	if err != nil {
+		return err
+	}
+	return repository.UpsertShippingRate(courierID, zone,
+		req.FirstWeightGrams, req.FirstPriceCents, req.AdditionalWeightGrams, req.AdditionalPriceCents)
+}
+
+// DeleteShippingRate 删除运费，该快递公司不再接受寄往该区域的订单
+func DeleteShippingRate(courierCode, zone string) error {
+	courierID, err := CourierIDByCode(courierCode)
+	if err != nil {
+		return err
+	}
+	err = repository.DeleteShippingRate(courierID, strings.ToLower(strings.TrimSpace(zone)))
+	if errors.Is(err, repository.ErrNotFound) {
+		return apperr.New(apperr.CodeShippingUnavailable)
+	}
+	return err
+}
+
+// CreateShipmentRequest 学生寄件下单
+type CreateShipmentRequest struct {
+	CourierCode string `json:"courier_code" binding:"required,max=20"`
+	// StationCode 交寄的驿站，为空时使用默认站点
+	StationCode string `json:"station_code"`
+	// SenderName 寄件人姓名，为空时使用账号姓名；寄件人手机号固定为账号手机号
+	SenderName        string `json:"sender_name" binding:"max=50"`
+	RecipientName     string `json:"recipient_name" binding:"required,max=50"`
+	RecipientPhone    string `json:"recipient_phone" binding:"required,cnmobile"`
+	RecipientProvince string `json:"recipient_province" binding:"required,max=20"`
+	RecipientAddress  string `json:"recipient_address" binding:"required,max=200"`
+	// WeightGrams 申报重量，驿站收件时按实际称重重新计算运费
+	WeightGrams int `json:"weight_grams" binding:"required,min=1,max=50000"`
+}
+
+func (r *CreateShipmentRequest) Normalize() {
+	r.CourierCode = validation.Code(r.CourierCode)
+	r.StationCode = validation.Code(r.StationCode)
+	r.SenderName = validation.Text(r.SenderName)
+	r.RecipientName = validation.Text(r.RecipientName)
+	r.RecipientPhone = validation.Phone(r.RecipientPhone)
+	r.RecipientProvince = normalizeProvince(r.RecipientProvince)
+	r.RecipientAddress = validation.Text(r.RecipientAddress)
+}
+
+// DropOffRequest 驿站收件；WeightGrams 为称重结果，为 0 时沿用申报重量
+type DropOffRequest struct {
+	WeightGrams int `json:"weight_grams" binding:"omitempty,min=1,max=50000"`
+}
+
+// CollectRequest 快递员揽收，登记面单上的运单号
+type CollectRequest struct {
+	TrackingNumber string `json:"tracking_number" binding:"required,tracking_number"`
+}
+
+func (r *CollectRequest) Normalize() {
+	r.TrackingNumber = validation.Code(r.TrackingNumber)
+}
+
+// CreateShipment 学生下单寄件：按申报重量报价后保存订单。
+// 寄件人手机号取自当前用户资料而非 JWT，换绑手机号后旧令牌不会带出旧号码
+func CreateShipment(userID int64, req CreateShipmentRequest) (*model.Shipment, error) {
+	courierID, err := CourierIDByCode(req.CourierCode)
+	if err != nil {
+		return nil, err
+	}
+	quote, err := quoteFor(req.CourierCode, req.RecipientProvince, req.WeightGrams)
+	if err != nil {
+		return nil, err
+	}
+	stationID, err := shipmentStationID(req.StationCode)
+	if err != nil {
+		return nil, err
+	}
+	sender, err := GetProfile(userID)
+	if err != nil {
+		return nil, err
+	}
+	senderName := req.SenderName
+	if senderName == "" {
+		senderName = sender.Name
+	}
+
+	orderNo, err := repository.CreateShipment(repository.NewShipment{
+		UserID:            userID,
+		CourierID:         courierID,
+		StationID:         stationID,
+		SenderName:        senderName,
+		SenderPhone:       sender.Phone,
+		RecipientName:     req.RecipientName,
+		RecipientPhone:    req.RecipientPhone,
+		RecipientProvince: req.RecipientProvince,
+		RecipientAddress:  req.RecipientAddress,
+		Zone:              quote.Zone,
+		WeightGrams:       req.WeightGrams,
+		PriceCents:        quote.PriceCents,
+	}, "user:"+strconv.FormatInt(userID, 10))
+	if err != nil {
+		return nil, err
+	}
+	return GetShipment(orderNo, repository.ShipmentScope{UserID: userID}, false)
+}
+
+// shipmentStationID 交寄站点：为空时使用默认站点（id 最小）
+func shipmentStationID(code string) (int64, error) {
+	if code != "" {
+		st, err := repository.GetStationByCode(code)
+		if err != nil {
+			if errors.Is(err, repository.ErrNotFound) {
+				return 0, apperr.New(apperr.CodeInvalidStation)
+			}
+			return 0, err
+		}
+		return st.ID, nil
+	}
+	stations, err := repository.ListStations()
+	if err != nil {
+		return 0, err
+	}
+	if len(stations) == 0 {
+		return 0, apperr.New(apperr.CodeInvalidStation)
+	}
+	return stations[0].ID, nil
+}
+
+// GetShipment 查询寄件订单及状态记录；withOperator 为 false 时隐藏操作人（学生视图）
+func GetShipment(orderNo string, scope repository.ShipmentScope, withOperator bool) (*model.Shipment, error) {
+	s, err := repository.GetShipment(orderNo, scope)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, apperr.New(apperr.CodeShipmentNotFound)
+		}
+		return nil, err
+	}
+	events, err := repository.ListShipmentEvents(orderNo)
+	if err != nil {
+		return nil, err
+	}
+	if !withOperator {
+		for i := range events {
+			events[i].Operator = ""
+		}
+	}
+	s.Events = events
+	return s, nil
+}
+
+// ListShipments 查询访问范围内的寄件订单
+func ListShipments(scope repository.ShipmentScope, status string, page, pageSize int) ([]model.Shipment, error) {
+	if page < 1 {
+		page = 1
+	}
+	if pageSize <= 0 || pageSize > 100 {
+		pageSize = 20
+	}
+	return repository.ListShipments(scope, status, pageSize, (page-1)*pageSize)
+}
+
+// shipmentTransitionError 状态变更失败时的错误：订单不存在 SHIPMENT_NOT_FOUND，当前状态不允许 ILLEGAL_TRANSITION
+func shipmentTransitionError(err error, target string) error {
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		return apperr.New(apperr.CodeShipmentNotFound)
+	case errors.Is(err, repository.ErrConflict):
+		return apperr.Newf(apperr.CodeIllegalTransition, "-> %s", target)
+	}
+	return err
+}
+
+// CancelShipment 学生取消尚未交寄的订单
+func CancelShipment(orderNo string, userID int64) error {
+	err := repository.CancelShipment(orderNo, repository.ShipmentScope{UserID: userID}, "user:"+strconv.FormatInt(userID, 10))
+	return shipmentTransitionError(err, "cancelled")
+}
+
+// DropOffShipment 驿站收件：按称重结果重新计算运费；stationID 非 0 时只能收本站点的订单
+func DropOffShipment(orderNo string, stationID int64, req DropOffRequest, operator string) (*model.Shipment, error) {
+	scope := repository.ShipmentScope{StationID: stationID}
+	s, err := repository.GetShipment(orderNo, scope)
+	if err != nil {
+		return nil, shipmentTransitionError(err, "dropped_off")
+	}
+	weight := req.WeightGrams
+	if weight == 0 {
+		weight = s.WeightGrams
+	}
+	quote, err := quoteFor(s.CourierCode, s.RecipientProvince, weight)
+	if err != nil {
+		return nil, err
+	}
+	err = repository.DropOffShipment(orderNo, scope, weight, quote.PriceCents, truncateOperator("admin:"+operator))
+	if err != nil {
+		return nil, shipmentTransitionError(err, "dropped_off")
+	}
+	return GetShipment(orderNo, scope, true)
+}
+
+// CollectShipment 快递员揽收本公司的订单并登记运单号（按快递公司的运单号规则校验）
+func CollectShipment(orderNo string, courierID int64, courierCode string, req CollectRequest) (*model.Shipment, error) {
+	if err := checkTrackingNumber(courierCode, req.TrackingNumber); err != nil {
+		return nil, err
+	}
+	scope := repository.ShipmentScope{CourierID: courierID}
+	err := repository.CollectShipment(orderNo, scope, req.TrackingNumber, "courier:"+courierCode)
+	if errors.Is(err, repository.ErrDuplicate) {
+		return nil, apperr.New(apperr.CodeDuplicateTracking)
+	}
+	if err != nil {
+		return nil, shipmentTransitionError(err, "collected")
+	}
+	return GetShipment(orderNo, scope, false)
+}
@@ -0,0 +1,72 @@
+package service
+
+import (
+	"errors"
+	"strings"
+
+	"campus-logistics/internal/model"
+	"campus-logistics/internal/repository"
+)
+
+var (
+	ErrStationForbidden = errors.New("station out of scope")
+	ErrInvalidStation   = errors.New("invalid station parameters")
+)
+
+func ListStationsService() ([]model.Station, error) {
+	return repository.ListStations()
+}
+
+func CreateStationService(code, name, address string) (*model.Station, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	name = strings.TrimSpace(name)
+	address = strings.TrimSpace(address)
+	if code == "" || len(code) > 20 || name == "" {
+		return nil, ErrInvalidStation
+	}
+	return repository.CreateStation(code, name, address)
+}
+
+func DeleteStationService(code string) error {
+	return repository.DeleteStationByCode(strings.ToUpper(strings.TrimSpace(code)))
+}
+
+// ResolveStationScope 计算本次请求可访问的站点：
+//   - 绑定站点的管理员（tokenStationID != 0）只能访问本站点，指定其他站点返回 ErrStationForbidden
+//   - 未绑定站点的管理员可通过 requestedCode 过滤，为空表示全部站点（返回 0）
+func ResolveStationScope(tokenStationID int64, requestedCode string) (int64, error) {
+	requestedCode = strings.ToUpper(strings.TrimSpace(requestedCode))
+	if tokenStationID != 0 {
+		if requestedCode != "" {
+			st, err := repository.GetStationByCode(requestedCode)
+			if err != nil || st.ID != tokenStationID {
+				return 0, ErrStationForbidden
+			}
+		}
+		return tokenStationID, nil
+	}
+	if requestedCode == "" {
+		return 0, nil
+	}
+	st, err := repository.GetStationByCode(requestedCode)
+	if err != nil {
+		return 0, err
+	}
+	return st.ID, nil
+}
+
+// lookupStationID 将站点编号转换为 ID；空字符串返回 nil（全部站点）
+func lookupStationID(code string) (*int64, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if code == "" {
+		return nil, nil
+	}
+	st, err := repository.GetStationByCode(code)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrInvalidStation
+		}
+		return nil, err
+	}
+	return &st.ID, nil
+}
@@ -0,0 +1,100 @@
+package service
+
+import (
+	"log"
+
+	"github.com/spf13/viper"
+
+	"campus-logistics/internal/apperr"
+	"campus-logistics/internal/model"
+	"campus-logistics/internal/repository"
+)
+
+// statsRollupLookbackDays 后台任务检查并补齐最近多少天的汇总，来自 stats.rollup_lookback_days，默认 7 天；
+// 更早的历史数据通过回填接口按需汇总
+func statsRollupLookbackDays() int {
+	if d := viper.GetInt("stats.rollup_lookback_days"); d > 0 {
+		return d
+	}
+	return 7
+}
+
+// RunDailyRollups 后台任务：补齐最近几天尚未最终汇总的日期，并刷新今天的汇总
+func RunDailyRollups() {
+	days, err := repository.ListStaleRollupDays(statsRollupLookbackDays())
+	if err != nil {
+		log.Printf("stats rollup failed: %v", err)
+		return
+	}
+	for _, day := range days {
+		if _, err := repository.RollupDailyStats(day); err != nil {
+			log.Printf("stats rollup failed: %v", err)
+		}
+	}
+}
+
+// StatsRollupRequest 按需回填每日统计汇总，日期包含两端
+type StatsRollupRequest struct {
+	From string `json:"from" binding:"required"`
+	To   string `json:"to" binding:"required"`
+}
+
+// StatsRollupResult 回填结果：汇总的天数与写入的汇总行数
+type StatsRollupResult struct {
+	Days int `json:"days"`
+	Rows int `json:"rows"`
+}
+
+// RollupDailyStats 逐天重算 [from, to] 的每日统计汇总；已汇总过的日期会被覆盖
+func RollupDailyStats(req StatsRollupRequest) (*StatsRollupResult, error) {
+	from, to, err := parseAnalyticsRange(req.From, req.To, "day")
+	if err != nil {
+		return nil, err
+	}
+
+	result := &StatsRollupResult{}
+	for day := from; day.Before(to); day = day.AddDate(0, 0, 1) {
+		n, err := repository.RollupDailyStats(day.Format("2006-01-02"))
+		if err != nil {
+			return nil, err
+		}
+		result.Days++
+		result.Rows += n
+	}
+	return result, nil
+}
+
+// DailyStatsResult 每日统计查询结果；MissingDays 为范围内尚未汇总的日期，可通过回填接口补齐
+type DailyStatsResult struct {
+	Stats       []model.DailyStat `json:"stats"`
+	MissingDays []string          `json:"missing_days"`
+}
+
+// GetDailyStats 查询每日统计汇总
+// from/to: 日期范围（包含两端，默认最近 7 天），groupBy: 空 / courier / zone，stationID: 0 表示全部站点
+func GetDailyStats(fromStr, toStr, groupBy string, stationID int64) (*DailyStatsResult, error) {
+	switch groupBy {
+	case "", "courier", "zone":
+	default:
+		return nil, apperr.Invalid("group_by", "oneof").WithParam("courier zone")
+	}
+	from, to, err := parseAnalyticsRange(fromStr, toStr, "day")
+	if err != nil {
+		return nil, err
+	}
+	fromDay, toDay := from.Format("2006-01-02"), to.AddDate(0, 0, -1).Format("2006-01-02")
+
+	stats, err := repository.GetDailyStats(fromDay, toDay, stationID, groupBy)
+	if err != nil {
+		return nil, err
+	}
+	for i := range stats {
+		s := &stats[i]
+		s.DwellHistogram = []int{s.Dwell1, s.Dwell2, s.Dwell3, s.Dwell4, s.Dwell5, s.Dwell6}
+	}
+	missing, err := repository.ListMissingRollupDays(fromDay, toDay)
+	if err != nil {
+		return nil, err
+	}
+	return &DailyStatsResult{Stats: stats, MissingDays: missing}, nil
+}
@@ -0,0 +1,50 @@
+package service
+
+import (
+	"testing"
+	"time"
+)
+
+// RFC 6238 附录 B 的 SHA-1 测试向量（密钥为 ASCII "12345678901234567890"），取 8 位结果的后 6 位
+func TestTOTPCodeRFC6238(t *testing.T) {
+	key := []byte("12345678901234567890")
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+	for _, tt := range tests {
+		if got := totpCode(key, tt.unix/totpPeriod); got != tt.want {
+			t.Errorf("totpCode(t=%d) = %s, want %s", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestVerifyTOTP(t *testing.T) {
+	secret := totpEncoding.EncodeToString([]byte("12345678901234567890"))
+	now := time.Unix(1111111111, 0)
+	tests := []struct {
+		name   string
+		code   string
+		wantOK bool
+		step   int64
+	}{
+		{"current step", "050471", true, 1111111111 / totpPeriod},
+		{"previous step within skew", "081804", true, 1111111109 / totpPeriod},
+		{"wrong code", "000000", false, 0},
+		{"wrong length", "05047", false, 0},
+		{"far away step", "287082", false, 0},
+	}
+	for _, tt := range tests {
+		step, ok := verifyTOTP(secret, tt.code, now)
+		if ok != tt.wantOK || step != tt.step {
+			t.Errorf("%s: verifyTOTP = (%d, %v), want (%d, %v)", tt.name, step, ok, tt.step, tt.wantOK)
+		}
+	}
+}
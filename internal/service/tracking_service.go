@@ -0,0 +1,164 @@
+package service
+
+import (
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"campus-logistics/internal/apperr"
+	"campus-logistics/internal/model"
+	"campus-logistics/internal/repository"
+	"campus-logistics/internal/tracking"
+	"campus-logistics/internal/validation"
+)
+
+// trackingRules 各快递公司的运单号规则缓存；多副本部署时其他副本在 trackingRulesTTL 内生效
+var (
+	trackingRules     = tracking.NewRegistry()
+	trackingRulesLoad sync.Mutex
+)
+
+const trackingRulesTTL = time.Minute
+
+// UpdateTrackingRuleRequest 修改运单号规则，未传的字段保持不变
+type UpdateTrackingRuleRequest struct {
+	// TrackingPattern 正则表达式，按整串匹配；空字符串表示不限制
+	TrackingPattern   *string `json:"tracking_pattern" binding:"omitempty,max=200,regexp"`
+	TrackingMinLength *int    `json:"tracking_min_length" binding:"omitempty,min=0,max=64"`
+	TrackingMaxLength *int    `json:"tracking_max_length" binding:"omitempty,min=0,max=64"`
+	// TrackingChecksum 校验位算法（s10、ems），空字符串表示不校验
+	TrackingChecksum *string `json:"tracking_checksum"`
+}
+
+func (r *UpdateTrackingRuleRequest) Normalize() {
+	if r.TrackingPattern != nil {
+		*r.TrackingPattern = strings.TrimSpace(*r.TrackingPattern)
+	}
+	if r.TrackingChecksum != nil {
+		*r.TrackingChecksum = strings.ToLower(validation.Text(*r.TrackingChecksum))
+	}
+}
+
+func courierRule(c model.Courier) tracking.Rule {
+	return tracking.Rule{
+		CourierCode: c.Code,
+		Pattern:     c.TrackingPattern,
+		MinLength:   c.TrackingMinLength,
+		MaxLength:   c.TrackingMaxLength,
+		Checksum:    c.TrackingChecksum,
+	}
+}
+
+// refreshTrackingRules 规则过期或 force 时从数据库重新加载
+func refreshTrackingRules(force bool) error {
+	if !force && !trackingRules.Stale(trackingRulesTTL) {
+		return nil
+	}
+	trackingRulesLoad.Lock()
+	defer trackingRulesLoad.Unlock()
+	if !force && !trackingRules.Stale(trackingRulesTTL) {
+		return nil
+	}
+
+	couriers, err := repository.ListCourierTrackingRules()
+	if err != nil {
+		return err
+	}
+	rules := make([]tracking.Rule, 0, len(couriers))
+	for _, c := range couriers {
+		r := courierRule(c)
+		if err := r.Compile(); err != nil {
+			// 库中的正则无法编译时忽略格式限制，长度与校验位仍然生效
+			log.Printf("invalid tracking pattern for courier %s: %v", c.Code, err)
+			r.Pattern = ""
+			_ = r.Compile()
+		}
+		rules = append(rules, r)
+	}
+	trackingRules.Replace(rules)
+	return nil
+}
+
+// checkTrackingNumber 按快递公司的规则校验运单号（长度、格式、校验位）
+func checkTrackingNumber(courierCode, trackingNumber string) error {
+	if err := refreshTrackingRules(false); err != nil {
+		return err
+	}
+	rule, ok := trackingRules.Get(courierCode)
+	if !ok {
+		// 新增的快递公司可能尚未加载
+		if err := refreshTrackingRules(true); err != nil {
+			return err
+		}
+		if rule, ok = trackingRules.Get(courierCode); !ok {
+			return apperr.New(apperr.CodeInvalidCourier)
+		}
+	}
+	if failed := rule.Check(trackingNumber); failed != "" {
+		return apperr.Invalid("tracking_number", failed)
+	}
+	return nil
+}
+
+// DetectCouriers 返回运单号符合其规则的快递公司代码；只有配置了格式或校验位的快递公司参与识别
+func DetectCouriers(trackingNumber string) ([]string, error) {
+	if err := refreshTrackingRules(false); err != nil {
+		return nil, err
+	}
+	return trackingRules.Detect(validation.Code(trackingNumber)), nil
+}
+
+// DetectCourier 根据运单号识别唯一的快递公司；无法识别或匹配多家时返回 COURIER_UNDETERMINED
+func DetectCourier(trackingNumber string) (string, error) {
+	codes, err := DetectCouriers(trackingNumber)
+	if err != nil {
+		return "", err
+	}
+	switch len(codes) {
+	case 1:
+		return codes[0], nil
+	case 0:
+		return "", apperr.New(apperr.CodeCourierUndetermined)
+	default:
+		return "", apperr.Newf(apperr.CodeCourierUndetermined, "%s", strings.Join(codes, ", "))
+	}
+}
+
+// UpdateCourierTrackingRule 管理员修改快递公司的运单号规则，修改后本副本立即生效
+func UpdateCourierTrackingRule(code string, req UpdateTrackingRuleRequest) (*model.Courier, error) {
+	c, err := repository.GetCourierByCode(code)
+	if err != nil {
+		return nil, err
+	}
+
+	rule := courierRule(*c)
+	if req.TrackingPattern != nil {
+		rule.Pattern = *req.TrackingPattern
+	}
+	if req.TrackingMinLength != nil {
+		rule.MinLength = *req.TrackingMinLength
+	}
+	if req.TrackingMaxLength != nil {
+		rule.MaxLength = *req.TrackingMaxLength
+	}
+	if req.TrackingChecksum != nil {
+		rule.Checksum = *req.TrackingChecksum
+	}
+
+	if !tracking.IsChecksum(rule.Checksum) {
+		return nil, &apperr.Error{Code: apperr.CodeValidationFailed, Fields: []apperr.FieldError{
+			{Field: "tracking_checksum", Rule: "oneof", Param: tracking.ChecksumS10 + " " + tracking.ChecksumEMS},
+		}}
+	}
+	if rule.MinLength > 0 && rule.MaxLength > 0 && rule.MinLength > rule.MaxLength {
+		return nil, apperr.Invalid("tracking_max_length", "tracking_length_range")
+	}
+
+	updated, err := repository.UpdateCourierTrackingRule(code, rule.Pattern, rule.MinLength, rule.MaxLength, rule.Checksum)
+	if err != nil {
+		return nil, err
+	}
+	trackingRules.Invalidate()
+	return updated, nil
+}
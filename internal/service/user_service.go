@@ -0,0 +1,273 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"campus-logistics/internal/apperr"
+	"campus-logistics/internal/model"
+	"campus-logistics/internal/repository"
+	"campus-logistics/internal/validation"
+)
+
+// UpdateProfileRequest 修改本人资料，未传的字段保持不变；学号、宿舍传空字符串表示清空
+type UpdateProfileRequest struct {
+	Name            *string `json:"name" binding:"omitempty,min=1,max=50"`
+	StudentID       *string `json:"student_id" binding:"omitempty,max=20"`
+	Dorm            *string `json:"dorm" binding:"omitempty,max=100"`
+	NotifyInbound   *bool   `json:"notify_inbound"`
+	NotifyRetention *bool   `json:"notify_retention"`
+}
+
+func (r *UpdateProfileRequest) Normalize() {
+	if r.Name != nil {
+		*r.Name = validation.Text(*r.Name)
+	}
+	if r.StudentID != nil {
+		*r.StudentID = validation.Code(*r.StudentID)
+	}
+	if r.Dorm != nil {
+		*r.Dorm = validation.Text(*r.Dorm)
+	}
+}
+
+// PhoneChangeRequest 申请更换手机号，验证码发送到新号码
+type PhoneChangeRequest struct {
+	Phone string `json:"phone" binding:"required,cnmobile"`
+}
+
+func (r *PhoneChangeRequest) Normalize() {
+	r.Phone = validation.Phone(r.Phone)
+}
+
+// ConfirmPhoneChangeRequest 提交新号码收到的验证码
+type ConfirmPhoneChangeRequest struct {
+	Code string `json:"code" binding:"required,len=6,numeric"`
+}
+
+func (r *ConfirmPhoneChangeRequest) Normalize() {
+	r.Code = validation.Text(r.Code)
+}
+
+// PhoneChangeResult 手机号变更完成：新的资料、从新号码下旧账号转移过来的包裹数与新 token
+type PhoneChangeResult struct {
+	User         *model.UserProfile `json:"user"`
+	ParcelsMoved int                `json:"parcels_moved"`
+	Token        *TokenResponse     `json:"token"`
+}
+
+// MergeUsersRequest 合并重复用户：source 的包裹等转移给 target 后删除 source
+type MergeUsersRequest struct {
+	SourcePhone string `json:"source_phone" binding:"required,cnmobile"`
+	TargetPhone string `json:"target_phone" binding:"required,cnmobile"`
+	Reason      string `json:"reason" binding:"max=200"`
+}
+
+func (r *MergeUsersRequest) Normalize() {
+	r.SourcePhone = validation.Phone(r.SourcePhone)
+	r.TargetPhone = validation.Phone(r.TargetPhone)
+	r.Reason = validation.Text(r.Reason)
+}
+
+// EraseUserRequest 删除学生个人信息（学生申请），按手机号指定
+type EraseUserRequest struct {
+	Phone  string `json:"phone" binding:"required,cnmobile"`
+	Reason string `json:"reason" binding:"max=200"`
+}
+
+func (r *EraseUserRequest) Normalize() {
+	r.Phone = validation.Phone(r.Phone)
+	r.Reason = validation.Text(r.Reason)
+}
+
+// phoneCodeTTL 手机号变更验证码有效期，来自 phone_change.code_ttl_minutes，默认 10 分钟
+func phoneCodeTTL() time.Duration {
+	if m := viper.GetInt("phone_change.code_ttl_minutes"); m > 0 {
+		return time.Duration(m) * time.Minute
+	}
+	return 10 * time.Minute
+}
+
+// phoneCodeMaxAttempts 验证码允许的失败次数，来自 phone_change.max_attempts，默认 5
+func phoneCodeMaxAttempts() int {
+	if n := viper.GetInt("phone_change.max_attempts"); n > 0 {
+		return n
+	}
+	return 5
+}
+
+// sendPhoneCode 发送验证码。尚未接入短信网关：只有开发环境开启 phone_change.log_codes 时写入服务日志
+// （手机号脱敏），否则返回 SMS_NOT_CONFIGURED；release 模式下忽略该开关，验证码绝不写入日志
+var sendPhoneCode = func(phone, code string) error {
+	if !phoneCodeLogEnabled() {
+		return apperr.New(apperr.CodeSMSNotConfigured)
+	}
+	log.Printf("[dev] phone change verification code for %s****%s: %s", phone[:3], phone[len(phone)-4:], code)
+	return nil
+}
+
+// phoneCodeLogEnabled 开发环境是否把验证码写入日志代替短信
+func phoneCodeLogEnabled() bool {
+	return viper.GetBool("phone_change.log_codes") && viper.GetString("server.mode") != "release"
+}
+
+func hashPhoneCode(code string) string {
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
+
+// GetProfile 查询本人资料
+func GetProfile(userID int64) (*model.UserProfile, error) {
+	u, err := repository.GetUserProfile(userID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, apperr.New(apperr.CodeUserNotFound)
+	}
+	return u, err
+}
+
+// UpdateProfile 修改本人资料；学号已被其他用户登记时返回字段错误
+func UpdateProfile(userID int64, req UpdateProfileRequest) (*model.UserProfile, error) {
+	u, err := repository.UpdateUserProfile(userID, repository.UserProfileUpdate{
+		Name:            req.Name,
+		StudentID:       req.StudentID,
+		Dorm:            req.Dorm,
+		NotifyInbound:   req.NotifyInbound,
+		NotifyRetention: req.NotifyRetention,
+	})
+	switch {
+	case errors.Is(err, repository.ErrDuplicate):
+		return nil, apperr.Invalid("student_id", "taken")
+	case errors.Is(err, repository.ErrNotFound):
+		return nil, apperr.New(apperr.CodeUserNotFound)
+	}
+	return u, err
+}
+
+// RequestPhoneChange 生成 6 位验证码并发送到新号码；重复申请会使之前的验证码失效
+func RequestPhoneChange(userID int64, req PhoneChangeRequest) (*model.PhoneChange, error) {
+	u, err := GetProfile(userID)
+	if err != nil {
+		return nil, err
+	}
+	if req.Phone == u.Phone {
+		return nil, apperr.Invalid("phone", "same_phone")
+	}
+	if !phoneCodeLogEnabled() {
+		return nil, apperr.New(apperr.CodeSMSNotConfigured)
+	}
+
+	n, err := rand.Int(rand.Reader, big.NewInt(1000000))
+	if err != nil {
+		return nil, err
+	}
+	code := fmt.Sprintf("%06d", n.Int64())
+	expiresAt := time.Now().Add(phoneCodeTTL())
+	if err := repository.SavePhoneChangeRequest(userID, req.Phone, hashPhoneCode(code), expiresAt); err != nil {
+		return nil, err
+	}
+	if err := sendPhoneCode(req.Phone, code); err != nil {
+		return nil, err
+	}
+	return &model.PhoneChange{NewPhone: req.Phone, ExpiresAt: expiresAt}, nil
+}
+
+// ConfirmPhoneChange 校验验证码并更换手机号；之后以新号码入库的包裹归属本人。
+// 新号码下已有入库时自动创建的账号会被合并到本人（包裹随之转移）
+func ConfirmPhoneChange(userID int64, req ConfirmPhoneChangeRequest) (*PhoneChangeResult, error) {
+	u, moved, err := repository.ConfirmPhoneChange(userID, hashPhoneCode(req.Code), phoneCodeMaxAttempts())
+	switch {
+	case errors.Is(err, repository.ErrNotFound), errors.Is(err, repository.ErrExpired):
+		return nil, apperr.New(apperr.CodeVerificationExpired)
+	case errors.Is(err, repository.ErrCodeMismatch):
+		return nil, apperr.New(apperr.CodeInvalidVerification)
+	case errors.Is(err, repository.ErrConflict):
+		return nil, apperr.New(apperr.CodePhoneInUse)
+	case err != nil:
+		return nil, err
+	}
+
+	tok, err := issueStudentToken(u.ID, u.Phone)
+	if err != nil {
+		return nil, err
+	}
+	return &PhoneChangeResult{User: u, ParcelsMoved: moved, Token: tok}, nil
+}
+
+// SearchUsers 管理员按手机号、学号或姓名查找用户，用于发现重复账号
+func SearchUsers(q string) ([]model.AdminUser, error) {
+	q = strings.TrimSpace(q)
+	if q == "" {
+		return nil, apperr.Invalid("q", "required")
+	}
+	if p := validation.Phone(q); validation.IsCNMobile(p) {
+		q = p
+	}
+	return repository.SearchUsers(q, 50)
+}
+
+// MergeUsers 合并重复用户，包裹转移逐个记入审计日志
+func MergeUsers(req MergeUsersRequest, operator string) (*model.UserMergeResult, error) {
+	if req.SourcePhone == req.TargetPhone {
+		return nil, apperr.Invalid("source_phone", "merge_self")
+	}
+	source, err := repository.GetUserByPhone(req.SourcePhone)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, apperr.New(apperr.CodeUserNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+	target, err := repository.GetUserByPhone(req.TargetPhone)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, apperr.New(apperr.CodeUserNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	moved, err := repository.MergeUsers(source.ID, target.ID, req.SourcePhone, operator, req.Reason)
+	if err != nil {
+		return nil, err
+	}
+	u, err := GetProfile(target.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &model.UserMergeResult{User: *u, ParcelsMoved: moved}, nil
+}
+
+// EraseUser 删除学生个人信息：资料与包裹、寄件订单上的快照匿名化，统计数据不变；
+// 仍有未取件包裹或未完成的寄件订单时返回 USER_HAS_ACTIVE_ITEMS
+func EraseUser(req EraseUserRequest, operator string) (*model.UserErasureResult, error) {
+	u, err := repository.GetUserByPhone(req.Phone)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, apperr.New(apperr.CodeUserNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	n, err := repository.EraseUser(u.ID, operator, "request", req.Reason)
+	if err != nil {
+		return nil, err
+	}
+	return &model.UserErasureResult{UserID: u.ID, ParcelsAnonymized: n}, nil
+}
+
+// ExportMyData 导出学生本人的全部记录
+func ExportMyData(userID int64) (*model.UserDataExport, error) {
+	e, err := repository.ExportUserData(userID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, apperr.New(apperr.CodeUserNotFound)
+	}
+	return e, err
+}
@@ -0,0 +1,84 @@
+// Package storage 附件（包裹照片、签名等）的对象存储抽象。
+// 支持本地文件系统与 S3 兼容存储（AWS S3、MinIO 等），由 attachments.storage.backend 选择。
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// ErrNotFound 对象不存在
+var ErrNotFound = errors.New("object not found")
+
+// Storage 对象存储，key 为 "/" 分隔的相对路径（如 parcels/12/inbound_photo/ab12.jpg）
+type Storage interface {
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	// Open 读取对象，不存在时返回 ErrNotFound；调用方负责关闭
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete 删除对象，对象不存在时不报错
+	Delete(ctx context.Context, key string) error
+}
+
+// Default 全局附件存储，由 Init 按配置创建
+var Default Storage
+
+// Init 按配置创建全局附件存储；启动时调用一次
+func Init() error {
+	s, err := New()
+	if err != nil {
+		return err
+	}
+	Default = s
+	return nil
+}
+
+// New 按 attachments.storage 配置创建存储：
+// local（默认）写入 attachments.storage.local.root；s3 使用 attachments.storage.s3 下的连接参数，
+// 访问密钥优先读取环境变量 S3_ACCESS_KEY / S3_SECRET_KEY
+func New() (Storage, error) {
+	switch backend := viper.GetString("attachments.storage.backend"); backend {
+	case "", "local":
+		root := viper.GetString("attachments.storage.local.root")
+		if root == "" {
+			root = "data/attachments"
+		}
+		return NewLocal(root)
+	case "s3":
+		return NewS3(S3Config{
+			Endpoint:  viper.GetString("attachments.storage.s3.endpoint"),
+			Region:    viper.GetString("attachments.storage.s3.region"),
+			Bucket:    viper.GetString("attachments.storage.s3.bucket"),
+			AccessKey: envOr("S3_ACCESS_KEY", viper.GetString("attachments.storage.s3.access_key")),
+			SecretKey: envOr("S3_SECRET_KEY", viper.GetString("attachments.storage.s3.secret_key")),
+			PathStyle: viper.GetBool("attachments.storage.s3.path_style"),
+		})
+	default:
+		return nil, fmt.Errorf("unknown attachments storage backend %q", backend)
+	}
+}
+
+func envOr(key, fallback string) string {
+	if v := strings.TrimSpace(os.Getenv(key)); v != "" {
+		return v
+	}
+	return fallback
+}
+
+// cleanKey 校验对象 key：必须是不含 ".." 的相对路径，防止越出存储根目录
+func cleanKey(key string) (string, error) {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return "", fmt.Errorf("invalid object key %q", key)
+	}
+	cleaned := path.Clean(key)
+	if cleaned != key || cleaned == "." || strings.HasPrefix(cleaned, "../") || cleaned == ".." {
+		return "", fmt.Errorf("invalid object key %q", key)
+	}
+	return cleaned, nil
+}
@@ -0,0 +1,67 @@
+package tracking
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Registry 按快递公司代码索引的运单号规则，整体替换、并发安全
+type Registry struct {
+	mu       sync.RWMutex
+	rules    map[string]*Rule
+	loadedAt time.Time
+}
+
+func NewRegistry() *Registry {
+	return &Registry{rules: map[string]*Rule{}}
+}
+
+// Replace 用新的规则集整体替换；规则需已 Compile
+func (reg *Registry) Replace(rules []Rule) {
+	m := make(map[string]*Rule, len(rules))
+	for i := range rules {
+		r := rules[i]
+		m[r.CourierCode] = &r
+	}
+	reg.mu.Lock()
+	reg.rules = m
+	reg.loadedAt = time.Now()
+	reg.mu.Unlock()
+}
+
+// Invalidate 标记规则需要重新加载（如管理员修改了规则）
+func (reg *Registry) Invalidate() {
+	reg.mu.Lock()
+	reg.loadedAt = time.Time{}
+	reg.mu.Unlock()
+}
+
+// Stale 距上次加载超过 ttl，或已被 Invalidate
+func (reg *Registry) Stale(ttl time.Duration) bool {
+	reg.mu.RLock()
+	defer reg.mu.RUnlock()
+	return reg.loadedAt.IsZero() || time.Since(reg.loadedAt) > ttl
+}
+
+func (reg *Registry) Get(courierCode string) (*Rule, bool) {
+	reg.mu.RLock()
+	defer reg.mu.RUnlock()
+	r, ok := reg.rules[courierCode]
+	return r, ok
+}
+
+// Detect 返回运单号完全符合其规则的快递公司代码（按代码排序）；
+// 只有配置了格式或校验位的规则参与识别
+func (reg *Registry) Detect(number string) []string {
+	reg.mu.RLock()
+	defer reg.mu.RUnlock()
+	var codes []string
+	for code, r := range reg.rules {
+		if r.distinctive() && r.Check(number) == "" {
+			codes = append(codes, code)
+		}
+	}
+	sort.Strings(codes)
+	return codes
+}
@@ -0,0 +1,66 @@
+package tracking
+
+import "testing"
+
+func TestValidS10(t *testing.T) {
+	tests := []struct {
+		number string
+		want   bool
+	}{
+		{"RA123456785CN", true},
+		{"EE123456785CN", true},
+		{"RR000000080CN", true}, // 11 - 和 % 11 = 10，校验位取 0
+		{"RR000000005CN", true}, // 11 - 和 % 11 = 11，校验位取 5
+		{"RA123456784CN", false},
+		{"RA12345678CN", false},
+		{"ra123456785CN", false},
+		{"RA1234567X5CN", false},
+		{"RA123456785C1", false},
+		{"1000000030001", false},
+	}
+	for _, tt := range tests {
+		if got := validS10(tt.number); got != tt.want {
+			t.Errorf("validS10(%q) = %v, want %v", tt.number, got, tt.want)
+		}
+	}
+}
+
+func TestValidEMS(t *testing.T) {
+	tests := []struct {
+		number string
+		want   bool
+	}{
+		{"1000000030001", true},
+		{"RA123456785CN", true},
+		{"RA123456784CN", false},
+		{"100000003000", false},
+		{"10000000300012", false},
+	}
+	for _, tt := range tests {
+		if got := validEMS(tt.number); got != tt.want {
+			t.Errorf("validEMS(%q) = %v, want %v", tt.number, got, tt.want)
+		}
+	}
+}
+
+func TestRuleCheck(t *testing.T) {
+	r := Rule{CourierCode: "EMS", Pattern: `[A-Z]{2}\d{9}[A-Z]{2}|\d{13}`, MinLength: 13, MaxLength: 13, Checksum: ChecksumEMS}
+	if err := r.Compile(); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		number string
+		want   string
+	}{
+		{"RA123456785CN", ""},
+		{"1000000030001", ""},
+		{"RA123456784CN", RuleChecksum},
+		{"RA1234567850N", RulePattern},
+		{"RA123456785", RuleLength},
+	}
+	for _, tt := range tests {
+		if got := r.Check(tt.number); got != tt.want {
+			t.Errorf("Check(%q) = %q, want %q", tt.number, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+// Package validation 注册 Gin 请求校验规则，并提供校验前的字段规范化。
+// 规范化（去空白、全角转半角、转大写）由各请求结构体的 Normalize 方法调用，
+// handler 先规范化再校验，保证入库的数据格式一致。
+package validation
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"github.com/spf13/viper"
+	"golang.org/x/text/width"
+)
+
+// 自定义校验标签
+const (
+	TagCNMobile       = "cnmobile"        // 大陆手机号
+	TagTrackingNumber = "tracking_number" // 运单号通用格式（各快递公司的规则另见 internal/tracking）
+	TagShelfCode      = "shelf_code"      // 货架编号
+	TagShelfZone      = "shelf_zone"      // 货架区域，取值受 validation.shelf_zones 白名单限制
+	TagRegexp         = "regexp"          // 合法的正则表达式
+)
+
+var (
+	cnMobileRe       = regexp.MustCompile(`^1[3-9]\d{9}$`)
+	trackingNumberRe = regexp.MustCompile(`^[A-Z0-9]{6,32}$`)
+	shelfCodeRe      = regexp.MustCompile(`^[A-Z][A-Z0-9]{0,9}(-[A-Z0-9]{1,9})?$`)
+	defaultZoneRe    = regexp.MustCompile(`^[A-Z]$`)
+)
+
+// Register 向 Gin 的校验器注册自定义规则，并让错误中的字段名使用 json 标签；启动时调用一次
+func Register() error {
+	v, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		return nil
+	}
+
+	v.RegisterTagNameFunc(func(f reflect.StructField) string {
+		name := strings.SplitN(f.Tag.Get("json"), ",", 2)[0]
+		if name == "-" {
+			return ""
+		}
+		if name == "" {
+			return f.Name
+		}
+		return name
+	})
+
+	rules := map[string]validator.Func{
+		TagCNMobile:       stringRule(IsCNMobile),
+		TagTrackingNumber: stringRule(trackingNumberRe.MatchString),
+		TagShelfCode:      stringRule(shelfCodeRe.MatchString),
+		TagShelfZone:      stringRule(IsAllowedZone),
+		TagRegexp:         stringRule(isRegexp),
+	}
+	for tag, fn := range rules {
+		if err := v.RegisterValidation(tag, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// stringRule 把字符串判断函数包装为校验规则；空值交给 required / omitempty 处理
+func stringRule(match func(string) bool) validator.Func {
+	return func(fl validator.FieldLevel) bool {
+		s := fl.Field().String()
+		return s == "" || match(s)
+	}
+}
+
+func IsCNMobile(s string) bool {
+	return cnMobileRe.MatchString(s)
+}
+
+// IsAllowedZone 区域在 validation.shelf_zones 白名单中；未配置白名单时允许单个大写字母
+func IsAllowedZone(zone string) bool {
+	zones := viper.GetStringSlice("validation.shelf_zones")
+	if len(zones) == 0 {
+		return defaultZoneRe.MatchString(zone)
+	}
+	for _, z := range zones {
+		if strings.EqualFold(strings.TrimSpace(z), zone) {
+			return true
+		}
+	}
+	return false
+}
+
+func isRegexp(s string) bool {
+	_, err := regexp.Compile(s)
+	return err == nil
+}
+
+// Text 去掉首尾空白并把全角字符转为半角
+func Text(s string) string {
+	return strings.TrimSpace(width.Narrow.String(s))
+}
+
+// Code 规范化编号类字段（运单号、快递公司代码、货架编号、站点编号等）：
+// 全角转半角、转大写并去掉其中的空白
+func Code(s string) string {
+	return strings.Join(strings.Fields(strings.ToUpper(Text(s))), "")
+}
+
+// Phone 规范化手机号：全角转半角，去掉空格、短横线和 +86 / 86 前缀
+func Phone(s string) string {
+	s = strings.NewReplacer(" ", "", "-", "").Replace(Text(s))
+	s = strings.TrimPrefix(s, "+")
+	if len(s) == 13 && strings.HasPrefix(s, "86") {
+		s = s[2:]
+	}
+	return s
+}
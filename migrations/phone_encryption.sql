@@ -0,0 +1,245 @@
+-- 手机号字段加密：已有数据库的表结构迁移 (新部署直接使用 init_full_schema.sql，无需执行)
+-- 适用于加密上线前 (手机号明文保存) 的数据库。执行后启动新版本服务：启动时加密 users 与代取授权中的明文手机号并补齐盲索引，
+-- 包裹、寄件订单与用户变更记录中的明文手机号由后台任务分批加密 (见 docs/restart_workflow.md 4.2)
+-- cat migrations/phone_encryption.sql | sudo docker-compose exec -T postgres psql -U campus_user -d campus_logistics
+
+BEGIN;
+
+ALTER TABLE users ALTER COLUMN phone DROP NOT NULL;
+ALTER TABLE users ADD COLUMN phone_enc TEXT, ADD COLUMN phone_hash CHAR(64) UNIQUE;
+
+ALTER TABLE parcels ADD COLUMN recipient_phone_enc TEXT, ADD COLUMN recipient_phone_masked VARCHAR(20);
+
+-- 密文长度超过 VARCHAR(20)
+ALTER TABLE pickup_delegations ALTER COLUMN delegate_phone TYPE TEXT;
+ALTER TABLE pickup_delegations ADD COLUMN delegate_phone_hash CHAR(64);
+DROP INDEX idx_delegations_delegate;
+CREATE INDEX idx_delegations_delegate ON pickup_delegations(delegate_phone_hash) WHERE revoked_at IS NULL;
+ALTER TABLE phone_change_requests ALTER COLUMN new_phone TYPE TEXT;
+ALTER TABLE user_change_logs ALTER COLUMN old_phone TYPE TEXT, ALTER COLUMN new_phone TYPE TEXT;
+ALTER TABLE shipments ALTER COLUMN sender_phone TYPE TEXT, ALTER COLUMN recipient_phone TYPE TEXT;
+
+-- 会话 / 事务设置 app.keep_updated_at = on 时保持不变 (密文重写、手机号脱敏等不属于业务修改的批量任务)
+CREATE OR REPLACE FUNCTION func_update_timestamp() RETURNS TRIGGER AS $$
+BEGIN
+    IF current_setting('app.keep_updated_at', true) = 'on' THEN
+        RETURN NEW;
+    END IF;
+    NEW.updated_at = NOW();
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+-- [4.2.1] 生效中的代取授权：返回被授权人 p_delegate_user_id 可代取包裹 p_parcel_id 的授权 ID，没有时返回 NULL
+-- 按被授权人当前手机号匹配，未撤销且处于时间窗口内
+CREATE OR REPLACE FUNCTION func_active_delegation(p_parcel_id BIGINT, p_delegate_user_id BIGINT) RETURNS BIGINT AS $$
+    SELECT d.id
+    FROM pickup_delegations d
+    JOIN parcels p ON p.id = p_parcel_id AND p.user_id = d.owner_id
+    JOIN users u ON u.id = p_delegate_user_id AND u.phone_hash = d.delegate_phone_hash
+    WHERE (d.parcel_id IS NULL OR d.parcel_id = p.id)
+      AND d.revoked_at IS NULL
+      AND NOW() >= d.starts_at AND NOW() < d.expires_at
+    ORDER BY d.id
+    LIMIT 1
+$$ LANGUAGE sql STABLE;
+
+DROP FUNCTION func_merge_users(BIGINT, BIGINT, VARCHAR, VARCHAR);
+-- [4.2.4] 合并重复用户：把 p_source 的包裹、代取授权、寄件订单转移给 p_target 并删除 p_source；
+-- 每个被转移的包裹写入一条 REASSIGN 审计日志，合并本身记入 user_change_logs (手机号密文由调用方传入 p_source_phone，未传入时使用被合并用户的密文)。
+-- 返回转移的包裹数
+CREATE OR REPLACE FUNCTION func_merge_users(p_source BIGINT, p_target BIGINT, p_operator VARCHAR, p_reason VARCHAR DEFAULT NULL,
+                                            p_source_phone VARCHAR DEFAULT NULL)
+RETURNS INT AS $$
+DECLARE
+    v_source users%ROWTYPE;
+    v_moved INT;
+BEGIN
+    IF p_source = p_target THEN
+        RAISE EXCEPTION 'cannot merge a user into itself' USING HINT = 'INVALID_REQUEST';
+    END IF;
+    -- 按 id 顺序加锁，避免并发合并死锁
+    PERFORM 1 FROM users WHERE id IN (p_source, p_target) ORDER BY id FOR UPDATE;
+    SELECT * INTO v_source FROM users WHERE id = p_source;
+    IF NOT FOUND OR NOT EXISTS (SELECT 1 FROM users WHERE id = p_target) THEN
+        RAISE EXCEPTION 'user not found' USING HINT = 'USER_NOT_FOUND';
+    END IF;
+
+    INSERT INTO parcel_audit_logs (parcel_id, action, old_status, new_status, operator, owner_user_id)
+    SELECT id, 'REASSIGN', status, status, p_operator, p_target FROM parcels WHERE user_id = p_source;
+    UPDATE parcels SET user_id = p_target WHERE user_id = p_source;
+    GET DIAGNOSTICS v_moved = ROW_COUNT;
+
+    UPDATE pickup_delegations SET owner_id = p_target WHERE owner_id = p_source;
+    UPDATE shipments SET user_id = p_target WHERE user_id = p_source;
+    DELETE FROM users WHERE id = p_source;
+
+    -- 保留用户缺少的资料用被合并用户的补齐 (学号唯一，需先删除被合并用户)
+    UPDATE users
+    SET student_id = COALESCE(student_id, v_source.student_id),
+        name = CASE WHEN name IS NULL OR name = '同学' THEN COALESCE(v_source.name, name) ELSE name END,
+        dorm = COALESCE(dorm, v_source.dorm)
+    WHERE id = p_target;
+
+    INSERT INTO user_change_logs (action, user_id, source_user_id, old_phone, parcels_moved, reason, operator)
+    VALUES ('MERGE', p_target, p_source, COALESCE(p_source_phone, v_source.phone_enc), v_moved, p_reason, p_operator);
+    RETURN v_moved;
+END;
+$$ LANGUAGE plpgsql;
+
+-- [4.2.5] 删除学生个人信息：users 行保留 (包裹、寄件订单的外键与统计口径不变)，
+-- 手机号 (密文、盲索引) 与学号、姓名、宿舍清空；包裹与寄件订单上的姓名 / 手机号快照清空，
+-- 代取授权、待验证的手机号变更删除，变更记录中的手机号清空。仍有未取件包裹或未完成的寄件订单时拒绝。
+-- 删除本身记入 user_erasure_logs，返回匿名化的包裹数
+CREATE OR REPLACE FUNCTION func_erase_user(p_user BIGINT, p_operator VARCHAR, p_source VARCHAR, p_reason VARCHAR DEFAULT NULL)
+RETURNS INT AS $$
+DECLARE
+    v_user users%ROWTYPE;
+    v_parcels INT;
+    v_shipments INT;
+BEGIN
+    SELECT * INTO v_user FROM users WHERE id = p_user FOR UPDATE;
+    IF NOT FOUND OR v_user.erased_at IS NOT NULL THEN
+        RAISE EXCEPTION 'user not found' USING HINT = 'USER_NOT_FOUND';
+    END IF;
+    IF EXISTS (SELECT 1 FROM parcels WHERE user_id = p_user AND status IN ('inbound', 'stored', 'pending'))
+       OR EXISTS (SELECT 1 FROM shipments WHERE user_id = p_user AND status IN ('created', 'dropped_off')) THEN
+        RAISE EXCEPTION 'user has parcels awaiting pickup or open shipments' USING HINT = 'USER_HAS_ACTIVE_ITEMS';
+    END IF;
+
+    -- 只修改快照列，状态不变，不会触发包裹审计
+    UPDATE parcels
+    SET recipient_name_snapshot = NULL, recipient_phone_snapshot = NULL, recipient_phone_enc = NULL, recipient_phone_masked = NULL
+    WHERE user_id = p_user;
+    GET DIAGNOSTICS v_parcels = ROW_COUNT;
+    UPDATE shipments
+    SET sender_name = '已删除', sender_phone = '-', recipient_name = '已删除', recipient_phone = '-', recipient_address = '已删除'
+    WHERE user_id = p_user;
+    GET DIAGNOSTICS v_shipments = ROW_COUNT;
+
+    DELETE FROM pickup_delegations WHERE owner_id = p_user OR delegate_phone_hash = v_user.phone_hash;
+    DELETE FROM phone_change_requests WHERE user_id = p_user;
+    UPDATE user_change_logs SET old_phone = NULL, new_phone = NULL WHERE user_id = p_user OR source_user_id = p_user;
+    UPDATE users
+    SET phone = NULL, phone_enc = NULL, phone_hash = NULL, student_id = NULL, name = NULL, dorm = NULL,
+        notify_inbound = FALSE, notify_retention = FALSE, erased_at = NOW()
+    WHERE id = p_user;
+
+    INSERT INTO user_erasure_logs (user_id, source, parcels_anonymized, shipments_anonymized, reason, operator)
+    VALUES (p_user, p_source, v_parcels, v_shipments, p_reason, p_operator);
+    RETURN v_parcels;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP PROCEDURE sp_parcel_inbound(VARCHAR, VARCHAR, VARCHAR, VARCHAR, VARCHAR, parcel_size, INT, BOOLEAN, BOOLEAN, BOOLEAN, BIGINT);
+-- [4.3] 智能入库存储过程 (事务原子性)
+CREATE OR REPLACE PROCEDURE sp_parcel_inbound(
+    p_tracking_no VARCHAR,
+    p_phone_hash VARCHAR,     -- 收件人手机号盲索引
+    p_phone_enc TEXT,         -- 收件人手机号密文
+    p_phone_masked VARCHAR,   -- 收件人手机号脱敏形式
+    p_courier_code VARCHAR,
+    p_user_name VARCHAR DEFAULT '同学',
+    p_station_code VARCHAR DEFAULT NULL,  -- 为空时仅在只有一个站点时使用该站点
+    p_size_class parcel_size DEFAULT 'small',
+    p_weight_grams INT DEFAULT NULL,
+    p_fragile BOOLEAN DEFAULT FALSE,
+    p_perishable BOOLEAN DEFAULT FALSE,
+    p_cold_chain BOOLEAN DEFAULT FALSE,
+    p_declared_value_cents BIGINT DEFAULT NULL
+)
+LANGUAGE plpgsql
+AS $$
+DECLARE
+    v_user_id BIGINT;
+    v_courier_id INT;
+    v_station_id INT;
+    v_shelf_id INT;
+    v_shelf_code VARCHAR;
+    v_pickup_code VARCHAR;
+BEGIN
+    -- A. 用户处理
+    SELECT id INTO v_user_id FROM users WHERE phone_hash = p_phone_hash;
+    IF v_user_id IS NULL THEN
+        INSERT INTO users (phone_hash, phone_enc, name) VALUES (p_phone_hash, p_phone_enc, p_user_name) RETURNING id INTO v_user_id;
+    END IF;
+
+    -- B. 快递商验证
+    SELECT id INTO v_courier_id FROM couriers WHERE code = p_courier_code;
+    IF v_courier_id IS NULL THEN
+        RAISE EXCEPTION '无效快递商: %', p_courier_code USING HINT = 'INVALID_COURIER';
+    END IF;
+
+    -- C. 站点解析
+    IF p_station_code IS NULL OR p_station_code = '' THEN
+        -- 多站点时不能替调用方猜测站点，必须显式指定
+        IF (SELECT COUNT(*) FROM stations) > 1 THEN
+            RAISE EXCEPTION '无效站点: 存在多个站点时必须指定站点编号' USING HINT = 'INVALID_STATION';
+        END IF;
+        SELECT id INTO v_station_id FROM stations;
+    ELSE
+        SELECT id INTO v_station_id FROM stations WHERE code = p_station_code;
+    END IF;
+    IF v_station_id IS NULL THEN
+        RAISE EXCEPTION '无效站点: %', p_station_code USING HINT = 'INVALID_STATION';
+    END IF;
+
+    -- D. 货架分配 (仅限本站点，行锁)
+    -- 尺寸不超过货架上限；冷链只放冷藏货架，其余优先常温货架；
+    -- 优先选择尺寸上限最小的货架，把大货架留给大件
+    SELECT id, code INTO v_shelf_id, v_shelf_code 
+    FROM shelves 
+    WHERE station_id = v_station_id AND current_load < capacity
+      AND max_size >= COALESCE(p_size_class, 'small')
+      AND (NOT COALESCE(p_cold_chain, FALSE) OR temperature = 'chilled')
+    ORDER BY (temperature = 'chilled') <> COALESCE(p_cold_chain, FALSE), max_size ASC, id ASC
+    LIMIT 1 FOR UPDATE;
+
+    IF v_shelf_id IS NULL THEN
+        -- 有空位但尺寸或温控不符时给出单独的错误码
+        IF EXISTS (SELECT 1 FROM shelves WHERE station_id = v_station_id AND current_load < capacity) THEN
+            RAISE EXCEPTION '没有符合尺寸或温控要求的货架' USING HINT = 'NO_SUITABLE_SHELF';
+        END IF;
+        RAISE EXCEPTION '仓库爆满，请扩容' USING HINT = 'WAREHOUSE_FULL';
+    END IF;
+
+    -- E. 生成取件码
+    v_pickup_code := v_shelf_code || '-' || LPAD(FLOOR(RANDOM() * 1000)::TEXT, 3, '0');
+
+    -- F. 落库
+    INSERT INTO parcels (tracking_number, user_id, courier_id, shelf_id, station_id, pickup_code, status,
+                         recipient_phone_enc, recipient_phone_masked,
+                         size_class, weight_grams, fragile, perishable, cold_chain, declared_value_cents)
+    VALUES (p_tracking_no, v_user_id, v_courier_id, v_shelf_id, v_station_id, v_pickup_code, 'stored',
+            p_phone_enc, p_phone_masked,
+            COALESCE(p_size_class, 'small'), p_weight_grams, COALESCE(p_fragile, FALSE),
+            COALESCE(p_perishable, FALSE) OR COALESCE(p_cold_chain, FALSE), COALESCE(p_cold_chain, FALSE), p_declared_value_cents);
+
+    -- G. 更新库存
+    UPDATE shelves SET current_load = current_load + 1 WHERE id = v_shelf_id;
+END;
+$$;
+
+DROP VIEW v_courier_tasks;
+-- [5.2] 快递员视图：只能看状态，不可看取件码
+CREATE OR REPLACE VIEW v_courier_tasks AS
+SELECT 
+    p.courier_id,
+    p.tracking_number,
+    -- 需要联系客户：密文 (加密迁移前为明文，已清理的为脱敏形式)，由应用层解密
+    COALESCE(p.recipient_phone_enc, p.recipient_phone_snapshot, p.recipient_phone_masked) AS phone,
+    p.recipient_phone_masked AS phone_masked,
+    p.status,
+    p.size_class, p.weight_grams, p.fragile, p.perishable, p.cold_chain,
+    st.code AS station_code, st.name AS station_name,
+    s.code AS shelf_code, s.zone AS shelf_zone, -- 货架位置，便于回答收件人询问
+    p.created_at,
+    p.picked_up_at,
+    -- 滞留时长：未取件的计到当前，已取件/退回/异常的计到取件或最后更新时间
+    EXTRACT(EPOCH FROM CASE WHEN p.status IN ('inbound', 'stored', 'pending') THEN NOW()
+                            ELSE COALESCE(p.picked_up_at, p.updated_at) END - p.created_at) AS dwell_seconds
+FROM parcels p
+JOIN stations st ON st.id = p.station_id
+LEFT JOIN shelves s ON s.id = p.shelf_id;
+
+COMMIT;
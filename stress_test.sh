@@ -63,7 +63,11 @@ run_with_concurrency() {
 # 入库压测：大量随机运单号入库
 inbound_once() {
   local idx=$1
-  local tracking="STRESS-$(date +%s%N)-$idx"
+  # 顺丰格式：SF + 13 位数字（纳秒时间戳后 8 位 + 5 位序号），满足运单号校验和快递公司规则
+  local ns
+  ns=$(date +%s%N)
+  local tracking
+  tracking=$(printf 'SF%s%05d' "${ns: -8}" "$idx")
   curl -s -X POST "$BASE_URL/api/v1/inbound" \
     -H "Content-Type: application/json" \
     -H "Authorization: Bearer $COURIER_TOKEN" \